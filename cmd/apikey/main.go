@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"tiny-bitly/internal/config"
+	"tiny-bitly/internal/dao"
+	"tiny-bitly/internal/service/apikey"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	var command = flag.String("command", "list", "API key command: create, list, revoke")
+	var owner = flag.String("owner", "", "Owner ID that the new key acts on behalf of (required for create)")
+	var name = flag.String("name", "", "Human-readable name for the new key (optional for create)")
+	var id = flag.Uint("id", 0, "ID of the key to revoke (required for revoke)")
+	flag.Parse()
+
+	// Load environment variables from .env file in development only.
+	if os.Getenv("ENV") != "production" {
+		if err := godotenv.Load(); err != nil {
+			slog.Warn("No .env file found, using environment variables", "error", err)
+		}
+	}
+
+	// Initialize config.
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		slog.Error("Failed to load config", "error", err)
+		os.Exit(1)
+	}
+
+	// Initialize logging.
+	initLogging(cfg)
+
+	// Initialize services.
+	appDAO, err := dao.NewDatabaseDAO(cfg.PostgresPort, cfg.PostgresDB, cfg.PostgresUser, cfg.PostgresPassword)
+	if err != nil {
+		slog.Error("Failed to initialize database DAO", "error", err)
+		os.Exit(1)
+	}
+	apiKeyService := apikey.NewService(*appDAO, cfg)
+
+	ctx := context.Background()
+
+	// Execute the command.
+	switch *command {
+	case "create":
+		created, err := apiKeyService.CreateAPIKey(ctx, *owner, *name)
+		if err != nil {
+			slog.Error("Failed to create API key", "error", err)
+			os.Exit(1)
+		}
+		// Print the key to stdout so that it can be captured. It cannot be
+		// retrieved again.
+		slog.Info("Created API key. Store it now; it will not be shown again.", "id", created.APIKey.ID, "owner", created.APIKey.OwnerID)
+		fmt.Println(created.Key)
+	case "list":
+		apiKeys, err := apiKeyService.ListAPIKeys(ctx)
+		if err != nil {
+			slog.Error("Failed to list API keys", "error", err)
+			os.Exit(1)
+		}
+		for _, apiKey := range apiKeys {
+			status := "active"
+			if apiKey.IsRevoked() {
+				status = "revoked"
+			}
+			fmt.Printf("%d\t%s...\t%s\t%s\t%s\t%s\n",
+				apiKey.ID,
+				apiKey.KeyPrefix,
+				apiKey.OwnerID,
+				apiKey.Name,
+				apiKey.CreatedAt.Format(time.RFC3339),
+				status,
+			)
+		}
+	case "revoke":
+		if *id == 0 {
+			slog.Error("ID is required. Use -id flag to specify the key to revoke")
+			os.Exit(1)
+		}
+		if err := apiKeyService.RevokeAPIKey(ctx, *id); err != nil {
+			slog.Error("Failed to revoke API key", "error", err, "id", *id)
+			os.Exit(1)
+		}
+		slog.Info("Successfully revoked API key", "id", *id)
+	default:
+		slog.Error("Unknown command", "command", *command)
+		os.Exit(1)
+	}
+}
+
+func initLogging(cfg *config.Config) {
+	// Log to stderr so that stdout carries only command output.
+	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: cfg.LogLevel,
+	})
+	slog.SetDefault(slog.New(handler))
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"tiny-bitly/internal/config"
+	"tiny-bitly/internal/dao"
+	"tiny-bitly/internal/service/domain"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	var command = flag.String("command", "list", "Domain command: add, list, remove")
+	var hostname = flag.String("hostname", "", "Hostname of the custom domain (required for add and remove)")
+	var owner = flag.String("owner", "", "Owner ID that may use the domain (optional for add; if omitted, every caller may use it)")
+	flag.Parse()
+
+	// Load environment variables from .env file in development only.
+	if os.Getenv("ENV") != "production" {
+		if err := godotenv.Load(); err != nil {
+			slog.Warn("No .env file found, using environment variables", "error", err)
+		}
+	}
+
+	// Initialize config.
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		slog.Error("Failed to load config", "error", err)
+		os.Exit(1)
+	}
+
+	// Initialize logging.
+	initLogging(cfg)
+
+	// Initialize services.
+	appDAO, err := dao.NewDatabaseDAO(cfg.PostgresPort, cfg.PostgresDB, cfg.PostgresUser, cfg.PostgresPassword)
+	if err != nil {
+		slog.Error("Failed to initialize database DAO", "error", err)
+		os.Exit(1)
+	}
+	domainService := domain.NewService(*appDAO, cfg)
+
+	ctx := context.Background()
+
+	// Execute the command.
+	switch *command {
+	case "add":
+		var ownerID *string
+		if *owner != "" {
+			ownerID = owner
+		}
+		added, err := domainService.AddDomain(ctx, *hostname, ownerID)
+		if err != nil {
+			slog.Error("Failed to add domain", "error", err, "hostname", *hostname)
+			os.Exit(1)
+		}
+		slog.Info("Successfully added domain", "hostname", added.Hostname)
+	case "list":
+		domains, err := domainService.ListDomains(ctx)
+		if err != nil {
+			slog.Error("Failed to list domains", "error", err)
+			os.Exit(1)
+		}
+		for _, d := range domains {
+			owner := "*"
+			if d.OwnerID != nil {
+				owner = *d.OwnerID
+			}
+			fmt.Printf("%s\t%s\t%s\n", d.Hostname, owner, d.CreatedAt.Format(time.RFC3339))
+		}
+	case "remove":
+		if *hostname == "" {
+			slog.Error("Hostname is required. Use -hostname flag to specify the domain to remove")
+			os.Exit(1)
+		}
+		if err := domainService.RemoveDomain(ctx, *hostname); err != nil {
+			slog.Error("Failed to remove domain", "error", err, "hostname", *hostname)
+			os.Exit(1)
+		}
+		slog.Info("Successfully removed domain", "hostname", *hostname)
+	default:
+		slog.Error("Unknown command", "command", *command)
+		os.Exit(1)
+	}
+}
+
+func initLogging(cfg *config.Config) {
+	// Log to stderr so that stdout carries only command output.
+	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: cfg.LogLevel,
+	})
+	slog.SetDefault(slog.New(handler))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"tiny-bitly/internal/cache"
+	"tiny-bitly/internal/config"
+	"tiny-bitly/internal/dao"
+	cacheDAO "tiny-bitly/internal/dao/cache"
+	"tiny-bitly/internal/service/purge"
+
+	"github.com/joho/godotenv"
+)
+
+// Purges expired and deleted URL records once and exits. Intended to run from
+// cron, with PURGE_INTERVAL_MILLIS=0 so that servers do not purge as well.
+func main() {
+	// Load environment variables from .env file in development only.
+	if os.Getenv("ENV") != "production" {
+		if err := godotenv.Load(); err != nil {
+			slog.Warn("No .env file found, using environment variables", "error", err)
+		}
+	}
+
+	// Initialize config.
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		slog.Error("Failed to load config", "error", err)
+		os.Exit(1)
+	}
+
+	// Initialize logging.
+	initLogging(cfg)
+
+	// Initialize services.
+	appDAO, err := dao.NewDatabaseDAO(cfg.PostgresPort, cfg.PostgresDB, cfg.PostgresUser, cfg.PostgresPassword)
+	if err != nil {
+		slog.Error("Failed to initialize database DAO", "error", err)
+		os.Exit(1)
+	}
+
+	// Evict purged records from Redis if it is available, so that servers stop
+	// serving them from the cache.
+	ctx := context.Background()
+	if err := cache.Init(ctx, cfg.RedisHost, cfg.RedisPort); err != nil {
+		slog.Warn("Redis initialization failed, purged records stay cached until they expire", "error", err)
+	} else if cachedDAO, err := cacheDAO.NewURLRecordCachedDAO(appDAO.URLRecordDAO); err == nil {
+		appDAO.SetURLRecordDAO(cachedDAO)
+	}
+	purgeService := purge.NewService(*appDAO, cfg)
+
+	purged, err := purgeService.PurgeExpired(ctx)
+	if err != nil {
+		slog.Error("Failed to purge expired URL records", "error", err, "purged", purged)
+		os.Exit(1)
+	}
+}
+
+func initLogging(cfg *config.Config) {
+	var handler slog.Handler
+	opts := &slog.HandlerOptions{
+		Level: cfg.LogLevel,
+	}
+	handler = slog.NewTextHandler(os.Stdout, opts)
+	slog.SetDefault(slog.New(handler))
+}
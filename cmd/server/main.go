@@ -14,17 +14,22 @@ import (
 	"tiny-bitly/internal/config"
 	"tiny-bitly/internal/dao"
 	cacheDAO "tiny-bitly/internal/dao/cache"
+	"tiny-bitly/internal/geoip"
 	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/router"
+	"tiny-bitly/internal/service/apikey"
 	"tiny-bitly/internal/service/create"
 	"tiny-bitly/internal/service/health"
+	"tiny-bitly/internal/service/list"
+	"tiny-bitly/internal/service/purge"
 	"tiny-bitly/internal/service/read"
-	versionService "tiny-bitly/internal/service/version"
+	"tiny-bitly/internal/service/remove"
+	"tiny-bitly/internal/service/update"
 	"tiny-bitly/internal/version"
 
 	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
@@ -71,15 +76,77 @@ func main() {
 		} else {
 			slog.Warn("Failed to create cached DAO, using database-only", "error", err)
 		}
+
+		// Store idempotency keys in Redis rather than in the database.
+		idempotencyKeyDAO, err := cacheDAO.NewIdempotencyKeyRedisDAO()
+		if err == nil {
+			appDAO.SetIdempotencyKeyDAO(idempotencyKeyDAO)
+			slog.Info("Idempotency keys stored in Redis")
+		} else {
+			slog.Warn("Failed to create Redis idempotency key DAO, using database", "error", err)
+		}
+
+		// Keep quota counters in Redis rather than in the database.
+		quotaDAO, err := cacheDAO.NewQuotaRedisDAO()
+		if err == nil {
+			appDAO.SetQuotaDAO(quotaDAO)
+			slog.Info("Quota counters stored in Redis")
+		} else {
+			slog.Warn("Failed to create Redis quota DAO, using database", "error", err)
+		}
+
+		// Keep failed password attempt counters in Redis rather than in the
+		// database.
+		passwordAttemptDAO, err := cacheDAO.NewPasswordAttemptRedisDAO()
+		if err == nil {
+			appDAO.SetPasswordAttemptDAO(passwordAttemptDAO)
+			slog.Info("Failed password attempt counters stored in Redis")
+		} else {
+			slog.Warn("Failed to create Redis password attempt DAO, using database", "error", err)
+		}
 	} else {
 		slog.Info("Using database-only DAO (Redis cache unavailable)")
 	}
+	apiKeyService := apikey.NewService(*appDAO, cfg)
 	createService := create.NewService(*appDAO, cfg)
 	readService := read.NewService(*appDAO, cfg)
+	listService := list.NewService(*appDAO, cfg)
+	updateService := update.NewService(*appDAO, cfg)
+	removeService := remove.NewService(*appDAO, cfg)
 	healthService := health.NewService(*appDAO)
 
-	router := buildRouter(createService, readService, healthService)
-	handler := middleware.RequestIDMiddleware(router)
+	// Purge expired records in the background, unless disabled in favor of
+	// running cmd/purge from cron. Instances purge concurrently without
+	// contending for rows.
+	purgeCtx, stopPurge := context.WithCancel(ctx)
+	defer stopPurge()
+	if cfg.PurgeInterval > 0 {
+		go purge.NewService(*appDAO, cfg).Run(purgeCtx)
+		slog.Info("Purging expired URL records in the background", "interval", cfg.PurgeInterval, "gracePeriod", cfg.PurgeGracePeriod)
+	}
+
+	// Send visitors of geo-targeted short URLs to the URL for their country,
+	// if a GeoIP database is configured. Pick up updates to the file in the
+	// background.
+	geoIPCtx, stopGeoIP := context.WithCancel(ctx)
+	defer stopGeoIP()
+	if cfg.GeoIPDatabasePath != "" {
+		geoIPDatabase, err := geoip.Open(cfg.GeoIPDatabasePath)
+		if err != nil {
+			logFatal("Failed to open GeoIP database", "path", cfg.GeoIPDatabasePath, "error", err)
+		}
+		defer geoIPDatabase.Close()
+		readService.SetGeoIPDatabase(geoIPDatabase)
+		go geoIPDatabase.Run(geoIPCtx, cfg.GeoIPReloadInterval)
+		slog.Info("Geo-targeting short URLs", "geoIPDatabasePath", cfg.GeoIPDatabasePath, "reloadInterval", cfg.GeoIPReloadInterval)
+	}
+
+	authenticate := func(next http.Handler) http.Handler {
+		return middleware.APIKeyAuthMiddleware(next, apiKeyService.Authenticate, cfg.RequireAPIKey)
+	}
+
+	mux := router.New(authenticate, createService, readService, listService, updateService, removeService, healthService)
+	handler := middleware.RequestIDMiddleware(mux)
 	handler = middleware.RateLimitMiddleware(handler, cfg.RateLimitRequestsPerSecond, cfg.RateLimitBurst)
 	handler = middleware.MetricsMiddleware(handler)
 	handler = middleware.SecurityMiddleware(handler)
@@ -179,24 +246,6 @@ func logVersionInfo() {
 	)
 }
 
-func buildRouter(createService *create.Service, readService *read.Service, healthService *health.Service) *http.ServeMux {
-	mux := http.NewServeMux()
-
-	// Health check endpoints
-	mux.HandleFunc("GET /health", health.NewGetHealthHandler(healthService))
-	mux.HandleFunc("GET /ready", health.NewGetReadyHandler(healthService))
-	mux.HandleFunc("GET /version", versionService.NewGetVersionHandler())
-
-	// Metrics endpoints
-	mux.Handle("GET /metrics", promhttp.Handler())
-
-	// Application endpoints
-	mux.HandleFunc("POST /urls", create.NewPostURLHandler(createService))
-	mux.HandleFunc("GET /{shortCode}", read.NewGetURLHandler(readService))
-
-	return mux
-}
-
 // Logs an error using structured logging and exits the program with code 1.
 func logFatal(msg string, args ...any) {
 	slog.Error(msg, args...)
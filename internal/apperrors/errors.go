@@ -5,29 +5,147 @@ import "errors"
 // Sentinel errors for system-level issues.
 // These can be checked using errors.Is(err, ErrShortCodeAlreadyInUse).
 var (
+	// Returned when an owner already has the maximum number of active short
+	// codes.
+	ErrActiveLinkQuotaExceeded = errors.New("active link quota exceeded")
+
 	// Returned when a custom alias is already in use.
 	ErrAliasAlreadyInUse = errors.New("alias already in use")
 
+	// Returned when no unrevoked API key has the provided ID.
+	ErrAPIKeyNotFound = errors.New("API key not found")
+
 	// Returned when a required configuration is missing.
 	ErrConfigurationMissing = errors.New("configuration missing")
 
+	// Returned when an owner has already created the maximum number of short
+	// codes for the day.
+	ErrDailyCreateQuotaExceeded = errors.New("daily create quota exceeded")
+
 	// Returned when the data store is not accessible.
 	ErrDataStoreUnavailable = errors.New("data store unavailable")
 
+	// Returned when a requested expiration time is not in the future.
+	ErrExpirationInPast = errors.New("expiration in past")
+
+	// Returned when a requested expiration falls outside the configured minimum
+	// and maximum TTL.
+	ErrExpirationOutOfRange = errors.New("expiration out of range")
+
+	// Returned when adding a domain whose hostname is already registered.
+	ErrDomainAlreadyExists = errors.New("domain already exists")
+
+	// Returned when no domain has the provided hostname.
+	ErrDomainNotFound = errors.New("domain not found")
+
+	// Returned when the password entered for a password-protected short code
+	// is wrong.
+	ErrIncorrectPassword = errors.New("incorrect password")
+
+	// Returned when an Idempotency-Key is reused while the original request is
+	// still in progress.
+	ErrIdempotencyKeyInProgress = errors.New("idempotency key in progress")
+
+	// Returned when an Idempotency-Key is reused with a different request.
+	ErrIdempotencyKeyMismatch = errors.New("idempotency key mismatch")
+
+	// Returned when a requested activation time is not before the expiration
+	// time.
+	ErrInvalidActivation = errors.New("invalid activation")
+
 	// Returned when the provided alias is invalid.
 	ErrInvalidAlias = errors.New("invalid alias")
 
+	// Returned when a batch request is empty or exceeds the maximum batch size.
+	ErrInvalidBatchSize = errors.New("invalid batch size")
+
+	// Returned when a per-country URL is for a malformed country code, or is
+	// itself invalid or too long.
+	ErrInvalidCountryURLs = errors.New("invalid country URLs")
+
+	// Returned when a listing cursor is malformed.
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	// Returned when the provided expiration is malformed, e.g. when both an
+	// absolute expiration time and a TTL are provided.
+	ErrInvalidExpiration = errors.New("invalid expiration")
+
+	// Returned when a requested domain is malformed, not registered, or not
+	// usable by the caller.
+	ErrInvalidDomain = errors.New("invalid domain")
+
+	// Returned when the provided fallback URL for expired short codes is
+	// invalid or too long.
+	ErrInvalidExpiredRedirectURL = errors.New("invalid expired redirect URL")
+
+	// Returned when a listing filter or page size is malformed.
+	ErrInvalidFilter = errors.New("invalid filter")
+
+	// Returned when a requested click limit is not positive.
+	ErrInvalidMaxClicks = errors.New("invalid max clicks")
+
+	// Returned when an Idempotency-Key header is too long.
+	ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+
+	// Returned when an API key owner ID is empty or too long.
+	ErrInvalidOwnerID = errors.New("invalid owner ID")
+
+	// Returned when a requested password is empty or too long.
+	ErrInvalidPassword = errors.New("invalid password")
+
+	// Returned when a per-platform URL is invalid, too long, or has a scheme
+	// that is not allowed for its platform.
+	ErrInvalidPlatformURL = errors.New("invalid platform URL")
+
+	// Returned when a query passthrough mode is not one of the supported modes.
+	ErrInvalidQueryPassthrough = errors.New("invalid query passthrough")
+
+	// Returned when there are too many redirect rules, or one has an empty or
+	// invalid condition or an invalid URL.
+	ErrInvalidRedirectRules = errors.New("invalid redirect rules")
+
+	// Returned when a requested redirect type is not a supported redirect
+	// status code, or is permanent for a short code that browsers must not
+	// cache.
+	ErrInvalidRedirectType = errors.New("invalid redirect type")
+
+	// Returned when an original URL contains an unknown or unclosed
+	// placeholder, or a template default is for an unknown placeholder.
+	ErrInvalidTemplate = errors.New("invalid template")
+
 	// Returned when the provided URL is invalid.
 	ErrInvalidURL = errors.New("invalid URL")
 
+	// Returned when the variants of a split test are too few or too many, or
+	// one has an invalid URL or weight, or sticky variants are requested
+	// without variants.
+	ErrInvalidVariants = errors.New("invalid variants")
+
 	// Returned when unable to generate a unique short code after maximum
 	// retries.
 	ErrMaxRetriesExceeded = errors.New("max retries exceeded")
 
+	// Returned when a short code or client has made too many failed password
+	// attempts recently.
+	ErrTooManyPasswordAttempts = errors.New("too many password attempts")
+
+	// Returned when an update request does not change any field.
+	ErrNoFieldsToUpdate = errors.New("no fields to update")
+
 	// Returned when attempting to create a URL record with a short code that is
 	// already in use by an active (not deleted and not expired) entity.
 	ErrShortCodeAlreadyInUse = errors.New("short code already in use")
 
+	// Returned when attempting to get a short code that has been deleted.
+	ErrShortCodeDeleted = errors.New("short code deleted")
+
+	// Returned when attempting to get a short code that has expired.
+	ErrShortCodeExpired = errors.New("short code expired")
+
+	// Returned when attempting to resolve a short code before its activation
+	// time.
+	ErrShortCodeNotYetActive = errors.New("short code not yet active")
+
 	// Returned when attempting to get a short code that does not exist.
 	ErrShortCodeNotFound = errors.New("short code not found")
 
@@ -8,9 +8,13 @@ import (
 var defaultAPIPort int = 8080
 var defaultAPIHostname string = fmt.Sprintf("http://localhost:%d", defaultAPIPort)
 var defaultLogLevel string = "info"
+var defaultDeduplicateURLs bool = false
+var defaultRedirectType int = 302
 var defaultMaxAliasLength int = 30
+var defaultMaxBatchSize int = 1000
 var defaultMaxRequestSizeBytes int = 1048576 // 1 MB, reasonable for a URL shortening service
 var defaultMaxTriesCreateShortCode int = 10
+var defaultRequireAPIKey bool = true
 var defaultMaxUrlLength int = 1000
 var defaultPostgresPort int = 5434
 var defaultPostgresDB string = ""
@@ -20,40 +24,83 @@ var defaultPostgresPassword string = ""
 var defaultRedisHost string = "localhost"
 var defaultRedisPort int = 6380
 
+var defaultGeoIPDatabasePath string = ""            // Geo-targeting disabled
+var defaultGeoIPReloadIntervalMillis int = 60000    // 1 minute in milliseconds
+var defaultIdempotencyKeyTtlMillis int = 86400000   // 24 hours in milliseconds
+var defaultLinkPasswordCookieSecret string = ""     // Generated at startup
+var defaultLinkPasswordCookieTtlMillis int = 900000 // 15 minutes in milliseconds
+var defaultLinkPasswordFailureWindowMillis int = 900000
+var defaultLinkPasswordMaxFailuresPerCode int = 10
+var defaultLinkPasswordMaxFailuresPerIP int = 20
+var defaultPurgeArchive bool = true
+var defaultPurgeBatchSize int = 1000
+var defaultPurgeGracePeriodMillis int = 604800000 // 7 days in milliseconds
+var defaultPurgeIntervalMillis int = 3600000      // 1 hour in milliseconds
+var defaultQuotaCreatesPerDay int = 0             // Unlimited
+var defaultQuotaMaxActiveLinks int = 0            // Unlimited
 var defaultRateLimitBurst int = 10
 var defaultRateLimitRequestsPerSecond int = 1
+var defaultRedirectCacheMaxAgeMillis int = 86400000 // 24 hours in milliseconds
+var defaultScheduledLinkPlaceholderURL string = ""  // Respond with 404 instead
 var defaultShortCodeLength int = 6
-var defaultShortCodeTtlMillis int = 157680000000 // 5 years in milliseconds
+var defaultShortCodeTtlMillis int = 157680000000    // 5 years in milliseconds
+var defaultShortCodeTtlMinMillis int = 60000        // 1 minute in milliseconds
+var defaultShortCodeTtlMaxMillis int = 315360000000 // 10 years in milliseconds
 var defaultTimeoutIdleMillis int = 60000
 var defaultTimeoutReadMillis int = 30000
 var defaultTimeoutRequestMillis int = 30000
 var defaultTimeoutShutdownMillis int = 30000
 var defaultTimeoutWriteMillis int = 30000
+var defaultTrustedProxies string = ""              // Proxy headers are ignored
+var defaultVariantCookieTtlMillis int = 2592000000 // 30 days in milliseconds
 
 // Returns a config object with sensible defaults in place for each key.
 func GetDefaultConfig() Config {
 	return Config{
-		APIPort:                    defaultAPIPort,
-		APIHostname:                defaultAPIHostname,
-		LogLevel:                   getLogLevelTyped(defaultLogLevel),
-		RateLimitRequestsPerSecond: defaultRateLimitRequestsPerSecond,
-		RateLimitBurst:             defaultRateLimitBurst,
-		MaxAliasLength:             defaultMaxAliasLength,
-		MaxRequestSizeBytes:        defaultMaxRequestSizeBytes,
-		MaxTriesCreateShortCode:    defaultMaxTriesCreateShortCode,
-		MaxURLLength:               defaultMaxUrlLength,
-		PostgresPort:               defaultPostgresPort,
-		PostgresDB:                 defaultPostgresDB,
-		PostgresUser:               defaultPostgresUser,
-		PostgresPassword:           defaultPostgresPassword,
-		RedisHost:                  defaultRedisHost,
-		RedisPort:                  defaultRedisPort,
-		ShortCodeLength:            defaultShortCodeLength,
-		ShortCodeTTL:               time.Duration(defaultShortCodeTtlMillis) * time.Millisecond,
-		IdleTimeout:                time.Duration(defaultTimeoutIdleMillis) * time.Millisecond,
-		ReadTimeout:                time.Duration(defaultTimeoutReadMillis) * time.Millisecond,
-		RequestTimeout:             time.Duration(defaultTimeoutRequestMillis) * time.Millisecond,
-		ShutdownTimeout:            time.Duration(defaultTimeoutShutdownMillis) * time.Millisecond,
-		WriteTimeout:               time.Duration(defaultTimeoutWriteMillis) * time.Millisecond,
+		APIPort:                        defaultAPIPort,
+		APIHostname:                    defaultAPIHostname,
+		LogLevel:                       getLogLevelTyped(defaultLogLevel),
+		RateLimitRequestsPerSecond:     defaultRateLimitRequestsPerSecond,
+		RateLimitBurst:                 defaultRateLimitBurst,
+		QuotaCreatesPerDay:             defaultQuotaCreatesPerDay,
+		QuotaMaxActiveLinks:            defaultQuotaMaxActiveLinks,
+		DeduplicateURLs:                defaultDeduplicateURLs,
+		DefaultRedirectType:            defaultRedirectType,
+		MaxAliasLength:                 defaultMaxAliasLength,
+		MaxBatchSize:                   defaultMaxBatchSize,
+		MaxRequestSizeBytes:            defaultMaxRequestSizeBytes,
+		MaxTriesCreateShortCode:        defaultMaxTriesCreateShortCode,
+		MaxURLLength:                   defaultMaxUrlLength,
+		RequireAPIKey:                  defaultRequireAPIKey,
+		PostgresPort:                   defaultPostgresPort,
+		PostgresDB:                     defaultPostgresDB,
+		PostgresUser:                   defaultPostgresUser,
+		PostgresPassword:               defaultPostgresPassword,
+		RedisHost:                      defaultRedisHost,
+		RedisPort:                      defaultRedisPort,
+		ShortCodeLength:                defaultShortCodeLength,
+		ShortCodeTTL:                   time.Duration(defaultShortCodeTtlMillis) * time.Millisecond,
+		ShortCodeTTLMin:                time.Duration(defaultShortCodeTtlMinMillis) * time.Millisecond,
+		ShortCodeTTLMax:                time.Duration(defaultShortCodeTtlMaxMillis) * time.Millisecond,
+		IdempotencyKeyTTL:              time.Duration(defaultIdempotencyKeyTtlMillis) * time.Millisecond,
+		LinkPasswordCookieSecret:       defaultLinkPasswordCookieSecret,
+		LinkPasswordCookieTTL:          time.Duration(defaultLinkPasswordCookieTtlMillis) * time.Millisecond,
+		LinkPasswordFailureWindow:      time.Duration(defaultLinkPasswordFailureWindowMillis) * time.Millisecond,
+		LinkPasswordMaxFailuresPerCode: defaultLinkPasswordMaxFailuresPerCode,
+		LinkPasswordMaxFailuresPerIP:   defaultLinkPasswordMaxFailuresPerIP,
+		ScheduledLinkPlaceholderURL:    defaultScheduledLinkPlaceholderURL,
+		RedirectCacheMaxAge:            time.Duration(defaultRedirectCacheMaxAgeMillis) * time.Millisecond,
+		GeoIPDatabasePath:              defaultGeoIPDatabasePath,
+		GeoIPReloadInterval:            time.Duration(defaultGeoIPReloadIntervalMillis) * time.Millisecond,
+		VariantCookieTTL:               time.Duration(defaultVariantCookieTtlMillis) * time.Millisecond,
+		PurgeArchive:                   defaultPurgeArchive,
+		PurgeBatchSize:                 defaultPurgeBatchSize,
+		PurgeGracePeriod:               time.Duration(defaultPurgeGracePeriodMillis) * time.Millisecond,
+		PurgeInterval:                  time.Duration(defaultPurgeIntervalMillis) * time.Millisecond,
+		IdleTimeout:                    time.Duration(defaultTimeoutIdleMillis) * time.Millisecond,
+		ReadTimeout:                    time.Duration(defaultTimeoutReadMillis) * time.Millisecond,
+		RequestTimeout:                 time.Duration(defaultTimeoutRequestMillis) * time.Millisecond,
+		ShutdownTimeout:                time.Duration(defaultTimeoutShutdownMillis) * time.Millisecond,
+		WriteTimeout:                   time.Duration(defaultTimeoutWriteMillis) * time.Millisecond,
 	}
 }
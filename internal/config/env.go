@@ -15,6 +15,23 @@ func getDurationEnvOrDefault(key string, defaultValue int) time.Duration {
 	return time.Duration(value) * time.Millisecond
 }
 
+// Retrieves a boolean environment variable, or returns the default if not set.
+// Accepts the values understood by strconv.ParseBool, e.g. "true" or "1".
+func getBoolEnvOrDefault(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
 // Retrieves an integer environment variable, or returns the default if not set.
 func getIntEnvOrDefault(key string, defaultValue int) int {
 	valueStr := os.Getenv(key)
@@ -3,7 +3,11 @@ package config
 import (
 	"fmt"
 	"log/slog"
+	"net/netip"
+	"strings"
 	"time"
+
+	"tiny-bitly/internal/model"
 )
 
 type Config struct {
@@ -12,8 +16,20 @@ type Config struct {
 	APIHostname string
 	LogLevel    slog.Leveler
 
+	// The addresses of the proxies in front of the server, whose
+	// X-Forwarded-For and X-Real-IP headers are trusted to name the client IP,
+	// and whose X-Forwarded-Host and Forwarded headers are trusted to name the
+	// host that redirects resolve short codes on
+	TrustedProxies []netip.Prefix
+
+	// Behavior
+	DeduplicateURLs     bool
+	DefaultRedirectType int // HTTP status code for short URLs without a redirectType
+	RequireAPIKey       bool
+
 	// Limits
 	MaxAliasLength          int
+	MaxBatchSize            int
 	MaxRequestSizeBytes     int
 	MaxTriesCreateShortCode int
 	MaxURLLength            int
@@ -33,13 +49,46 @@ type Config struct {
 	RateLimitRequestsPerSecond int
 	RateLimitBurst             int
 
+	// Quotas per API key owner (0 means unlimited)
+	QuotaCreatesPerDay  int
+	QuotaMaxActiveLinks int
+
+	// Password-protected links
+	LinkPasswordCookieSecret       string
+	LinkPasswordCookieTTL          time.Duration
+	LinkPasswordFailureWindow      time.Duration
+	LinkPasswordMaxFailuresPerCode int
+	LinkPasswordMaxFailuresPerIP   int
+
+	// Scheduled links
+	ScheduledLinkPlaceholderURL string
+
+	// The longest that browsers and CDNs may cache a temporary redirect
+	RedirectCacheMaxAge time.Duration
+
+	// Geo-targeted links (an empty GeoIPDatabasePath disables geo-targeting)
+	GeoIPDatabasePath   string
+	GeoIPReloadInterval time.Duration
+
+	// How long split-tested links with sticky variants remember a visitor's variant
+	VariantCookieTTL time.Duration
+
+	// Purging expired records (a PurgeInterval of 0 disables the in-process purger)
+	PurgeArchive     bool
+	PurgeBatchSize   int
+	PurgeGracePeriod time.Duration
+	PurgeInterval    time.Duration
+
 	// Timeouts
-	IdleTimeout     time.Duration
-	ReadTimeout     time.Duration
-	RequestTimeout  time.Duration
-	ShortCodeTTL    time.Duration
-	ShutdownTimeout time.Duration
-	WriteTimeout    time.Duration
+	IdempotencyKeyTTL time.Duration
+	IdleTimeout       time.Duration
+	ReadTimeout       time.Duration
+	RequestTimeout    time.Duration
+	ShortCodeTTL      time.Duration
+	ShortCodeTTLMin   time.Duration
+	ShortCodeTTLMax   time.Duration
+	ShutdownTimeout   time.Duration
+	WriteTimeout      time.Duration
 }
 
 // Loads environment variables and returns them into a central config object.
@@ -49,16 +98,53 @@ func LoadConfig() (*Config, error) {
 	defaultHostname := fmt.Sprintf("http://localhost:%d", port)
 	hostname := getStringEnvOrDefault("API_HOSTNAME", defaultHostname)
 	logLevel := getStringEnvOrDefault("LOG_LEVEL", defaultLogLevel)
+	trustedProxies, err := parsePrefixes(getStringEnvOrDefault("TRUSTED_PROXIES", defaultTrustedProxies))
+	if err != nil {
+		return nil, fmt.Errorf("TRUSTED_PROXIES must be a comma-separated list of IP addresses or CIDR ranges: %w", err)
+	}
 
 	rateLimitRPS := getIntEnvOrDefault("RATE_LIMIT_REQUESTS_PER_SECOND", defaultRateLimitRequestsPerSecond)
 	rateLimitBurst := getIntEnvOrDefault("RATE_LIMIT_BURST", defaultRateLimitBurst)
 
+	quotaCreatesPerDay := getIntEnvOrDefault("QUOTA_CREATES_PER_DAY", defaultQuotaCreatesPerDay)
+	quotaMaxActiveLinks := getIntEnvOrDefault("QUOTA_MAX_ACTIVE_LINKS", defaultQuotaMaxActiveLinks)
+
+	linkPasswordCookieSecret := getStringEnvOrDefault("LINK_PASSWORD_COOKIE_SECRET", defaultLinkPasswordCookieSecret)
+	linkPasswordCookieTTL := getDurationEnvOrDefault("LINK_PASSWORD_COOKIE_TTL_MILLIS", defaultLinkPasswordCookieTtlMillis)
+	linkPasswordFailureWindow := getDurationEnvOrDefault("LINK_PASSWORD_FAILURE_WINDOW_MILLIS", defaultLinkPasswordFailureWindowMillis)
+	linkPasswordMaxFailuresPerCode := getIntEnvOrDefault("LINK_PASSWORD_MAX_FAILURES_PER_CODE", defaultLinkPasswordMaxFailuresPerCode)
+	linkPasswordMaxFailuresPerIP := getIntEnvOrDefault("LINK_PASSWORD_MAX_FAILURES_PER_IP", defaultLinkPasswordMaxFailuresPerIP)
+
+	scheduledLinkPlaceholderURL := getStringEnvOrDefault("SCHEDULED_LINK_PLACEHOLDER_URL", defaultScheduledLinkPlaceholderURL)
+
+	redirectCacheMaxAge := getDurationEnvOrDefault("REDIRECT_CACHE_MAX_AGE_MILLIS", defaultRedirectCacheMaxAgeMillis)
+
+	geoIPDatabasePath := getStringEnvOrDefault("GEOIP_DATABASE_PATH", defaultGeoIPDatabasePath)
+	geoIPReloadInterval := getDurationEnvOrDefault("GEOIP_RELOAD_INTERVAL_MILLIS", defaultGeoIPReloadIntervalMillis)
+
+	variantCookieTTL := getDurationEnvOrDefault("VARIANT_COOKIE_TTL_MILLIS", defaultVariantCookieTtlMillis)
+
+	purgeArchive := getBoolEnvOrDefault("PURGE_ARCHIVE", defaultPurgeArchive)
+	purgeBatchSize := getIntEnvOrDefault("PURGE_BATCH_SIZE", defaultPurgeBatchSize)
+	purgeGracePeriod := getDurationEnvOrDefault("PURGE_GRACE_PERIOD_MILLIS", defaultPurgeGracePeriodMillis)
+	purgeInterval := getDurationEnvOrDefault("PURGE_INTERVAL_MILLIS", defaultPurgeIntervalMillis)
+
+	deduplicateURLs := getBoolEnvOrDefault("DEDUPLICATE_URLS", defaultDeduplicateURLs)
+	redirectType := getIntEnvOrDefault("DEFAULT_REDIRECT_TYPE", defaultRedirectType)
+	if !model.IsValidRedirectType(redirectType) {
+		return nil, fmt.Errorf("DEFAULT_REDIRECT_TYPE must be 301, 302, 307 or 308, got %d", redirectType)
+	}
+	requireAPIKey := getBoolEnvOrDefault("REQUIRE_API_KEY", defaultRequireAPIKey)
+
 	maxAliasLength := getIntEnvOrDefault("MAX_ALIAS_LENGTH", defaultMaxAliasLength)
+	maxBatchSize := getIntEnvOrDefault("MAX_BATCH_SIZE", defaultMaxBatchSize)
 	maxRequestSizeBytes := getIntEnvOrDefault("MAX_REQUEST_SIZE_BYTES", defaultMaxRequestSizeBytes)
 	maxTries := getIntEnvOrDefault("MAX_TRIES_CREATE_SHORT_CODE", defaultMaxTriesCreateShortCode)
 	maxURLLength := getIntEnvOrDefault("MAX_URL_LENGTH", defaultMaxUrlLength)
 	shortCodeLength := getIntEnvOrDefault("SHORT_CODE_LENGTH", defaultShortCodeLength)
 	shortCodeTTL := getDurationEnvOrDefault("SHORT_CODE_TTL_MILLIS", defaultShortCodeTtlMillis)
+	shortCodeTTLMin := getDurationEnvOrDefault("SHORT_CODE_TTL_MIN_MILLIS", defaultShortCodeTtlMinMillis)
+	shortCodeTTLMax := getDurationEnvOrDefault("SHORT_CODE_TTL_MAX_MILLIS", defaultShortCodeTtlMaxMillis)
 
 	postgresPort := getIntEnvOrDefault("POSTGRES_PORT", defaultPostgresPort)
 	postgresDB := getStringEnvOrDefault("POSTGRES_DB", defaultPostgresDB)
@@ -68,6 +154,7 @@ func LoadConfig() (*Config, error) {
 	redisHost := getStringEnvOrDefault("REDIS_HOST", defaultRedisHost)
 	redisPort := getIntEnvOrDefault("REDIS_PORT", defaultRedisPort)
 
+	idempotencyKeyTTL := getDurationEnvOrDefault("IDEMPOTENCY_KEY_TTL_MILLIS", defaultIdempotencyKeyTtlMillis)
 	idleTimeout := getDurationEnvOrDefault("TIMEOUT_IDLE_MILLIS", defaultTimeoutIdleMillis)
 	requestTimeout := getDurationEnvOrDefault("TIMEOUT_REQUEST_MILLIS", defaultTimeoutRequestMillis)
 	readTimeout := getDurationEnvOrDefault("TIMEOUT_READ_MILLIS", defaultTimeoutReadMillis)
@@ -79,10 +166,40 @@ func LoadConfig() (*Config, error) {
 		APIHostname: hostname,
 		LogLevel:    getLogLevelTyped(logLevel),
 
+		TrustedProxies: trustedProxies,
+
 		RateLimitRequestsPerSecond: rateLimitRPS,
 		RateLimitBurst:             rateLimitBurst,
 
+		QuotaCreatesPerDay:  quotaCreatesPerDay,
+		QuotaMaxActiveLinks: quotaMaxActiveLinks,
+
+		LinkPasswordCookieSecret:       linkPasswordCookieSecret,
+		LinkPasswordCookieTTL:          linkPasswordCookieTTL,
+		LinkPasswordFailureWindow:      linkPasswordFailureWindow,
+		LinkPasswordMaxFailuresPerCode: linkPasswordMaxFailuresPerCode,
+		LinkPasswordMaxFailuresPerIP:   linkPasswordMaxFailuresPerIP,
+
+		ScheduledLinkPlaceholderURL: scheduledLinkPlaceholderURL,
+
+		RedirectCacheMaxAge: redirectCacheMaxAge,
+
+		GeoIPDatabasePath:   geoIPDatabasePath,
+		GeoIPReloadInterval: geoIPReloadInterval,
+
+		VariantCookieTTL: variantCookieTTL,
+
+		PurgeArchive:     purgeArchive,
+		PurgeBatchSize:   purgeBatchSize,
+		PurgeGracePeriod: purgeGracePeriod,
+		PurgeInterval:    purgeInterval,
+
+		DeduplicateURLs:     deduplicateURLs,
+		DefaultRedirectType: redirectType,
+		RequireAPIKey:       requireAPIKey,
+
 		MaxAliasLength:          maxAliasLength,
+		MaxBatchSize:            maxBatchSize,
 		MaxRequestSizeBytes:     maxRequestSizeBytes,
 		MaxTriesCreateShortCode: maxTries,
 		MaxURLLength:            maxURLLength,
@@ -96,15 +213,45 @@ func LoadConfig() (*Config, error) {
 		RedisHost: redisHost,
 		RedisPort: redisPort,
 
-		IdleTimeout:     idleTimeout,
-		ReadTimeout:     readTimeout,
-		RequestTimeout:  requestTimeout,
-		ShortCodeTTL:    shortCodeTTL,
-		ShutdownTimeout: shutdownTimeout,
-		WriteTimeout:    writeTimeout,
+		IdempotencyKeyTTL: idempotencyKeyTTL,
+		IdleTimeout:       idleTimeout,
+		ReadTimeout:       readTimeout,
+		RequestTimeout:    requestTimeout,
+		ShortCodeTTL:      shortCodeTTL,
+		ShortCodeTTLMin:   shortCodeTTLMin,
+		ShortCodeTTLMax:   shortCodeTTLMax,
+		ShutdownTimeout:   shutdownTimeout,
+		WriteTimeout:      writeTimeout,
 	}, nil
 }
 
+// Parses a comma-separated list of IP addresses and CIDR ranges, e.g.
+// "10.0.0.0/8, 192.0.2.1". An address is parsed as a range that holds only
+// that address. Returns nil for an empty list.
+func parsePrefixes(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, value := range strings.Split(list, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if strings.Contains(value, "/") {
+			prefix, err := netip.ParsePrefix(value)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return prefixes, nil
+}
+
 func getLogLevelTyped(logLevel string) slog.Level {
 	if logLevel == "debug" {
 		return slog.LevelDebug
@@ -11,15 +11,72 @@ func GetTestConfig(cfg Config) Config {
 	if cfg.APIHostname != "" {
 		newCfg.APIHostname = cfg.APIHostname
 	}
+	if cfg.TrustedProxies != nil {
+		newCfg.TrustedProxies = cfg.TrustedProxies
+	}
 	if cfg.RateLimitRequestsPerSecond != 0 {
 		newCfg.RateLimitRequestsPerSecond = cfg.RateLimitRequestsPerSecond
 	}
 	if cfg.RateLimitBurst != 0 {
 		newCfg.RateLimitBurst = cfg.RateLimitBurst
 	}
+	if cfg.QuotaCreatesPerDay != 0 {
+		newCfg.QuotaCreatesPerDay = cfg.QuotaCreatesPerDay
+	}
+	if cfg.QuotaMaxActiveLinks != 0 {
+		newCfg.QuotaMaxActiveLinks = cfg.QuotaMaxActiveLinks
+	}
+	if cfg.LinkPasswordCookieSecret != "" {
+		newCfg.LinkPasswordCookieSecret = cfg.LinkPasswordCookieSecret
+	}
+	if cfg.LinkPasswordCookieTTL != 0 {
+		newCfg.LinkPasswordCookieTTL = cfg.LinkPasswordCookieTTL
+	}
+	if cfg.LinkPasswordFailureWindow != 0 {
+		newCfg.LinkPasswordFailureWindow = cfg.LinkPasswordFailureWindow
+	}
+	if cfg.LinkPasswordMaxFailuresPerCode != 0 {
+		newCfg.LinkPasswordMaxFailuresPerCode = cfg.LinkPasswordMaxFailuresPerCode
+	}
+	if cfg.LinkPasswordMaxFailuresPerIP != 0 {
+		newCfg.LinkPasswordMaxFailuresPerIP = cfg.LinkPasswordMaxFailuresPerIP
+	}
+	if cfg.ScheduledLinkPlaceholderURL != "" {
+		newCfg.ScheduledLinkPlaceholderURL = cfg.ScheduledLinkPlaceholderURL
+	}
+	if cfg.RedirectCacheMaxAge != 0 {
+		newCfg.RedirectCacheMaxAge = cfg.RedirectCacheMaxAge
+	}
+	if cfg.GeoIPDatabasePath != "" {
+		newCfg.GeoIPDatabasePath = cfg.GeoIPDatabasePath
+	}
+	if cfg.GeoIPReloadInterval != 0 {
+		newCfg.GeoIPReloadInterval = cfg.GeoIPReloadInterval
+	}
+	if cfg.VariantCookieTTL != 0 {
+		newCfg.VariantCookieTTL = cfg.VariantCookieTTL
+	}
+	if cfg.PurgeBatchSize != 0 {
+		newCfg.PurgeBatchSize = cfg.PurgeBatchSize
+	}
+	if cfg.PurgeGracePeriod != 0 {
+		newCfg.PurgeGracePeriod = cfg.PurgeGracePeriod
+	}
+	if cfg.PurgeInterval != 0 {
+		newCfg.PurgeInterval = cfg.PurgeInterval
+	}
+	if cfg.DeduplicateURLs {
+		newCfg.DeduplicateURLs = cfg.DeduplicateURLs
+	}
+	if cfg.DefaultRedirectType != 0 {
+		newCfg.DefaultRedirectType = cfg.DefaultRedirectType
+	}
 	if cfg.MaxAliasLength != 0 {
 		newCfg.MaxAliasLength = cfg.MaxAliasLength
 	}
+	if cfg.MaxBatchSize != 0 {
+		newCfg.MaxBatchSize = cfg.MaxBatchSize
+	}
 	if cfg.MaxRequestSizeBytes != 0 {
 		newCfg.MaxRequestSizeBytes = cfg.MaxRequestSizeBytes
 	}
@@ -44,6 +101,9 @@ func GetTestConfig(cfg Config) Config {
 	if cfg.ShortCodeLength != 0 {
 		newCfg.ShortCodeLength = cfg.ShortCodeLength
 	}
+	if cfg.IdempotencyKeyTTL != 0 {
+		newCfg.IdempotencyKeyTTL = cfg.IdempotencyKeyTTL
+	}
 	if cfg.IdleTimeout != 0 {
 		newCfg.IdleTimeout = cfg.IdleTimeout
 	}
@@ -56,6 +116,12 @@ func GetTestConfig(cfg Config) Config {
 	if cfg.ShortCodeTTL != 0 {
 		newCfg.ShortCodeTTL = cfg.ShortCodeTTL
 	}
+	if cfg.ShortCodeTTLMin != 0 {
+		newCfg.ShortCodeTTLMin = cfg.ShortCodeTTLMin
+	}
+	if cfg.ShortCodeTTLMax != 0 {
+		newCfg.ShortCodeTTLMax = cfg.ShortCodeTTLMax
+	}
 	if cfg.ShutdownTimeout != 0 {
 		newCfg.ShutdownTimeout = cfg.ShutdownTimeout
 	}
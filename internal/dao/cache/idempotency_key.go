@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	redisCache "tiny-bitly/internal/cache"
+	"tiny-bitly/internal/dao"
+	"tiny-bitly/internal/model"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Compile-time interface satisfaction check.
+var _ dao.IdempotencyKeyDAO = (*IdempotencyKeyRedisDAO)(nil)
+
+// IdempotencyKeyRedisDAO is a Redis implementation of IdempotencyKeyDAO. Unlike
+// URLRecordCachedDAO, Redis is the primary store here: Redis expires records
+// on its own, and idempotency keys need not outlive a Redis restart.
+type IdempotencyKeyRedisDAO struct {
+	redis *redis.Client
+}
+
+// NewIdempotencyKeyRedisDAO creates a new Redis DAO instance.
+func NewIdempotencyKeyRedisDAO() (*IdempotencyKeyRedisDAO, error) {
+	redisClient := redisCache.GetClient()
+	if redisClient == nil {
+		return nil, fmt.Errorf("Redis client not initialized")
+	}
+
+	return &IdempotencyKeyRedisDAO{redis: redisClient}, nil
+}
+
+func (d *IdempotencyKeyRedisDAO) Reserve(ctx context.Context, idempotencyKey model.IdempotencyKey) (*model.IdempotencyKey, error) {
+	ttl := time.Until(idempotencyKey.ExpiresAt)
+	if ttl <= 0 {
+		return nil, fmt.Errorf("idempotency key already expired")
+	}
+
+	data, err := json.Marshal(idempotencyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal idempotency key: %w", err)
+	}
+
+	// SET NX only succeeds if no live record has this key. Redis evicts
+	// expired records by itself.
+	reserved, err := d.redis.SetNX(ctx, d.getKey(idempotencyKey.Key), data, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	existing, err := d.get(ctx, idempotencyKey.Key)
+	if err == redis.Nil {
+		// The existing record expired in the meantime. Try again.
+		return d.Reserve(ctx, idempotencyKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+func (d *IdempotencyKeyRedisDAO) Complete(ctx context.Context, key string, statusCode int, responseBody []byte) error {
+	existing, err := d.get(ctx, key)
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	existing.StatusCode = statusCode
+	existing.ResponseBody = responseBody
+	data, err := json.Marshal(existing)
+	if err != nil {
+		return fmt.Errorf("failed to marshal idempotency key: %w", err)
+	}
+
+	// Keep the expiration set when the key was reserved. Only the request that
+	// reserved the key completes it, so this read-modify-write cannot race.
+	if err := d.redis.SetArgs(ctx, d.getKey(key), data, redis.SetArgs{Mode: "XX", KeepTTL: true}).Err(); err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+func (d *IdempotencyKeyRedisDAO) Release(ctx context.Context, key string) error {
+	existing, err := d.get(ctx, key)
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.IsCompleted() {
+		return nil
+	}
+
+	if err := d.redis.Del(ctx, d.getKey(key)).Err(); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// getKey returns the Redis key for an idempotency key.
+func (d *IdempotencyKeyRedisDAO) getKey(key string) string {
+	return fmt.Sprintf("idempotency:%s", key)
+}
+
+// get retrieves an idempotency key record from Redis. Returns redis.Nil if no
+// record exists.
+func (d *IdempotencyKeyRedisDAO) get(ctx context.Context, key string) (*model.IdempotencyKey, error) {
+	val, err := d.redis.Get(ctx, d.getKey(key)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var idempotencyKey model.IdempotencyKey
+	if err := json.Unmarshal([]byte(val), &idempotencyKey); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal idempotency key: %w", err)
+	}
+
+	return &idempotencyKey, nil
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	redisCache "tiny-bitly/internal/cache"
+	"tiny-bitly/internal/dao"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Compile-time interface satisfaction check.
+var _ dao.PasswordAttemptDAO = (*PasswordAttemptRedisDAO)(nil)
+
+// Increments the counter and starts its window of ARGV[1] milliseconds if the
+// counter is new. Returns the new count. Runs atomically in Redis.
+var incrementFailedAttemptsScript = redis.NewScript(`
+local attempts = redis.call("INCR", KEYS[1])
+if attempts == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return attempts
+`)
+
+// Decrements the counter if it exists and is positive, keeping its window.
+// Runs atomically in Redis, so that a counter that expires in the meantime is
+// not recreated without a window.
+var decrementFailedAttemptsScript = redis.NewScript(`
+local attempts = tonumber(redis.call("GET", KEYS[1]) or "0")
+if attempts > 0 then
+	redis.call("DECR", KEYS[1])
+end
+return 0
+`)
+
+// PasswordAttemptRedisDAO is a Redis implementation of PasswordAttemptDAO.
+// Like QuotaRedisDAO, Redis is the primary store here: losing the counters in
+// a Redis restart only resets the current windows.
+type PasswordAttemptRedisDAO struct {
+	redis *redis.Client
+}
+
+// NewPasswordAttemptRedisDAO creates a new Redis DAO instance.
+func NewPasswordAttemptRedisDAO() (*PasswordAttemptRedisDAO, error) {
+	redisClient := redisCache.GetClient()
+	if redisClient == nil {
+		return nil, fmt.Errorf("Redis client not initialized")
+	}
+
+	return &PasswordAttemptRedisDAO{redis: redisClient}, nil
+}
+
+func (d *PasswordAttemptRedisDAO) IncrementFailedAttempts(ctx context.Context, key string, window time.Duration) (int, error) {
+	attempts, err := incrementFailedAttemptsScript.Run(
+		ctx,
+		d.redis,
+		[]string{d.getKey(key)},
+		window.Milliseconds(),
+	).Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment failed password attempts: %w", err)
+	}
+
+	return attempts, nil
+}
+
+func (d *PasswordAttemptRedisDAO) DecrementFailedAttempts(ctx context.Context, key string) error {
+	if err := decrementFailedAttemptsScript.Run(ctx, d.redis, []string{d.getKey(key)}).Err(); err != nil {
+		return fmt.Errorf("failed to decrement failed password attempts: %w", err)
+	}
+
+	return nil
+}
+
+// getKey returns the Redis key for the failed attempts counted against a key.
+func (d *PasswordAttemptRedisDAO) getKey(key string) string {
+	return fmt.Sprintf("password:failures:%s", key)
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	redisCache "tiny-bitly/internal/cache"
+	"tiny-bitly/internal/dao"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Compile-time interface satisfaction check.
+var _ dao.QuotaDAO = (*QuotaRedisDAO)(nil)
+
+// How long a daily counter outlives the start of its day. Longer than a day so
+// that a counter is never evicted while requests may still count against it.
+const dailyCreatesTTL = 48 * time.Hour
+
+// Increments the counter by ARGV[1] unless the result would exceed ARGV[2].
+// Returns 1 if incremented, or 0 otherwise. Runs atomically in Redis.
+var incrementDailyCreatesScript = redis.NewScript(`
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+if current + tonumber(ARGV[1]) > tonumber(ARGV[2]) then
+	return 0
+end
+redis.call("INCRBY", KEYS[1], ARGV[1])
+redis.call("EXPIRE", KEYS[1], ARGV[3])
+return 1
+`)
+
+// QuotaRedisDAO is a Redis implementation of QuotaDAO. Like
+// IdempotencyKeyRedisDAO, Redis is the primary store here: losing the counters
+// in a Redis restart only resets the current day's quotas.
+type QuotaRedisDAO struct {
+	redis *redis.Client
+}
+
+// NewQuotaRedisDAO creates a new Redis DAO instance.
+func NewQuotaRedisDAO() (*QuotaRedisDAO, error) {
+	redisClient := redisCache.GetClient()
+	if redisClient == nil {
+		return nil, fmt.Errorf("Redis client not initialized")
+	}
+
+	return &QuotaRedisDAO{redis: redisClient}, nil
+}
+
+func (d *QuotaRedisDAO) IncrementDailyCreates(ctx context.Context, ownerID string, day string, n int, limit int) (bool, error) {
+	incremented, err := incrementDailyCreatesScript.Run(
+		ctx,
+		d.redis,
+		[]string{d.getKey(ownerID, day)},
+		n, limit, int(dailyCreatesTTL.Seconds()),
+	).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to increment daily creates: %w", err)
+	}
+
+	return incremented == 1, nil
+}
+
+func (d *QuotaRedisDAO) DecrementDailyCreates(ctx context.Context, ownerID string, day string, n int) error {
+	if err := d.redis.DecrBy(ctx, d.getKey(ownerID, day), int64(n)).Err(); err != nil {
+		return fmt.Errorf("failed to decrement daily creates: %w", err)
+	}
+
+	return nil
+}
+
+// getKey returns the Redis key for an owner's create count on a day.
+func (d *QuotaRedisDAO) getKey(ownerID string, day string) string {
+	return fmt.Sprintf("quota:creates:%s:%s", day, ownerID)
+}
@@ -14,6 +14,28 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// cacheTombstone is stored in place of a record after a write, so that reads
+// which loaded the record from the database before the write cannot cache
+// their stale copy afterwards.
+const cacheTombstone = "tombstone"
+
+// cacheTombstoneTTL is how long a tombstone blocks caching. It outlasts the
+// database query timeout, so any read still in flight when the write lands has
+// finished by the time the tombstone expires.
+const cacheTombstoneTTL = 10 * time.Second
+
+// Stores ARGV[2] in KEYS[1] with a TTL of ARGV[3] milliseconds, unless KEYS[1]
+// holds the tombstone ARGV[1]. Returns 1 if the value was stored and 0
+// otherwise. Runs atomically in Redis, so a write's tombstone cannot be
+// overwritten between the check and the set.
+var setUnlessTombstoneScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+return 1
+`)
+
 // URLRecordCachedDAO wraps a URLRecordDAO with Redis caching for read operations.
 type URLRecordCachedDAO struct {
 	underlying     dao.URLRecordDAO
@@ -35,13 +57,18 @@ func NewURLRecordCachedDAO(underlying dao.URLRecordDAO) (*URLRecordCachedDAO, er
 	}, nil
 }
 
-// Create delegates to the underlying DAO and invalidates the cache for the short code.
+// Create delegates to the underlying DAO, evicts any cached record whose short
+// code the new record reclaimed, and caches the new record.
 func (d *URLRecordCachedDAO) Create(ctx context.Context, urlRecord model.URLRecord) (*model.URLRecordEntity, error) {
 	entity, err := d.underlying.Create(ctx, urlRecord)
 	if err != nil {
 		return nil, err
 	}
 
+	// Evict the short code in case it was reclaimed from an expired record
+	// that is still cached.
+	d.evictAll(ctx, []*model.URLRecordEntity{entity})
+
 	// Cache the newly created record asynchronously (non-blocking - failures don't affect create).
 	// Fire-and-forget to avoid blocking the write response.
 	if entity != nil && !d.circuitBreaker.IsOpen() {
@@ -59,13 +86,45 @@ func (d *URLRecordCachedDAO) Create(ctx context.Context, urlRecord model.URLReco
 	return entity, nil
 }
 
+// CreateBatch delegates to the underlying DAO, evicts any cached records whose
+// short codes the new records reclaimed, and caches the new records.
+func (d *URLRecordCachedDAO) CreateBatch(ctx context.Context, urlRecords []model.URLRecord) ([]*model.URLRecordEntity, error) {
+	entities, err := d.underlying.CreateBatch(ctx, urlRecords)
+	if err != nil {
+		return nil, err
+	}
+
+	// Evict the short codes in case they were reclaimed, as in Create.
+	d.evictAll(ctx, entities)
+
+	// Cache the newly created records asynchronously, as in Create.
+	if !d.circuitBreaker.IsOpen() {
+		go func() {
+			// Use background context since this is async.
+			for _, entity := range entities {
+				if entity == nil {
+					continue
+				}
+				if err := d.setCache(context.Background(), entity); err != nil {
+					d.circuitBreaker.RecordFailure()
+					slog.Warn("Failed to cache created record", "error", err, "shortCode", entity.ShortCode, "circuitState", d.circuitBreaker.GetState())
+					return
+				}
+			}
+			d.circuitBreaker.RecordSuccess()
+		}()
+	}
+
+	return entities, nil
+}
+
 // GetByShortCode checks Redis cache first, then falls back to the underlying DAO.
 // Uses circuit breaker to prevent cascading failures when Redis is down.
-func (d *URLRecordCachedDAO) GetByShortCode(ctx context.Context, shortCode string) (*model.URLRecordEntity, error) {
+func (d *URLRecordCachedDAO) GetByShortCode(ctx context.Context, domain string, shortCode string) (*model.URLRecordEntity, error) {
 	// Check circuit breaker - if open, skip Redis and go straight to DB
 	if !d.circuitBreaker.IsOpen() {
 		// Try to get from cache first
-		cachedEntity, err := d.getFromCache(ctx, shortCode)
+		cachedEntity, err := d.getFromCache(ctx, domain, shortCode)
 		if err == nil && cachedEntity != nil {
 			// Cache hit - verify it hasn't expired
 			if !cachedEntity.IsExpired() {
@@ -73,7 +132,7 @@ func (d *URLRecordCachedDAO) GetByShortCode(ctx context.Context, shortCode strin
 				return cachedEntity, nil
 			}
 			// Expired - remove from cache and fall through to database
-			d.deleteFromCache(ctx, shortCode)
+			d.deleteFromCache(ctx, domain, shortCode)
 			d.circuitBreaker.RecordSuccess() // Cache operation succeeded
 		} else if err != redis.Nil {
 			// Redis error (not a cache miss) - record failure
@@ -91,7 +150,7 @@ func (d *URLRecordCachedDAO) GetByShortCode(ctx context.Context, shortCode strin
 	// Cache miss or expired - get from database
 	// Multiple concurrent requests for the same key may all hit the DB,
 	// but that's acceptable - the DB connection pool handles it efficiently.
-	entity, err := d.underlying.GetByShortCode(ctx, shortCode)
+	entity, err := d.underlying.GetByShortCode(ctx, domain, shortCode)
 	if err != nil {
 		return nil, err
 	}
@@ -109,19 +168,148 @@ func (d *URLRecordCachedDAO) GetByShortCode(ctx context.Context, shortCode strin
 	return entity, nil
 }
 
-// getCacheKey returns the Redis key for a short code.
-func (d *URLRecordCachedDAO) getCacheKey(shortCode string) string {
-	return fmt.Sprintf("url:%s", shortCode)
+// GetByOriginalURL delegates to the underlying DAO without caching, since the
+// lookup is not on the redirect path.
+func (d *URLRecordCachedDAO) GetByOriginalURL(ctx context.Context, originalURL string, domain string, ownerID *string) (*model.URLRecordEntity, error) {
+	return d.underlying.GetByOriginalURL(ctx, originalURL, domain, ownerID)
+}
+
+// GetByShortCodeIncludingInactive delegates to the underlying DAO without
+// caching, since inactive records are never cached and the lookup is not on
+// the redirect path.
+func (d *URLRecordCachedDAO) GetByShortCodeIncludingInactive(ctx context.Context, domain string, shortCode string, ownerID *string) (*model.URLRecordEntity, error) {
+	return d.underlying.GetByShortCodeIncludingInactive(ctx, domain, shortCode, ownerID)
+}
+
+// ConsumeClick delegates to the underlying DAO without caching, so that the
+// database's conditional update is the only count of a record's remaining
+// clicks.
+func (d *URLRecordCachedDAO) ConsumeClick(ctx context.Context, urlRecord *model.URLRecordEntity) (bool, error) {
+	return d.underlying.ConsumeClick(ctx, urlRecord)
+}
+
+// List delegates to the underlying DAO without caching, since listings are
+// not on the redirect path.
+func (d *URLRecordCachedDAO) List(ctx context.Context, params model.URLRecordListParams) ([]model.URLRecordEntity, error) {
+	return d.underlying.List(ctx, params)
+}
+
+// ReserveActiveLinks delegates to the underlying DAO without caching, since
+// the database's per-owner counter is the only count of active links.
+func (d *URLRecordCachedDAO) ReserveActiveLinks(ctx context.Context, ownerID string, n int, limit int) (bool, error) {
+	return d.underlying.ReserveActiveLinks(ctx, ownerID, n, limit)
+}
+
+// ReleaseActiveLinks delegates to the underlying DAO without caching, like
+// ReserveActiveLinks.
+func (d *URLRecordCachedDAO) ReleaseActiveLinks(ctx context.Context, ownerID string, n int) error {
+	return d.underlying.ReleaseActiveLinks(ctx, ownerID, n)
+}
+
+// Update delegates to the underlying DAO and then evicts the short code from
+// the cache, so that no instance keeps serving the previous destination.
+func (d *URLRecordCachedDAO) Update(ctx context.Context, domain string, shortCode string, ownerID *string, update model.URLRecordUpdate) (*model.URLRecordEntity, error) {
+	entity, err := d.underlying.Update(ctx, domain, shortCode, ownerID, update)
+	if err != nil {
+		return nil, err
+	}
+
+	d.invalidate(ctx, domain, shortCode)
+
+	return entity, nil
 }
 
-// getFromCache retrieves a URL record from Redis.
-func (d *URLRecordCachedDAO) getFromCache(ctx context.Context, shortCode string) (*model.URLRecordEntity, error) {
-	key := d.getCacheKey(shortCode)
+// Delete delegates to the underlying DAO and then evicts the short code from
+// the cache, so that every instance sharing the cache stops resolving it.
+func (d *URLRecordCachedDAO) Delete(ctx context.Context, domain string, shortCode string, ownerID *string) error {
+	if err := d.underlying.Delete(ctx, domain, shortCode, ownerID); err != nil {
+		return err
+	}
+
+	d.invalidate(ctx, domain, shortCode)
+
+	return nil
+}
+
+// PurgeExpired delegates to the underlying DAO and then evicts the purged short
+// codes from the cache.
+func (d *URLRecordCachedDAO) PurgeExpired(ctx context.Context, before time.Time, limit int, archive bool) ([]model.URLRecordEntity, error) {
+	purged, err := d.underlying.PurgeExpired(ctx, before, limit, archive)
+	if err != nil {
+		return nil, err
+	}
+
+	entities := make([]*model.URLRecordEntity, len(purged))
+	for i := range purged {
+		entities[i] = &purged[i]
+	}
+	d.evictAll(ctx, entities)
+
+	return purged, nil
+}
+
+// invalidate evicts a short code from the cache after a write by replacing it
+// with a tombstone. A concurrent read may have loaded the record from the
+// database just before the write; setCache will not overwrite the tombstone
+// with its stale copy.
+func (d *URLRecordCachedDAO) invalidate(ctx context.Context, domain string, shortCode string) {
+	// Evict even if the circuit is open: a stale entry would otherwise be
+	// served once Redis recovers.
+	if err := d.redis.Set(ctx, d.getCacheKey(domain, shortCode), cacheTombstone, cacheTombstoneTTL).Err(); err != nil {
+		d.circuitBreaker.RecordFailure()
+		slog.Warn("Failed to evict record from cache", "error", err, "shortCode", shortCode, "circuitState", d.circuitBreaker.GetState())
+	} else {
+		d.circuitBreaker.RecordSuccess()
+	}
+}
+
+// evictAll evicts the short codes of the provided records from the cache. Nil
+// entries are ignored.
+func (d *URLRecordCachedDAO) evictAll(ctx context.Context, entities []*model.URLRecordEntity) {
+	keys := make([]string, 0, len(entities))
+	for _, entity := range entities {
+		if entity != nil {
+			keys = append(keys, d.getCacheKey(entity.Domain, entity.ShortCode))
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	// Evict even if the circuit is open, as in invalidate. Use a single DEL so
+	// that large batches cost one round trip.
+	if err := d.redis.Del(ctx, keys...).Err(); err != nil {
+		d.circuitBreaker.RecordFailure()
+		slog.Warn("Failed to evict records from cache", "error", err, "count", len(keys), "circuitState", d.circuitBreaker.GetState())
+	} else {
+		d.circuitBreaker.RecordSuccess()
+	}
+}
+
+// getCacheKey returns the Redis key for a short code on a domain. Keeps the
+// original key format for the default domain, so that existing entries stay
+// valid.
+func (d *URLRecordCachedDAO) getCacheKey(domain string, shortCode string) string {
+	if domain == "" {
+		return fmt.Sprintf("url:%s", shortCode)
+	}
+	return fmt.Sprintf("url:%s/%s", domain, shortCode)
+}
+
+// getFromCache retrieves a URL record from Redis. Returns redis.Nil for a
+// tombstone, as for a missing key.
+func (d *URLRecordCachedDAO) getFromCache(ctx context.Context, domain string, shortCode string) (*model.URLRecordEntity, error) {
+	key := d.getCacheKey(domain, shortCode)
 	val, err := d.redis.Get(ctx, key).Result()
 	if err != nil {
 		return nil, err
 	}
+	if val == cacheTombstone {
+		return nil, redis.Nil
+	}
 
+	// Unmarshaling compiles the record's redirect rules, if any, so that cache
+	// hits can evaluate them right away.
 	var entity model.URLRecordEntity
 	if err := json.Unmarshal([]byte(val), &entity); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal cached record: %w", err)
@@ -130,16 +318,25 @@ func (d *URLRecordCachedDAO) getFromCache(ctx context.Context, shortCode string)
 	return &entity, nil
 }
 
-// setCache stores a URL record in Redis with TTL based on expiration time.
+// setCache stores a URL record in Redis with TTL based on expiration time,
+// unless a recent write left a tombstone for its short code.
 func (d *URLRecordCachedDAO) setCache(ctx context.Context, entity *model.URLRecordEntity) error {
-	key := d.getCacheKey(entity.ShortCode)
+	key := d.getCacheKey(entity.Domain, entity.ShortCode)
 
-	// Serialize the entity
+	// Serialize the entity, including every variant of a split-tested record
+	// and every redirect rule, so that cache hits can choose among them without
+	// loading them from the database.
 	data, err := json.Marshal(entity)
 	if err != nil {
 		return fmt.Errorf("failed to marshal entity for cache: %w", err)
 	}
 
+	// Never cache click-limited records: a cached copy of the remaining clicks
+	// would go stale with the first click.
+	if entity.IsClickLimited() {
+		return nil
+	}
+
 	// Calculate TTL - use the time until expires_at, with a minimum of 1 second
 	now := time.Now()
 	ttl := entity.ExpiresAt.Sub(now)
@@ -150,8 +347,17 @@ func (d *URLRecordCachedDAO) setCache(ctx context.Context, entity *model.URLReco
 	// Add a small buffer (1 second) to ensure we don't serve expired records
 	ttl = ttl + time.Second
 
-	// Set in Redis with TTL
-	if err := d.redis.Set(ctx, key, data, ttl).Err(); err != nil {
+	// Drop a scheduled record from the cache when it activates, so that the
+	// first clicks after launch see the record as it is in the database then,
+	// rather than a copy cached days before.
+	if entity.ActivatesAt != nil {
+		if untilActivation := entity.ActivatesAt.Sub(now); untilActivation > 0 && untilActivation < ttl {
+			ttl = untilActivation
+		}
+	}
+
+	// Set in Redis with TTL, leaving any tombstone in place
+	if err := setUnlessTombstoneScript.Run(ctx, d.redis, []string{key}, cacheTombstone, data, max(ttl.Milliseconds(), 1)).Err(); err != nil {
 		return fmt.Errorf("failed to set cache: %w", err)
 	}
 
@@ -159,8 +365,8 @@ func (d *URLRecordCachedDAO) setCache(ctx context.Context, entity *model.URLReco
 }
 
 // deleteFromCache removes a URL record from Redis.
-func (d *URLRecordCachedDAO) deleteFromCache(ctx context.Context, shortCode string) {
-	key := d.getCacheKey(shortCode)
+func (d *URLRecordCachedDAO) deleteFromCache(ctx context.Context, domain string, shortCode string) {
+	key := d.getCacheKey(domain, shortCode)
 	if err := d.redis.Del(ctx, key).Err(); err != nil {
 		slog.Warn("Failed to delete from cache", "error", err, "shortCode", shortCode)
 	}
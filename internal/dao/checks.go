@@ -10,4 +10,19 @@ import (
 var (
 	_ URLRecordDAO = (*database.URLRecordDatabaseDAO)(nil)
 	_ URLRecordDAO = (*memory.URLRecordMemoryDAO)(nil)
+
+	_ IdempotencyKeyDAO = (*database.IdempotencyKeyDatabaseDAO)(nil)
+	_ IdempotencyKeyDAO = (*memory.IdempotencyKeyMemoryDAO)(nil)
+
+	_ APIKeyDAO = (*database.APIKeyDatabaseDAO)(nil)
+	_ APIKeyDAO = (*memory.APIKeyMemoryDAO)(nil)
+
+	_ DomainDAO = (*database.DomainDatabaseDAO)(nil)
+	_ DomainDAO = (*memory.DomainMemoryDAO)(nil)
+
+	_ QuotaDAO = (*database.QuotaDatabaseDAO)(nil)
+	_ QuotaDAO = (*memory.QuotaMemoryDAO)(nil)
+
+	_ PasswordAttemptDAO = (*database.PasswordAttemptDatabaseDAO)(nil)
+	_ PasswordAttemptDAO = (*memory.PasswordAttemptMemoryDAO)(nil)
 )
@@ -1,39 +1,52 @@
 package dao
 
 import (
-	"context"
+	"fmt"
+	"log/slog"
+
 	"tiny-bitly/internal/dao/database"
 	"tiny-bitly/internal/dao/memory"
-	"tiny-bitly/internal/model"
+	"tiny-bitly/internal/db"
 )
 
 // DAO is the main Data-Access Object that contains all entity-specific DAOs.
 type DAO struct {
-	URLRecordDAO URLRecordDAO
-}
-
-// URLRecordDAO defines the interface for URL record data access operations.
-type URLRecordDAO interface {
-	Create(ctx context.Context, urlRecord model.URLRecord) (*model.URLRecordEntity, error)
-	GetByShortCode(ctx context.Context, shortCode string) (*model.URLRecordEntity, error)
+	URLRecordDAO       URLRecordDAO
+	IdempotencyKeyDAO  IdempotencyKeyDAO
+	APIKeyDAO          APIKeyDAO
+	DomainDAO          DomainDAO
+	QuotaDAO           QuotaDAO
+	PasswordAttemptDAO PasswordAttemptDAO
 }
 
 // NewMemoryDAO creates a new DAO instance using the in-memory implementation.
 // This is useful for testing and development.
 func NewMemoryDAO() *DAO {
 	return &DAO{
-		URLRecordDAO: memory.NewURLRecordMemoryDAO(),
+		URLRecordDAO:       memory.NewURLRecordMemoryDAO(),
+		IdempotencyKeyDAO:  memory.NewIdempotencyKeyMemoryDAO(),
+		APIKeyDAO:          memory.NewAPIKeyMemoryDAO(),
+		DomainDAO:          memory.NewDomainMemoryDAO(),
+		QuotaDAO:           memory.NewQuotaMemoryDAO(),
+		PasswordAttemptDAO: memory.NewPasswordAttemptMemoryDAO(),
 	}
 }
 
 // NewDatabaseDAO creates a new DAO instance using the database implementation.
+// All entity-specific DAOs share a single connection pool.
 func NewDatabaseDAO(dbPort int, dbName string, dbUser string, dbPassword string) (*DAO, error) {
-	urlRecordDAO, err := database.NewURLRecordDatabaseDAO(dbPort, dbName, dbUser, dbPassword)
+	dbConnection, err := db.OpenConnectionGORM(dbPort, dbName, dbUser, dbPassword)
 	if err != nil {
-		return nil, err
+		slog.Error("Failed to connect to database", "error", err, "dbPort", dbPort, "dbName", dbName, "dbUser", dbUser)
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 	return &DAO{
-		URLRecordDAO: urlRecordDAO,
+		URLRecordDAO:       database.NewURLRecordDatabaseDAO(dbConnection),
+		IdempotencyKeyDAO:  database.NewIdempotencyKeyDatabaseDAO(dbConnection),
+		APIKeyDAO:          database.NewAPIKeyDatabaseDAO(dbConnection),
+		DomainDAO:          database.NewDomainDatabaseDAO(dbConnection),
+		QuotaDAO:           database.NewQuotaDatabaseDAO(dbConnection),
+		PasswordAttemptDAO: database.NewPasswordAttemptDatabaseDAO(dbConnection),
 	}, nil
 }
 
@@ -42,3 +55,21 @@ func NewDatabaseDAO(dbPort int, dbName string, dbUser string, dbPassword string)
 func (d *DAO) SetURLRecordDAO(dao URLRecordDAO) {
 	d.URLRecordDAO = dao
 }
+
+// SetIdempotencyKeyDAO allows setting a custom IdempotencyKeyDAO
+// implementation. This is useful for storing idempotency keys in Redis.
+func (d *DAO) SetIdempotencyKeyDAO(dao IdempotencyKeyDAO) {
+	d.IdempotencyKeyDAO = dao
+}
+
+// SetQuotaDAO allows setting a custom QuotaDAO implementation. This is useful
+// for keeping usage counters in Redis.
+func (d *DAO) SetQuotaDAO(dao QuotaDAO) {
+	d.QuotaDAO = dao
+}
+
+// SetPasswordAttemptDAO allows setting a custom PasswordAttemptDAO
+// implementation. This is useful for keeping failed attempt counters in Redis.
+func (d *DAO) SetPasswordAttemptDAO(dao PasswordAttemptDAO) {
+	d.PasswordAttemptDAO = dao
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyDatabaseDAO is a database implementation of APIKeyDAO.
+type APIKeyDatabaseDAO struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyDatabaseDAO creates a new database DAO instance that uses the
+// provided connection.
+func NewAPIKeyDatabaseDAO(dbConnection *gorm.DB) *APIKeyDatabaseDAO {
+	return &APIKeyDatabaseDAO{db: dbConnection}
+}
+
+func (d *APIKeyDatabaseDAO) Create(ctx context.Context, apiKey model.APIKey) (*model.APIKey, error) {
+	// Add query timeout (5s for writes - they're typically fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := gorm.G[model.APIKey](d.db).Create(queryCtx, &apiKey); err != nil {
+		slog.Error("Failed to create API key in database", "error", err, "ownerID", apiKey.OwnerID)
+		return nil, fmt.Errorf("failed to create API key in database: %w", err)
+	}
+
+	return &apiKey, nil
+}
+
+func (d *APIKeyDatabaseDAO) GetByKeyHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	// Add query timeout (5s for reads - allows for slow queries under load while still failing fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	apiKey, err := gorm.G[model.APIKey](d.db).
+		Where("key_hash = ? AND revoked_at IS NULL", keyHash).
+		First(queryCtx)
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Not found is a normal case
+			return nil, nil
+		}
+		// Actual database error
+		slog.Error("Failed to query API key in database", "error", err)
+		return nil, fmt.Errorf("failed to query API key in database: %w", err)
+	}
+
+	return &apiKey, nil
+}
+
+func (d *APIKeyDatabaseDAO) List(ctx context.Context) ([]model.APIKey, error) {
+	// Add query timeout (5s for reads - allows for slow queries under load while still failing fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	apiKeys, err := gorm.G[model.APIKey](d.db).
+		Order("id").
+		Find(queryCtx)
+
+	if err != nil {
+		slog.Error("Failed to list API keys in database", "error", err)
+		return nil, fmt.Errorf("failed to list API keys in database: %w", err)
+	}
+
+	return apiKeys, nil
+}
+
+func (d *APIKeyDatabaseDAO) Revoke(ctx context.Context, id uint) error {
+	// Add query timeout (5s for writes - they're typically fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rowsAffected, err := gorm.G[model.APIKey](d.db).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update(queryCtx, "revoked_at", time.Now())
+
+	if err != nil {
+		slog.Error("Failed to revoke API key in database", "error", err, "id", id)
+		return fmt.Errorf("failed to revoke API key in database: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperrors.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
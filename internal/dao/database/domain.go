@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DomainDatabaseDAO is a database implementation of DomainDAO.
+type DomainDatabaseDAO struct {
+	db *gorm.DB
+}
+
+// NewDomainDatabaseDAO creates a new database DAO instance that uses the
+// provided connection.
+func NewDomainDatabaseDAO(dbConnection *gorm.DB) *DomainDatabaseDAO {
+	return &DomainDatabaseDAO{db: dbConnection}
+}
+
+func (d *DomainDatabaseDAO) Create(ctx context.Context, domain model.Domain) (*model.Domain, error) {
+	// Add query timeout (5s for writes - they're typically fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Use INSERT ... ON CONFLICT DO NOTHING to detect an existing hostname
+	// without a separate SELECT query.
+	result := d.db.WithContext(queryCtx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&domain)
+
+	if result.Error != nil {
+		slog.Error("Failed to create domain in database", "error", result.Error, "hostname", domain.Hostname)
+		return nil, fmt.Errorf("failed to create domain in database: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, apperrors.ErrDomainAlreadyExists
+	}
+
+	return &domain, nil
+}
+
+func (d *DomainDatabaseDAO) GetByHostname(ctx context.Context, hostname string) (*model.Domain, error) {
+	// Add query timeout (5s for reads - allows for slow queries under load while still failing fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	domain, err := gorm.G[model.Domain](d.db).
+		Where("hostname = ?", hostname).
+		First(queryCtx)
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Not found is a normal case
+			return nil, nil
+		}
+		// Actual database error
+		slog.Error("Failed to query domain in database", "error", err, "hostname", hostname)
+		return nil, fmt.Errorf("failed to query domain in database: %w", err)
+	}
+
+	return &domain, nil
+}
+
+func (d *DomainDatabaseDAO) List(ctx context.Context) ([]model.Domain, error) {
+	// Add query timeout (5s for reads - allows for slow queries under load while still failing fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	domains, err := gorm.G[model.Domain](d.db).
+		Order("hostname").
+		Find(queryCtx)
+
+	if err != nil {
+		slog.Error("Failed to list domains in database", "error", err)
+		return nil, fmt.Errorf("failed to list domains in database: %w", err)
+	}
+
+	return domains, nil
+}
+
+func (d *DomainDatabaseDAO) Delete(ctx context.Context, hostname string) error {
+	// Add query timeout (5s for writes - they're typically fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rowsAffected, err := gorm.G[model.Domain](d.db).
+		Where("hostname = ?", hostname).
+		Delete(queryCtx)
+
+	if err != nil {
+		slog.Error("Failed to delete domain in database", "error", err, "hostname", hostname)
+		return fmt.Errorf("failed to delete domain in database: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperrors.ErrDomainNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"tiny-bitly/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyKeyDatabaseDAO is a database implementation of IdempotencyKeyDAO.
+type IdempotencyKeyDatabaseDAO struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyKeyDatabaseDAO creates a new database DAO instance that uses
+// the provided connection.
+func NewIdempotencyKeyDatabaseDAO(dbConnection *gorm.DB) *IdempotencyKeyDatabaseDAO {
+	return &IdempotencyKeyDatabaseDAO{db: dbConnection}
+}
+
+func (d *IdempotencyKeyDatabaseDAO) Reserve(ctx context.Context, idempotencyKey model.IdempotencyKey) (*model.IdempotencyKey, error) {
+	// Add query timeout (5s for writes - they're typically fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Use INSERT ... ON CONFLICT DO UPDATE ... WHERE to insert the key, or to
+	// take over an existing record only if it has expired. Either way exactly
+	// one row is affected, so a concurrent request cannot reserve it as well.
+	result := d.db.WithContext(queryCtx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"fingerprint", "created_at", "expires_at", "status_code", "response_body",
+			}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Expr{SQL: "idempotency_keys.expires_at <= ?", Vars: []any{idempotencyKey.CreatedAt}},
+			}},
+		}).
+		Create(&idempotencyKey)
+
+	if result.Error != nil {
+		slog.Error("Failed to reserve idempotency key in database", "error", result.Error)
+		return nil, fmt.Errorf("failed to reserve idempotency key in database: %w", result.Error)
+	}
+
+	if result.RowsAffected > 0 {
+		return nil, nil
+	}
+
+	// A live record already has this key.
+	existing, err := gorm.G[model.IdempotencyKey](d.db).
+		Where("key = ?", idempotencyKey.Key).
+		First(queryCtx)
+	if err != nil {
+		slog.Error("Failed to query idempotency key from database", "error", err)
+		return nil, fmt.Errorf("failed to query idempotency key from database: %w", err)
+	}
+
+	return &existing, nil
+}
+
+func (d *IdempotencyKeyDatabaseDAO) Complete(ctx context.Context, key string, statusCode int, responseBody []byte) error {
+	// Add query timeout (5s for writes - they're typically fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result := d.db.WithContext(queryCtx).
+		Model(&model.IdempotencyKey{}).
+		Where("key = ?", key).
+		Updates(map[string]any{
+			"status_code":   statusCode,
+			"response_body": responseBody,
+		})
+
+	if result.Error != nil {
+		slog.Error("Failed to complete idempotency key in database", "error", result.Error)
+		return fmt.Errorf("failed to complete idempotency key in database: %w", result.Error)
+	}
+
+	return nil
+}
+
+func (d *IdempotencyKeyDatabaseDAO) Release(ctx context.Context, key string) error {
+	// Add query timeout (5s for writes - they're typically fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := gorm.G[model.IdempotencyKey](d.db).
+		Where("key = ? AND status_code = 0", key).
+		Delete(queryCtx)
+
+	if err != nil {
+		slog.Error("Failed to release idempotency key in database", "error", err)
+		return fmt.Errorf("failed to release idempotency key in database: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PasswordAttemptDatabaseDAO is a database implementation of
+// PasswordAttemptDAO.
+type PasswordAttemptDatabaseDAO struct {
+	db *gorm.DB
+}
+
+// NewPasswordAttemptDatabaseDAO creates a new database DAO instance that uses
+// the provided connection.
+func NewPasswordAttemptDatabaseDAO(dbConnection *gorm.DB) *PasswordAttemptDatabaseDAO {
+	return &PasswordAttemptDatabaseDAO{db: dbConnection}
+}
+
+func (d *PasswordAttemptDatabaseDAO) IncrementFailedAttempts(ctx context.Context, key string, window time.Duration) (int, error) {
+	// Add query timeout (5s for writes - they're typically fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Use INSERT ... ON CONFLICT DO UPDATE ... RETURNING so that the increment
+	// and the read happen in one statement. A window that has ended restarts
+	// at one attempt. Use raw SQL since the update expression refers to
+	// EXCLUDED.
+	now := time.Now()
+	var attempts int
+	err := d.db.WithContext(queryCtx).
+		Raw(`
+			INSERT INTO failed_password_attempts (key, attempts, window_expires_at)
+			VALUES (?, 1, ?)
+			ON CONFLICT (key) DO UPDATE
+			SET attempts = CASE WHEN failed_password_attempts.window_expires_at > ? THEN failed_password_attempts.attempts + 1 ELSE 1 END,
+				window_expires_at = CASE WHEN failed_password_attempts.window_expires_at > ? THEN failed_password_attempts.window_expires_at ELSE EXCLUDED.window_expires_at END
+			RETURNING attempts`,
+			key, now.Add(window), now, now,
+		).
+		Row().
+		Scan(&attempts)
+
+	if err != nil {
+		slog.Error("Failed to increment failed password attempts in database", "error", err)
+		return 0, fmt.Errorf("failed to increment failed password attempts in database: %w", err)
+	}
+
+	return attempts, nil
+}
+
+func (d *PasswordAttemptDatabaseDAO) DecrementFailedAttempts(ctx context.Context, key string) error {
+	// Add query timeout (5s for writes - they're typically fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Leave a window that has ended alone, so that the next attempt restarts
+	// it at one attempt.
+	err := d.db.WithContext(queryCtx).
+		Exec(`
+			UPDATE failed_password_attempts
+			SET attempts = attempts - 1
+			WHERE key = ? AND window_expires_at > ? AND attempts > 0`,
+			key, time.Now(),
+		).
+		Error
+
+	if err != nil {
+		slog.Error("Failed to decrement failed password attempts in database", "error", err)
+		return fmt.Errorf("failed to decrement failed password attempts in database: %w", err)
+	}
+
+	return nil
+}
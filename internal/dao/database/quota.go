@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// QuotaDatabaseDAO is a database implementation of QuotaDAO.
+type QuotaDatabaseDAO struct {
+	db *gorm.DB
+}
+
+// NewQuotaDatabaseDAO creates a new database DAO instance that uses the
+// provided connection.
+func NewQuotaDatabaseDAO(dbConnection *gorm.DB) *QuotaDatabaseDAO {
+	return &QuotaDatabaseDAO{db: dbConnection}
+}
+
+func (d *QuotaDatabaseDAO) IncrementDailyCreates(ctx context.Context, ownerID string, day string, n int, limit int) (bool, error) {
+	if n > limit {
+		return false, nil
+	}
+
+	// Add query timeout (5s for writes - they're typically fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Use INSERT ... ON CONFLICT DO UPDATE ... WHERE so that the check and the
+	// increment happen in one statement. The row lock taken on conflict makes
+	// concurrent increments for the same owner and day wait for each other.
+	// Use raw SQL since the update expression refers to EXCLUDED.
+	result := d.db.WithContext(queryCtx).Exec(`
+		INSERT INTO owner_daily_creates (owner_id, day, creates)
+		VALUES (?, ?, ?)
+		ON CONFLICT (owner_id, day) DO UPDATE
+		SET creates = owner_daily_creates.creates + EXCLUDED.creates
+		WHERE owner_daily_creates.creates + EXCLUDED.creates <= ?`,
+		ownerID, day, n, limit,
+	)
+
+	if result.Error != nil {
+		slog.Error("Failed to increment daily creates in database", "error", result.Error, "ownerID", ownerID)
+		return false, fmt.Errorf("failed to increment daily creates in database: %w", result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (d *QuotaDatabaseDAO) DecrementDailyCreates(ctx context.Context, ownerID string, day string, n int) error {
+	// Add query timeout (5s for writes - they're typically fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result := d.db.WithContext(queryCtx).Exec(`
+		UPDATE owner_daily_creates
+		SET creates = GREATEST(creates - ?, 0)
+		WHERE owner_id = ? AND day = ?`,
+		n, ownerID, day,
+	)
+
+	if result.Error != nil {
+		slog.Error("Failed to decrement daily creates in database", "error", result.Error, "ownerID", ownerID)
+		return fmt.Errorf("failed to decrement daily creates in database: %w", result.Error)
+	}
+
+	return nil
+}
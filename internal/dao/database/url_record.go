@@ -5,10 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
 	"tiny-bitly/internal/apperrors"
-	"tiny-bitly/internal/db"
 	"tiny-bitly/internal/model"
 
 	"gorm.io/gorm"
@@ -20,14 +22,10 @@ type URLRecordDatabaseDAO struct {
 	db *gorm.DB
 }
 
-// NewURLRecordDatabaseDAO creates a new database DAO instance.
-func NewURLRecordDatabaseDAO(dbPort int, dbName string, dbUser string, dbPassword string) (*URLRecordDatabaseDAO, error) {
-	dbConnection, err := db.OpenConnectionGORM(dbPort, dbName, dbUser, dbPassword)
-	if err != nil {
-		slog.Error("Failed to connect to database", "error", err, "dbPort", dbPort, "dbName", dbName, "dbUser", dbUser)
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
-	}
-	return &URLRecordDatabaseDAO{db: dbConnection}, nil
+// NewURLRecordDatabaseDAO creates a new database DAO instance that uses the
+// provided connection.
+func NewURLRecordDatabaseDAO(dbConnection *gorm.DB) *URLRecordDatabaseDAO {
+	return &URLRecordDatabaseDAO{db: dbConnection}
 }
 
 func (d *URLRecordDatabaseDAO) Create(ctx context.Context, urlRecord model.URLRecord) (*model.URLRecordEntity, error) {
@@ -35,50 +33,316 @@ func (d *URLRecordDatabaseDAO) Create(ctx context.Context, urlRecord model.URLRe
 	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	// Create new entity
-	entity := model.URLRecordEntity{
-		Entity:    model.Entity{},
-		URLRecord: urlRecord,
-	}
-
-	// Use INSERT ... ON CONFLICT DO NOTHING. This detect conflicts without a
-	// separate SELECT query. Use the traditional API for Clauses since generics
-	// API doesn't support it directly.
-	result := d.db.WithContext(queryCtx).
-		Model(&entity).
-		Clauses(clause.OnConflict{
-			Columns:   []clause.Column{{Name: "short_code"}},
-			DoNothing: true,
-		}).
-		Create(&entity)
-
-	if result.Error != nil {
+	inserted, err := d.insertReclaiming(queryCtx, []model.URLRecord{urlRecord})
+	if err != nil {
 		slog.Error(
 			"Failed to create record in database",
-			"error", result.Error,
+			"error", err,
 			"originalUrl", urlRecord.OriginalURL,
 			"shortCode", urlRecord.ShortCode,
 		)
-		return nil, fmt.Errorf("failed to create record in database: %w", result.Error)
+		return nil, fmt.Errorf("failed to create record in database: %w", err)
 	}
 
-	if result.RowsAffected == 0 {
-		// An existing record already has the target short_code.
+	if len(inserted) == 0 {
+		// An active record already has the target short_code.
 		return nil, apperrors.ErrShortCodeAlreadyInUse
 	}
 
-	return &entity, nil
+	return &inserted[0], nil
 }
 
-func (d *URLRecordDatabaseDAO) GetByShortCode(ctx context.Context, shortCode string) (*model.URLRecordEntity, error) {
+func (d *URLRecordDatabaseDAO) CreateBatch(ctx context.Context, urlRecords []model.URLRecord) ([]*model.URLRecordEntity, error) {
+	if len(urlRecords) == 0 {
+		return []*model.URLRecordEntity{}, nil
+	}
+
+	// Add query timeout (5s for writes - they're typically fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	inserted, err := d.insertReclaiming(queryCtx, urlRecords)
+	if err != nil {
+		slog.Error("Failed to create records in database", "error", err, "count", len(urlRecords))
+		return nil, fmt.Errorf("failed to create records in database: %w", err)
+	}
+
+	// Align inserted rows with the input by domain and short code. Only the
+	// first input with a given domain and short code can have been inserted.
+	type recordKey struct{ domain, shortCode string }
+	insertedByKey := make(map[recordKey]*model.URLRecordEntity, len(inserted))
+	for i := range inserted {
+		insertedByKey[recordKey{inserted[i].Domain, inserted[i].ShortCode}] = &inserted[i]
+	}
+	entities := make([]*model.URLRecordEntity, len(urlRecords))
+	for i, urlRecord := range urlRecords {
+		key := recordKey{urlRecord.Domain, urlRecord.ShortCode}
+		if entity, ok := insertedByKey[key]; ok {
+			entities[i] = entity
+			delete(insertedByKey, key)
+		}
+	}
+
+	return entities, nil
+}
+
+// The columns of url_records that a new record sets, and the values that it
+// sets them to, in the order of the INSERT statement. created_at, updated_at
+// and counted_as_active are set along with them.
+var urlRecordColumns = []struct {
+	name  string
+	value func(urlRecord *model.URLRecord) any
+}{
+	{"original_url", func(urlRecord *model.URLRecord) any { return urlRecord.OriginalURL }},
+	{"short_code", func(urlRecord *model.URLRecord) any { return urlRecord.ShortCode }},
+	{"domain", func(urlRecord *model.URLRecord) any { return urlRecord.Domain }},
+	{"expires_at", func(urlRecord *model.URLRecord) any { return urlRecord.ExpiresAt }},
+	{"activates_at", func(urlRecord *model.URLRecord) any { return urlRecord.ActivatesAt }},
+	{"expired_redirect_url", func(urlRecord *model.URLRecord) any { return urlRecord.ExpiredRedirectURL }},
+	{"owner_id", func(urlRecord *model.URLRecord) any { return urlRecord.OwnerID }},
+	{"password_hash", func(urlRecord *model.URLRecord) any { return urlRecord.PasswordHash }},
+	{"max_clicks", func(urlRecord *model.URLRecord) any { return urlRecord.MaxClicks }},
+	{"remaining_clicks", func(urlRecord *model.URLRecord) any { return urlRecord.RemainingClicks }},
+	{"redirect_type", func(urlRecord *model.URLRecord) any { return urlRecord.RedirectType }},
+	{"uncacheable", func(urlRecord *model.URLRecord) any { return urlRecord.Uncacheable }},
+	{"query_passthrough", func(urlRecord *model.URLRecord) any { return urlRecord.QueryPassthrough }},
+	{"path_passthrough", func(urlRecord *model.URLRecord) any { return urlRecord.PathPassthrough }},
+	{"template_defaults", func(urlRecord *model.URLRecord) any { return urlRecord.TemplateDefaults }},
+	{"country_urls", func(urlRecord *model.URLRecord) any { return urlRecord.CountryURLs }},
+	{"ios_url", func(urlRecord *model.URLRecord) any { return urlRecord.IOSURL }},
+	{"android_url", func(urlRecord *model.URLRecord) any { return urlRecord.AndroidURL }},
+	{"sticky_variants", func(urlRecord *model.URLRecord) any { return urlRecord.StickyVariants }},
+	{"redirect_rules", func(urlRecord *model.URLRecord) any { return urlRecord.RedirectRules }},
+}
+
+// The columns that are copied from url_records to url_record_history when a
+// record is archived, besides archived_at.
+var urlRecordHistoryColumns = func() string {
+	names := []string{"id"}
+	for _, column := range urlRecordColumns {
+		names = append(names, column.name)
+	}
+	names = append(names, "created_at", "updated_at", "deleted_at", "exhausted_at")
+	return strings.Join(names, ", ")
+}()
+
+// Inserts the provided records and returns the inserted rows, in no particular
+// order. A record whose domain and short code are in use by an expired or
+// deleted record reclaims them: the old record is moved to url_record_history
+// in the same transaction, so that concurrent inserts cannot both reclaim it.
+// Records whose domain and short code are in use by an active record
+// (including an earlier record in the same call) are skipped and simply
+// absent from the returned rows.
+func (d *URLRecordDatabaseDAO) insertReclaiming(ctx context.Context, urlRecords []model.URLRecord) ([]model.URLRecordEntity, error) {
+	now := time.Now()
+	keys := make([][]any, len(urlRecords))
+	for i, urlRecord := range urlRecords {
+		keys[i] = []any{urlRecord.Domain, urlRecord.ShortCode}
+	}
+
+	// Use raw SQL for the INSERT since GORM cannot scan the RETURNING rows of
+	// an INSERT that skips conflicting rows: it assigns them to the records in
+	// order.
+	names := make([]string, 0, len(urlRecordColumns)+3)
+	for _, column := range urlRecordColumns {
+		names = append(names, column.name)
+	}
+	names = append(names, "created_at", "updated_at", "counted_as_active")
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ") + ")"
+	rows := make([]string, len(urlRecords))
+	args := make([]any, 0, len(urlRecords)*len(names))
+	for i := range urlRecords {
+		rows[i] = placeholders
+		for _, column := range urlRecordColumns {
+			args = append(args, column.value(&urlRecords[i]))
+		}
+		args = append(args, now, now, urlRecords[i].OwnerID != nil)
+	}
+	sql := "INSERT INTO url_records (" + strings.Join(names, ", ") + ") VALUES " + strings.Join(rows, ", ") +
+		" ON CONFLICT (domain, short_code) DO NOTHING RETURNING *"
+
+	// Insert the variants of the inserted records in the same transaction, so
+	// that no split-tested record is ever visible without them.
+	var inserted []model.URLRecordEntity
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the expired and deleted records whose short codes are
+		// reclaimed, so that a concurrent reclaim of the same short code
+		// waits for this transaction and then finds nothing to reclaim. The
+		// INSERT's conflict check then ignores the records archived here.
+		var reclaimedIDs []uint
+		err := tx.Model(&model.URLRecordEntity{}).
+			Unscoped().
+			Where("(domain, short_code) IN ?", keys).
+			Where("deleted_at IS NOT NULL OR expires_at <= ? OR remaining_clicks <= 0", now).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Pluck("id", &reclaimedIDs).Error
+		if err != nil {
+			return err
+		}
+		reclaimed, err := removeRecords(tx, reclaimedIDs, true, now)
+		if err != nil {
+			return err
+		}
+		if err := uncountActiveLinks(tx, reclaimed); err != nil {
+			return err
+		}
+
+		if err := tx.Raw(sql, args...).Scan(&inserted).Error; err != nil {
+			return err
+		}
+		return insertVariants(tx, urlRecords, inserted)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return inserted, nil
+}
+
+// Removes the records with the provided IDs, which the caller must have
+// locked, and returns the removed rows. Moves them to url_record_history at
+// the provided time if archive is true; archived records keep their variants,
+// since they keep their IDs. Deletes their variants along with them otherwise.
+func removeRecords(tx *gorm.DB, ids []uint, archive bool, archivedAt time.Time) ([]model.URLRecordEntity, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	if archive {
+		sql := "INSERT INTO url_record_history (" + urlRecordHistoryColumns + ", archived_at)" +
+			" SELECT " + urlRecordHistoryColumns + ", ? FROM url_records WHERE id IN ?"
+		if err := tx.Exec(sql, archivedAt, ids).Error; err != nil {
+			return nil, err
+		}
+	} else {
+		if err := tx.Where("url_record_id IN ?", ids).Delete(&model.URLVariantEntity{}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// Use raw SQL since the entity supports soft deletes, so GORM would only
+	// set deleted_at.
+	var removed []model.URLRecordEntity
+	if err := tx.Raw("DELETE FROM url_records WHERE id IN ? RETURNING *", ids).Scan(&removed).Error; err != nil {
+		return nil, err
+	}
+
+	return removed, nil
+}
+
+// Inserts the variants of the provided records for the rows that were inserted
+// for them, and sets them on those rows. Only the first record with a given
+// domain and short code can have been inserted.
+func insertVariants(tx *gorm.DB, urlRecords []model.URLRecord, inserted []model.URLRecordEntity) error {
+	type recordKey struct{ domain, shortCode string }
+	variantsByKey := make(map[recordKey][]model.URLVariant, len(urlRecords))
+	for _, urlRecord := range urlRecords {
+		key := recordKey{urlRecord.Domain, urlRecord.ShortCode}
+		if _, ok := variantsByKey[key]; !ok {
+			variantsByKey[key] = urlRecord.Variants
+		}
+	}
+
+	var rows []model.URLVariantEntity
+	for i := range inserted {
+		variants := variantsByKey[recordKey{inserted[i].Domain, inserted[i].ShortCode}]
+		inserted[i].Variants = variants
+		for position, variant := range variants {
+			rows = append(rows, model.URLVariantEntity{URLRecordID: inserted[i].ID, Position: position, URLVariant: variant})
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	return tx.Create(&rows).Error
+}
+
+// Sets the variants of the provided records, loading those of all of them in
+// one query. Records that are not split-tested are left without variants.
+func (d *URLRecordDatabaseDAO) loadVariants(ctx context.Context, entities ...*model.URLRecordEntity) error {
+	if len(entities) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(entities))
+	entitiesByID := make(map[uint]*model.URLRecordEntity, len(entities))
+	for i, entity := range entities {
+		ids[i] = entity.ID
+		entitiesByID[entity.ID] = entity
+	}
+
+	// Served by the primary key of url_record_variants.
+	rows, err := gorm.G[model.URLVariantEntity](d.db).
+		Where("url_record_id IN ?", ids).
+		Order("url_record_id, position").
+		Find(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		entity := entitiesByID[row.URLRecordID]
+		entity.Variants = append(entity.Variants, row.URLVariant)
+	}
+	return nil
+}
+
+func (d *URLRecordDatabaseDAO) GetByShortCode(ctx context.Context, domain string, shortCode string) (*model.URLRecordEntity, error) {
 	// Add query timeout (5s for reads - allows for slow queries under load while still failing fast)
 	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var entity model.URLRecordEntity
 
+	// Match records regardless of expires_at and activates_at, so that the
+	// caller can tell an expired or scheduled record apart from a missing one.
+	// GORM implicitly adds "deleted_at IS NULL" since the entity supports soft
+	// deletes.
+	entity, err := gorm.G[model.URLRecordEntity](d.db).
+		Where("domain = ? AND short_code = ?", domain, shortCode).
+		First(queryCtx)
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Not found is a normal case
+			return nil, nil
+		}
+		// Actual database error
+		slog.Error(
+			"Failed to query record by short code in database",
+			"error", err,
+			"domain", domain,
+			"shortCode", shortCode,
+		)
+		return nil, fmt.Errorf("failed to query record by short code in database: %w", err)
+	}
+
+	if err := d.loadVariants(queryCtx, &entity); err != nil {
+		slog.Error("Failed to query variants of record by short code in database", "error", err, "domain", domain, "shortCode", shortCode)
+		return nil, fmt.Errorf("failed to query variants of record by short code in database: %w", err)
+	}
+
+	return &entity, nil
+}
+
+func (d *URLRecordDatabaseDAO) GetByOriginalURL(ctx context.Context, originalURL string, domain string, ownerID *string) (*model.URLRecordEntity, error) {
+	// Add query timeout (5s for reads - allows for slow queries under load while still failing fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Filter on md5(original_url) so that the lookup can use the expression
+	// index, since original_url itself is unbounded and unindexed. Compare the
+	// full URL as well to rule out hash collisions. Skip scheduled records,
+	// whose short codes do not resolve yet. GORM implicitly adds "deleted_at IS
+	// NULL" since the entity supports soft deletes.
+	now := time.Now()
 	entity, err := gorm.G[model.URLRecordEntity](d.db).
-		Where("short_code = ? AND expires_at > ?", shortCode, time.Now()).
+		Where("md5(original_url) = md5(?) AND original_url = ? AND domain = ?", originalURL, originalURL, domain).
+		Where(activeCondition(now)).
+		Where("activates_at IS NULL OR activates_at <= ?", now).
+		Where(ownerCondition(ownerID)).
+		Order("expires_at DESC").
 		First(queryCtx)
 
 	if err != nil {
@@ -87,13 +351,402 @@ func (d *URLRecordDatabaseDAO) GetByShortCode(ctx context.Context, shortCode str
 			return nil, nil
 		}
 		// Actual database error
+		slog.Error("Failed to query record by original URL from database", "error", err)
+		return nil, fmt.Errorf("failed to query record by original URL from database: %w", err)
+	}
+
+	if err := d.loadVariants(queryCtx, &entity); err != nil {
+		slog.Error("Failed to query variants of record by original URL in database", "error", err)
+		return nil, fmt.Errorf("failed to query variants of record by original URL in database: %w", err)
+	}
+
+	return &entity, nil
+}
+
+func (d *URLRecordDatabaseDAO) GetByShortCodeIncludingInactive(ctx context.Context, domain string, shortCode string, ownerID *string) (*model.URLRecordEntity, error) {
+	// Add query timeout (5s for reads - allows for slow queries under load while still failing fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Use Unscoped to include soft-deleted rows. The generics API doesn't
+	// support Unscoped, so use the traditional API.
+	var entity model.URLRecordEntity
+	err := d.db.WithContext(queryCtx).
+		Unscoped().
+		Where("domain = ? AND short_code = ?", domain, shortCode).
+		Where(ownerCondition(ownerID)).
+		Order("id DESC").
+		First(&entity).
+		Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Not found is a normal case
+			return nil, nil
+		}
 		slog.Error(
 			"Failed to query record by short code in database",
 			"error", err,
+			"domain", domain,
 			"shortCode", shortCode,
 		)
 		return nil, fmt.Errorf("failed to query record by short code in database: %w", err)
 	}
 
+	if err := d.loadVariants(queryCtx, &entity); err != nil {
+		slog.Error("Failed to query variants of record by short code in database", "error", err, "domain", domain, "shortCode", shortCode)
+		return nil, fmt.Errorf("failed to query variants of record by short code in database: %w", err)
+	}
+
+	return &entity, nil
+}
+
+func (d *URLRecordDatabaseDAO) ConsumeClick(ctx context.Context, urlRecord *model.URLRecordEntity) (bool, error) {
+	// Add query timeout (5s for writes - they're typically fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Use a conditional UPDATE ... RETURNING so that concurrent clicks cannot
+	// both take the last remaining click: the row lock serializes them, and the
+	// second re-checks the condition after the first commits. The last click
+	// also sets exhausted_at, which the purge goes by. Use UpdateColumns so
+	// that a click does not count as an edit in updated_at. GORM excludes
+	// soft-deleted rows.
+	now := time.Now()
+	var entity model.URLRecordEntity
+	result := d.db.WithContext(queryCtx).
+		Model(&entity).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "remaining_clicks"}}}).
+		Where("id = ? AND remaining_clicks > 0 AND expires_at > ?", urlRecord.ID, now).
+		UpdateColumns(map[string]any{
+			"remaining_clicks": gorm.Expr("remaining_clicks - 1"),
+			"exhausted_at":     gorm.Expr("CASE WHEN remaining_clicks = 1 THEN ? ELSE exhausted_at END", now),
+		})
+
+	if result.Error != nil {
+		slog.Error(
+			"Failed to consume click in database",
+			"error", result.Error,
+			"domain", urlRecord.Domain,
+			"shortCode", urlRecord.ShortCode,
+		)
+		return false, fmt.Errorf("failed to consume click in database: %w", result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (d *URLRecordDatabaseDAO) List(ctx context.Context, params model.URLRecordListParams) ([]model.URLRecordEntity, error) {
+	// Add query timeout (5s for reads - allows for slow queries under load while still failing fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Build the query conditionally with the traditional API. GORM implicitly
+	// adds "deleted_at IS NULL" since the entity supports soft deletes.
+	query := d.db.WithContext(queryCtx).
+		Model(&model.URLRecordEntity{}).
+		Where(ownerCondition(params.OwnerID))
+
+	filter := params.Filter
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at > ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
+	}
+	if filter.ExpiringBefore != nil {
+		query = query.Where("expires_at < ?", *filter.ExpiringBefore)
+	}
+	switch filter.Status {
+	case model.URLRecordStatusActive:
+		query = query.Where(activeCondition(time.Now()))
+	case model.URLRecordStatusExpired:
+		query = query.Where("(expires_at <= ? OR remaining_clicks <= 0)", time.Now())
+	}
+	if filter.Host != "" {
+		// original_host is a generated column, see migration 00005.
+		query = query.Where("original_host = ?", strings.ToLower(filter.Host))
+	}
+
+	// Keyset pagination: the row-value comparison lets PostgreSQL seek
+	// directly to the cursor using idx_url_records_created_at_id.
+	if params.After != nil {
+		query = query.Where("(created_at, id) < (?, ?)", params.After.CreatedAt, params.After.ID)
+	}
+
+	var entities []model.URLRecordEntity
+	err := query.
+		Order("created_at DESC, id DESC").
+		Limit(params.Limit).
+		Find(&entities).
+		Error
+
+	if err != nil {
+		slog.Error("Failed to list records in database", "error", err)
+		return nil, fmt.Errorf("failed to list records in database: %w", err)
+	}
+
+	listed := make([]*model.URLRecordEntity, len(entities))
+	for i := range entities {
+		listed[i] = &entities[i]
+	}
+	if err := d.loadVariants(queryCtx, listed...); err != nil {
+		slog.Error("Failed to query variants of listed records in database", "error", err)
+		return nil, fmt.Errorf("failed to query variants of listed records in database: %w", err)
+	}
+
+	return entities, nil
+}
+
+func (d *URLRecordDatabaseDAO) ReserveActiveLinks(ctx context.Context, ownerID string, n int, limit int) (bool, error) {
+	if limit > 0 && n > limit {
+		return false, nil
+	}
+
+	// Add query timeout (5s for writes - they're typically fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	reserved, err := incrementActiveLinks(d.db.WithContext(queryCtx), ownerID, n, limit)
+	if err == nil && !reserved {
+		// Stop counting the owner's records that are no longer active, and
+		// try again if there were any. Lock the records before the owner's
+		// count, like Delete and PurgeExpired, so that they cannot deadlock.
+		// Served by idx_url_records_owner_id_counted_as_active, which holds no
+		// more of the owner's records than the quota allows plus those that
+		// became inactive since the owner last reached it.
+		err = d.db.WithContext(queryCtx).Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&model.URLRecordEntity{}).
+				Where("owner_id = ? AND counted_as_active", ownerID).
+				Where("expires_at <= ? OR remaining_clicks <= 0", time.Now()).
+				UpdateColumn("counted_as_active", false)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			if err := decrementActiveLinks(tx, ownerID, int(result.RowsAffected)); err != nil {
+				return err
+			}
+			var err error
+			reserved, err = incrementActiveLinks(tx, ownerID, n, limit)
+			return err
+		})
+	}
+
+	if err != nil {
+		slog.Error("Failed to reserve active links in database", "error", err, "ownerID", ownerID)
+		return false, fmt.Errorf("failed to reserve active links in database: %w", err)
+	}
+
+	return reserved, nil
+}
+
+func (d *URLRecordDatabaseDAO) ReleaseActiveLinks(ctx context.Context, ownerID string, n int) error {
+	// Add query timeout (5s for writes - they're typically fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := decrementActiveLinks(d.db.WithContext(queryCtx), ownerID, n); err != nil {
+		slog.Error("Failed to release active links in database", "error", err, "ownerID", ownerID)
+		return fmt.Errorf("failed to release active links in database: %w", err)
+	}
+
+	return nil
+}
+
+func (d *URLRecordDatabaseDAO) Update(ctx context.Context, domain string, shortCode string, ownerID *string, update model.URLRecordUpdate) (*model.URLRecordEntity, error) {
+	// Add query timeout (5s for writes - they're typically fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	columns := map[string]any{}
+	if update.OriginalURL != nil {
+		columns["original_url"] = *update.OriginalURL
+	}
+	if update.ExpiresAt != nil {
+		columns["expires_at"] = *update.ExpiresAt
+	}
+
+	// Use UPDATE ... RETURNING to read back the updated row without a separate
+	// SELECT query. GORM sets updated_at and excludes soft-deleted rows.
+	var entity model.URLRecordEntity
+	result := d.db.WithContext(queryCtx).
+		Model(&entity).
+		Clauses(clause.Returning{}).
+		Where("domain = ? AND short_code = ?", domain, shortCode).
+		Where(activeCondition(time.Now())).
+		Where(ownerCondition(ownerID)).
+		Updates(columns)
+
+	if result.Error != nil {
+		slog.Error(
+			"Failed to update record in database",
+			"error", result.Error,
+			"domain", domain,
+			"shortCode", shortCode,
+		)
+		return nil, fmt.Errorf("failed to update record in database: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, apperrors.ErrShortCodeNotFound
+	}
+
+	if err := d.loadVariants(queryCtx, &entity); err != nil {
+		slog.Error("Failed to query variants of updated record in database", "error", err, "domain", domain, "shortCode", shortCode)
+		return nil, fmt.Errorf("failed to query variants of updated record in database: %w", err)
+	}
+
 	return &entity, nil
 }
+
+func (d *URLRecordDatabaseDAO) Delete(ctx context.Context, domain string, shortCode string, ownerID *string) error {
+	// Add query timeout (5s for writes - they're typically fast)
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Lock the record before deleting it, so that the owner's count of active
+	// records is decremented exactly once even if a reservation stops counting
+	// the record concurrently.
+	err := d.db.WithContext(queryCtx).Transaction(func(tx *gorm.DB) error {
+		var entity model.URLRecordEntity
+		result := tx.
+			Where("domain = ? AND short_code = ?", domain, shortCode).
+			Where(activeCondition(time.Now())).
+			Where(ownerCondition(ownerID)).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Limit(1).
+			Find(&entity)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return apperrors.ErrShortCodeNotFound
+		}
+
+		// Set deleted_at directly rather than through GORM's soft delete, so
+		// that the record stops being counted in the same statement.
+		err := tx.Model(&entity).UpdateColumns(map[string]any{
+			"deleted_at":        time.Now(),
+			"counted_as_active": false,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return uncountActiveLinks(tx, []model.URLRecordEntity{entity})
+	})
+
+	if errors.Is(err, apperrors.ErrShortCodeNotFound) {
+		return err
+	}
+	if err != nil {
+		slog.Error(
+			"Failed to delete record in database",
+			"error", err,
+			"domain", domain,
+			"shortCode", shortCode,
+		)
+		return fmt.Errorf("failed to delete record in database: %w", err)
+	}
+
+	return nil
+}
+
+func (d *URLRecordDatabaseDAO) PurgeExpired(ctx context.Context, before time.Time, limit int, archive bool) ([]model.URLRecordEntity, error) {
+	// Add query timeout (30s, since a batch may delete many rows)
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var purged []model.URLRecordEntity
+	err := d.db.WithContext(queryCtx).Transaction(func(tx *gorm.DB) error {
+		// SKIP LOCKED lets several purgers work through the table
+		// concurrently, and leaves rows alone that a create is reclaiming.
+		// Served by idx_url_records_expires_at, idx_url_records_deleted_at and
+		// idx_url_records_exhausted_at.
+		var ids []uint
+		err := tx.Model(&model.URLRecordEntity{}).
+			Unscoped().
+			Where("expires_at <= ? OR deleted_at <= ? OR exhausted_at <= ?", before, before, before).
+			Limit(limit).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return err
+		}
+
+		purged, err = removeRecords(tx, ids, archive, time.Now())
+		if err != nil {
+			return err
+		}
+		return uncountActiveLinks(tx, purged)
+	})
+	if err != nil {
+		slog.Error("Failed to purge expired records in database", "error", err, "before", before, "limit", limit)
+		return nil, fmt.Errorf("failed to purge expired records in database: %w", err)
+	}
+
+	return purged, nil
+}
+
+// Adds n to the owner's count of active records, unless the result would
+// exceed limit (0 for no limit). Returns false, leaving the count unchanged,
+// if it would.
+func incrementActiveLinks(tx *gorm.DB, ownerID string, n int, limit int) (bool, error) {
+	// Use INSERT ... ON CONFLICT DO UPDATE ... WHERE so that the check and the
+	// increment happen in one statement, as for daily creates.
+	sql := "INSERT INTO owner_active_links (owner_id, active_links) VALUES (?, ?)" +
+		" ON CONFLICT (owner_id) DO UPDATE" +
+		" SET active_links = owner_active_links.active_links + EXCLUDED.active_links"
+	args := []any{ownerID, n}
+	if limit > 0 {
+		sql += " WHERE owner_active_links.active_links + EXCLUDED.active_links <= ?"
+		args = append(args, limit)
+	}
+
+	result := tx.Exec(sql, args...)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Subtracts n from the owner's count of active records.
+func decrementActiveLinks(tx *gorm.DB, ownerID string, n int) error {
+	return tx.Exec(
+		"UPDATE owner_active_links SET active_links = GREATEST(active_links - ?, 0) WHERE owner_id = ?",
+		n, ownerID,
+	).Error
+}
+
+// Subtracts the provided records that were counted as active from their
+// owners' counts, e.g. once they have been removed. Owners are updated in
+// order, so that concurrent transactions lock their rows in the same order.
+func uncountActiveLinks(tx *gorm.DB, entities []model.URLRecordEntity) error {
+	counts := map[string]int{}
+	for _, entity := range entities {
+		if entity.CountedAsActive && entity.OwnerID != nil {
+			counts[*entity.OwnerID]++
+		}
+	}
+
+	for _, ownerID := range slices.Sorted(maps.Keys(counts)) {
+		if err := decrementActiveLinks(tx, ownerID, counts[ownerID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns a condition that matches records that have not expired by the
+// provided time and, if click-limited, have clicks left.
+func activeCondition(now time.Time) clause.Expr {
+	return clause.Expr{SQL: "expires_at > ? AND (remaining_clicks IS NULL OR remaining_clicks > 0)", Vars: []any{now}}
+}
+
+// Returns a condition that matches records with the provided owner. A nil
+// ownerID matches records without an owner.
+func ownerCondition(ownerID *string) clause.Expr {
+	if ownerID == nil {
+		return clause.Expr{SQL: "owner_id IS NULL"}
+	}
+	return clause.Expr{SQL: "owner_id = ?", Vars: []any{*ownerID}}
+}
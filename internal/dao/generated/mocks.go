@@ -12,6 +12,7 @@ package mock_dao
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 	model "tiny-bitly/internal/model"
 
 	gomock "go.uber.org/mock/gomock"
@@ -41,6 +42,21 @@ func (m *MockURLRecordDAO) EXPECT() *MockURLRecordDAOMockRecorder {
 	return m.recorder
 }
 
+// ConsumeClick mocks base method.
+func (m *MockURLRecordDAO) ConsumeClick(ctx context.Context, urlRecord *model.URLRecordEntity) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeClick", ctx, urlRecord)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeClick indicates an expected call of ConsumeClick.
+func (mr *MockURLRecordDAOMockRecorder) ConsumeClick(ctx, urlRecord any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeClick", reflect.TypeOf((*MockURLRecordDAO)(nil).ConsumeClick), ctx, urlRecord)
+}
+
 // Create mocks base method.
 func (m *MockURLRecordDAO) Create(ctx context.Context, urlRecord model.URLRecord) (*model.URLRecordEntity, error) {
 	m.ctrl.T.Helper()
@@ -56,17 +72,489 @@ func (mr *MockURLRecordDAOMockRecorder) Create(ctx, urlRecord any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockURLRecordDAO)(nil).Create), ctx, urlRecord)
 }
 
+// CreateBatch mocks base method.
+func (m *MockURLRecordDAO) CreateBatch(ctx context.Context, urlRecords []model.URLRecord) ([]*model.URLRecordEntity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBatch", ctx, urlRecords)
+	ret0, _ := ret[0].([]*model.URLRecordEntity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateBatch indicates an expected call of CreateBatch.
+func (mr *MockURLRecordDAOMockRecorder) CreateBatch(ctx, urlRecords any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBatch", reflect.TypeOf((*MockURLRecordDAO)(nil).CreateBatch), ctx, urlRecords)
+}
+
+// Delete mocks base method.
+func (m *MockURLRecordDAO) Delete(ctx context.Context, domain, shortCode string, ownerID *string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, domain, shortCode, ownerID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockURLRecordDAOMockRecorder) Delete(ctx, domain, shortCode, ownerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockURLRecordDAO)(nil).Delete), ctx, domain, shortCode, ownerID)
+}
+
+// GetByOriginalURL mocks base method.
+func (m *MockURLRecordDAO) GetByOriginalURL(ctx context.Context, originalURL, domain string, ownerID *string) (*model.URLRecordEntity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByOriginalURL", ctx, originalURL, domain, ownerID)
+	ret0, _ := ret[0].(*model.URLRecordEntity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByOriginalURL indicates an expected call of GetByOriginalURL.
+func (mr *MockURLRecordDAOMockRecorder) GetByOriginalURL(ctx, originalURL, domain, ownerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByOriginalURL", reflect.TypeOf((*MockURLRecordDAO)(nil).GetByOriginalURL), ctx, originalURL, domain, ownerID)
+}
+
 // GetByShortCode mocks base method.
-func (m *MockURLRecordDAO) GetByShortCode(ctx context.Context, shortCode string) (*model.URLRecordEntity, error) {
+func (m *MockURLRecordDAO) GetByShortCode(ctx context.Context, domain, shortCode string) (*model.URLRecordEntity, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByShortCode", ctx, shortCode)
+	ret := m.ctrl.Call(m, "GetByShortCode", ctx, domain, shortCode)
 	ret0, _ := ret[0].(*model.URLRecordEntity)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetByShortCode indicates an expected call of GetByShortCode.
-func (mr *MockURLRecordDAOMockRecorder) GetByShortCode(ctx, shortCode any) *gomock.Call {
+func (mr *MockURLRecordDAOMockRecorder) GetByShortCode(ctx, domain, shortCode any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByShortCode", reflect.TypeOf((*MockURLRecordDAO)(nil).GetByShortCode), ctx, domain, shortCode)
+}
+
+// GetByShortCodeIncludingInactive mocks base method.
+func (m *MockURLRecordDAO) GetByShortCodeIncludingInactive(ctx context.Context, domain, shortCode string, ownerID *string) (*model.URLRecordEntity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByShortCodeIncludingInactive", ctx, domain, shortCode, ownerID)
+	ret0, _ := ret[0].(*model.URLRecordEntity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByShortCodeIncludingInactive indicates an expected call of GetByShortCodeIncludingInactive.
+func (mr *MockURLRecordDAOMockRecorder) GetByShortCodeIncludingInactive(ctx, domain, shortCode, ownerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByShortCodeIncludingInactive", reflect.TypeOf((*MockURLRecordDAO)(nil).GetByShortCodeIncludingInactive), ctx, domain, shortCode, ownerID)
+}
+
+// List mocks base method.
+func (m *MockURLRecordDAO) List(ctx context.Context, params model.URLRecordListParams) ([]model.URLRecordEntity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, params)
+	ret0, _ := ret[0].([]model.URLRecordEntity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockURLRecordDAOMockRecorder) List(ctx, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockURLRecordDAO)(nil).List), ctx, params)
+}
+
+// PurgeExpired mocks base method.
+func (m *MockURLRecordDAO) PurgeExpired(ctx context.Context, before time.Time, limit int, archive bool) ([]model.URLRecordEntity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeExpired", ctx, before, limit, archive)
+	ret0, _ := ret[0].([]model.URLRecordEntity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeExpired indicates an expected call of PurgeExpired.
+func (mr *MockURLRecordDAOMockRecorder) PurgeExpired(ctx, before, limit, archive any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeExpired", reflect.TypeOf((*MockURLRecordDAO)(nil).PurgeExpired), ctx, before, limit, archive)
+}
+
+// ReleaseActiveLinks mocks base method.
+func (m *MockURLRecordDAO) ReleaseActiveLinks(ctx context.Context, ownerID string, n int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseActiveLinks", ctx, ownerID, n)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseActiveLinks indicates an expected call of ReleaseActiveLinks.
+func (mr *MockURLRecordDAOMockRecorder) ReleaseActiveLinks(ctx, ownerID, n any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseActiveLinks", reflect.TypeOf((*MockURLRecordDAO)(nil).ReleaseActiveLinks), ctx, ownerID, n)
+}
+
+// ReserveActiveLinks mocks base method.
+func (m *MockURLRecordDAO) ReserveActiveLinks(ctx context.Context, ownerID string, n, limit int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReserveActiveLinks", ctx, ownerID, n, limit)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReserveActiveLinks indicates an expected call of ReserveActiveLinks.
+func (mr *MockURLRecordDAOMockRecorder) ReserveActiveLinks(ctx, ownerID, n, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReserveActiveLinks", reflect.TypeOf((*MockURLRecordDAO)(nil).ReserveActiveLinks), ctx, ownerID, n, limit)
+}
+
+// Update mocks base method.
+func (m *MockURLRecordDAO) Update(ctx context.Context, domain, shortCode string, ownerID *string, update model.URLRecordUpdate) (*model.URLRecordEntity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, domain, shortCode, ownerID, update)
+	ret0, _ := ret[0].(*model.URLRecordEntity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockURLRecordDAOMockRecorder) Update(ctx, domain, shortCode, ownerID, update any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockURLRecordDAO)(nil).Update), ctx, domain, shortCode, ownerID, update)
+}
+
+// MockIdempotencyKeyDAO is a mock of IdempotencyKeyDAO interface.
+type MockIdempotencyKeyDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdempotencyKeyDAOMockRecorder
+	isgomock struct{}
+}
+
+// MockIdempotencyKeyDAOMockRecorder is the mock recorder for MockIdempotencyKeyDAO.
+type MockIdempotencyKeyDAOMockRecorder struct {
+	mock *MockIdempotencyKeyDAO
+}
+
+// NewMockIdempotencyKeyDAO creates a new mock instance.
+func NewMockIdempotencyKeyDAO(ctrl *gomock.Controller) *MockIdempotencyKeyDAO {
+	mock := &MockIdempotencyKeyDAO{ctrl: ctrl}
+	mock.recorder = &MockIdempotencyKeyDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdempotencyKeyDAO) EXPECT() *MockIdempotencyKeyDAOMockRecorder {
+	return m.recorder
+}
+
+// Complete mocks base method.
+func (m *MockIdempotencyKeyDAO) Complete(ctx context.Context, key string, statusCode int, responseBody []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Complete", ctx, key, statusCode, responseBody)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Complete indicates an expected call of Complete.
+func (mr *MockIdempotencyKeyDAOMockRecorder) Complete(ctx, key, statusCode, responseBody any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Complete", reflect.TypeOf((*MockIdempotencyKeyDAO)(nil).Complete), ctx, key, statusCode, responseBody)
+}
+
+// Release mocks base method.
+func (m *MockIdempotencyKeyDAO) Release(ctx context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockIdempotencyKeyDAOMockRecorder) Release(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockIdempotencyKeyDAO)(nil).Release), ctx, key)
+}
+
+// Reserve mocks base method.
+func (m *MockIdempotencyKeyDAO) Reserve(ctx context.Context, idempotencyKey model.IdempotencyKey) (*model.IdempotencyKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reserve", ctx, idempotencyKey)
+	ret0, _ := ret[0].(*model.IdempotencyKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reserve indicates an expected call of Reserve.
+func (mr *MockIdempotencyKeyDAOMockRecorder) Reserve(ctx, idempotencyKey any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*MockIdempotencyKeyDAO)(nil).Reserve), ctx, idempotencyKey)
+}
+
+// MockAPIKeyDAO is a mock of APIKeyDAO interface.
+type MockAPIKeyDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockAPIKeyDAOMockRecorder
+	isgomock struct{}
+}
+
+// MockAPIKeyDAOMockRecorder is the mock recorder for MockAPIKeyDAO.
+type MockAPIKeyDAOMockRecorder struct {
+	mock *MockAPIKeyDAO
+}
+
+// NewMockAPIKeyDAO creates a new mock instance.
+func NewMockAPIKeyDAO(ctrl *gomock.Controller) *MockAPIKeyDAO {
+	mock := &MockAPIKeyDAO{ctrl: ctrl}
+	mock.recorder = &MockAPIKeyDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAPIKeyDAO) EXPECT() *MockAPIKeyDAOMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockAPIKeyDAO) Create(ctx context.Context, apiKey model.APIKey) (*model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, apiKey)
+	ret0, _ := ret[0].(*model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockAPIKeyDAOMockRecorder) Create(ctx, apiKey any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockAPIKeyDAO)(nil).Create), ctx, apiKey)
+}
+
+// GetByKeyHash mocks base method.
+func (m *MockAPIKeyDAO) GetByKeyHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByKeyHash", ctx, keyHash)
+	ret0, _ := ret[0].(*model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByKeyHash indicates an expected call of GetByKeyHash.
+func (mr *MockAPIKeyDAOMockRecorder) GetByKeyHash(ctx, keyHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByKeyHash", reflect.TypeOf((*MockAPIKeyDAO)(nil).GetByKeyHash), ctx, keyHash)
+}
+
+// List mocks base method.
+func (m *MockAPIKeyDAO) List(ctx context.Context) ([]model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx)
+	ret0, _ := ret[0].([]model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockAPIKeyDAOMockRecorder) List(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockAPIKeyDAO)(nil).List), ctx)
+}
+
+// Revoke mocks base method.
+func (m *MockAPIKeyDAO) Revoke(ctx context.Context, id uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockAPIKeyDAOMockRecorder) Revoke(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockAPIKeyDAO)(nil).Revoke), ctx, id)
+}
+
+// MockDomainDAO is a mock of DomainDAO interface.
+type MockDomainDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockDomainDAOMockRecorder
+	isgomock struct{}
+}
+
+// MockDomainDAOMockRecorder is the mock recorder for MockDomainDAO.
+type MockDomainDAOMockRecorder struct {
+	mock *MockDomainDAO
+}
+
+// NewMockDomainDAO creates a new mock instance.
+func NewMockDomainDAO(ctrl *gomock.Controller) *MockDomainDAO {
+	mock := &MockDomainDAO{ctrl: ctrl}
+	mock.recorder = &MockDomainDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDomainDAO) EXPECT() *MockDomainDAOMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockDomainDAO) Create(ctx context.Context, domain model.Domain) (*model.Domain, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, domain)
+	ret0, _ := ret[0].(*model.Domain)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockDomainDAOMockRecorder) Create(ctx, domain any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockDomainDAO)(nil).Create), ctx, domain)
+}
+
+// Delete mocks base method.
+func (m *MockDomainDAO) Delete(ctx context.Context, hostname string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, hostname)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockDomainDAOMockRecorder) Delete(ctx, hostname any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockDomainDAO)(nil).Delete), ctx, hostname)
+}
+
+// GetByHostname mocks base method.
+func (m *MockDomainDAO) GetByHostname(ctx context.Context, hostname string) (*model.Domain, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByHostname", ctx, hostname)
+	ret0, _ := ret[0].(*model.Domain)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByHostname indicates an expected call of GetByHostname.
+func (mr *MockDomainDAOMockRecorder) GetByHostname(ctx, hostname any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByHostname", reflect.TypeOf((*MockDomainDAO)(nil).GetByHostname), ctx, hostname)
+}
+
+// List mocks base method.
+func (m *MockDomainDAO) List(ctx context.Context) ([]model.Domain, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx)
+	ret0, _ := ret[0].([]model.Domain)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockDomainDAOMockRecorder) List(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockDomainDAO)(nil).List), ctx)
+}
+
+// MockQuotaDAO is a mock of QuotaDAO interface.
+type MockQuotaDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockQuotaDAOMockRecorder
+	isgomock struct{}
+}
+
+// MockQuotaDAOMockRecorder is the mock recorder for MockQuotaDAO.
+type MockQuotaDAOMockRecorder struct {
+	mock *MockQuotaDAO
+}
+
+// NewMockQuotaDAO creates a new mock instance.
+func NewMockQuotaDAO(ctrl *gomock.Controller) *MockQuotaDAO {
+	mock := &MockQuotaDAO{ctrl: ctrl}
+	mock.recorder = &MockQuotaDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockQuotaDAO) EXPECT() *MockQuotaDAOMockRecorder {
+	return m.recorder
+}
+
+// DecrementDailyCreates mocks base method.
+func (m *MockQuotaDAO) DecrementDailyCreates(ctx context.Context, ownerID, day string, n int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecrementDailyCreates", ctx, ownerID, day, n)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DecrementDailyCreates indicates an expected call of DecrementDailyCreates.
+func (mr *MockQuotaDAOMockRecorder) DecrementDailyCreates(ctx, ownerID, day, n any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecrementDailyCreates", reflect.TypeOf((*MockQuotaDAO)(nil).DecrementDailyCreates), ctx, ownerID, day, n)
+}
+
+// IncrementDailyCreates mocks base method.
+func (m *MockQuotaDAO) IncrementDailyCreates(ctx context.Context, ownerID, day string, n, limit int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementDailyCreates", ctx, ownerID, day, n, limit)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrementDailyCreates indicates an expected call of IncrementDailyCreates.
+func (mr *MockQuotaDAOMockRecorder) IncrementDailyCreates(ctx, ownerID, day, n, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementDailyCreates", reflect.TypeOf((*MockQuotaDAO)(nil).IncrementDailyCreates), ctx, ownerID, day, n, limit)
+}
+
+// MockPasswordAttemptDAO is a mock of PasswordAttemptDAO interface.
+type MockPasswordAttemptDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockPasswordAttemptDAOMockRecorder
+	isgomock struct{}
+}
+
+// MockPasswordAttemptDAOMockRecorder is the mock recorder for MockPasswordAttemptDAO.
+type MockPasswordAttemptDAOMockRecorder struct {
+	mock *MockPasswordAttemptDAO
+}
+
+// NewMockPasswordAttemptDAO creates a new mock instance.
+func NewMockPasswordAttemptDAO(ctrl *gomock.Controller) *MockPasswordAttemptDAO {
+	mock := &MockPasswordAttemptDAO{ctrl: ctrl}
+	mock.recorder = &MockPasswordAttemptDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPasswordAttemptDAO) EXPECT() *MockPasswordAttemptDAOMockRecorder {
+	return m.recorder
+}
+
+// DecrementFailedAttempts mocks base method.
+func (m *MockPasswordAttemptDAO) DecrementFailedAttempts(ctx context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecrementFailedAttempts", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DecrementFailedAttempts indicates an expected call of DecrementFailedAttempts.
+func (mr *MockPasswordAttemptDAOMockRecorder) DecrementFailedAttempts(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecrementFailedAttempts", reflect.TypeOf((*MockPasswordAttemptDAO)(nil).DecrementFailedAttempts), ctx, key)
+}
+
+// IncrementFailedAttempts mocks base method.
+func (m *MockPasswordAttemptDAO) IncrementFailedAttempts(ctx context.Context, key string, window time.Duration) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementFailedAttempts", ctx, key, window)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrementFailedAttempts indicates an expected call of IncrementFailedAttempts.
+func (mr *MockPasswordAttemptDAOMockRecorder) IncrementFailedAttempts(ctx, key, window any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByShortCode", reflect.TypeOf((*MockURLRecordDAO)(nil).GetByShortCode), ctx, shortCode)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementFailedAttempts", reflect.TypeOf((*MockPasswordAttemptDAO)(nil).IncrementFailedAttempts), ctx, key, window)
 }
@@ -0,0 +1,158 @@
+package dao
+
+import (
+	"context"
+	"time"
+	"tiny-bitly/internal/model"
+)
+
+// URLRecordDAO defines the interface for URL record data access operations.
+type URLRecordDAO interface {
+	// Creates a record, reclaiming its short code from an expired or deleted
+	// record on the same domain, if any. Returns
+	// apperrors.ErrShortCodeAlreadyInUse if an active record has the short
+	// code on the record's domain.
+	Create(ctx context.Context, urlRecord model.URLRecord) (*model.URLRecordEntity, error)
+
+	// Creates many records at once, reclaiming short codes as in Create.
+	// Returns a slice aligned with urlRecords, holding nil wherever the short
+	// code is already in use on the record's domain by an active record
+	// (including by an earlier record in the same batch).
+	CreateBatch(ctx context.Context, urlRecords []model.URLRecord) ([]*model.URLRecordEntity, error)
+
+	// Returns the non-deleted record with the provided short code on the
+	// provided domain ("" for the default domain), including one that has
+	// expired or whose activation time has not passed yet, so that callers can
+	// tell it apart from a missing record. Returns nil if no such record
+	// exists.
+	GetByShortCode(ctx context.Context, domain string, shortCode string) (*model.URLRecordEntity, error)
+
+	// Returns the active record with exactly the provided original URL, domain
+	// and owner that expires last, ignoring records whose activation time has
+	// not passed yet. A nil ownerID matches records without an
+	// owner. Returns nil if no such record exists.
+	GetByOriginalURL(ctx context.Context, originalURL string, domain string, ownerID *string) (*model.URLRecordEntity, error)
+
+	// Returns the most recent record with the provided short code on the
+	// provided domain and owner, even if it has expired or been soft-deleted.
+	// A nil ownerID matches records without an owner. Returns nil if no such
+	// record exists.
+	GetByShortCodeIncludingInactive(ctx context.Context, domain string, shortCode string, ownerID *string) (*model.URLRecordEntity, error)
+
+	// Atomically consumes one click of the provided active click-limited
+	// record. Returns false if the record has no clicks left, or is no longer
+	// active, in which case it must not be resolved.
+	ConsumeClick(ctx context.Context, urlRecord *model.URLRecordEntity) (bool, error)
+
+	// Returns up to params.Limit non-deleted records owned by params.OwnerID
+	// and matching params.Filter, ordered by creation time and then ID, both
+	// descending, starting after params.After if set.
+	List(ctx context.Context, params model.URLRecordListParams) ([]model.URLRecordEntity, error)
+
+	// Atomically adds n to the number of records counted as active for the
+	// provided owner, unless the result would exceed limit (0 for no limit).
+	// If it would, first stops counting the owner's records that have expired
+	// or run out of clicks since. Returns false, leaving the count unchanged,
+	// if the result would still exceed limit. Create counts every record with
+	// an owner, so callers reserve before creating one.
+	ReserveActiveLinks(ctx context.Context, ownerID string, n int, limit int) (bool, error)
+
+	// Subtracts n from the number of records counted as active for the
+	// provided owner, e.g. to return reservations that no record was created
+	// for. Delete, PurgeExpired and reclaiming short codes stop counting the
+	// records that they remove by themselves.
+	ReleaseActiveLinks(ctx context.Context, ownerID string, n int) error
+
+	// Applies the provided update to the active record with the provided
+	// domain, short code and owner, and returns the updated record. A nil
+	// ownerID matches records without an owner. Returns
+	// apperrors.ErrShortCodeNotFound if no such record exists.
+	Update(ctx context.Context, domain string, shortCode string, ownerID *string, update model.URLRecordUpdate) (*model.URLRecordEntity, error)
+
+	// Soft-deletes the active record with the provided domain, short code and
+	// owner. A nil ownerID matches records without an owner. Returns
+	// apperrors.ErrShortCodeNotFound if no such record exists.
+	Delete(ctx context.Context, domain string, shortCode string, ownerID *string) error
+
+	// Permanently removes up to limit records that expired, were
+	// soft-deleted or ran out of clicks at or before the provided time, moving
+	// them to the history table if archive is true. Returns the removed
+	// records.
+	PurgeExpired(ctx context.Context, before time.Time, limit int, archive bool) ([]model.URLRecordEntity, error)
+}
+
+// IdempotencyKeyDAO defines the interface for idempotency key data access
+// operations.
+type IdempotencyKeyDAO interface {
+	// Reserves the key for an in-progress request, replacing any expired
+	// record with the same key. Returns nil if the key was reserved, or the
+	// existing live record if the key is already in use.
+	Reserve(ctx context.Context, idempotencyKey model.IdempotencyKey) (*model.IdempotencyKey, error)
+
+	// Stores the response for a reserved key.
+	Complete(ctx context.Context, key string, statusCode int, responseBody []byte) error
+
+	// Removes a reserved key that has no stored response, so that the request
+	// may be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// APIKeyDAO defines the interface for API key data access operations.
+type APIKeyDAO interface {
+	Create(ctx context.Context, apiKey model.APIKey) (*model.APIKey, error)
+
+	// Returns the unrevoked key with the provided hash. Returns nil if no such
+	// key exists.
+	GetByKeyHash(ctx context.Context, keyHash string) (*model.APIKey, error)
+
+	// Returns all keys, including revoked ones, ordered by ID.
+	List(ctx context.Context) ([]model.APIKey, error)
+
+	// Revokes the key with the provided ID. Returns apperrors.ErrAPIKeyNotFound
+	// if no unrevoked key has that ID.
+	Revoke(ctx context.Context, id uint) error
+}
+
+// DomainDAO defines the interface for custom domain data access operations.
+type DomainDAO interface {
+	// Creates the domain. Returns apperrors.ErrDomainAlreadyExists if a domain
+	// with the same hostname exists.
+	Create(ctx context.Context, domain model.Domain) (*model.Domain, error)
+
+	// Returns the domain with the provided hostname. Returns nil if no such
+	// domain exists.
+	GetByHostname(ctx context.Context, hostname string) (*model.Domain, error)
+
+	// Returns all domains, ordered by hostname.
+	List(ctx context.Context) ([]model.Domain, error)
+
+	// Deletes the domain with the provided hostname. Returns
+	// apperrors.ErrDomainNotFound if no such domain exists.
+	Delete(ctx context.Context, hostname string) error
+}
+
+// QuotaDAO defines the interface for per-owner usage counters.
+type QuotaDAO interface {
+	// Atomically adds n to the owner's create count for the provided day
+	// ("2006-01-02"), unless the result would exceed limit. Returns false,
+	// leaving the count unchanged, if it would.
+	IncrementDailyCreates(ctx context.Context, ownerID string, day string, n int, limit int) (bool, error)
+
+	// Subtracts n from the owner's create count for the provided day, e.g. to
+	// return creates that were counted but failed.
+	DecrementDailyCreates(ctx context.Context, ownerID string, day string, n int) error
+}
+
+// PasswordAttemptDAO defines the interface for counting failed password
+// attempts, e.g. per short code or per client IP. Counts reset at the end of a
+// fixed window that starts with the first failed attempt.
+type PasswordAttemptDAO interface {
+	// Atomically adds a failed attempt to the key's count and returns the new
+	// count. Starts a new window of the provided length if the key has none.
+	IncrementFailedAttempts(ctx context.Context, key string, window time.Duration) (int, error)
+
+	// Subtracts a failed attempt from the key's count in its current window,
+	// e.g. to return an attempt that was counted but succeeded. Does nothing if
+	// the key has no attempts in its current window.
+	DecrementFailedAttempts(ctx context.Context, key string) error
+}
@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/model"
+)
+
+// APIKeyMemoryDAO is an in-memory implementation of APIKeyDAO.
+type APIKeyMemoryDAO struct {
+	mu        sync.RWMutex
+	idCounter uint
+	apiKeys   map[uint]*model.APIKey // Map from ID to API key
+}
+
+// NewAPIKeyMemoryDAO creates a new in-memory DAO instance.
+func NewAPIKeyMemoryDAO() *APIKeyMemoryDAO {
+	return &APIKeyMemoryDAO{
+		idCounter: 1,
+		apiKeys:   make(map[uint]*model.APIKey),
+	}
+}
+
+func (m *APIKeyMemoryDAO) Create(_ctx context.Context, apiKey model.APIKey) (*model.APIKey, error) {
+	// Context is not needed for in-memory store, since in-memory store is very fast.
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	apiKey.ID = m.idCounter
+	apiKey.CreatedAt = time.Now()
+	m.apiKeys[apiKey.ID] = &apiKey
+	m.idCounter++
+
+	created := apiKey
+	return &created, nil
+}
+
+func (m *APIKeyMemoryDAO) GetByKeyHash(_ctx context.Context, keyHash string) (*model.APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Simulate the indexed database lookup with a full scan, since the
+	// in-memory store is only intended for small data sets.
+	for _, apiKey := range m.apiKeys {
+		if apiKey.KeyHash == keyHash && !apiKey.IsRevoked() {
+			found := *apiKey
+			return &found, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (m *APIKeyMemoryDAO) List(_ctx context.Context) ([]model.APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	apiKeys := make([]model.APIKey, 0, len(m.apiKeys))
+	for _, apiKey := range m.apiKeys {
+		apiKeys = append(apiKeys, *apiKey)
+	}
+	slices.SortFunc(apiKeys, func(a, b model.APIKey) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return apiKeys, nil
+}
+
+func (m *APIKeyMemoryDAO) Revoke(_ctx context.Context, id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	apiKey, ok := m.apiKeys[id]
+	if !ok || apiKey.IsRevoked() {
+		return apperrors.ErrAPIKeyNotFound
+	}
+
+	// Replace rather than mutate the key, since callers may hold a pointer to
+	// it.
+	revokedAt := time.Now()
+	revokedKey := *apiKey
+	revokedKey.RevokedAt = &revokedAt
+	m.apiKeys[id] = &revokedKey
+
+	return nil
+}
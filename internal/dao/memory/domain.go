@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/model"
+)
+
+// DomainMemoryDAO is an in-memory implementation of DomainDAO.
+type DomainMemoryDAO struct {
+	mu      sync.RWMutex
+	domains map[string]*model.Domain // Map from hostname to domain
+}
+
+// NewDomainMemoryDAO creates a new in-memory DAO instance.
+func NewDomainMemoryDAO() *DomainMemoryDAO {
+	return &DomainMemoryDAO{
+		domains: make(map[string]*model.Domain),
+	}
+}
+
+func (m *DomainMemoryDAO) Create(_ctx context.Context, domain model.Domain) (*model.Domain, error) {
+	// Context is not needed for in-memory store, since in-memory store is very fast.
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.domains[domain.Hostname]; ok {
+		return nil, apperrors.ErrDomainAlreadyExists
+	}
+
+	domain.CreatedAt = time.Now()
+	m.domains[domain.Hostname] = &domain
+
+	created := domain
+	return &created, nil
+}
+
+func (m *DomainMemoryDAO) GetByHostname(_ctx context.Context, hostname string) (*model.Domain, error) {
+	// Context is not needed for in-memory store, since in-memory store is very fast.
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if domain, ok := m.domains[hostname]; ok {
+		domainCopy := *domain
+		return &domainCopy, nil
+	}
+
+	return nil, nil
+}
+
+func (m *DomainMemoryDAO) List(_ctx context.Context) ([]model.Domain, error) {
+	// Context is not needed for in-memory store, since in-memory store is very fast.
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	domains := make([]model.Domain, 0, len(m.domains))
+	for _, domain := range m.domains {
+		domains = append(domains, *domain)
+	}
+	slices.SortFunc(domains, func(a, b model.Domain) int {
+		return cmp.Compare(a.Hostname, b.Hostname)
+	})
+
+	return domains, nil
+}
+
+func (m *DomainMemoryDAO) Delete(_ctx context.Context, hostname string) error {
+	// Context is not needed for in-memory store, since in-memory store is very fast.
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.domains[hostname]; !ok {
+		return apperrors.ErrDomainNotFound
+	}
+	delete(m.domains, hostname)
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"tiny-bitly/internal/model"
+)
+
+// IdempotencyKeyMemoryDAO is an in-memory implementation of IdempotencyKeyDAO.
+type IdempotencyKeyMemoryDAO struct {
+	mu              sync.Mutex
+	idempotencyKeys map[string]*model.IdempotencyKey // Map from key to record
+}
+
+// NewIdempotencyKeyMemoryDAO creates a new in-memory DAO instance.
+func NewIdempotencyKeyMemoryDAO() *IdempotencyKeyMemoryDAO {
+	return &IdempotencyKeyMemoryDAO{
+		idempotencyKeys: make(map[string]*model.IdempotencyKey),
+	}
+}
+
+func (m *IdempotencyKeyMemoryDAO) Reserve(_ctx context.Context, idempotencyKey model.IdempotencyKey) (*model.IdempotencyKey, error) {
+	// Context is not needed for in-memory store, since in-memory store is very fast.
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.idempotencyKeys[idempotencyKey.Key]; ok && !existing.IsExpired() {
+		existingCopy := *existing
+		return &existingCopy, nil
+	}
+
+	m.idempotencyKeys[idempotencyKey.Key] = &idempotencyKey
+	return nil, nil
+}
+
+func (m *IdempotencyKeyMemoryDAO) Complete(_ctx context.Context, key string, statusCode int, responseBody []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.idempotencyKeys[key]
+	if !ok {
+		return nil
+	}
+
+	// Replace rather than mutate, since callers may hold copies.
+	updated := *existing
+	updated.StatusCode = statusCode
+	updated.ResponseBody = responseBody
+	m.idempotencyKeys[key] = &updated
+
+	return nil
+}
+
+func (m *IdempotencyKeyMemoryDAO) Release(_ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.idempotencyKeys[key]; ok && !existing.IsCompleted() {
+		delete(m.idempotencyKeys, key)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PasswordAttemptMemoryDAO is an in-memory implementation of
+// PasswordAttemptDAO.
+type PasswordAttemptMemoryDAO struct {
+	mu       sync.Mutex
+	attempts map[string]*failedAttempts // Map from key to its current window
+}
+
+type failedAttempts struct {
+	count     int
+	expiresAt time.Time
+}
+
+// NewPasswordAttemptMemoryDAO creates a new in-memory DAO instance.
+func NewPasswordAttemptMemoryDAO() *PasswordAttemptMemoryDAO {
+	return &PasswordAttemptMemoryDAO{
+		attempts: make(map[string]*failedAttempts),
+	}
+}
+
+func (m *PasswordAttemptMemoryDAO) IncrementFailedAttempts(_ctx context.Context, key string, window time.Duration) (int, error) {
+	// Context is not needed for in-memory store, since in-memory store is very fast.
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	attempts, ok := m.attempts[key]
+	if !ok || !attempts.expiresAt.After(now) {
+		attempts = &failedAttempts{expiresAt: now.Add(window)}
+		m.attempts[key] = attempts
+	}
+	attempts.count++
+	return attempts.count, nil
+}
+
+func (m *PasswordAttemptMemoryDAO) DecrementFailedAttempts(_ctx context.Context, key string) error {
+	// Context is not needed for in-memory store, since in-memory store is very fast.
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempts, ok := m.attempts[key]
+	if ok && attempts.expiresAt.After(time.Now()) && attempts.count > 0 {
+		attempts.count--
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"context"
+	"sync"
+)
+
+// QuotaMemoryDAO is an in-memory implementation of QuotaDAO.
+type QuotaMemoryDAO struct {
+	mu           sync.Mutex
+	dailyCreates map[dailyCreatesKey]int // Map from owner and day to create count
+}
+
+type dailyCreatesKey struct {
+	ownerID string
+	day     string
+}
+
+// NewQuotaMemoryDAO creates a new in-memory DAO instance.
+func NewQuotaMemoryDAO() *QuotaMemoryDAO {
+	return &QuotaMemoryDAO{
+		dailyCreates: make(map[dailyCreatesKey]int),
+	}
+}
+
+func (m *QuotaMemoryDAO) IncrementDailyCreates(_ctx context.Context, ownerID string, day string, n int, limit int) (bool, error) {
+	// Context is not needed for in-memory store, since in-memory store is very fast.
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := dailyCreatesKey{ownerID: ownerID, day: day}
+	if m.dailyCreates[key]+n > limit {
+		return false, nil
+	}
+	m.dailyCreates[key] += n
+	return true, nil
+}
+
+func (m *QuotaMemoryDAO) DecrementDailyCreates(_ctx context.Context, ownerID string, day string, n int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := dailyCreatesKey{ownerID: ownerID, day: day}
+	m.dailyCreates[key] = max(m.dailyCreates[key]-n, 0)
+	return nil
+}
@@ -1,26 +1,36 @@
 package memory
 
 import (
+	"cmp"
 	"context"
+	"net/url"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"tiny-bitly/internal/apperrors"
 	"tiny-bitly/internal/model"
+
+	"gorm.io/gorm"
 )
 
 // URLRecordMemoryDAO is an in-memory implementation of URLRecordDAO.
 type URLRecordMemoryDAO struct {
-	mu        sync.RWMutex
-	idCounter uint
-	entities  map[string]*model.URLRecordEntity // Map from short code to URL Record
+	mu            sync.RWMutex
+	idCounter     uint
+	entities      map[string]*model.URLRecordEntity // Map from record key to URL Record
+	byOriginalURL map[string]map[string]struct{}    // Map from original URL to record keys
+	activeLinks   map[string]int                    // Map from owner to number of records counted as active
 }
 
 // NewURLRecordMemoryDAO creates a new in-memory DAO instance.
 func NewURLRecordMemoryDAO() *URLRecordMemoryDAO {
 	return &URLRecordMemoryDAO{
-		idCounter: 1,
-		entities:  make(map[string]*model.URLRecordEntity),
+		idCounter:     1,
+		entities:      make(map[string]*model.URLRecordEntity),
+		byOriginalURL: make(map[string]map[string]struct{}),
+		activeLinks:   make(map[string]int),
 	}
 }
 
@@ -30,41 +40,367 @@ func (m *URLRecordMemoryDAO) Create(_ctx context.Context, urlRecord model.URLRec
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Fail if this short code is already in use by an active record.
+	entity := m.create(urlRecord, time.Now())
+	if entity == nil {
+		// Simulate a DB query that filters by deleted and expired status:
+		return nil, apperrors.ErrShortCodeAlreadyInUse
+	}
+
+	return entity, nil
+}
+
+func (m *URLRecordMemoryDAO) CreateBatch(_ctx context.Context, urlRecords []model.URLRecord) ([]*model.URLRecordEntity, error) {
+	// Context is not needed for in-memory store, since in-memory store is very fast.
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	entities := make([]*model.URLRecordEntity, len(urlRecords))
+	for i, urlRecord := range urlRecords {
+		entities[i] = m.create(urlRecord, now)
+	}
+
+	return entities, nil
+}
+
+func (m *URLRecordMemoryDAO) GetByShortCode(_ctx context.Context, domain string, shortCode string) (*model.URLRecordEntity, error) {
+	// Context is not needed for in-memory store, since in-memory store is very fast.
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if existingEntity, ok := m.entities[recordKey(domain, shortCode)]; ok {
+		if !existingEntity.IsDeleted() {
+			return existingEntity, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (m *URLRecordMemoryDAO) GetByOriginalURL(_ctx context.Context, originalURL string, domain string, ownerID *string) (*model.URLRecordEntity, error) {
+	// Context is not needed for in-memory store, since in-memory store is very fast.
 
-	if existingEntity, ok := m.entities[urlRecord.ShortCode]; ok {
-		if !existingEntity.IsExpired() {
-			// Simulate a DB query that filters by deleted and expired status:
-			return nil, apperrors.ErrShortCodeAlreadyInUse
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var latestEntity *model.URLRecordEntity
+	for key := range m.byOriginalURL[originalURL] {
+		entity := m.entities[key]
+		if entity.IsExpired() || entity.IsDeleted() || entity.IsNotYetActive() || entity.Domain != domain || !isOwnedBy(entity, ownerID) {
+			continue
 		}
+		if latestEntity == nil || entity.ExpiresAt.After(latestEntity.ExpiresAt) {
+			latestEntity = entity
+		}
+	}
+
+	return latestEntity, nil
+}
+
+func (m *URLRecordMemoryDAO) GetByShortCodeIncludingInactive(_ctx context.Context, domain string, shortCode string, ownerID *string) (*model.URLRecordEntity, error) {
+	// Context is not needed for in-memory store, since in-memory store is very fast.
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if existingEntity, ok := m.entities[recordKey(domain, shortCode)]; ok && isOwnedBy(existingEntity, ownerID) {
+		return existingEntity, nil
+	}
+
+	return nil, nil
+}
+
+func (m *URLRecordMemoryDAO) ConsumeClick(_ctx context.Context, urlRecord *model.URLRecordEntity) (bool, error) {
+	// Context is not needed for in-memory store, since in-memory store is very fast.
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := recordKey(urlRecord.Domain, urlRecord.ShortCode)
+	existingEntity, ok := m.entities[key]
+	if !ok || existingEntity.ID != urlRecord.ID || !existingEntity.IsClickLimited() || existingEntity.IsExpired() || existingEntity.IsDeleted() {
+		return false, nil
+	}
+
+	// Replace rather than mutate the entity, since callers may hold a pointer
+	// to it.
+	clickedEntity := *existingEntity
+	remainingClicks := *existingEntity.RemainingClicks - 1
+	clickedEntity.RemainingClicks = &remainingClicks
+	if remainingClicks == 0 {
+		now := time.Now()
+		clickedEntity.ExhaustedAt = &now
 	}
+	m.entities[key] = &clickedEntity
+
+	return true, nil
+}
+
+func (m *URLRecordMemoryDAO) List(_ctx context.Context, params model.URLRecordListParams) ([]model.URLRecordEntity, error) {
+	// Context is not needed for in-memory store, since in-memory store is very fast.
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
+	// Simulate the database query with a full scan, since the in-memory store
+	// is only intended for small data sets.
 	now := time.Now()
+	entities := make([]model.URLRecordEntity, 0)
+	for _, entity := range m.entities {
+		if entity.IsDeleted() || !isOwnedBy(entity, params.OwnerID) || !matchesFilter(entity, params.Filter, now) {
+			continue
+		}
+		if params.After != nil && !isAfterCursor(entity, *params.After) {
+			continue
+		}
+		entities = append(entities, *entity)
+	}
+
+	slices.SortFunc(entities, func(a, b model.URLRecordEntity) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+
+	if len(entities) > params.Limit {
+		entities = entities[:params.Limit]
+	}
+	return entities, nil
+}
+
+func (m *URLRecordMemoryDAO) ReserveActiveLinks(_ctx context.Context, ownerID string, n int, limit int) (bool, error) {
+	// Context is not needed for in-memory store, since in-memory store is very fast.
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if limit > 0 && m.activeLinks[ownerID]+n > limit {
+		// Stop counting the owner's records that are no longer active.
+		for key, entity := range m.entities {
+			if entity.CountedAsActive && entity.IsExpired() && isOwnedBy(entity, &ownerID) {
+				m.entities[key] = m.uncount(entity)
+			}
+		}
+		if m.activeLinks[ownerID]+n > limit {
+			return false, nil
+		}
+	}
+
+	m.activeLinks[ownerID] += n
+	return true, nil
+}
+
+func (m *URLRecordMemoryDAO) ReleaseActiveLinks(_ctx context.Context, ownerID string, n int) error {
+	// Context is not needed for in-memory store, since in-memory store is very fast.
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.activeLinks[ownerID] = max(m.activeLinks[ownerID]-n, 0)
+	return nil
+}
+
+func (m *URLRecordMemoryDAO) Update(_ctx context.Context, domain string, shortCode string, ownerID *string, update model.URLRecordUpdate) (*model.URLRecordEntity, error) {
+	// Context is not needed for in-memory store, since in-memory store is very fast.
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := recordKey(domain, shortCode)
+	existingEntity, ok := m.entities[key]
+	if !ok || existingEntity.IsExpired() || existingEntity.IsDeleted() || !isOwnedBy(existingEntity, ownerID) {
+		return nil, apperrors.ErrShortCodeNotFound
+	}
+
+	// Replace rather than mutate the entity, since callers may hold a pointer
+	// to it.
+	updatedEntity := *existingEntity
+	if update.OriginalURL != nil {
+		updatedEntity.OriginalURL = *update.OriginalURL
+	}
+	if update.ExpiresAt != nil {
+		updatedEntity.ExpiresAt = *update.ExpiresAt
+	}
+	updatedEntity.UpdatedAt = time.Now()
+	m.unindexOriginalURL(existingEntity)
+	m.entities[key] = &updatedEntity
+	m.indexOriginalURL(&updatedEntity)
+
+	return &updatedEntity, nil
+}
+
+func (m *URLRecordMemoryDAO) Delete(_ctx context.Context, domain string, shortCode string, ownerID *string) error {
+	// Context is not needed for in-memory store, since in-memory store is very fast.
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := recordKey(domain, shortCode)
+	existingEntity, ok := m.entities[key]
+	if !ok || existingEntity.IsExpired() || existingEntity.IsDeleted() || !isOwnedBy(existingEntity, ownerID) {
+		return apperrors.ErrShortCodeNotFound
+	}
+
+	// Replace rather than mutate the entity, since callers may hold a pointer
+	// to it.
+	deletedEntity := *m.uncount(existingEntity)
+	deletedEntity.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	m.entities[key] = &deletedEntity
+
+	return nil
+}
+
+func (m *URLRecordMemoryDAO) PurgeExpired(_ctx context.Context, before time.Time, limit int, _archive bool) ([]model.URLRecordEntity, error) {
+	// Context is not needed for in-memory store, since in-memory store is very fast.
+	// There is no history table in memory, so purged records are simply dropped.
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	purged := make([]model.URLRecordEntity, 0)
+	for key, entity := range m.entities {
+		if len(purged) >= limit {
+			break
+		}
+		isExpired := !entity.ExpiresAt.After(before)
+		isDeleted := entity.IsDeleted() && !entity.DeletedAt.Time.After(before)
+		isExhausted := entity.ExhaustedAt != nil && !entity.ExhaustedAt.After(before)
+		if !isExpired && !isDeleted && !isExhausted {
+			continue
+		}
+		m.uncount(entity)
+		m.unindexOriginalURL(entity)
+		delete(m.entities, key)
+		purged = append(purged, *entity)
+	}
+
+	return purged, nil
+}
+
+// Subtracts the entity from its owner's count of active records if it is
+// counted, and returns a copy of it that is not. Callers must hold the write
+// lock.
+func (m *URLRecordMemoryDAO) uncount(entity *model.URLRecordEntity) *model.URLRecordEntity {
+	if !entity.CountedAsActive {
+		return entity
+	}
+	if entity.OwnerID != nil {
+		m.activeLinks[*entity.OwnerID] = max(m.activeLinks[*entity.OwnerID]-1, 0)
+	}
+
+	// Replace rather than mutate the entity, since callers may hold a pointer
+	// to it.
+	uncountedEntity := *entity
+	uncountedEntity.CountedAsActive = false
+	return &uncountedEntity
+}
+
+// Returns the key of a record in the entities map. Short codes are unique per
+// domain.
+func recordKey(domain string, shortCode string) string {
+	return domain + "/" + shortCode
+}
+
+// Returns true if the entity has the provided owner. A nil ownerID matches
+// entities without an owner.
+func isOwnedBy(entity *model.URLRecordEntity, ownerID *string) bool {
+	if ownerID == nil || entity.OwnerID == nil {
+		return ownerID == nil && entity.OwnerID == nil
+	}
+	return *entity.OwnerID == *ownerID
+}
+
+// Returns true if the entity satisfies every set field of the filter.
+func matchesFilter(entity *model.URLRecordEntity, filter model.URLRecordFilter, now time.Time) bool {
+	if filter.CreatedAfter != nil && !entity.CreatedAt.After(*filter.CreatedAfter) {
+		return false
+	}
+	if filter.CreatedBefore != nil && !entity.CreatedAt.Before(*filter.CreatedBefore) {
+		return false
+	}
+	if filter.ExpiringBefore != nil && !entity.ExpiresAt.Before(*filter.ExpiringBefore) {
+		return false
+	}
+	isActive := entity.ExpiresAt.After(now) && !entity.IsExhausted()
+	switch filter.Status {
+	case model.URLRecordStatusActive:
+		if !isActive {
+			return false
+		}
+	case model.URLRecordStatusExpired:
+		if isActive {
+			return false
+		}
+	}
+	if filter.Host != "" {
+		parsedURL, err := url.Parse(entity.OriginalURL)
+		if err != nil || !strings.EqualFold(parsedURL.Hostname(), filter.Host) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns true if the entity sorts strictly after the cursor, in order of
+// creation time and then ID, both descending.
+func isAfterCursor(entity *model.URLRecordEntity, cursor model.URLRecordCursor) bool {
+	if entity.CreatedAt.Equal(cursor.CreatedAt) {
+		return entity.ID < cursor.ID
+	}
+	return entity.CreatedAt.Before(cursor.CreatedAt)
+}
+
+// Stores a new entity for the URL record, or returns nil if the short code is
+// already in use on the record's domain by an active record. Callers must hold
+// the write lock.
+func (m *URLRecordMemoryDAO) create(urlRecord model.URLRecord, now time.Time) *model.URLRecordEntity {
+	// Fail if this short code is already in use by an active record.
+	key := recordKey(urlRecord.Domain, urlRecord.ShortCode)
+	existingEntity, ok := m.entities[key]
+	if ok {
+		if !existingEntity.IsExpired() && !existingEntity.IsDeleted() {
+			return nil
+		}
+		m.uncount(existingEntity)
+		m.unindexOriginalURL(existingEntity)
+	}
+
 	entity := &model.URLRecordEntity{
 		Entity: model.Entity{
 			ID:        m.idCounter,
 			CreatedAt: now,
+			UpdatedAt: now,
 		},
-		URLRecord: urlRecord,
+		URLRecord:       urlRecord,
+		CountedAsActive: urlRecord.OwnerID != nil,
 	}
 
-	m.entities[entity.ShortCode] = entity
+	m.entities[key] = entity
+	m.indexOriginalURL(entity)
 	m.idCounter++
 
-	return entity, nil
+	return entity
 }
 
-func (m *URLRecordMemoryDAO) GetByShortCode(_ctx context.Context, shortCode string) (*model.URLRecordEntity, error) {
-	// Context is not needed for in-memory store, since in-memory store is very fast.
-
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	if existingEntity, ok := m.entities[shortCode]; ok {
-		if !existingEntity.IsExpired() {
-			return existingEntity, nil
-		}
+// Adds the entity to the original URL index. Callers must hold the write lock.
+func (m *URLRecordMemoryDAO) indexOriginalURL(entity *model.URLRecordEntity) {
+	keys, ok := m.byOriginalURL[entity.OriginalURL]
+	if !ok {
+		keys = make(map[string]struct{})
+		m.byOriginalURL[entity.OriginalURL] = keys
 	}
+	keys[recordKey(entity.Domain, entity.ShortCode)] = struct{}{}
+}
 
-	return nil, nil
+// Removes the entity from the original URL index. Callers must hold the write
+// lock.
+func (m *URLRecordMemoryDAO) unindexOriginalURL(entity *model.URLRecordEntity) {
+	keys := m.byOriginalURL[entity.OriginalURL]
+	delete(keys, recordKey(entity.Domain, entity.ShortCode))
+	if len(keys) == 0 {
+		delete(m.byOriginalURL, entity.OriginalURL)
+	}
 }
@@ -0,0 +1,7 @@
+-- Restore the full composite index
+DROP INDEX IF EXISTS idx_url_records_short_code_expires_at;
+CREATE INDEX idx_url_records_short_code_expires_at ON url_records(short_code, expires_at);
+COMMENT ON INDEX idx_url_records_short_code_expires_at IS 'Composite index for efficient lookups by short_code with expiration filtering';
+
+-- Drop soft delete column
+ALTER TABLE url_records DROP COLUMN IF EXISTS deleted_at;
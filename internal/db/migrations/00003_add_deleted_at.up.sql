@@ -0,0 +1,11 @@
+-- Add soft delete support
+ALTER TABLE url_records ADD COLUMN deleted_at TIMESTAMP NULL;
+
+-- Recreate the lookup index as a partial index so that it only covers records
+-- that have not been soft-deleted. Lookups filter on: short_code = ? AND
+-- expires_at > ? AND deleted_at IS NULL
+DROP INDEX IF EXISTS idx_url_records_short_code_expires_at;
+CREATE INDEX idx_url_records_short_code_expires_at ON url_records(short_code, expires_at) WHERE deleted_at IS NULL;
+
+COMMENT ON COLUMN url_records.deleted_at IS 'Set when the record is soft-deleted; NULL for live records';
+COMMENT ON INDEX idx_url_records_short_code_expires_at IS 'Partial composite index for efficient lookups of non-deleted records by short_code with expiration filtering';
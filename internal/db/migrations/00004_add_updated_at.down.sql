@@ -0,0 +1,2 @@
+-- Drop updated_at column
+ALTER TABLE url_records DROP COLUMN IF EXISTS updated_at;
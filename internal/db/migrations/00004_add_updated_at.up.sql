@@ -0,0 +1,8 @@
+-- Track when a record was last modified. Backfill existing rows with their
+-- creation time before enforcing NOT NULL.
+ALTER TABLE url_records ADD COLUMN updated_at TIMESTAMP NULL;
+UPDATE url_records SET updated_at = created_at;
+ALTER TABLE url_records ALTER COLUMN updated_at SET NOT NULL;
+ALTER TABLE url_records ALTER COLUMN updated_at SET DEFAULT NOW();
+
+COMMENT ON COLUMN url_records.updated_at IS 'Set when the record is created and whenever its destination or expiration changes';
@@ -0,0 +1,6 @@
+-- Drop listing indexes
+DROP INDEX IF EXISTS idx_url_records_original_host_created_at_id;
+DROP INDEX IF EXISTS idx_url_records_created_at_id;
+
+-- Drop generated column
+ALTER TABLE url_records DROP COLUMN IF EXISTS original_host;
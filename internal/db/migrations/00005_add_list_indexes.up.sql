@@ -0,0 +1,14 @@
+-- Derive the destination host from original_url so that listings can filter by
+-- host using an index. Matches "scheme://[userinfo@]host" and lowercases host.
+ALTER TABLE url_records ADD COLUMN original_host TEXT
+    GENERATED ALWAYS AS (lower(substring(original_url from '^[A-Za-z][A-Za-z0-9+.-]*://(?:[^/?#@]*@)?([^/?#:]+)'))) STORED;
+
+-- Create indexes for keyset pagination ordered by (created_at, id) descending
+-- This index covers: ORDER BY created_at DESC, id DESC with (created_at, id) < (?, ?)
+CREATE INDEX idx_url_records_created_at_id ON url_records(created_at DESC, id DESC) WHERE deleted_at IS NULL;
+-- This index covers the same ordering when filtering by destination host
+CREATE INDEX idx_url_records_original_host_created_at_id ON url_records(original_host, created_at DESC, id DESC) WHERE deleted_at IS NULL;
+
+COMMENT ON COLUMN url_records.original_host IS 'Lowercased host of original_url, generated for filtering listings by destination host';
+COMMENT ON INDEX idx_url_records_created_at_id IS 'Partial index for keyset pagination of non-deleted records';
+COMMENT ON INDEX idx_url_records_original_host_created_at_id IS 'Partial index for keyset pagination of non-deleted records filtered by destination host';
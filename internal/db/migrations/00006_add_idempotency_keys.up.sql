@@ -0,0 +1,16 @@
+-- Create idempotency_keys table
+CREATE TABLE idempotency_keys (
+    key VARCHAR(255) PRIMARY KEY,
+    fingerprint VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    response_body BYTEA NULL
+);
+
+-- Create index for finding expired keys
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+COMMENT ON TABLE idempotency_keys IS 'Stores Idempotency-Key headers with a request fingerprint and the response to replay';
+COMMENT ON COLUMN idempotency_keys.fingerprint IS 'SHA-256 hex digest of the request the key was first used with';
+COMMENT ON COLUMN idempotency_keys.status_code IS 'HTTP status of the stored response; 0 while the request is in progress';
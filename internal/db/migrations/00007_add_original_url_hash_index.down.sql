@@ -0,0 +1,2 @@
+-- Drop original URL hash index
+DROP INDEX IF EXISTS idx_url_records_original_url_md5;
@@ -0,0 +1,7 @@
+-- Index a hash of original_url so that deduplication can find existing records
+-- for a URL. original_url is unbounded TEXT, so index md5(original_url) rather
+-- than the column itself. Lookups filter on:
+-- md5(original_url) = md5(?) AND original_url = ? AND deleted_at IS NULL
+CREATE INDEX idx_url_records_original_url_md5 ON url_records(md5(original_url)) WHERE deleted_at IS NULL;
+
+COMMENT ON INDEX idx_url_records_original_url_md5 IS 'Partial expression index for looking up non-deleted records by original_url';
@@ -0,0 +1,17 @@
+-- Create api_keys table
+CREATE TABLE api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    owner_id VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    key_prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMP NULL
+);
+
+-- Create index for authenticating requests by key hash
+CREATE UNIQUE INDEX idx_api_keys_key_hash ON api_keys(key_hash);
+
+COMMENT ON TABLE api_keys IS 'Stores hashed API keys and the owner each key acts on behalf of';
+COMMENT ON COLUMN api_keys.key_hash IS 'SHA-256 hex digest of the key; the key itself is never stored';
+COMMENT ON COLUMN api_keys.revoked_at IS 'Set when the key is revoked; NULL for usable keys';
@@ -0,0 +1,5 @@
+-- Drop owner index
+DROP INDEX IF EXISTS idx_url_records_owner_id_created_at_id;
+
+-- Drop owner column
+ALTER TABLE url_records DROP COLUMN IF EXISTS owner_id;
@@ -0,0 +1,10 @@
+-- Record which API key owner created each record. Existing records have no
+-- owner.
+ALTER TABLE url_records ADD COLUMN owner_id VARCHAR(255) NULL;
+
+-- Create index for keyset pagination of an owner's records, ordered by
+-- (created_at, id) descending. Listings always filter on owner_id.
+CREATE INDEX idx_url_records_owner_id_created_at_id ON url_records(owner_id, created_at DESC, id DESC) WHERE deleted_at IS NULL;
+
+COMMENT ON COLUMN url_records.owner_id IS 'Owner of the API key that created the record; NULL for records created without one';
+COMMENT ON INDEX idx_url_records_owner_id_created_at_id IS 'Partial index for keyset pagination of non-deleted records by owner';
@@ -0,0 +1,2 @@
+-- Drop table
+DROP TABLE IF EXISTS owner_daily_creates;
@@ -0,0 +1,10 @@
+-- Create owner_daily_creates table
+CREATE TABLE owner_daily_creates (
+    owner_id VARCHAR(255) NOT NULL,
+    day DATE NOT NULL,
+    creates INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (owner_id, day)
+);
+
+COMMENT ON TABLE owner_daily_creates IS 'Counts the short URLs each API key owner created per UTC day, for enforcing quotas';
+COMMENT ON COLUMN owner_daily_creates.day IS 'UTC date the creates are counted against';
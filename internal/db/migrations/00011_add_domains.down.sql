@@ -0,0 +1,12 @@
+-- Restore the global short code indexes. Fails if the same short code is used
+-- on more than one domain.
+DROP INDEX IF EXISTS idx_url_records_domain_short_code_expires_at;
+CREATE INDEX idx_url_records_short_code_expires_at ON url_records(short_code, expires_at) WHERE deleted_at IS NULL;
+DROP INDEX IF EXISTS idx_url_records_domain_short_code;
+CREATE UNIQUE INDEX idx_url_records_short_code ON url_records(short_code);
+
+-- Drop column
+ALTER TABLE url_records DROP COLUMN IF EXISTS domain;
+
+-- Drop table
+DROP TABLE IF EXISTS domains;
@@ -0,0 +1,27 @@
+-- Create domains table
+CREATE TABLE domains (
+    hostname VARCHAR(255) PRIMARY KEY,
+    owner_id VARCHAR(255) NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE domains IS 'Custom domains that serve short codes from their own namespace';
+COMMENT ON COLUMN domains.hostname IS 'Lowercased hostname without a port, matched against the Host header of redirects';
+COMMENT ON COLUMN domains.owner_id IS 'Owner that may create short codes on the domain; NULL if every owner may';
+
+-- Add the domain of each record. Existing records belong to the default
+-- domain, which is stored as an empty string rather than NULL so that the
+-- unique index below also applies to it.
+ALTER TABLE url_records ADD COLUMN domain VARCHAR(255) NOT NULL DEFAULT '';
+
+-- Short codes are unique per domain rather than globally.
+DROP INDEX IF EXISTS idx_url_records_short_code;
+CREATE UNIQUE INDEX idx_url_records_domain_short_code ON url_records(domain, short_code);
+
+-- Recreate the lookup index to cover the domain. Lookups filter on:
+-- domain = ? AND short_code = ? AND expires_at > ? AND deleted_at IS NULL
+DROP INDEX IF EXISTS idx_url_records_short_code_expires_at;
+CREATE INDEX idx_url_records_domain_short_code_expires_at ON url_records(domain, short_code, expires_at) WHERE deleted_at IS NULL;
+
+COMMENT ON COLUMN url_records.domain IS 'Custom domain whose namespace the short code belongs to; empty for the default domain';
+COMMENT ON INDEX idx_url_records_domain_short_code_expires_at IS 'Partial composite index for efficient lookups of non-deleted records by domain and short_code with expiration filtering';
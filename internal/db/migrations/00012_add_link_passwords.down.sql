@@ -0,0 +1,5 @@
+-- Drop table
+DROP TABLE IF EXISTS failed_password_attempts;
+
+-- Drop column
+ALTER TABLE url_records DROP COLUMN IF EXISTS password_hash;
@@ -0,0 +1,15 @@
+-- Add the password hash of password-protected records
+ALTER TABLE url_records ADD COLUMN password_hash VARCHAR(255) NULL;
+
+COMMENT ON COLUMN url_records.password_hash IS 'bcrypt hash of the password visitors must enter before being redirected; NULL if not password protected';
+
+-- Create failed_password_attempts table
+CREATE TABLE failed_password_attempts (
+    key VARCHAR(512) PRIMARY KEY,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    window_expires_at TIMESTAMP NOT NULL
+);
+
+COMMENT ON TABLE failed_password_attempts IS 'Counts failed password attempts per short code and per client IP, for brute-force protection';
+COMMENT ON COLUMN failed_password_attempts.key IS 'What the attempts are counted against, e.g. a short code or a client IP';
+COMMENT ON COLUMN failed_password_attempts.window_expires_at IS 'Time at which the count resets';
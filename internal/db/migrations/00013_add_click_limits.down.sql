@@ -0,0 +1,3 @@
+-- Drop columns
+ALTER TABLE url_records DROP COLUMN IF EXISTS remaining_clicks;
+ALTER TABLE url_records DROP COLUMN IF EXISTS max_clicks;
@@ -0,0 +1,6 @@
+-- Add the click limit of click-limited records
+ALTER TABLE url_records ADD COLUMN max_clicks INTEGER NULL;
+ALTER TABLE url_records ADD COLUMN remaining_clicks INTEGER NULL;
+
+COMMENT ON COLUMN url_records.max_clicks IS 'Number of times the record may be resolved in total; NULL if not click-limited';
+COMMENT ON COLUMN url_records.remaining_clicks IS 'Number of times the record may still be resolved; NULL if not click-limited. The record behaves like an expired record once this reaches 0';
@@ -0,0 +1,4 @@
+-- Add the activation time of scheduled records
+ALTER TABLE url_records ADD COLUMN activates_at TIMESTAMP NULL;
+
+COMMENT ON COLUMN url_records.activates_at IS 'Time before which the record must not be resolved; NULL if active from creation';
@@ -0,0 +1,2 @@
+-- Drop column
+ALTER TABLE url_records DROP COLUMN IF EXISTS expired_redirect_url;
@@ -0,0 +1,4 @@
+-- Add the fallback URL of expired records
+ALTER TABLE url_records ADD COLUMN expired_redirect_url TEXT NULL;
+
+COMMENT ON COLUMN url_records.expired_redirect_url IS 'URL to redirect visitors to once the record has expired; NULL to respond with 410 Gone';
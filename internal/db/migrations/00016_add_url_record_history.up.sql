@@ -0,0 +1,26 @@
+-- Create url_record_history table. Expired and deleted records are moved here
+-- when a new record reclaims their short code, so that url_records holds at
+-- most one record per domain and short code.
+CREATE TABLE url_record_history (
+    id BIGINT PRIMARY KEY,
+    original_url TEXT NOT NULL,
+    short_code VARCHAR(255) NOT NULL,
+    domain VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    activates_at TIMESTAMP NULL,
+    expired_redirect_url TEXT NULL,
+    owner_id VARCHAR(255) NULL,
+    password_hash VARCHAR(255) NULL,
+    max_clicks INTEGER NULL,
+    remaining_clicks INTEGER NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NULL,
+    deleted_at TIMESTAMP NULL,
+    archived_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_url_record_history_domain_short_code ON url_record_history(domain, short_code);
+
+COMMENT ON TABLE url_record_history IS 'Expired and deleted url_records whose short code was reclaimed by a new record';
+COMMENT ON COLUMN url_record_history.id IS 'ID of the record in url_records';
+COMMENT ON COLUMN url_record_history.archived_at IS 'Time at which a new record reclaimed the short code';
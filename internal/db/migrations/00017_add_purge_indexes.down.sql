@@ -0,0 +1,7 @@
+-- Restore comments
+COMMENT ON TABLE url_record_history IS 'Expired and deleted url_records whose short code was reclaimed by a new record';
+COMMENT ON COLUMN url_record_history.archived_at IS 'Time at which a new record reclaimed the short code';
+COMMENT ON INDEX idx_url_records_expires_at IS NULL;
+
+-- Drop index
+DROP INDEX IF EXISTS idx_url_records_deleted_at;
@@ -0,0 +1,10 @@
+-- Add an index for finding soft-deleted records to purge. Expired records are
+-- found with idx_url_records_expires_at from the initial schema.
+CREATE INDEX idx_url_records_deleted_at ON url_records(deleted_at) WHERE deleted_at IS NOT NULL;
+
+COMMENT ON INDEX idx_url_records_expires_at IS 'Index for finding expired records to purge';
+COMMENT ON INDEX idx_url_records_deleted_at IS 'Partial index for finding soft-deleted records to purge';
+
+-- Purged records are archived to url_record_history as well.
+COMMENT ON TABLE url_record_history IS 'Expired and deleted url_records whose short code was reclaimed by a new record, or that were purged';
+COMMENT ON COLUMN url_record_history.archived_at IS 'Time at which a new record reclaimed the short code, or the record was purged';
@@ -0,0 +1,5 @@
+-- Add the HTTP status code that each record redirects with
+ALTER TABLE url_records ADD COLUMN redirect_type SMALLINT NULL CHECK (redirect_type IN (301, 302, 307, 308));
+ALTER TABLE url_record_history ADD COLUMN redirect_type SMALLINT NULL;
+
+COMMENT ON COLUMN url_records.redirect_type IS 'HTTP status code to redirect visitors with (301, 302, 307 or 308); NULL for the server default';
@@ -0,0 +1,3 @@
+-- Drop columns
+ALTER TABLE url_record_history DROP COLUMN IF EXISTS uncacheable;
+ALTER TABLE url_records DROP COLUMN IF EXISTS uncacheable;
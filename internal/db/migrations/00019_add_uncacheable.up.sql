@@ -0,0 +1,6 @@
+-- Add a flag for records whose redirects must not be cached, e.g. because they
+-- are about to be retargeted
+ALTER TABLE url_records ADD COLUMN uncacheable BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE url_record_history ADD COLUMN uncacheable BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN url_records.uncacheable IS 'Whether browsers and CDNs must not cache redirects of the record';
@@ -0,0 +1,5 @@
+-- Drop columns
+ALTER TABLE url_record_history DROP COLUMN IF EXISTS path_passthrough;
+ALTER TABLE url_record_history DROP COLUMN IF EXISTS query_passthrough;
+ALTER TABLE url_records DROP COLUMN IF EXISTS path_passthrough;
+ALTER TABLE url_records DROP COLUMN IF EXISTS query_passthrough;
@@ -0,0 +1,9 @@
+-- Add per-record settings for passing the query string and path suffix of a
+-- request for a short URL through to its original URL
+ALTER TABLE url_records ADD COLUMN query_passthrough TEXT NULL CHECK (query_passthrough IN ('destination', 'request', 'append'));
+ALTER TABLE url_records ADD COLUMN path_passthrough BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE url_record_history ADD COLUMN query_passthrough TEXT NULL;
+ALTER TABLE url_record_history ADD COLUMN path_passthrough BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN url_records.query_passthrough IS 'How request query parameters are merged into the original URL (destination, request or append); NULL to ignore them';
+COMMENT ON COLUMN url_records.path_passthrough IS 'Whether the path after the short code in a request is appended to the original URL';
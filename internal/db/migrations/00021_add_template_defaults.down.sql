@@ -0,0 +1,3 @@
+-- Drop columns
+ALTER TABLE url_record_history DROP COLUMN IF EXISTS template_defaults;
+ALTER TABLE url_records DROP COLUMN IF EXISTS template_defaults;
@@ -0,0 +1,6 @@
+-- Add the default values of placeholders such as {utm_campaign} in each
+-- record's original URL
+ALTER TABLE url_records ADD COLUMN template_defaults JSONB NULL;
+ALTER TABLE url_record_history ADD COLUMN template_defaults JSONB NULL;
+
+COMMENT ON COLUMN url_records.template_defaults IS 'Default values of placeholders in the original URL, keyed by placeholder name; NULL if they default to the empty string';
@@ -0,0 +1,5 @@
+-- Add per-country destination overrides for geo-targeted records
+ALTER TABLE url_records ADD COLUMN country_urls JSONB NULL;
+ALTER TABLE url_record_history ADD COLUMN country_urls JSONB NULL;
+
+COMMENT ON COLUMN url_records.country_urls IS 'URLs to redirect visitors to instead of the original URL, keyed by ISO 3166-1 alpha-2 country code; NULL if not geo-targeted';
@@ -0,0 +1,5 @@
+-- Drop columns
+ALTER TABLE url_record_history DROP COLUMN IF EXISTS android_url;
+ALTER TABLE url_record_history DROP COLUMN IF EXISTS ios_url;
+ALTER TABLE url_records DROP COLUMN IF EXISTS android_url;
+ALTER TABLE url_records DROP COLUMN IF EXISTS ios_url;
@@ -0,0 +1,8 @@
+-- Add per-platform destinations, e.g. app deep links, for iOS and Android
+ALTER TABLE url_records ADD COLUMN ios_url TEXT NULL;
+ALTER TABLE url_records ADD COLUMN android_url TEXT NULL;
+ALTER TABLE url_record_history ADD COLUMN ios_url TEXT NULL;
+ALTER TABLE url_record_history ADD COLUMN android_url TEXT NULL;
+
+COMMENT ON COLUMN url_records.ios_url IS 'URL to redirect visitors on iOS devices to instead, e.g. an App Store or universal link URL; NULL for the usual destination';
+COMMENT ON COLUMN url_records.android_url IS 'URL to redirect visitors on Android devices to instead, e.g. a Play Store or intent URL; NULL for the usual destination';
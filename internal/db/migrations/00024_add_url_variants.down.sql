@@ -0,0 +1,6 @@
+-- Drop columns
+ALTER TABLE url_record_history DROP COLUMN IF EXISTS sticky_variants;
+ALTER TABLE url_records DROP COLUMN IF EXISTS sticky_variants;
+
+-- Drop table
+DROP TABLE IF EXISTS url_record_variants;
@@ -0,0 +1,21 @@
+-- Create url_record_variants table. Rows are keyed by record ID rather than
+-- referencing url_records, so that they stay with a record that is archived to
+-- url_record_history, which keeps its ID.
+CREATE TABLE url_record_variants (
+    url_record_id BIGINT NOT NULL,
+    position SMALLINT NOT NULL,
+    url TEXT NOT NULL,
+    weight INTEGER NOT NULL CHECK (weight > 0),
+    PRIMARY KEY (url_record_id, position)
+);
+
+COMMENT ON TABLE url_record_variants IS 'Destinations that visitors of split-tested records are spread across in proportion to their weights';
+COMMENT ON COLUMN url_record_variants.position IS 'Index of the variant among its record''s variants, starting at 0';
+COMMENT ON COLUMN url_record_variants.weight IS 'Share of visits the variant receives, relative to the weights of the record''s other variants';
+
+-- Add a flag for split-tested records whose returning visitors keep their
+-- variant
+ALTER TABLE url_records ADD COLUMN sticky_variants BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE url_record_history ADD COLUMN sticky_variants BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN url_records.sticky_variants IS 'Whether returning visitors are sent to the variant they were sent to before, as remembered by a cookie';
@@ -0,0 +1,6 @@
+-- Add ordered redirect rules for records whose destination depends on the
+-- attributes of each request
+ALTER TABLE url_records ADD COLUMN redirect_rules JSONB NULL;
+ALTER TABLE url_record_history ADD COLUMN redirect_rules JSONB NULL;
+
+COMMENT ON COLUMN url_records.redirect_rules IS 'JSON array of rules, each a condition ("when") and the URL to redirect requests that meet it to, evaluated in order; NULL if not rule-based';
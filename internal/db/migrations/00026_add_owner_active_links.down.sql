@@ -0,0 +1,8 @@
+-- Drop index
+DROP INDEX IF EXISTS idx_url_records_owner_id_counted_as_active;
+
+-- Drop column
+ALTER TABLE url_records DROP COLUMN IF EXISTS counted_as_active;
+
+-- Drop table
+DROP TABLE IF EXISTS owner_active_links;
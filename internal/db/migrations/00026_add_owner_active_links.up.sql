@@ -0,0 +1,26 @@
+-- Create owner_active_links table
+CREATE TABLE owner_active_links (
+    owner_id VARCHAR(255) PRIMARY KEY,
+    active_links INTEGER NOT NULL DEFAULT 0
+);
+
+COMMENT ON TABLE owner_active_links IS 'Counts the active short URLs of each API key owner, for enforcing quotas';
+COMMENT ON COLUMN owner_active_links.active_links IS 'Number of the owner''s url_records with counted_as_active set';
+
+-- Add a flag for records that are counted in owner_active_links
+ALTER TABLE url_records ADD COLUMN counted_as_active BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN url_records.counted_as_active IS 'Whether the record is counted in its owner''s active_links, until it is deleted, purged, reclaimed or found to be inactive';
+
+-- Count the existing records with an owner. Those that have expired or run
+-- out of clicks stop being counted once their owner reaches the quota.
+UPDATE url_records SET counted_as_active = TRUE WHERE owner_id IS NOT NULL AND deleted_at IS NULL;
+
+INSERT INTO owner_active_links (owner_id, active_links)
+SELECT owner_id, COUNT(*) FROM url_records WHERE counted_as_active GROUP BY owner_id;
+
+-- Add an index for finding the counted records of an owner that are no longer
+-- active
+CREATE INDEX idx_url_records_owner_id_counted_as_active ON url_records(owner_id) WHERE counted_as_active;
+
+COMMENT ON INDEX idx_url_records_owner_id_counted_as_active IS 'Partial index for finding the records counted in an owner''s active_links';
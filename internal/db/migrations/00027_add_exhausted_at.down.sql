@@ -0,0 +1,6 @@
+-- Drop index
+DROP INDEX IF EXISTS idx_url_records_exhausted_at;
+
+-- Drop columns
+ALTER TABLE url_record_history DROP COLUMN IF EXISTS exhausted_at;
+ALTER TABLE url_records DROP COLUMN IF EXISTS exhausted_at;
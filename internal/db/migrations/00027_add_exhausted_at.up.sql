@@ -0,0 +1,15 @@
+-- Add the time at which click-limited records ran out of clicks, so that they
+-- can be purged a grace period later, like expired and deleted records
+ALTER TABLE url_records ADD COLUMN exhausted_at TIMESTAMP NULL;
+ALTER TABLE url_record_history ADD COLUMN exhausted_at TIMESTAMP NULL;
+
+COMMENT ON COLUMN url_records.exhausted_at IS 'Time at which the last remaining click was consumed; NULL if the record has clicks left or is not click-limited';
+
+-- Start the grace period of records that already ran out of clicks now, since
+-- when they ran out is not known.
+UPDATE url_records SET exhausted_at = NOW() WHERE remaining_clicks <= 0;
+
+-- Add an index for finding records that ran out of clicks to purge
+CREATE INDEX idx_url_records_exhausted_at ON url_records(exhausted_at) WHERE exhausted_at IS NOT NULL;
+
+COMMENT ON INDEX idx_url_records_exhausted_at IS 'Partial index for finding records that ran out of clicks to purge';
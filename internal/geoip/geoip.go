@@ -0,0 +1,145 @@
+package geoip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Database looks up the countries of IP addresses in a MaxMind-format (.mmdb)
+// database file, such as GeoLite2 Country. Safe for concurrent use, including
+// while the file is being reloaded.
+type Database struct {
+	path string
+
+	lock    sync.RWMutex
+	reader  *maxminddb.Reader
+	modTime time.Time
+	size    int64
+}
+
+// The fields of a country database record that lookups need.
+type countryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+}
+
+// Open opens the database file at the provided path.
+func Open(path string) (*Database, error) {
+	d := &Database{path: path}
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the country that the IP
+// address is located in, e.g. "DE", or "" if the database does not know.
+func (d *Database) Country(ip string) (string, error) {
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return "", fmt.Errorf("invalid IP address %q", ip)
+	}
+
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	var record countryRecord
+	if err := d.reader.Lookup(parsedIP, &record); err != nil {
+		return "", err
+	}
+	return record.Country.ISOCode, nil
+}
+
+// Run reloads the database whenever its file changes, checking every interval,
+// until the context is done. A file that fails to load is logged and the
+// previous database kept.
+func (d *Database) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Errors are logged by Reload.
+			_, _ = d.Reload()
+		}
+	}
+}
+
+// Reload reloads the database if its file has changed since it was last
+// loaded. Returns true if it was reloaded.
+func (d *Database) Reload() (bool, error) {
+	info, err := os.Stat(d.path)
+	if err != nil {
+		slog.Error("Failed to check GeoIP database file", "path", d.path, "error", err)
+		return false, err
+	}
+
+	d.lock.RLock()
+	isUnchanged := info.ModTime().Equal(d.modTime) && info.Size() == d.size
+	d.lock.RUnlock()
+	if isUnchanged {
+		return false, nil
+	}
+
+	if err := d.load(); err != nil {
+		slog.Error("Failed to reload GeoIP database file", "path", d.path, "error", err)
+		return false, err
+	}
+	slog.Info("Reloaded GeoIP database file", "path", d.path)
+	return true, nil
+}
+
+// Close closes the database file.
+func (d *Database) Close() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.reader.Close()
+}
+
+// Opens the database file and swaps it in for the current one, if any.
+func (d *Database) load() error {
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return err
+	}
+	// Read the whole file rather than mapping it into memory, so that
+	// replacing it in place cannot corrupt lookups in progress.
+	data, err := os.ReadFile(d.path)
+	if err != nil {
+		return err
+	}
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return err
+	}
+	if err := reader.Verify(); err != nil {
+		reader.Close()
+		return errors.Join(errors.New("invalid GeoIP database file"), err)
+	}
+
+	d.lock.Lock()
+	previous := d.reader
+	d.reader = reader
+	d.modTime = info.ModTime()
+	d.size = info.Size()
+	d.lock.Unlock()
+
+	// Lookups hold the read lock, so none still use the previous reader.
+	if previous != nil {
+		return previous.Close()
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package geoip
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// The fixture databases map 192.0.2.0/24 and 2001:db8::/32 to DE (JP in the
+// reloaded fixture) and 198.51.100.0/24 to FR.
+const (
+	fixturePath         = "testdata/country-test.mmdb"
+	reloadedFixturePath = "testdata/country-test-reloaded.mmdb"
+)
+
+type GeoIPSuite struct {
+	suite.Suite
+	database *Database
+}
+
+func TestGeoIPSuite(t *testing.T) {
+	suite.Run(t, new(GeoIPSuite))
+}
+
+func (suite *GeoIPSuite) SetupTest() {
+	database, err := Open(fixturePath)
+	suite.Require().NoError(err)
+	suite.database = database
+}
+
+func (suite *GeoIPSuite) TearDownTest() {
+	suite.database.Close()
+}
+
+func (suite *GeoIPSuite) TestCountry() {
+	for ip, expectedCountry := range map[string]string{
+		"192.0.2.10":   "DE",
+		"198.51.100.1": "FR",
+		"2001:db8::1":  "DE",
+		"203.0.113.1":  "",
+	} {
+		country, err := suite.database.Country(ip)
+		suite.NoError(err)
+		suite.Equal(expectedCountry, country, ip)
+	}
+}
+
+func (suite *GeoIPSuite) TestCountryInvalidIP() {
+	_, err := suite.database.Country("not-an-ip")
+	suite.Error(err)
+}
+
+func (suite *GeoIPSuite) TestOpenMissingFile() {
+	_, err := Open("testdata/missing.mmdb")
+	suite.Error(err)
+}
+
+func (suite *GeoIPSuite) TestReload() {
+	// Work on a copy, since reloading replaces the file.
+	path := filepath.Join(suite.T().TempDir(), "country.mmdb")
+	copyFile(suite.T(), fixturePath, path)
+	database, err := Open(path)
+	suite.Require().NoError(err)
+	defer database.Close()
+
+	reloaded, err := database.Reload()
+	suite.NoError(err)
+	suite.False(reloaded)
+
+	// Replace the file atomically, as database updaters do.
+	replacementPath := path + ".new"
+	copyFile(suite.T(), reloadedFixturePath, replacementPath)
+	suite.Require().NoError(os.Chtimes(replacementPath, time.Now(), time.Now().Add(time.Minute)))
+	suite.Require().NoError(os.Rename(replacementPath, path))
+
+	reloaded, err = database.Reload()
+	suite.NoError(err)
+	suite.True(reloaded)
+
+	country, err := database.Country("192.0.2.10")
+	suite.NoError(err)
+	suite.Equal("JP", country)
+}
+
+func (suite *GeoIPSuite) TestReloadKeepsDatabaseIfFileIsInvalid() {
+	path := filepath.Join(suite.T().TempDir(), "country.mmdb")
+	copyFile(suite.T(), fixturePath, path)
+	database, err := Open(path)
+	suite.Require().NoError(err)
+	defer database.Close()
+
+	suite.Require().NoError(os.WriteFile(path, []byte("not a database"), 0o644))
+
+	_, err = database.Reload()
+	suite.Error(err)
+
+	country, err := database.Country("192.0.2.10")
+	suite.NoError(err)
+	suite.Equal("DE", country)
+}
+
+func copyFile(t *testing.T, src string, dst string) {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
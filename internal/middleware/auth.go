@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+const principalKey contextType = "principal"
+
+// Principal identifies the authenticated caller of a request.
+type Principal struct {
+	APIKeyID uint
+	OwnerID  string
+}
+
+// Authenticator resolves a raw API key into the principal it belongs to.
+// Returns nil if the key is unknown or revoked.
+type Authenticator func(ctx context.Context, rawKey string) (*Principal, error)
+
+// Returns a middleware that authenticates requests bearing an
+// "Authorization: Bearer <key>" header and adds the principal to the request
+// context. Returns HTTP 401 Unauthorized if the key is malformed, unknown or
+// revoked, or if no key is provided but one is required. Requests without a
+// key proceed anonymously if a key is not required.
+func APIKeyAuthMiddleware(next http.Handler, authenticate Authenticator, required bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		if authorization == "" {
+			if required {
+				writeUnauthorized(w, "API key required")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rawKey, ok := strings.CutPrefix(authorization, "Bearer ")
+		if !ok || rawKey == "" {
+			writeUnauthorized(w, "Malformed Authorization header")
+			return
+		}
+
+		principal, err := authenticate(r.Context(), rawKey)
+		if err != nil {
+			LogErrorWithRequestID(r.Context(), err, "Failed to authenticate API key")
+			http.Error(w, "Service temporarily unavailable. Please try again later", http.StatusServiceUnavailable)
+			return
+		}
+		if principal == nil {
+			slog.Debug("Rejected unknown or revoked API key", "requestID", GetRequestID(r.Context()))
+			writeUnauthorized(w, "Invalid API key")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// Returns a copy of the context carrying the provided principal.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+// Extracts the principal from the context. Returns nil if the request is
+// anonymous.
+func GetPrincipal(ctx context.Context) *Principal {
+	if principal, ok := ctx.Value(principalKey).(*Principal); ok {
+		return principal
+	}
+	return nil
+}
+
+// Extracts the owner ID of the principal from the context. Returns nil if the
+// request is anonymous.
+func GetOwnerID(ctx context.Context) *string {
+	principal := GetPrincipal(ctx)
+	if principal == nil {
+		return nil
+	}
+	ownerID := principal.OwnerID
+	return &ownerID
+}
+
+// Writes an HTTP 401 Unauthorized response that asks for a bearer token.
+func writeUnauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="tiny-bitly"`)
+	http.Error(w, message, http.StatusUnauthorized)
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIP returns the IP address of the client that sent the request. If the
+// request comes from one of the trusted proxies, it prefers proxy headers
+// (X-Forwarded-For, then X-Real-IP). Otherwise, or if neither is set, it
+// returns the remote address of the connection, since a client that connects
+// directly could set the headers to anything.
+func ClientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	host := remoteIP(r)
+	if !isTrustedProxy(host, trustedProxies) {
+		return host
+	}
+
+	// Each proxy appends the address it received the request from to any
+	// existing X-Forwarded-For. Walk back past the trusted proxies, since the
+	// entries before the first untrusted one may be spoofed by the client.
+	if xff := strings.TrimSpace(r.Header.Get("X-Forwarded-For")); xff != "" {
+		entries := strings.Split(xff, ",")
+		for i := len(entries) - 1; i >= 0; i-- {
+			entry := strings.TrimSpace(entries[i])
+			if entry != "" && (i == 0 || !isTrustedProxy(entry, trustedProxies)) {
+				return entry
+			}
+		}
+	}
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); xri != "" {
+		return xri
+	}
+
+	return host
+}
+
+// Returns the IP address of the connection that the request arrived on.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Returns true if the IP address is in one of the trusted proxy ranges.
+func isTrustedProxy(ip string, trustedProxies []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
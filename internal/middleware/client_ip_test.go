@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ClientIPSuite struct {
+	suite.Suite
+}
+
+func TestClientIPSuite(t *testing.T) {
+	suite.Run(t, new(ClientIPSuite))
+}
+
+// The proxies in front of the server in these tests. httptest requests come
+// from 192.0.2.1.
+var trustedProxies = []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")}
+
+func (suite *ClientIPSuite) TestUsesLastXForwardedForValue() {
+	r := httptest.NewRequest("GET", "http://internal.svc/abc123", nil)
+	r.Header.Set("X-Forwarded-For", "198.51.100.7, 203.0.113.9")
+
+	suite.Equal("203.0.113.9", ClientIP(r, trustedProxies))
+}
+
+func (suite *ClientIPSuite) TestSkipsTrustedProxiesInXForwardedFor() {
+	r := httptest.NewRequest("GET", "http://internal.svc/abc123", nil)
+	r.Header.Set("X-Forwarded-For", "198.51.100.7, 203.0.113.9, 192.0.2.20")
+
+	suite.Equal("203.0.113.9", ClientIP(r, trustedProxies))
+}
+
+func (suite *ClientIPSuite) TestFallsBackToXRealIP() {
+	r := httptest.NewRequest("GET", "http://internal.svc/abc123", nil)
+	r.Header.Set("X-Real-IP", "203.0.113.9")
+
+	suite.Equal("203.0.113.9", ClientIP(r, trustedProxies))
+}
+
+func (suite *ClientIPSuite) TestIgnoresHeadersFromUntrustedClient() {
+	r := httptest.NewRequest("GET", "http://internal.svc/abc123", nil)
+	r.RemoteAddr = "198.51.100.7:54321"
+	r.Header.Set("X-Forwarded-For", "203.0.113.9")
+	r.Header.Set("X-Real-IP", "203.0.113.9")
+
+	suite.Equal("198.51.100.7", ClientIP(r, trustedProxies))
+	suite.Equal("198.51.100.7", ClientIP(r, nil))
+}
+
+func (suite *ClientIPSuite) TestFallsBackToRemoteAddr() {
+	r := httptest.NewRequest("GET", "http://internal.svc/abc123", nil)
+	r.RemoteAddr = "[2001:db8::1]:54321"
+
+	suite.Equal("2001:db8::1", ClientIP(r, trustedProxies))
+}
@@ -116,11 +116,11 @@ func normalizeEndpoint(path string) string {
 	}
 
 	// Single segment that's not a known endpoint - likely a short code
-	if len(parts) == 1 && firstPart != "" {
+	if len(parts) == 1 {
 		return "/{shortCode}"
 	}
 
-	// For multi-segment paths, return the normalized pattern
-	// This handles edge cases like nested paths
-	return "/" + strings.Join(parts, "/")
+	// Multiple segments - a short code with a path suffix to pass through.
+	// Never use the suffix itself, which would make the labels unbounded.
+	return "/{shortCode}/{path...}"
 }
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type NormalizeEndpointSuite struct {
+	suite.Suite
+}
+
+func TestNormalizeEndpointSuite(t *testing.T) {
+	suite.Run(t, new(NormalizeEndpointSuite))
+}
+
+func (suite *NormalizeEndpointSuite) TestRoot() {
+	suite.Equal("/", normalizeEndpoint(""))
+	suite.Equal("/", normalizeEndpoint("/"))
+}
+
+func (suite *NormalizeEndpointSuite) TestReservedPaths() {
+	suite.Equal("/health", normalizeEndpoint("/health"))
+	suite.Equal("/urls", normalizeEndpoint("/urls/abc123"))
+}
+
+func (suite *NormalizeEndpointSuite) TestShortCode() {
+	suite.Equal("/{shortCode}", normalizeEndpoint("/abc123"))
+	suite.Equal("/{shortCode}", normalizeEndpoint("/abc123/"))
+}
+
+func (suite *NormalizeEndpointSuite) TestShortCodeWithPathSuffixIsBounded() {
+	suite.Equal("/{shortCode}/{path...}", normalizeEndpoint("/abc123/extra"))
+	suite.Equal("/{shortCode}/{path...}", normalizeEndpoint("/abc123/extra/path"))
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net/http"
+	"net/netip"
 	"strings"
 )
 
@@ -22,16 +23,34 @@ func PublicBaseURL(r *http.Request, fallbackBaseURL string) string {
 		return strings.TrimRight(strings.TrimSpace(fallbackBaseURL), "/")
 	}
 
-	proto := firstForwardedProto(r)
-	if proto == "" {
-		if r.TLS != nil {
-			proto = "https"
-		} else {
-			proto = "http"
-		}
+	return PublicProto(r) + "://" + host
+}
+
+// PublicProto returns the public-facing scheme of the incoming request, i.e.
+// "https" or "http". It prefers proxy headers and falls back to whether the
+// request arrived over TLS.
+func PublicProto(r *http.Request) string {
+	if proto := firstForwardedProto(r); proto != "" {
+		return strings.TrimRight(proto, " :/")
+	}
+	if r.TLS != nil {
+		return "https"
 	}
+	return "http"
+}
 
-	return strings.TrimRight(proto, " :/") + "://" + host
+// PublicHost returns the public-facing host of the incoming request, e.g.
+// "example.com[:port]". If the request comes from one of the trusted proxies,
+// it prefers proxy headers (X-Forwarded-Host, or Forwarded). Otherwise, or if
+// neither is set, it returns the request Host, since a client that connects
+// directly could set the headers to anything. Returns "" if no host is set.
+func PublicHost(r *http.Request, trustedProxies []netip.Prefix) string {
+	if isTrustedProxy(remoteIP(r), trustedProxies) {
+		if host := firstForwardedHost(r); host != "" {
+			return host
+		}
+	}
+	return strings.TrimSpace(r.Host)
 }
 
 func firstForwardedHost(r *http.Request) string {
@@ -3,6 +3,7 @@ package middleware
 import (
 	"crypto/tls"
 	"net/http/httptest"
+	"net/netip"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
@@ -73,3 +74,30 @@ func (suite *PublicBaseURLSuite) TestFallsBackToConfiguredBaseURLWhenHostMissing
 	want := "http://fallback.example.com"
 	suite.Equal(want, got)
 }
+
+func (suite *PublicBaseURLSuite) TestPublicHostPrefersForwardedHostFromTrustedProxy() {
+	r := httptest.NewRequest("GET", "http://internal.svc/promo", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("X-Forwarded-Host", "go.example.com")
+
+	got := PublicHost(r, []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")})
+	suite.Equal("go.example.com", got)
+
+	r.Header.Del("X-Forwarded-Host")
+	r.Header.Set("Forwarded", `for=198.51.100.7;host=go.example.com`)
+	got = PublicHost(r, []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")})
+	suite.Equal("go.example.com", got)
+}
+
+func (suite *PublicBaseURLSuite) TestPublicHostIgnoresForwardedHostFromUntrustedClient() {
+	r := httptest.NewRequest("GET", "http://internal.svc/promo", nil)
+	r.RemoteAddr = "198.51.100.7:1234"
+	r.Header.Set("X-Forwarded-Host", "go.example.com")
+	r.Header.Set("Forwarded", `host=go.example.com`)
+
+	got := PublicHost(r, []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")})
+	suite.Equal("internal.svc", got)
+
+	got = PublicHost(r, nil)
+	suite.Equal("internal.svc", got)
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// APIKey authenticates requests on behalf of an owner. Only a hash of the
+// secret key is stored; the key itself is shown once, when it is created.
+type APIKey struct {
+	ID uint `gorm:"primaryKey"`
+
+	// An opaque identifier for the owner of the links created with this key.
+	// Several keys may share an owner, e.g. while rotating keys.
+	OwnerID string
+
+	// A human-readable label for the key.
+	Name string
+
+	// The first characters of the key, so that admins can tell keys apart.
+	KeyPrefix string
+
+	// The SHA-256 hex digest of the key.
+	KeyHash string
+
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+// TableName specifies the table name for GORM.
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// IsRevoked returns true if the key may no longer be used.
+func (k APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
@@ -1,6 +1,10 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // Entity provides common fields for database entities.
 // Inspired by gorm.Model, but simplified for our use case.
@@ -8,4 +12,14 @@ import "time"
 type Entity struct {
 	ID        uint `gorm:"primaryKey"`
 	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// Set when the entity is soft-deleted. GORM automatically excludes
+	// soft-deleted rows from queries.
+	DeletedAt gorm.DeletedAt
+}
+
+// IsDeleted returns true if the entity has been soft-deleted.
+func (e Entity) IsDeleted() bool {
+	return e.DeletedAt.Valid
 }
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"database/sql/driver"
+)
+
+// CountryURLs maps ISO 3166-1 alpha-2 country codes, e.g. "DE", to the URLs
+// that visitors from those countries are redirected to instead of the original
+// URL. Stored as a JSON object.
+type CountryURLs map[string]string
+
+// Value implements driver.Valuer, storing nil or empty values as NULL.
+func (c CountryURLs) Value() (driver.Value, error) {
+	return jsonMapValue(c)
+}
+
+// Scan implements sql.Scanner.
+func (c *CountryURLs) Scan(src any) error {
+	return scanJSONMap(src, (*map[string]string)(c))
+}
+
+// IsValidCountryCode returns true if the code looks like an ISO 3166-1 alpha-2
+// country code: two uppercase ASCII letters.
+func IsValidCountryCode(code string) bool {
+	return len(code) == 2 && isUpperASCIILetter(code[0]) && isUpperASCIILetter(code[1])
+}
+
+func isUpperASCIILetter(char byte) bool {
+	return char >= 'A' && char <= 'Z'
+}
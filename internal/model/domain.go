@@ -0,0 +1,45 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Domain is a custom domain that serves short codes from its own namespace,
+// e.g. "go.example.com". Short codes on the default domain, i.e. the domain
+// of the API itself, have an empty domain.
+type Domain struct {
+	// The lowercased hostname, without a port.
+	Hostname string `gorm:"primaryKey"`
+
+	// The owner that may create short codes on the domain, or nil if every
+	// owner may.
+	OwnerID *string
+
+	CreatedAt time.Time
+}
+
+// TableName specifies the table name for GORM.
+func (Domain) TableName() string {
+	return "domains"
+}
+
+// IsUsableBy returns true if the provided owner may create short codes on the
+// domain. A nil ownerID stands for an anonymous caller.
+func (d Domain) IsUsableBy(ownerID *string) bool {
+	if d.OwnerID == nil {
+		return true
+	}
+	return ownerID != nil && *ownerID == *d.OwnerID
+}
+
+// NormalizeHostname returns the canonical form of a hostname or "host:port"
+// pair, so that it can be compared with Domain.Hostname. Lowercases it and
+// drops the port and any trailing dot.
+func NormalizeHostname(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if i := strings.LastIndex(host, ":"); i != -1 && !strings.Contains(host[i:], "]") {
+		host = host[:i]
+	}
+	return strings.TrimSuffix(host, ".")
+}
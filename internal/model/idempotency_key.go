@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// IdempotencyKey records a client-supplied Idempotency-Key, a fingerprint of
+// the request it was first used with and, once that request completes, the
+// response to replay.
+type IdempotencyKey struct {
+	Key         string    `gorm:"primaryKey" json:"key"`
+	Fingerprint string    `json:"fingerprint"`
+	CreatedAt   time.Time `json:"createdAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+
+	// The stored response. StatusCode is 0 while the request is in progress.
+	StatusCode   int    `json:"statusCode"`
+	ResponseBody []byte `json:"responseBody"`
+}
+
+// TableName specifies the table name for GORM.
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
+
+// IsCompleted returns true if a response has been stored for the key.
+func (k IdempotencyKey) IsCompleted() bool {
+	return k.StatusCode != 0
+}
+
+func (k IdempotencyKey) IsExpired() bool {
+	return k.ExpiresAt.Before(time.Now())
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Returns the JSON object for a map stored in a JSONB column, or nil to store
+// NULL for a nil or empty map.
+func jsonMapValue(m map[string]string) (driver.Value, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Reads a map from the JSON object in a JSONB column, or nil from NULL.
+func scanJSONMap(src any, m *map[string]string) error {
+	switch data := src.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(data, m)
+	case string:
+		return json.Unmarshal([]byte(data), m)
+	}
+	return fmt.Errorf("cannot scan %T into a map", src)
+}
@@ -0,0 +1,465 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	// Rules must find their time zones even on hosts without a time zone
+	// database.
+	_ "time/tzdata"
+)
+
+// MaxRedirectRules is the most redirect rules that a record may have.
+const MaxRedirectRules = 20
+
+// The longest pattern that a rule may match a header against.
+const maxHeaderPatternLength = 256
+
+// The days of the week that a rule may be limited to, indexed by time.Weekday.
+var ruleWeekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// RedirectRules is an ordered list of rules that send visitors whose requests
+// meet a rule's condition to the rule's URL instead. Stored as a JSON array.
+// The rules are compiled whenever they are decoded from JSON or scanned from
+// the database, so that loaded rules are ready to evaluate.
+type RedirectRules []RedirectRule
+
+// RedirectRule sends visitors whose requests meet its condition to its URL.
+type RedirectRule struct {
+	When RedirectCondition `json:"when"`
+	URL  string            `json:"url"`
+
+	// The parts of the condition that are set, in a form that is quick to
+	// evaluate, or nil until the rule is compiled.
+	compiled []conditionPart
+}
+
+// RedirectCondition is what a request must meet for a redirect rule to apply.
+// Every part that is set must match; parts that are not set match any request.
+// At least one part must be set.
+type RedirectCondition struct {
+	// The IANA time zone, e.g. "Europe/Berlin", that TimeOfDay and DaysOfWeek
+	// are in, or "" for UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// The time of day at which the request must be made.
+	TimeOfDay *TimeOfDayWindow `json:"timeOfDay,omitempty"`
+
+	// The days of the week, e.g. "mon", on one of which the request must be
+	// made.
+	DaysOfWeek []string `json:"daysOfWeek,omitempty"`
+
+	// Language tags, e.g. "de" or "en-US", one of which must match the
+	// language that the request's Accept-Language header prefers most. A tag
+	// also matches the tags that refine it, e.g. "en" matches "en-GB".
+	Languages []string `json:"languages,omitempty"`
+
+	// RE2 regular expressions by header name, each of which must match a
+	// value of its header in the request.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Hosts, e.g. "example.com", one of which or one of whose subdomains the
+	// request's Referer must be on.
+	ReferrerHosts []string `json:"referrerHosts,omitempty"`
+}
+
+// TimeOfDayWindow is a window of time within each day, from Start (inclusive)
+// to End (exclusive), both as "HH:MM". Windows that end before they start span
+// midnight.
+type TimeOfDayWindow struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// RedirectRequest holds the attributes of a request that redirect rules are
+// evaluated against.
+type RedirectRequest struct {
+	Time   time.Time
+	Header http.Header
+}
+
+// RedirectConditionResult tells whether a request meets one part of a redirect
+// rule's condition, named like its field in JSON, e.g. "timeOfDay".
+type RedirectConditionResult struct {
+	Condition string `json:"condition"`
+	Matched   bool   `json:"matched"`
+}
+
+// Value implements driver.Valuer, storing nil or empty values as NULL.
+func (r RedirectRules) Value() (driver.Value, error) {
+	if len(r) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal([]RedirectRule(r))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner, compiling the rules.
+func (r *RedirectRules) Scan(src any) error {
+	switch data := src.(type) {
+	case nil:
+		*r = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(data, r)
+	case string:
+		return json.Unmarshal([]byte(data), r)
+	}
+	return fmt.Errorf("cannot scan %T into redirect rules", src)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, compiling the rules.
+func (r *RedirectRules) UnmarshalJSON(data []byte) error {
+	var rules []RedirectRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return err
+	}
+	for i := range rules {
+		if err := rules[i].Compile(); err != nil {
+			return fmt.Errorf("invalid redirect rule %d: %w", i, err)
+		}
+	}
+	*r = rules
+	return nil
+}
+
+// Match returns the index of the first rule whose condition the request meets,
+// or -1 if it meets none.
+func (r RedirectRules) Match(request RedirectRequest) int {
+	return slices.IndexFunc(r, func(rule RedirectRule) bool {
+		return rule.Matches(request)
+	})
+}
+
+// Compile validates the rule's condition and prepares it for evaluation.
+// Returns an error if no part of the condition is set or any part is invalid.
+func (r *RedirectRule) Compile() error {
+	when := r.When
+
+	// Times and days are in UTC unless the rule names a time zone. "Local"
+	// would depend on the server.
+	location := time.UTC
+	if when.TimeZone != "" {
+		if when.TimeZone == "Local" {
+			return errors.New("time zone must not be Local")
+		}
+		loaded, err := time.LoadLocation(when.TimeZone)
+		if err != nil {
+			return fmt.Errorf("invalid time zone: %w", err)
+		}
+		location = loaded
+	}
+
+	var parts []conditionPart
+	if when.TimeOfDay != nil {
+		start, startErr := parseTimeOfDay(when.TimeOfDay.Start)
+		end, endErr := parseTimeOfDay(when.TimeOfDay.End)
+		if startErr != nil || endErr != nil {
+			return errors.New("time of day must be given as HH:MM")
+		}
+		if start == end {
+			return errors.New("time of day must not start and end at the same time")
+		}
+		parts = append(parts, timeOfDayPart{location: location, start: start, end: end})
+	}
+
+	if len(when.DaysOfWeek) > 0 {
+		part := daysOfWeekPart{location: location}
+		for _, day := range when.DaysOfWeek {
+			weekday := slices.Index(ruleWeekdays, strings.ToLower(day))
+			if weekday < 0 {
+				return fmt.Errorf("invalid day of week %q", day)
+			}
+			part.days[weekday] = true
+		}
+		parts = append(parts, part)
+	}
+
+	if len(when.Languages) > 0 {
+		part := languagesPart{}
+		for _, language := range when.Languages {
+			if !isValidLanguageTag(language) {
+				return fmt.Errorf("invalid language tag %q", language)
+			}
+			part.languages = append(part.languages, strings.ToLower(language))
+		}
+		parts = append(parts, part)
+	}
+
+	if len(when.Headers) > 0 {
+		part := headersPart{}
+		for name, pattern := range when.Headers {
+			if !isValidHeaderName(name) {
+				return fmt.Errorf("invalid header name %q", name)
+			}
+			if len(pattern) > maxHeaderPatternLength {
+				return fmt.Errorf("pattern for header %q is longer than %d characters", name, maxHeaderPatternLength)
+			}
+			compiledPattern, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("invalid pattern for header %q: %w", name, err)
+			}
+			part.patterns = append(part.patterns, headerPattern{name: http.CanonicalHeaderKey(name), pattern: compiledPattern})
+		}
+		// Evaluate the headers in a stable order.
+		slices.SortFunc(part.patterns, func(a, b headerPattern) int {
+			return strings.Compare(a.name, b.name)
+		})
+		parts = append(parts, part)
+	}
+
+	if len(when.ReferrerHosts) > 0 {
+		part := referrerHostsPart{}
+		for _, host := range when.ReferrerHosts {
+			if !isValidHost(host) {
+				return fmt.Errorf("invalid referrer host %q", host)
+			}
+			part.hosts = append(part.hosts, strings.ToLower(host))
+		}
+		parts = append(parts, part)
+	}
+
+	if len(parts) == 0 {
+		return errors.New("condition must have at least one part besides its time zone")
+	}
+	r.compiled = parts
+	return nil
+}
+
+// Matches returns true if the request meets every part of the rule's
+// condition. A rule that has not been compiled never matches.
+func (r RedirectRule) Matches(request RedirectRequest) bool {
+	if len(r.compiled) == 0 {
+		return false
+	}
+	for _, part := range r.compiled {
+		if !part.matches(request) {
+			return false
+		}
+	}
+	return true
+}
+
+// Explain returns whether the request meets each part of the rule's condition
+// that is set, in the order in which they are evaluated, or nil if the rule has
+// not been compiled.
+func (r RedirectRule) Explain(request RedirectRequest) []RedirectConditionResult {
+	var results []RedirectConditionResult
+	for _, part := range r.compiled {
+		results = append(results, RedirectConditionResult{Condition: part.name(), Matched: part.matches(request)})
+	}
+	return results
+}
+
+// A part of a compiled redirect condition.
+type conditionPart interface {
+	// The name of the part's field in RedirectCondition's JSON.
+	name() string
+	matches(request RedirectRequest) bool
+}
+
+// Matches requests made between the start and end minute of the day in a time
+// zone.
+type timeOfDayPart struct {
+	location   *time.Location
+	start, end int
+}
+
+func (p timeOfDayPart) name() string {
+	return "timeOfDay"
+}
+
+func (p timeOfDayPart) matches(request RedirectRequest) bool {
+	local := request.Time.In(p.location)
+	minute := local.Hour()*60 + local.Minute()
+	if p.start < p.end {
+		return minute >= p.start && minute < p.end
+	}
+	return minute >= p.start || minute < p.end
+}
+
+// Matches requests made on certain days of the week in a time zone.
+type daysOfWeekPart struct {
+	location *time.Location
+	days     [7]bool
+}
+
+func (p daysOfWeekPart) name() string {
+	return "daysOfWeek"
+}
+
+func (p daysOfWeekPart) matches(request RedirectRequest) bool {
+	return p.days[request.Time.In(p.location).Weekday()]
+}
+
+// Matches requests whose most preferred language is, or refines, one of the
+// lowercased language tags.
+type languagesPart struct {
+	languages []string
+}
+
+func (p languagesPart) name() string {
+	return "languages"
+}
+
+func (p languagesPart) matches(request RedirectRequest) bool {
+	preferred := preferredLanguage(request.Header.Get("Accept-Language"))
+	if preferred == "" {
+		return false
+	}
+	for _, language := range p.languages {
+		if preferred == language || strings.HasPrefix(preferred, language+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches requests with a value of each header that matches its pattern.
+type headersPart struct {
+	patterns []headerPattern
+}
+
+type headerPattern struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+func (p headersPart) name() string {
+	return "headers"
+}
+
+func (p headersPart) matches(request RedirectRequest) bool {
+	for _, header := range p.patterns {
+		if !slices.ContainsFunc(request.Header.Values(header.name), header.pattern.MatchString) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches requests whose Referer is on one of the lowercased hosts or their
+// subdomains.
+type referrerHostsPart struct {
+	hosts []string
+}
+
+func (p referrerHostsPart) name() string {
+	return "referrerHosts"
+}
+
+func (p referrerHostsPart) matches(request RedirectRequest) bool {
+	referrer, err := url.Parse(request.Header.Get("Referer"))
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(referrer.Hostname())
+	if host == "" {
+		return false
+	}
+	for _, ruleHost := range p.hosts {
+		if host == ruleHost || strings.HasSuffix(host, "."+ruleHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the minute of the day that a time given as "HH:MM" stands for.
+func parseTimeOfDay(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// Returns the lowercased language tag that an Accept-Language header value
+// prefers most, i.e. the first one with the highest quality, or "" if it names
+// none.
+func preferredLanguage(acceptLanguage string) string {
+	preferred, preferredQuality := "", 0.0
+	for _, entry := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(entry, ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality > preferredQuality {
+			preferred, preferredQuality = tag, quality
+		}
+	}
+	return preferred
+}
+
+// Returns true if the tag looks like a BCP 47 language tag: subtags of ASCII
+// letters and digits separated by hyphens, starting with a language of letters.
+func isValidLanguageTag(tag string) bool {
+	if tag == "" || len(tag) > 35 {
+		return false
+	}
+	for i, subtag := range strings.Split(tag, "-") {
+		if subtag == "" || len(subtag) > 8 {
+			return false
+		}
+		for j := 0; j < len(subtag); j++ {
+			char := subtag[j]
+			isLetter := (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z')
+			isDigit := char >= '0' && char <= '9'
+			if !isLetter && !(isDigit && i > 0) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Returns true if the name consists of ASCII letters, digits and hyphens, as
+// the names of nearly all HTTP headers do.
+func isValidHeaderName(name string) bool {
+	return name != "" && isAlphanumericOrHyphen(name)
+}
+
+// Returns true if the host is a hostname of dot-separated labels of ASCII
+// letters, digits and hyphens, without a port.
+func isValidHost(host string) bool {
+	if host == "" || len(host) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || !isAlphanumericOrHyphen(label) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphanumericOrHyphen(value string) bool {
+	for i := 0; i < len(value); i++ {
+		char := value[i]
+		if !(char >= 'a' && char <= 'z') && !(char >= 'A' && char <= 'Z') && !(char >= '0' && char <= '9') && char != '-' {
+			return false
+		}
+	}
+	return true
+}
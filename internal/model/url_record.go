@@ -1,18 +1,160 @@
 package model
 
-import "time"
+import (
+	"net/http"
+	"time"
+)
 
 // URLRecord is the structure for use in code.
 type URLRecord struct {
 	OriginalURL string    `json:"originalUrl"`
 	ShortCode   string    `json:"shortCode"`
 	ExpiresAt   time.Time `json:"expiresAt"`
+
+	// The custom domain whose namespace the short code belongs to, or empty for
+	// the default domain.
+	Domain string `json:"domain,omitempty"`
+
+	// The owner of the API key that created the record, or nil if the record
+	// was created without one.
+	OwnerID *string `json:"ownerId,omitempty"`
+
+	// The bcrypt hash of the password that visitors must enter before being
+	// redirected, or nil if the record is not password protected. The
+	// plaintext password is never stored.
+	PasswordHash *string `json:"passwordHash,omitempty"`
+
+	// The number of times the record may be resolved in total, or nil if the
+	// record is not click-limited.
+	MaxClicks *int `json:"maxClicks,omitempty"`
+
+	// The number of times a click-limited record may still be resolved, or nil
+	// if the record is not click-limited. The record behaves like an expired
+	// record once this reaches zero.
+	RemainingClicks *int `json:"remainingClicks,omitempty"`
+
+	// The time before which the record must not be resolved, or nil if the
+	// record is active from creation.
+	ActivatesAt *time.Time `json:"activatesAt,omitempty"`
+
+	// The URL to redirect visitors to once the record has expired, or nil to
+	// tell them that it has expired instead.
+	ExpiredRedirectURL *string `json:"expiredRedirectUrl,omitempty"`
+
+	// The HTTP status code to redirect visitors with, or nil for the server's
+	// default. One of 301, 302, 307 or 308.
+	RedirectType *int `json:"redirectType,omitempty"`
+
+	// Whether browsers and CDNs must not cache redirects of the record, e.g.
+	// because it is about to be retargeted.
+	Uncacheable bool `json:"uncacheable,omitempty"`
+
+	// How to merge the query parameters of a request for the short URL into
+	// the original URL, or nil to ignore them. One of the QueryPassthrough
+	// constants.
+	QueryPassthrough *string `json:"queryPassthrough,omitempty"`
+
+	// Whether to append the path that follows the short code in a request,
+	// e.g. "extra/path" in /abc123/extra/path, to the original URL's path.
+	PathPassthrough bool `json:"pathPassthrough,omitempty"`
+
+	// The values that placeholders in the original URL, e.g. {utm_campaign},
+	// default to when the request does not provide them, or nil if they
+	// default to "".
+	TemplateDefaults TemplateValues `gorm:"type:jsonb" json:"templateDefaults,omitempty"`
+
+	// The URLs to redirect visitors to instead of the original URL, by the
+	// country that they visit from, or nil to redirect every visitor to the
+	// original URL.
+	CountryURLs CountryURLs `gorm:"type:jsonb" json:"countryUrls,omitempty"`
+
+	// The URLs to redirect visitors on iOS and Android devices to instead,
+	// e.g. App Store, universal link or intent URLs, or nil to redirect them
+	// like everyone else.
+	IOSURL     *string `gorm:"column:ios_url" json:"iosUrl,omitempty"`
+	AndroidURL *string `gorm:"column:android_url" json:"androidUrl,omitempty"`
+
+	// The destinations that visitors are split across instead of the original
+	// URL, in proportion to their weights, or nil to redirect every visitor to
+	// the original URL. Stored in a table of their own rather than a column.
+	Variants []URLVariant `gorm:"-" json:"variants,omitempty"`
+
+	// Whether returning visitors are sent to the variant that they were sent
+	// to before, as remembered by a cookie, rather than to one chosen anew.
+	StickyVariants bool `json:"stickyVariants,omitempty"`
+
+	// The rules that send visitors whose requests meet their conditions to
+	// other URLs, evaluated in order before any other targeting, or nil to
+	// target visitors only as above.
+	RedirectRules RedirectRules `gorm:"type:jsonb" json:"redirectRules,omitempty"`
+}
+
+// The ways in which the query parameters of a request may be merged into the
+// original URL when a parameter appears in both.
+const (
+	// The original URL's values are kept.
+	QueryPassthroughDestination = "destination"
+
+	// The request's values replace the original URL's values.
+	QueryPassthroughRequest = "request"
+
+	// The request's values are added after the original URL's values.
+	QueryPassthroughAppend = "append"
+)
+
+// IsValidQueryPassthrough returns true if the mode is one of the
+// QueryPassthrough constants.
+func IsValidQueryPassthrough(mode string) bool {
+	switch mode {
+	case QueryPassthroughDestination, QueryPassthroughRequest, QueryPassthroughAppend:
+		return true
+	}
+	return false
+}
+
+// IsValidRedirectType returns true if the status code is one that short URLs
+// may redirect with.
+func IsValidRedirectType(statusCode int) bool {
+	switch statusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// IsPermanentRedirectType returns true if browsers may cache a redirect with
+// the status code indefinitely.
+func IsPermanentRedirectType(statusCode int) bool {
+	return statusCode == http.StatusMovedPermanently || statusCode == http.StatusPermanentRedirect
+}
+
+// URLRecordUpdate holds the fields of a URL record that may be changed after
+// creation. Nil fields are left unchanged.
+type URLRecordUpdate struct {
+	OriginalURL *string
+	ExpiresAt   *time.Time
+}
+
+// IsEmpty returns true if the update does not change any field.
+func (u URLRecordUpdate) IsEmpty() bool {
+	return u.OriginalURL == nil && u.ExpiresAt == nil
 }
 
 // URLRecordEntity will be stored as a row in the database.
 type URLRecordEntity struct {
 	Entity
 	URLRecord
+
+	// Whether the record is counted in its owner's number of active records,
+	// which the active link quota limits. Records with an owner are counted
+	// from creation until they are deleted, purged or reclaimed, or found to
+	// have expired or run out of clicks.
+	CountedAsActive bool `json:"-"`
+
+	// The time at which a click-limited record ran out of clicks, or nil if it
+	// has clicks left or is not click-limited. The record is purged a grace
+	// period later, as if it had expired then.
+	ExhaustedAt *time.Time `json:"-"`
 }
 
 // TableName specifies the table name for GORM.
@@ -20,6 +162,85 @@ func (URLRecordEntity) TableName() string {
 	return "url_records"
 }
 
+// IsPasswordProtected returns true if visitors must enter a password before
+// being redirected.
+func (u URLRecordEntity) IsPasswordProtected() bool {
+	return u.PasswordHash != nil
+}
+
+// IsNotYetActive returns true if the record has an activation time that has
+// not passed yet.
+func (u URLRecordEntity) IsNotYetActive() bool {
+	return u.ActivatesAt != nil && u.ActivatesAt.After(time.Now())
+}
+
+// IsClickLimited returns true if the record may only be resolved a limited
+// number of times.
+func (u URLRecordEntity) IsClickLimited() bool {
+	return u.RemainingClicks != nil
+}
+
+// IsExhausted returns true if the record is click-limited and has no clicks
+// left.
+func (u URLRecordEntity) IsExhausted() bool {
+	return u.RemainingClicks != nil && *u.RemainingClicks <= 0
+}
+
+// IsSplitTested returns true if visitors of the record are split across
+// several variants.
+func (u URLRecordEntity) IsSplitTested() bool {
+	return len(u.Variants) > 0
+}
+
+// HasRedirectRules returns true if visitors of the record may be redirected per
+// its rules.
+func (u URLRecordEntity) HasRedirectRules() bool {
+	return len(u.RedirectRules) > 0
+}
+
+// IsCacheable returns true if browsers and CDNs may cache redirects of the
+// record. Redirects of password-protected, click-limited, split-tested and
+// rule-based records must reach the server on every visit, so that it can
+// check the password, count the click, choose a variant or evaluate the rules,
+// which may depend on the time of the visit.
+func (u URLRecordEntity) IsCacheable() bool {
+	return !u.Uncacheable && !u.IsPasswordProtected() && !u.IsClickLimited() && !u.IsSplitTested() && !u.HasRedirectRules()
+}
+
+// RedirectStatus returns the HTTP status code to redirect visitors with: the
+// record's redirect type if set, or else the provided default. A permanent
+// default becomes its temporary counterpart for records that are not
+// cacheable, since browsers cache permanent redirects regardless.
+func (u URLRecordEntity) RedirectStatus(defaultStatus int) int {
+	if u.RedirectType != nil {
+		return *u.RedirectType
+	}
+	if !u.IsCacheable() {
+		switch defaultStatus {
+		case http.StatusMovedPermanently:
+			return http.StatusFound
+		case http.StatusPermanentRedirect:
+			return http.StatusTemporaryRedirect
+		}
+	}
+	return defaultStatus
+}
+
+// VariesByVisitor returns true if redirects of the record may lead visitors to
+// different URLs depending on who they are, e.g. where they visit from, so that
+// shared caches must not serve one visitor's redirect to another.
+func (u URLRecordEntity) VariesByVisitor() bool {
+	return len(u.CountryURLs) > 0
+}
+
+// IsPlatformTargeted returns true if redirects of the record lead visitors on
+// some devices, e.g. iOS, to different URLs than others.
+func (u URLRecordEntity) IsPlatformTargeted() bool {
+	return u.IOSURL != nil || u.AndroidURL != nil
+}
+
+// IsExpired returns true if the record has passed its expiration time or, if it
+// is click-limited, has no clicks left.
 func (u URLRecordEntity) IsExpired() bool {
-	return u.ExpiresAt.Before(time.Now())
+	return u.ExpiresAt.Before(time.Now()) || u.IsExhausted()
 }
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// URLRecordStatus filters URL records by whether they have expired.
+type URLRecordStatus string
+
+const (
+	URLRecordStatusAny     URLRecordStatus = ""
+	URLRecordStatusActive  URLRecordStatus = "active"
+	URLRecordStatusExpired URLRecordStatus = "expired"
+)
+
+// URLRecordFilter restricts which URL records are listed. Zero-valued fields
+// do not restrict the results. Soft-deleted records are never listed.
+type URLRecordFilter struct {
+	CreatedAfter   *time.Time
+	CreatedBefore  *time.Time
+	ExpiringBefore *time.Time
+	Status         URLRecordStatus
+
+	// Matches records whose destination host equals this value,
+	// case-insensitively.
+	Host string
+}
+
+// URLRecordCursor identifies a position in a listing ordered by creation time
+// and then ID, both descending. A listing resumes strictly after the cursor.
+type URLRecordCursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// URLRecordListParams describes one page of a URL record listing.
+type URLRecordListParams struct {
+	// Only records with this owner are listed. If nil, only records without an
+	// owner are listed.
+	OwnerID *string
+
+	Filter URLRecordFilter
+	After  *URLRecordCursor
+	Limit  int
+}
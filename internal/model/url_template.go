@@ -0,0 +1,104 @@
+package model
+
+import (
+	"database/sql/driver"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// The prefix of placeholders that are filled in from a query parameter of the
+// request, e.g. {query.ref} from ?ref=.
+const templateQueryPrefix = "query."
+
+// The placeholders, besides {query.*}, that an original URL may contain. Each
+// is filled in from the query parameter of the same name.
+var templateUTMPlaceholders = []string{"utm_campaign", "utm_content", "utm_medium", "utm_source", "utm_term"}
+
+// TemplateValues maps the names of placeholders in an original URL, e.g.
+// "utm_campaign" or "query.ref", to the values they default to. Stored as a
+// JSON object.
+type TemplateValues map[string]string
+
+// Value implements driver.Valuer, storing nil or empty values as NULL.
+func (v TemplateValues) Value() (driver.Value, error) {
+	return jsonMapValue(v)
+}
+
+// Scan implements sql.Scanner.
+func (v *TemplateValues) Scan(src any) error {
+	return scanJSONMap(src, (*map[string]string)(v))
+}
+
+// TemplatePlaceholders returns the names of the placeholders in the URL, e.g.
+// "utm_campaign" for {utm_campaign}, in order of appearance. Returns false if a
+// placeholder is not closed.
+func TemplatePlaceholders(rawURL string) ([]string, bool) {
+	var names []string
+	rest := rawURL
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start == -1 {
+			return names, true
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end == -1 {
+			return nil, false
+		}
+		names = append(names, rest[start+1:start+end])
+		rest = rest[start+end+1:]
+	}
+}
+
+// IsValidTemplatePlaceholder returns true if the name is a placeholder that an
+// original URL may contain: a UTM parameter such as "utm_campaign", or
+// "query." followed by the name of any query parameter.
+func IsValidTemplatePlaceholder(name string) bool {
+	if param, ok := strings.CutPrefix(name, templateQueryPrefix); ok {
+		return param != "" && !strings.ContainsAny(param, "{}")
+	}
+	return slices.Contains(templateUTMPlaceholders, name)
+}
+
+// TemplateQueryParam returns the name of the request query parameter that
+// fills in the placeholder.
+func TemplateQueryParam(name string) string {
+	if param, ok := strings.CutPrefix(name, templateQueryPrefix); ok {
+		return param
+	}
+	return name
+}
+
+// ExpandTemplate replaces each placeholder in the URL with the value returned
+// for its name, escaped for the part of the URL that it appears in.
+// Placeholders that are not closed are left as they are.
+func ExpandTemplate(rawURL string, value func(name string) string) string {
+	var expanded strings.Builder
+	inQuery := false
+	rest := rawURL
+	for {
+		start := strings.IndexByte(rest, '{')
+		end := -1
+		if start != -1 {
+			end = strings.IndexByte(rest[start:], '}')
+		}
+		if end == -1 {
+			expanded.WriteString(rest)
+			return expanded.String()
+		}
+
+		// Path segments and query values escape differently. A fragment
+		// escapes like a query value.
+		literal := rest[:start]
+		if strings.ContainsAny(literal, "?#") {
+			inQuery = true
+		}
+		expanded.WriteString(literal)
+		if inQuery {
+			expanded.WriteString(url.QueryEscape(value(rest[start+1 : start+end])))
+		} else {
+			expanded.WriteString(url.PathEscape(value(rest[start+1 : start+end])))
+		}
+		rest = rest[start+end+1:]
+	}
+}
@@ -0,0 +1,28 @@
+package model
+
+// MaxURLVariants is the most variants that a record may have. Also bounds the
+// number of distinct variant labels in metrics.
+const MaxURLVariants = 10
+
+// URLVariant is one of the destinations of a split-tested record. Each visit
+// goes to one of them, chosen at random in proportion to their weights.
+type URLVariant struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// URLVariantEntity will be stored as a row in the database. Holds one variant
+// of a record, keyed by the record's ID.
+type URLVariantEntity struct {
+	URLRecordID uint `gorm:"primaryKey"`
+
+	// The index of the variant among the record's variants.
+	Position int `gorm:"primaryKey"`
+
+	URLVariant
+}
+
+// TableName specifies the table name for GORM.
+func (URLVariantEntity) TableName() string {
+	return "url_record_variants"
+}
@@ -0,0 +1,54 @@
+package router
+
+import (
+	"net/http"
+
+	"tiny-bitly/internal/service/create"
+	"tiny-bitly/internal/service/health"
+	"tiny-bitly/internal/service/list"
+	"tiny-bitly/internal/service/read"
+	"tiny-bitly/internal/service/remove"
+	"tiny-bitly/internal/service/update"
+	versionService "tiny-bitly/internal/service/version"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// New returns the router for every endpoint of the API server. authenticate
+// wraps the endpoints that act on behalf of an API key's owner.
+func New(
+	authenticate func(http.Handler) http.Handler,
+	createService *create.Service,
+	readService *read.Service,
+	listService *list.Service,
+	updateService *update.Service,
+	removeService *remove.Service,
+	healthService *health.Service,
+) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	// Health check endpoints
+	mux.HandleFunc("GET /health", health.NewGetHealthHandler(healthService))
+	mux.HandleFunc("GET /ready", health.NewGetReadyHandler(healthService))
+	mux.HandleFunc("GET /version", versionService.NewGetVersionHandler())
+
+	// Metrics endpoints
+	mux.Handle("GET /metrics", promhttp.Handler())
+
+	// Application endpoints (authenticated by API key)
+	mux.Handle("POST /urls", authenticate(create.NewPostURLHandler(createService)))
+	mux.Handle("POST /urls/batch", authenticate(create.NewPostURLBatchHandler(createService)))
+	mux.Handle("GET /urls", authenticate(list.NewGetURLsHandler(listService)))
+	mux.Handle("GET /urls/{shortCode}", authenticate(read.NewGetURLMetadataHandler(readService)))
+	mux.Handle("POST /urls/{shortCode}/rules/dry-run", authenticate(read.NewPostRedirectRulesDryRunHandler(readService)))
+	mux.Handle("PATCH /urls/{shortCode}", authenticate(update.NewPatchURLHandler(updateService)))
+	mux.Handle("DELETE /urls/{shortCode}", authenticate(remove.NewDeleteURLHandler(removeService)))
+
+	// Redirects are public.
+	mux.HandleFunc("GET /{shortCode}", read.NewGetURLHandler(readService))
+	mux.HandleFunc("POST /{shortCode}", read.NewPostURLPasswordHandler(readService))
+	mux.HandleFunc("GET /{shortCode}/{path...}", read.NewGetURLHandler(readService))
+	mux.HandleFunc("POST /{shortCode}/{path...}", read.NewPostURLPasswordHandler(readService))
+
+	return mux
+}
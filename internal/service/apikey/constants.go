@@ -0,0 +1,15 @@
+package apikey
+
+// The prefix of every API key, which makes keys easy to recognize, e.g. by
+// secret scanners.
+const keyPrefix = "tb_"
+
+// The number of random bytes in an API key.
+const keyRandomBytes = 32
+
+// The number of leading characters of an API key that are stored in plain text,
+// so that admins can tell keys apart.
+const displayedKeyLength = len(keyPrefix) + 8
+
+// The maximum permitted length of an owner ID.
+const maxOwnerIDLength = 255
@@ -0,0 +1,121 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/config"
+	"tiny-bitly/internal/dao"
+	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/model"
+)
+
+// Service handles API key management and authentication.
+type Service struct {
+	dao    dao.DAO
+	config *config.Config
+}
+
+// NewService creates a new API key service with the provided dependencies.
+func NewService(dao dao.DAO, config *config.Config) *Service {
+	return &Service{
+		dao:    dao,
+		config: config,
+	}
+}
+
+// CreatedAPIKey holds a newly created API key. Key is the only copy of the
+// secret, since only its hash is stored.
+type CreatedAPIKey struct {
+	APIKey model.APIKey
+	Key    string
+}
+
+// CreateAPIKey creates a new API key that acts on behalf of the provided owner.
+func (s *Service) CreateAPIKey(ctx context.Context, ownerID string, name string) (*CreatedAPIKey, error) {
+	if ownerID == "" || len(ownerID) > maxOwnerIDLength {
+		return nil, apperrors.ErrInvalidOwnerID
+	}
+
+	rawKey, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, err := s.dao.APIKeyDAO.Create(ctx, model.APIKey{
+		OwnerID:   ownerID,
+		Name:      name,
+		KeyPrefix: rawKey[:displayedKeyLength],
+		KeyHash:   hashKey(rawKey),
+	})
+	if err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to save API key")
+		return nil, apperrors.ErrDataStoreUnavailable
+	}
+
+	middleware.LogWithRequestID(ctx, "Created API key", "id", apiKey.ID, "ownerID", ownerID, "keyPrefix", apiKey.KeyPrefix)
+	return &CreatedAPIKey{APIKey: *apiKey, Key: rawKey}, nil
+}
+
+// ListAPIKeys returns all API keys, including revoked ones.
+func (s *Service) ListAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	apiKeys, err := s.dao.APIKeyDAO.List(ctx)
+	if err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to list API keys")
+		return nil, apperrors.ErrDataStoreUnavailable
+	}
+	return apiKeys, nil
+}
+
+// RevokeAPIKey revokes the API key with the provided ID, so that it can no
+// longer authenticate requests.
+func (s *Service) RevokeAPIKey(ctx context.Context, id uint) error {
+	err := s.dao.APIKeyDAO.Revoke(ctx, id)
+	if errors.Is(err, apperrors.ErrAPIKeyNotFound) {
+		return err
+	}
+	if err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to revoke API key", "id", id)
+		return apperrors.ErrDataStoreUnavailable
+	}
+
+	middleware.LogWithRequestID(ctx, "Revoked API key", "id", id)
+	return nil
+}
+
+// Authenticate returns the principal for the provided raw API key, or nil if
+// the key is unknown or revoked. Satisfies middleware.Authenticator.
+func (s *Service) Authenticate(ctx context.Context, rawKey string) (*middleware.Principal, error) {
+	apiKey, err := s.dao.APIKeyDAO.GetByKeyHash(ctx, hashKey(rawKey))
+	if err != nil {
+		return nil, apperrors.ErrDataStoreUnavailable
+	}
+	if apiKey == nil {
+		return nil, nil
+	}
+
+	return &middleware.Principal{
+		APIKeyID: apiKey.ID,
+		OwnerID:  apiKey.OwnerID,
+	}, nil
+}
+
+// Generates a new random API key.
+func generateKey() (string, error) {
+	b := make([]byte, keyRandomBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Returns the SHA-256 hex digest of the provided API key. A fast hash suffices
+// since keys are long and random, unlike passwords.
+func hashKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,154 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/config"
+	"tiny-bitly/internal/dao"
+	mock_daotypes "tiny-bitly/internal/dao/generated"
+	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/model"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type APIKeyServiceSuite struct {
+	suite.Suite
+	ctrl      *gomock.Controller
+	service   *Service
+	dao       dao.DAO
+	apiKeyDAO *mock_daotypes.MockAPIKeyDAO
+}
+
+func TestAPIKeyServiceSuite(t *testing.T) {
+	suite.Run(t, new(APIKeyServiceSuite))
+}
+
+func (suite *APIKeyServiceSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.apiKeyDAO = mock_daotypes.NewMockAPIKeyDAO(suite.ctrl)
+	suite.dao = dao.DAO{
+		APIKeyDAO: suite.apiKeyDAO,
+	}
+	cfg := config.GetTestConfig(config.Config{})
+	suite.service = NewService(suite.dao, &cfg)
+}
+
+func (suite *APIKeyServiceSuite) TestCreateOwnerEmpty() {
+	created, err := suite.service.CreateAPIKey(context.Background(), "", "ci")
+	suite.ErrorIs(err, apperrors.ErrInvalidOwnerID)
+	suite.Nil(created)
+}
+
+func (suite *APIKeyServiceSuite) TestCreateOwnerTooLong() {
+	created, err := suite.service.CreateAPIKey(context.Background(), strings.Repeat("a", maxOwnerIDLength+1), "ci")
+	suite.ErrorIs(err, apperrors.ErrInvalidOwnerID)
+	suite.Nil(created)
+}
+
+func (suite *APIKeyServiceSuite) TestCreateStoresOnlyHash() {
+	var saved model.APIKey
+	suite.apiKeyDAO.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, apiKey model.APIKey) (*model.APIKey, error) {
+			saved = apiKey
+			apiKey.ID = 1
+			return &apiKey, nil
+		})
+
+	created, err := suite.service.CreateAPIKey(context.Background(), "alice", "ci")
+	suite.NoError(err)
+	suite.True(strings.HasPrefix(created.Key, keyPrefix))
+	suite.Equal(uint(1), created.APIKey.ID)
+	suite.Equal("alice", saved.OwnerID)
+	suite.Equal("ci", saved.Name)
+	suite.Equal(created.Key[:displayedKeyLength], saved.KeyPrefix)
+	suite.Equal(hashKey(created.Key), saved.KeyHash)
+	suite.NotContains(saved.KeyHash, created.Key)
+}
+
+func (suite *APIKeyServiceSuite) TestCreateGeneratesUniqueKeys() {
+	suite.apiKeyDAO.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, apiKey model.APIKey) (*model.APIKey, error) {
+			return &apiKey, nil
+		}).
+		Times(2)
+
+	first, err := suite.service.CreateAPIKey(context.Background(), "alice", "")
+	suite.NoError(err)
+	second, err := suite.service.CreateAPIKey(context.Background(), "alice", "")
+	suite.NoError(err)
+	suite.NotEqual(first.Key, second.Key)
+}
+
+func (suite *APIKeyServiceSuite) TestCreateError() {
+	suite.apiKeyDAO.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("database error"))
+
+	created, err := suite.service.CreateAPIKey(context.Background(), "alice", "ci")
+	suite.ErrorIs(err, apperrors.ErrDataStoreUnavailable)
+	suite.Nil(created)
+}
+
+func (suite *APIKeyServiceSuite) TestRevokeNotFound() {
+	suite.apiKeyDAO.
+		EXPECT().
+		Revoke(gomock.Any(), uint(7)).
+		Return(apperrors.ErrAPIKeyNotFound)
+
+	err := suite.service.RevokeAPIKey(context.Background(), 7)
+	suite.ErrorIs(err, apperrors.ErrAPIKeyNotFound)
+}
+
+func (suite *APIKeyServiceSuite) TestRevokeError() {
+	suite.apiKeyDAO.
+		EXPECT().
+		Revoke(gomock.Any(), uint(7)).
+		Return(errors.New("database error"))
+
+	err := suite.service.RevokeAPIKey(context.Background(), 7)
+	suite.ErrorIs(err, apperrors.ErrDataStoreUnavailable)
+}
+
+func (suite *APIKeyServiceSuite) TestAuthenticateSuccess() {
+	rawKey := "tb_secret"
+	suite.apiKeyDAO.
+		EXPECT().
+		GetByKeyHash(gomock.Any(), hashKey(rawKey)).
+		Return(&model.APIKey{ID: 3, OwnerID: "alice"}, nil)
+
+	principal, err := suite.service.Authenticate(context.Background(), rawKey)
+	suite.NoError(err)
+	suite.Equal(&middleware.Principal{APIKeyID: 3, OwnerID: "alice"}, principal)
+}
+
+func (suite *APIKeyServiceSuite) TestAuthenticateUnknownKey() {
+	suite.apiKeyDAO.
+		EXPECT().
+		GetByKeyHash(gomock.Any(), gomock.Any()).
+		Return(nil, nil)
+
+	principal, err := suite.service.Authenticate(context.Background(), "tb_unknown")
+	suite.NoError(err)
+	suite.Nil(principal)
+}
+
+func (suite *APIKeyServiceSuite) TestAuthenticateError() {
+	suite.apiKeyDAO.
+		EXPECT().
+		GetByKeyHash(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("database error"))
+
+	principal, err := suite.service.Authenticate(context.Background(), "tb_secret")
+	suite.ErrorIs(err, apperrors.ErrDataStoreUnavailable)
+	suite.Nil(principal)
+}
@@ -0,0 +1,128 @@
+package create
+
+import (
+	"context"
+	"slices"
+	"time"
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/model"
+)
+
+// BatchItem is one URL to shorten in a batch.
+type BatchItem struct {
+	OriginalURL string
+	Options     CreateOptions
+}
+
+// BatchResult is the outcome for one BatchItem. Exactly one of ShortCode and
+// Err is set.
+type BatchResult struct {
+	ShortCode *string
+	Err       error
+}
+
+// CreateShortCodes creates short codes for many URLs at once. Returns one
+// result per item, in the same order. A failure for one item, such as an
+// invalid URL or an alias conflict, does not fail the other items. Returns an
+// error only if the batch as a whole is invalid.
+func (s *Service) CreateShortCodes(ctx context.Context, items []BatchItem) ([]BatchResult, error) {
+	if len(items) == 0 || len(items) > s.config.MaxBatchSize {
+		return nil, apperrors.ErrInvalidBatchSize
+	}
+
+	results := make([]BatchResult, len(items))
+
+	// Validate every item up front. Only valid items without an existing short
+	// code to reuse are saved.
+	now := time.Now()
+	urlRecords := make([]model.URLRecord, len(items))
+	pending := make([]int, 0, len(items))
+	for i, item := range items {
+		urlRecord, err := s.prepareURLRecord(ctx, item.OriginalURL, item.Options, now)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		existingShortCode, err := s.findDuplicate(ctx, urlRecord, item.Options)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		if existingShortCode != nil {
+			results[i].ShortCode = existingShortCode
+			continue
+		}
+		urlRecords[i] = *urlRecord
+		pending = append(pending, i)
+	}
+
+	// Count the pending items against the caller's quotas as a whole, and give
+	// back those that end up failing.
+	ownerID := middleware.GetOwnerID(ctx)
+	if err := s.reserveQuota(ctx, ownerID, len(pending), now); err != nil {
+		for _, i := range pending {
+			results[i].Err = err
+		}
+		return results, nil
+	}
+	reserved := slices.Clone(pending)
+	defer func() {
+		failed := 0
+		for _, i := range reserved {
+			if results[i].ShortCode == nil {
+				failed++
+			}
+		}
+		s.releaseQuota(ctx, ownerID, failed, now)
+	}()
+
+	// Save all pending items in one round trip per attempt. Items with a
+	// random short code that collides are retried in the next attempt.
+	for numTries := 0; numTries < s.config.MaxTriesCreateShortCode && len(pending) > 0; numTries++ {
+		batch := make([]model.URLRecord, len(pending))
+		for j, i := range pending {
+			if alias := items[i].Options.Alias; alias != nil {
+				urlRecords[i].ShortCode = *alias
+			} else {
+				urlRecords[i].ShortCode = generateShortCode(s.config.ShortCodeLength)
+			}
+			batch[j] = urlRecords[i]
+		}
+
+		middleware.LogDebugWithRequestID(ctx, "Creating a batch of URL records", "count", len(batch), "attempt", numTries+1)
+
+		entities, err := s.dao.URLRecordDAO.CreateBatch(ctx, batch)
+		if err != nil {
+			middleware.LogErrorWithRequestID(ctx, err, "Failed to save URL records")
+			for _, i := range pending {
+				results[i].Err = apperrors.ErrDataStoreUnavailable
+			}
+			return results, nil
+		}
+
+		retry := pending[:0]
+		for j, i := range pending {
+			switch {
+			case entities[j] != nil:
+				shortCode := entities[j].ShortCode
+				results[i].ShortCode = &shortCode
+			case items[i].Options.Alias != nil:
+				// If we're using a custom alias, fail outright.
+				results[i].Err = apperrors.ErrAliasAlreadyInUse
+			default:
+				// Else, try again with a new randomly generated short code.
+				retry = append(retry, i)
+			}
+		}
+		pending = retry
+	}
+
+	// Any items still pending exceeded max retries without success.
+	for _, i := range pending {
+		results[i].Err = apperrors.ErrMaxRetriesExceeded
+	}
+
+	middleware.LogWithRequestID(ctx, "Generated a batch of short codes", "count", len(items))
+	return results, nil
+}
@@ -2,3 +2,7 @@ package create
 
 // Contains all characters that can appear in a short code.
 var allowedChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// The maximum permitted length of a short code's password in bytes, since
+// bcrypt ignores any further bytes.
+const maxPasswordLength = 72
@@ -0,0 +1,34 @@
+package create
+
+import (
+	"context"
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/model"
+)
+
+// Returns the normalized domain to create a short code on, or "" for the
+// default domain. Returns apperrors.ErrInvalidDomain if the domain is not
+// registered or belongs to another owner.
+func (s *Service) resolveDomain(ctx context.Context, domain *string, ownerID *string) (string, error) {
+	if domain == nil {
+		return "", nil
+	}
+
+	hostname := model.NormalizeHostname(*domain)
+	if hostname == "" {
+		return "", apperrors.ErrInvalidDomain
+	}
+
+	entity, err := s.dao.DomainDAO.GetByHostname(ctx, hostname)
+	if err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to look up domain", "domain", hostname)
+		return "", apperrors.ErrDataStoreUnavailable
+	}
+	if entity == nil || !entity.IsUsableBy(ownerID) {
+		middleware.LogDebugWithRequestID(ctx, "Rejected create on unusable domain", "domain", hostname)
+		return "", apperrors.ErrInvalidDomain
+	}
+
+	return hostname, nil
+}
@@ -10,34 +10,121 @@ import (
 // Maps service errors to appropriate HTTP status codes and responses. Logs
 // detailed error information while returning user-friendly messages.
 func handleServiceError(ctx context.Context, w http.ResponseWriter, err error) {
-	service.HandleServiceError(ctx, w, err, map[error]service.ErrorMapping{
-		apperrors.ErrInvalidURL: {
-			StatusCode:  http.StatusBadRequest,
-			UserMessage: "Invalid URL format",
-		},
-		apperrors.ErrURLLengthExceeded: {
-			StatusCode:  http.StatusBadRequest,
-			UserMessage: "URL exceeds maximum length",
-		},
-		apperrors.ErrInvalidAlias: {
-			StatusCode:  http.StatusBadRequest,
-			UserMessage: "Invalid alias format. Alias must contain only letters, numbers, and be non-empty",
-		},
-		apperrors.ErrAliasAlreadyInUse: {
-			StatusCode:  http.StatusConflict,
-			UserMessage: "Alias is already in use",
-		},
-		apperrors.ErrMaxRetriesExceeded: {
-			StatusCode:  http.StatusInternalServerError,
-			UserMessage: "Unable to generate unique short code. Please try again",
-		},
-		apperrors.ErrConfigurationMissing: {
-			StatusCode:  http.StatusInternalServerError,
-			UserMessage: "Service configuration error",
-		},
-		apperrors.ErrDataStoreUnavailable: {
-			StatusCode:  http.StatusServiceUnavailable,
-			UserMessage: "Service temporarily unavailable. Please try again later",
-		},
-	})
+	service.HandleServiceError(ctx, w, err, errorMappings)
+}
+
+// Maps service errors to HTTP status codes and user-friendly messages.
+var errorMappings = map[error]service.ErrorMapping{
+	apperrors.ErrInvalidURL: {
+		StatusCode:  http.StatusBadRequest,
+		UserMessage: "Invalid URL format",
+	},
+	apperrors.ErrURLLengthExceeded: {
+		StatusCode:  http.StatusBadRequest,
+		UserMessage: "URL exceeds maximum length",
+	},
+	apperrors.ErrInvalidAlias: {
+		StatusCode:  http.StatusBadRequest,
+		UserMessage: "Invalid alias format. Alias must contain only letters, numbers, and be non-empty",
+	},
+	apperrors.ErrInvalidExpiration: {
+		StatusCode:  http.StatusBadRequest,
+		UserMessage: "Invalid expiration. Provide either expiresAt or a positive ttlSeconds, not both",
+	},
+	apperrors.ErrExpirationInPast: {
+		StatusCode:  http.StatusBadRequest,
+		UserMessage: "Expiration must be in the future",
+	},
+	apperrors.ErrExpirationOutOfRange: {
+		StatusCode:  http.StatusBadRequest,
+		UserMessage: "Expiration is outside the allowed range",
+	},
+	apperrors.ErrInvalidActivation: {
+		StatusCode:  http.StatusBadRequest,
+		UserMessage: "activatesAt must be before the expiration time",
+	},
+	apperrors.ErrInvalidExpiredRedirectURL: {
+		StatusCode:  http.StatusBadRequest,
+		UserMessage: "Invalid expiredRedirectUrl format or length",
+	},
+	apperrors.ErrInvalidDomain: {
+		StatusCode:  http.StatusBadRequest,
+		UserMessage: "Domain is not registered or not available to this API key",
+	},
+	apperrors.ErrInvalidPassword: {
+		StatusCode:  http.StatusBadRequest,
+		UserMessage: "Password must be non-empty and at most 72 bytes",
+	},
+	apperrors.ErrInvalidMaxClicks: {
+		StatusCode:  http.StatusBadRequest,
+		UserMessage: "maxClicks must be a positive integer",
+	},
+	apperrors.ErrInvalidRedirectType: {
+		StatusCode:  http.StatusBadRequest,
+		UserMessage: "redirectType must be 301, 302, 307 or 308, and may only be 301 or 308 without a password, maxClicks, uncacheable, variants or redirectRules",
+	},
+	apperrors.ErrInvalidCountryURLs: {
+		StatusCode:  http.StatusBadRequest,
+		UserMessage: "countryUrls must map two-letter country codes to valid URLs within the maximum length",
+	},
+	apperrors.ErrInvalidPlatformURL: {
+		StatusCode:  http.StatusBadRequest,
+		UserMessage: "iosUrl must use https or itms-apps, and androidUrl must use https, intent or market, each within the maximum length",
+	},
+	apperrors.ErrInvalidQueryPassthrough: {
+		StatusCode:  http.StatusBadRequest,
+		UserMessage: "queryPassthrough must be \"destination\", \"request\" or \"append\"",
+	},
+	apperrors.ErrInvalidTemplate: {
+		StatusCode:  http.StatusBadRequest,
+		UserMessage: "URL placeholders must be closed and one of {utm_source}, {utm_medium}, {utm_campaign}, {utm_term}, {utm_content} or {query.<name>}, with templateDefaults only for those placeholders",
+	},
+	apperrors.ErrInvalidVariants: {
+		StatusCode:  http.StatusBadRequest,
+		UserMessage: "variants must have 2 to 10 entries, each with a valid URL within the maximum length and a weight from 1 to 10000, and stickyVariants requires variants",
+	},
+	apperrors.ErrInvalidRedirectRules: {
+		StatusCode:  http.StatusBadRequest,
+		UserMessage: "redirectRules must have at most 20 entries, each with a valid URL within the maximum length and a condition with at least one valid timeOfDay, daysOfWeek, languages, headers or referrerHosts",
+	},
+	apperrors.ErrInvalidBatchSize: {
+		StatusCode:  http.StatusBadRequest,
+		UserMessage: "Batch must contain at least one item and no more than the maximum batch size",
+	},
+	apperrors.ErrInvalidIdempotencyKey: {
+		StatusCode:  http.StatusBadRequest,
+		UserMessage: "Idempotency-Key must be at most 255 characters",
+	},
+	apperrors.ErrAliasAlreadyInUse: {
+		StatusCode:  http.StatusConflict,
+		UserMessage: "Alias is already in use",
+	},
+	apperrors.ErrIdempotencyKeyInProgress: {
+		StatusCode:  http.StatusConflict,
+		UserMessage: "A request with this Idempotency-Key is still in progress",
+	},
+	apperrors.ErrActiveLinkQuotaExceeded: {
+		StatusCode:  http.StatusForbidden,
+		UserMessage: "Active link quota exceeded. Delete unused short URLs before creating more",
+	},
+	apperrors.ErrIdempotencyKeyMismatch: {
+		StatusCode:  http.StatusUnprocessableEntity,
+		UserMessage: "Idempotency-Key was already used with a different request",
+	},
+	apperrors.ErrDailyCreateQuotaExceeded: {
+		StatusCode:  http.StatusTooManyRequests,
+		UserMessage: "Daily link creation quota exceeded. Please try again tomorrow (UTC)",
+	},
+	apperrors.ErrMaxRetriesExceeded: {
+		StatusCode:  http.StatusInternalServerError,
+		UserMessage: "Unable to generate unique short code. Please try again",
+	},
+	apperrors.ErrConfigurationMissing: {
+		StatusCode:  http.StatusInternalServerError,
+		UserMessage: "Service configuration error",
+	},
+	apperrors.ErrDataStoreUnavailable: {
+		StatusCode:  http.StatusServiceUnavailable,
+		UserMessage: "Service temporarily unavailable. Please try again later",
+	},
 }
@@ -2,9 +2,12 @@ package create
 
 import (
 	"encoding/json"
+	"math"
 	"net/http"
-	"net/url"
+	"time"
 	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/model"
+	"tiny-bitly/internal/service"
 )
 
 type CreateURLRequest struct {
@@ -13,6 +16,97 @@ type CreateURLRequest struct {
 	// A specific user-provided alias to use in the short URL.
 	// If not provided, a random short code will be created.
 	Alias *string `json:"alias"`
+
+	// An RFC 3339 timestamp at which the short URL expires.
+	// Mutually exclusive with TTLSeconds.
+	ExpiresAt *time.Time `json:"expiresAt"`
+
+	// The number of seconds until the short URL expires.
+	// Mutually exclusive with ExpiresAt.
+	TTLSeconds *int64 `json:"ttlSeconds"`
+
+	// Whether to return the existing short URL if this URL was already
+	// shortened. If not provided, the server's default applies.
+	Deduplicate *bool `json:"deduplicate"`
+
+	// A registered custom domain to create the short URL on.
+	// If not provided, the short URL uses the default domain.
+	Domain *string `json:"domain"`
+
+	// A password that visitors must enter before being redirected.
+	// If not provided, the short URL redirects immediately.
+	Password *string `json:"password"`
+
+	// The number of times the short URL may be followed before it behaves
+	// like an expired short URL. If not provided, it never runs out.
+	MaxClicks *int `json:"maxClicks"`
+
+	// An RFC 3339 timestamp before which the short URL does not redirect.
+	// If not provided, it redirects from creation.
+	ActivatesAt *time.Time `json:"activatesAt"`
+
+	// A URL to redirect visitors to once the short URL has expired.
+	// If not provided, expired short URLs respond with 410 Gone.
+	ExpiredRedirectURL *string `json:"expiredRedirectUrl"`
+
+	// The HTTP status code to redirect with: 301 or 308 for permanent links,
+	// or 302 or 307 for links that may be retargeted. If not provided, the
+	// server's default applies.
+	RedirectType *int `json:"redirectType"`
+
+	// Whether browsers and CDNs must not cache redirects of the short URL,
+	// e.g. because it is about to be retargeted. If not provided, they may.
+	Uncacheable *bool `json:"uncacheable"`
+
+	// How to merge the query parameters of a visit into the URL when both
+	// have the same parameter: "destination" keeps the URL's values, "request"
+	// uses the visit's values, and "append" keeps both. If not provided, the
+	// visit's query parameters are ignored.
+	QueryPassthrough *string `json:"queryPassthrough"`
+
+	// Whether to append the path after the short code in a visit, e.g.
+	// "extra/path" in /abc123/extra/path, to the URL's path. If not provided,
+	// it is not.
+	PathPassthrough *bool `json:"pathPassthrough"`
+
+	// The values that placeholders in the URL, e.g. {utm_campaign} or
+	// {query.ref}, default to when a visit does not provide them. If not
+	// provided, they default to "".
+	TemplateDefaults map[string]string `json:"templateDefaults"`
+
+	// The URLs to redirect visitors to instead of URL, by ISO 3166-1 alpha-2
+	// code of the country they visit from, e.g. {"DE": "https://example.de"}.
+	// If not provided, every visitor is redirected to URL.
+	CountryURLs map[string]string `json:"countryUrls"`
+
+	// A URL to redirect visitors on iOS devices to instead, e.g. an App Store
+	// or universal link URL. Must use https or itms-apps. If not provided,
+	// they are redirected like everyone else.
+	IOSURL *string `json:"iosUrl"`
+
+	// A URL to redirect visitors on Android devices to instead, e.g. a Play
+	// Store or intent URL. Must use https, intent or market. If not provided,
+	// they are redirected like everyone else.
+	AndroidURL *string `json:"androidUrl"`
+
+	// The URLs to split visitors across instead of URL, each with a positive
+	// integer weight that sets its share of visits, e.g.
+	// [{"url": "https://example.com/a", "weight": 3}, {"url": "https://example.com/b", "weight": 1}].
+	// If not provided, every visitor is redirected to URL.
+	Variants []model.URLVariant `json:"variants"`
+
+	// Whether returning visitors are sent to the variant they were sent to
+	// before, as remembered by a cookie. If not provided, each visit is sent
+	// to a variant chosen anew.
+	StickyVariants *bool `json:"stickyVariants"`
+
+	// Rules that send visitors whose requests meet a condition to another URL,
+	// evaluated in order before any other targeting, e.g.
+	// [{"when": {"daysOfWeek": ["sat", "sun"], "languages": ["de"]}, "url": "https://example.com/wochenende"}].
+	// Conditions may cover timeOfDay, daysOfWeek (in timeZone), languages,
+	// headers and referrerHosts. If not provided, or no rule matches, visitors
+	// are redirected as usual.
+	RedirectRules []model.RedirectRule `json:"redirectRules"`
 }
 
 type CreateURLResponse struct {
@@ -20,15 +114,18 @@ type CreateURLResponse struct {
 }
 
 // NewPostURLHandler creates an HTTP handler for POST /urls that uses the provided service.
+// If the request has an Idempotency-Key header, retries with the same key and
+// body replay the original response instead of creating another short code.
 // - 201 Created with a CreateUrlResponse on success
-// - 400 Bad Request if the URL is invalid, exceeds length, or alias is invalid
-// - 409 Conflict if the alias is already in use
+// - 400 Bad Request if the URL is invalid, exceeds length, alias is invalid, expiration is invalid, domain is unusable, password is invalid, maxClicks is not positive, activatesAt is not before the expiration, expiredRedirectUrl is invalid, redirectType is unsupported, queryPassthrough is unsupported, the URL has an unknown placeholder, countryUrls is invalid, iosUrl or androidUrl is invalid, variants is invalid, or redirectRules is invalid
+// - 409 Conflict if the alias is already in use, or the Idempotency-Key is still in progress
+// - 422 Unprocessable Entity if the Idempotency-Key was already used with a different body
 // - 500 Internal Server Error for other errors
 // - 503 Service Unavailable if the data store is unavailable
-func NewPostURLHandler(service *Service) http.HandlerFunc {
+func NewPostURLHandler(createService *Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Attempt to read the JSON request body.
-		request, err := readRequestJson[CreateURLRequest](r)
+		request, err := service.ReadRequestJson[CreateURLRequest](r)
 		if err != nil {
 			http.Error(w, "Malformatted request JSON", http.StatusBadRequest)
 			return
@@ -37,29 +134,85 @@ func NewPostURLHandler(service *Service) http.HandlerFunc {
 		// Log the inbound request.
 		middleware.LogWithRequestID(r.Context(), "Request received", "requestURL", request.URL)
 
+		// If an idempotency key was provided, reserve it or replay the
+		// response stored for it.
+		idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+		if idempotencyKey != "" {
+			fingerprint, err := fingerprintRequest(*request)
+			if err != nil {
+				handleServiceError(r.Context(), w, err)
+				return
+			}
+			stored, err := createService.BeginIdempotentRequest(r.Context(), idempotencyKey, fingerprint)
+			if err != nil {
+				handleServiceError(r.Context(), w, err)
+				return
+			}
+			if stored != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(stored.StatusCode)
+				w.Write(stored.ResponseBody)
+				return
+			}
+		}
+
 		// Create the short URL.
-		shortCode, err := service.CreateShortCode(r.Context(), request.URL, request.Alias)
+		shortCode, err := createService.CreateShortCode(r.Context(), request.URL, CreateOptions{
+			Alias:              request.Alias,
+			ExpiresAt:          request.ExpiresAt,
+			TTL:                ttlFromSeconds(request.TTLSeconds),
+			Deduplicate:        request.Deduplicate,
+			Domain:             request.Domain,
+			Password:           request.Password,
+			MaxClicks:          request.MaxClicks,
+			ActivatesAt:        request.ActivatesAt,
+			ExpiredRedirectURL: request.ExpiredRedirectURL,
+			RedirectType:       request.RedirectType,
+			Uncacheable:        request.Uncacheable,
+			QueryPassthrough:   request.QueryPassthrough,
+			PathPassthrough:    request.PathPassthrough,
+			TemplateDefaults:   request.TemplateDefaults,
+			CountryURLs:        request.CountryURLs,
+			IOSURL:             request.IOSURL,
+			AndroidURL:         request.AndroidURL,
+			Variants:           request.Variants,
+			StickyVariants:     request.StickyVariants,
+			RedirectRules:      request.RedirectRules,
+		})
 		if err != nil {
+			if idempotencyKey != "" {
+				createService.ReleaseIdempotentRequest(r.Context(), idempotencyKey)
+			}
 			handleServiceError(r.Context(), w, err)
 			return
 		}
 
-		// Build the short URL using the public URL, which may differ from the
-		// URL to the K8s pod.
-		baseURL := middleware.PublicBaseURL(r, service.config.APIHostname)
-		shortURL, err := url.JoinPath(baseURL, *shortCode)
+		// Build the short URL on the requested domain.
+		shortURL, err := service.BuildShortURL(r, createService.config.APIHostname, requestDomain(request.Domain), *shortCode)
 		if err != nil {
 			middleware.LogErrorWithRequestID(r.Context(), err, "Failed to build short URL")
 			handleServiceError(r.Context(), w, err)
 			return
 		}
+		response := CreateURLResponse{
+			ShortURL: shortURL,
+		}
+
+		// Store the response for replay before sending it, since the client
+		// may give up waiting for it.
+		if idempotencyKey != "" {
+			responseBody, err := json.Marshal(response)
+			if err != nil {
+				handleServiceError(r.Context(), w, err)
+				return
+			}
+			createService.CompleteIdempotentRequest(r.Context(), idempotencyKey, http.StatusCreated, responseBody)
+		}
 
 		// Send the JSON response.
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
-		err = writeResponseJson(w, CreateURLResponse{
-			ShortURL: shortURL,
-		})
+		err = service.WriteResponseJson(w, response)
 		if err != nil {
 			handleServiceError(r.Context(), w, err)
 			return
@@ -67,29 +220,127 @@ func NewPostURLHandler(service *Service) http.HandlerFunc {
 	}
 }
 
-// Reads a JSON object of type T from the provided HTTP request.
-// Returns an error if decoding fails.
-func readRequestJson[T any](r *http.Request) (*T, error) {
-	var request T
-	err := json.NewDecoder(r.Body).Decode(&request)
-	if err != nil {
-		middleware.LogErrorWithRequestID(r.Context(), err, "Bad request")
-		return nil, err
+// Returns the normalized domain of a successful create request, or "" for the
+// default domain.
+func requestDomain(domain *string) string {
+	if domain == nil {
+		return ""
 	}
-	return &request, nil
+	return model.NormalizeHostname(*domain)
 }
 
-// Writes a JSON object of type T to the provided HTTP response.
-// Returns an error if encoding fails.
-// Note: Content-Type header should be set before calling this function.
-func writeResponseJson[T any](w http.ResponseWriter, body T) error {
-	// Send the JSON response - or an error.
-	err := json.NewEncoder(w).Encode(body)
-	if err != nil {
-		// Note: We don't have request context here, so use regular log
-		// In practice, this error is rare and would be caught by the handler
-		return err
+// Converts an optional number of seconds into an optional duration.
+func ttlFromSeconds(seconds *int64) *time.Duration {
+	if seconds == nil {
+		return nil
 	}
+	// Clamp to avoid overflowing time.Duration. Out-of-range values are still
+	// rejected by the service's TTL bounds.
+	ttl := time.Duration(math.MaxInt64)
+	if *seconds < int64(ttl/time.Second) {
+		ttl = time.Duration(*seconds) * time.Second
+	}
+	return &ttl
+}
+
+type BatchCreateURLResult struct {
+	ShortURL *string           `json:"shortUrl,omitempty"`
+	Error    *BatchCreateError `json:"error,omitempty"`
+}
 
-	return nil
+type BatchCreateError struct {
+	StatusCode int    `json:"status"`
+	Message    string `json:"message"`
+}
+
+type BatchCreateURLResponse struct {
+	Results []BatchCreateURLResult `json:"results"`
+}
+
+// NewPostURLBatchHandler creates an HTTP handler for POST /urls/batch that uses the provided service.
+// Accepts a JSON array of CreateURLRequest objects and returns one result per
+// item, in the same order. Each result holds either a shortUrl or an error with
+// the status code and message that POST /urls would have returned.
+// - 200 OK with a BatchCreateURLResponse, even if some items failed
+// - 400 Bad Request if the body is malformed, empty, or exceeds the maximum batch size
+// - 500 Internal Server Error for other errors
+func NewPostURLBatchHandler(createService *Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Attempt to read the JSON request body.
+		request, err := service.ReadRequestJson[[]CreateURLRequest](r)
+		if err != nil {
+			http.Error(w, "Malformatted request JSON", http.StatusBadRequest)
+			return
+		}
+
+		// Log the inbound request.
+		middleware.LogWithRequestID(r.Context(), "Batch request received", "count", len(*request))
+
+		items := make([]BatchItem, len(*request))
+		for i, itemRequest := range *request {
+			items[i] = BatchItem{
+				OriginalURL: itemRequest.URL,
+				Options: CreateOptions{
+					Alias:              itemRequest.Alias,
+					ExpiresAt:          itemRequest.ExpiresAt,
+					TTL:                ttlFromSeconds(itemRequest.TTLSeconds),
+					Deduplicate:        itemRequest.Deduplicate,
+					Domain:             itemRequest.Domain,
+					Password:           itemRequest.Password,
+					MaxClicks:          itemRequest.MaxClicks,
+					ActivatesAt:        itemRequest.ActivatesAt,
+					ExpiredRedirectURL: itemRequest.ExpiredRedirectURL,
+					RedirectType:       itemRequest.RedirectType,
+					Uncacheable:        itemRequest.Uncacheable,
+					QueryPassthrough:   itemRequest.QueryPassthrough,
+					PathPassthrough:    itemRequest.PathPassthrough,
+					TemplateDefaults:   itemRequest.TemplateDefaults,
+					CountryURLs:        itemRequest.CountryURLs,
+					IOSURL:             itemRequest.IOSURL,
+					AndroidURL:         itemRequest.AndroidURL,
+					Variants:           itemRequest.Variants,
+					StickyVariants:     itemRequest.StickyVariants,
+					RedirectRules:      itemRequest.RedirectRules,
+				},
+			}
+		}
+
+		// Create the short URLs.
+		results, err := createService.CreateShortCodes(r.Context(), items)
+		if err != nil {
+			handleServiceError(r.Context(), w, err)
+			return
+		}
+
+		// Build the short URLs on their requested domains.
+		response := BatchCreateURLResponse{
+			Results: make([]BatchCreateURLResult, len(results)),
+		}
+		for i, result := range results {
+			if result.Err != nil {
+				entry := service.LookupErrorMapping(result.Err, errorMappings)
+				response.Results[i].Error = &BatchCreateError{
+					StatusCode: entry.StatusCode,
+					Message:    entry.UserMessage,
+				}
+				continue
+			}
+			shortURL, err := service.BuildShortURL(r, createService.config.APIHostname, requestDomain((*request)[i].Domain), *result.ShortCode)
+			if err != nil {
+				middleware.LogErrorWithRequestID(r.Context(), err, "Failed to build short URL")
+				handleServiceError(r.Context(), w, err)
+				return
+			}
+			response.Results[i].ShortURL = &shortURL
+		}
+
+		// Send the JSON response.
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = service.WriteResponseJson(w, response)
+		if err != nil {
+			handleServiceError(r.Context(), w, err)
+			return
+		}
+	}
 }
@@ -0,0 +1,98 @@
+package create
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/model"
+)
+
+// The header clients use to make a request safe to retry.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// The maximum permitted length of an Idempotency-Key header.
+const maxIdempotencyKeyLength = 255
+
+// BeginIdempotentRequest reserves the provided idempotency key for a request
+// with the provided fingerprint. Returns nil if the request should proceed, or
+// the stored response if the key was already used for an identical request
+// that has completed.
+func (s *Service) BeginIdempotentRequest(ctx context.Context, key string, fingerprint string) (*model.IdempotencyKey, error) {
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, apperrors.ErrInvalidIdempotencyKey
+	}
+
+	now := time.Now()
+	existing, err := s.dao.IdempotencyKeyDAO.Reserve(ctx, model.IdempotencyKey{
+		Key:         scopeIdempotencyKey(ctx, key),
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.config.IdempotencyKeyTTL),
+	})
+	if err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to reserve idempotency key")
+		return nil, apperrors.ErrDataStoreUnavailable
+	}
+
+	// Proceed if the key is new.
+	if existing == nil {
+		return nil, nil
+	}
+
+	if existing.Fingerprint != fingerprint {
+		return nil, apperrors.ErrIdempotencyKeyMismatch
+	}
+	if !existing.IsCompleted() {
+		return nil, apperrors.ErrIdempotencyKeyInProgress
+	}
+
+	middleware.LogWithRequestID(ctx, "Replaying response for idempotency key", "statusCode", existing.StatusCode)
+	return existing, nil
+}
+
+// CompleteIdempotentRequest stores the response to replay for the provided
+// idempotency key. Stores the response even if the request context has been
+// canceled, e.g. by a request timeout after the short code was saved, since a
+// retry must then replay this response rather than create another short code.
+func (s *Service) CompleteIdempotentRequest(ctx context.Context, key string, statusCode int, responseBody []byte) {
+	err := s.dao.IdempotencyKeyDAO.Complete(context.WithoutCancel(ctx), scopeIdempotencyKey(ctx, key), statusCode, responseBody)
+	if err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to store response for idempotency key")
+	}
+}
+
+// ReleaseIdempotentRequest frees the provided idempotency key after a failed
+// request, so that the client may retry with the same key.
+func (s *Service) ReleaseIdempotentRequest(ctx context.Context, key string) {
+	err := s.dao.IdempotencyKeyDAO.Release(context.WithoutCancel(ctx), scopeIdempotencyKey(ctx, key))
+	if err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to release idempotency key")
+	}
+}
+
+// Returns the key under which to store the provided idempotency key. Keys are
+// scoped to the caller's owner, so that owners cannot replay each other's
+// responses. Hashing keeps the result within the stored key's length limit.
+func scopeIdempotencyKey(ctx context.Context, key string) string {
+	ownerID := ""
+	if owner := middleware.GetOwnerID(ctx); owner != nil {
+		ownerID = *owner
+	}
+	sum := sha256.Sum256([]byte(ownerID + "\n" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Returns a stable hash of the provided request. Requests that decode to the
+// same fields share a fingerprint regardless of formatting or field order.
+func fingerprintRequest(request CreateURLRequest) (string, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
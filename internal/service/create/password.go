@@ -0,0 +1,27 @@
+package create
+
+import (
+	"tiny-bitly/internal/apperrors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Returns the bcrypt hash of a short code's password, or nil if no password
+// was requested. Returns apperrors.ErrInvalidPassword if the password is empty
+// or too long.
+func hashPassword(password *string) (*string, error) {
+	if password == nil {
+		return nil, nil
+	}
+	if *password == "" || len(*password) > maxPasswordLength {
+		return nil, apperrors.ErrInvalidPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(*password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordHash := string(hash)
+	return &passwordHash, nil
+}
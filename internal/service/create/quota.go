@@ -0,0 +1,84 @@
+package create
+
+import (
+	"context"
+	"time"
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/middleware"
+)
+
+// Reserves the caller's quota for n new short codes. Returns
+// apperrors.ErrActiveLinkQuotaExceeded or apperrors.ErrDailyCreateQuotaExceeded
+// if the owner would exceed a quota. Anonymous callers have no quotas.
+//
+// Both quotas are atomic counters. New short codes are counted as active even
+// without an active link quota, so that the count is right if one is
+// configured later.
+func (s *Service) reserveQuota(ctx context.Context, ownerID *string, n int, now time.Time) error {
+	if ownerID == nil || n == 0 {
+		return nil
+	}
+
+	limit := s.config.QuotaMaxActiveLinks
+	reserved, err := s.dao.URLRecordDAO.ReserveActiveLinks(ctx, *ownerID, n, limit)
+	if err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to reserve active links")
+		return apperrors.ErrDataStoreUnavailable
+	}
+	if !reserved {
+		middleware.LogWithRequestID(ctx, "Rejected create over active link quota", "ownerID", *ownerID, "limit", limit)
+		return apperrors.ErrActiveLinkQuotaExceeded
+	}
+
+	if limit := s.config.QuotaCreatesPerDay; limit > 0 {
+		reserved, err := s.dao.QuotaDAO.IncrementDailyCreates(ctx, *ownerID, quotaDay(now), n, limit)
+		if err != nil {
+			s.releaseActiveLinks(ctx, *ownerID, n)
+			middleware.LogErrorWithRequestID(ctx, err, "Failed to increment daily creates")
+			return apperrors.ErrDataStoreUnavailable
+		}
+		if !reserved {
+			s.releaseActiveLinks(ctx, *ownerID, n)
+			middleware.LogWithRequestID(ctx, "Rejected create over daily create quota", "ownerID", *ownerID, "limit", limit)
+			return apperrors.ErrDailyCreateQuotaExceeded
+		}
+	}
+
+	return nil
+}
+
+// Returns n reserved but unused short codes to the caller's quotas. Uses the
+// same now as the reservation, so that the creates are returned to the day
+// they were counted against.
+func (s *Service) releaseQuota(ctx context.Context, ownerID *string, n int, now time.Time) {
+	if ownerID == nil || n == 0 {
+		return
+	}
+
+	s.releaseActiveLinks(ctx, *ownerID, n)
+
+	if s.config.QuotaCreatesPerDay <= 0 {
+		return
+	}
+
+	// Release even if the request was cancelled, since the creates were counted.
+	releaseCtx := context.WithoutCancel(ctx)
+	if err := s.dao.QuotaDAO.DecrementDailyCreates(releaseCtx, *ownerID, quotaDay(now), n); err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to release daily creates", "ownerID", *ownerID, "count", n)
+	}
+}
+
+// Returns n reserved but unused short codes to the owner's count of active
+// links.
+func (s *Service) releaseActiveLinks(ctx context.Context, ownerID string, n int) {
+	// Release even if the request was cancelled, since the links were counted.
+	releaseCtx := context.WithoutCancel(ctx)
+	if err := s.dao.URLRecordDAO.ReleaseActiveLinks(releaseCtx, ownerID, n); err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to release active links", "ownerID", ownerID, "count", n)
+	}
+}
+
+// Returns the UTC day that creates at the provided time count against.
+func quotaDay(now time.Time) string {
+	return now.UTC().Format(time.DateOnly)
+}
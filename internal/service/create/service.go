@@ -25,36 +25,133 @@ func NewService(dao dao.DAO, config *config.Config) *Service {
 	}
 }
 
+// CreateOptions holds the optional, per-request settings for a new short code.
+type CreateOptions struct {
+	// A specific user-provided alias to use as the short code. If nil, a random
+	// short code is generated.
+	Alias *string
+
+	// An absolute expiration time. Mutually exclusive with TTL.
+	ExpiresAt *time.Time
+
+	// A lifetime relative to the time of creation. Mutually exclusive with
+	// ExpiresAt.
+	TTL *time.Duration
+
+	// Whether to return the short code of an active record for the same URL
+	// instead of creating a new one. If nil, the configured default applies.
+	Deduplicate *bool
+
+	// A registered custom domain to create the short code on. If nil, the
+	// short code is created on the default domain.
+	Domain *string
+
+	// A password that visitors must enter before being redirected. If nil, the
+	// short code redirects immediately. Only a hash of it is stored.
+	Password *string
+
+	// The number of times the short code may be resolved before it behaves
+	// like an expired short code. If nil, it may be resolved any number of
+	// times.
+	MaxClicks *int
+
+	// A time before which the short code must not resolve. If nil, it resolves
+	// from creation.
+	ActivatesAt *time.Time
+
+	// A URL to redirect visitors to once the short code has expired. If nil,
+	// visitors are told that it has expired instead.
+	ExpiredRedirectURL *string
+
+	// The HTTP status code to redirect visitors with. If nil, the configured
+	// default applies.
+	RedirectType *int
+
+	// Whether browsers and CDNs must not cache redirects of the short code. If
+	// nil or false, they may.
+	Uncacheable *bool
+
+	// How to merge the query parameters of a visit into the original URL, as
+	// one of the model.QueryPassthrough constants. If nil, they are ignored.
+	QueryPassthrough *string
+
+	// Whether to append the path after the short code in a visit to the
+	// original URL's path. If nil or false, it is ignored.
+	PathPassthrough *bool
+
+	// The values that placeholders in the original URL, e.g. {utm_campaign},
+	// default to when a visit does not provide them. If nil, they default to
+	// "".
+	TemplateDefaults map[string]string
+
+	// The URLs to redirect visitors to instead of the original URL, by ISO
+	// 3166-1 alpha-2 code of the country they visit from. If nil, every
+	// visitor is redirected to the original URL.
+	CountryURLs map[string]string
+
+	// The URLs to redirect visitors on iOS and Android devices to instead,
+	// e.g. app store or deep link URLs. If nil, they are redirected like
+	// everyone else.
+	IOSURL     *string
+	AndroidURL *string
+
+	// The destinations to split visitors across instead of the original URL,
+	// in proportion to their weights. If nil, every visitor is redirected to
+	// the original URL.
+	Variants []model.URLVariant
+
+	// Whether returning visitors are sent to the variant that they were sent
+	// to before. If nil or false, each visit is sent to a variant chosen
+	// anew.
+	StickyVariants *bool
+
+	// The rules that send visitors whose requests meet their conditions to
+	// other URLs, evaluated in order. If nil, visitors are redirected as
+	// usual.
+	RedirectRules []model.RedirectRule
+}
+
 // CreateShortCode creates and saves an alias for the provided long URL, then returns the short code.
 func (s *Service) CreateShortCode(
 	ctx context.Context,
 	originalURL string,
-	alias *string,
+	opts CreateOptions,
 ) (*string, error) {
-	// Validate the URL.
-	validatedURL, err := validateURL(originalURL)
-	if err != nil {
-		return nil, apperrors.ErrInvalidURL
-	}
-
-	maxAliasLength := s.config.MaxAliasLength
+	alias := opts.Alias
 	maxTries := s.config.MaxTriesCreateShortCode
-	maxURLLength := s.config.MaxURLLength
 	shortCodeLength := s.config.ShortCodeLength
-	shortCodeTTL := s.config.ShortCodeTTL
 
-	if len(originalURL) > maxURLLength {
-		return nil, apperrors.ErrURLLengthExceeded
+	// Validate the request and determine the record to save.
+	now := time.Now()
+	urlRecord, err := s.prepareURLRecord(ctx, originalURL, opts, now)
+	if err != nil {
+		return nil, err
 	}
 
-	// If a custom alias was provided, validate it.
-	if alias != nil && !validateAlias(*alias, maxAliasLength) {
-		return nil, apperrors.ErrInvalidAlias
+	// Reuse an existing short code for the same URL if requested.
+	existingShortCode, err := s.findDuplicate(ctx, urlRecord, opts)
+	if err != nil {
+		return nil, err
+	}
+	if existingShortCode != nil {
+		middleware.LogWithRequestID(ctx, "Reused existing short code for URL", "originalURL", urlRecord.OriginalURL, "shortCode", *existingShortCode)
+		return existingShortCode, nil
+	}
+
+	// Count the new short code against the caller's quotas, and give it back
+	// if none is created.
+	if err := s.reserveQuota(ctx, urlRecord.OwnerID, 1, now); err != nil {
+		return nil, err
 	}
+	var hasCreated bool
+	defer func() {
+		if !hasCreated {
+			s.releaseQuota(ctx, urlRecord.OwnerID, 1, now)
+		}
+	}()
 
 	// Retry until we find a short code not taken yet.
 	var shortCode string
-	var hasCreated bool
 	numTries := 0
 	for numTries < maxTries {
 		numTries += 1
@@ -65,17 +162,11 @@ func (s *Service) CreateShortCode(
 			shortCode = generateShortCode(shortCodeLength)
 		}
 
-		// Set expiration time based on configured TTL.
-		expiresAt := time.Now().Add(shortCodeTTL)
-
-		middleware.LogDebugWithRequestID(ctx, "Creating a new URL record", "shortCode", shortCode, "expiresAt", expiresAt)
+		middleware.LogDebugWithRequestID(ctx, "Creating a new URL record", "domain", urlRecord.Domain, "shortCode", shortCode, "expiresAt", urlRecord.ExpiresAt)
 
 		// Save a new URL record.
-		_, err = s.dao.URLRecordDAO.Create(ctx, model.URLRecord{
-			OriginalURL: *validatedURL,
-			ShortCode:   shortCode,
-			ExpiresAt:   expiresAt,
-		})
+		urlRecord.ShortCode = shortCode
+		_, err = s.dao.URLRecordDAO.Create(ctx, *urlRecord)
 
 		// If the short code is already in use:
 		if errors.Is(err, apperrors.ErrShortCodeAlreadyInUse) {
@@ -103,6 +194,214 @@ func (s *Service) CreateShortCode(
 		return nil, apperrors.ErrMaxRetriesExceeded
 	}
 
-	middleware.LogWithRequestID(ctx, "Generated a new short code for URL", "originalURL", *validatedURL, "shortCode", shortCode)
+	middleware.LogWithRequestID(ctx, "Generated a new short code for URL", "originalURL", urlRecord.OriginalURL, "shortCode", shortCode)
 	return &shortCode, nil
 }
+
+// Validates a request to shorten the provided URL and returns the record to
+// save, without a short code. The record is owned by the caller, if any.
+func (s *Service) prepareURLRecord(ctx context.Context, originalURL string, opts CreateOptions, now time.Time) (*model.URLRecord, error) {
+	// Validate the URL.
+	validatedURL, err := ValidateURL(originalURL)
+	if err != nil {
+		return nil, apperrors.ErrInvalidURL
+	}
+
+	if !ValidateURLLength(originalURL, s.config.MaxURLLength) {
+		return nil, apperrors.ErrURLLengthExceeded
+	}
+
+	// Validate the placeholders in the URL, if any, and their defaults.
+	if err := ValidateTemplate(originalURL, opts.TemplateDefaults, s.config.MaxURLLength); err != nil {
+		return nil, err
+	}
+
+	// If a custom alias was provided, validate it.
+	if opts.Alias != nil && !validateAlias(*opts.Alias, s.config.MaxAliasLength) {
+		return nil, apperrors.ErrInvalidAlias
+	}
+
+	// Determine when the short code expires.
+	expiresAt, err := s.resolveExpiresAt(now, opts.ExpiresAt, opts.TTL)
+	if err != nil {
+		return nil, err
+	}
+
+	// The short code must resolve for some time before it expires.
+	if opts.ActivatesAt != nil && !opts.ActivatesAt.Before(expiresAt) {
+		return nil, apperrors.ErrInvalidActivation
+	}
+
+	// Validate the fallback URL for after expiration, if any.
+	var expiredRedirectURL *string
+	if opts.ExpiredRedirectURL != nil {
+		validatedExpiredRedirectURL, err := ValidateURL(*opts.ExpiredRedirectURL)
+		if err != nil || !ValidateURLLength(*opts.ExpiredRedirectURL, s.config.MaxURLLength) {
+			return nil, apperrors.ErrInvalidExpiredRedirectURL
+		}
+		normalizedExpiredRedirectURL := NormalizeURL(*validatedExpiredRedirectURL)
+		expiredRedirectURL = &normalizedExpiredRedirectURL
+	}
+
+	// Validate the redirect type, if any. Browsers cache permanent redirects
+	// regardless of cache headers, which would skip the password, click count,
+	// choice of variant or rules on later visits, or keep an uncacheable short
+	// code from being retargeted.
+	uncacheable := opts.Uncacheable != nil && *opts.Uncacheable
+	if opts.RedirectType != nil {
+		if !model.IsValidRedirectType(*opts.RedirectType) {
+			return nil, apperrors.ErrInvalidRedirectType
+		}
+		if model.IsPermanentRedirectType(*opts.RedirectType) && (opts.Password != nil || opts.MaxClicks != nil || uncacheable || len(opts.Variants) > 0 || len(opts.RedirectRules) > 0) {
+			return nil, apperrors.ErrInvalidRedirectType
+		}
+	}
+
+	// Validate the per-country URLs, if any.
+	countryURLs, err := validateCountryURLs(opts.CountryURLs, opts.TemplateDefaults, s.config.MaxURLLength)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the per-platform URLs, if any.
+	iosURL, err := validatePlatformURL(opts.IOSURL, iosURLSchemes, opts.TemplateDefaults, s.config.MaxURLLength)
+	if err != nil {
+		return nil, err
+	}
+	androidURL, err := validatePlatformURL(opts.AndroidURL, androidURLSchemes, opts.TemplateDefaults, s.config.MaxURLLength)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the variants, if any. Only split tests have variants to stick
+	// to.
+	variants, err := validateVariants(opts.Variants, opts.TemplateDefaults, s.config.MaxURLLength)
+	if err != nil {
+		return nil, err
+	}
+	stickyVariants := opts.StickyVariants != nil && *opts.StickyVariants
+	if stickyVariants && len(variants) == 0 {
+		return nil, apperrors.ErrInvalidVariants
+	}
+
+	// Validate the redirect rules, if any.
+	redirectRules, err := validateRedirectRules(opts.RedirectRules, opts.TemplateDefaults, s.config.MaxURLLength)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the query passthrough mode, if any.
+	if opts.QueryPassthrough != nil && !model.IsValidQueryPassthrough(*opts.QueryPassthrough) {
+		return nil, apperrors.ErrInvalidQueryPassthrough
+	}
+
+	// Determine which domain the short code is created on.
+	ownerID := middleware.GetOwnerID(ctx)
+	domain, err := s.resolveDomain(ctx, opts.Domain, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hash the password, if any, so that the plaintext is never stored.
+	passwordHash, err := hashPassword(opts.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the click limit, if any. Every click is still to come.
+	var remainingClicks *int
+	if opts.MaxClicks != nil {
+		if *opts.MaxClicks < 1 {
+			return nil, apperrors.ErrInvalidMaxClicks
+		}
+		remainingClicks = new(int)
+		*remainingClicks = *opts.MaxClicks
+	}
+
+	return &model.URLRecord{
+		OriginalURL:        NormalizeURL(*validatedURL),
+		Domain:             domain,
+		ExpiresAt:          expiresAt,
+		OwnerID:            ownerID,
+		PasswordHash:       passwordHash,
+		MaxClicks:          opts.MaxClicks,
+		RemainingClicks:    remainingClicks,
+		ActivatesAt:        opts.ActivatesAt,
+		ExpiredRedirectURL: expiredRedirectURL,
+		RedirectType:       opts.RedirectType,
+		Uncacheable:        uncacheable,
+		QueryPassthrough:   opts.QueryPassthrough,
+		PathPassthrough:    opts.PathPassthrough != nil && *opts.PathPassthrough,
+		TemplateDefaults:   opts.TemplateDefaults,
+		CountryURLs:        countryURLs,
+		IOSURL:             iosURL,
+		AndroidURL:         androidURL,
+		Variants:           variants,
+		StickyVariants:     stickyVariants,
+		RedirectRules:      redirectRules,
+	}, nil
+}
+
+// Returns the short code of an active record for the same URL, domain and owner if
+// deduplication applies to the request, or nil otherwise. Concurrent requests for the same
+// URL may still each create a short code.
+func (s *Service) findDuplicate(ctx context.Context, urlRecord *model.URLRecord, opts CreateOptions) (*string, error) {
+	if !s.shouldDeduplicate(opts) {
+		return nil, nil
+	}
+
+	entity, err := s.dao.URLRecordDAO.GetByOriginalURL(ctx, urlRecord.OriginalURL, urlRecord.Domain, urlRecord.OwnerID)
+	if err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to look up URL record by original URL")
+		return nil, apperrors.ErrDataStoreUnavailable
+	}
+	if entity == nil {
+		return nil, nil
+	}
+
+	shortCode := entity.ShortCode
+	return &shortCode, nil
+}
+
+// Returns true if the request should reuse an existing short code. Never
+// applies to requests for a specific alias, expiration, password, click limit,
+// activation time, fallback URL, redirect type, caching, passthrough, template
+// defaults, per-country URLs, per-platform URLs or variants, which an existing
+// record may not satisfy.
+func (s *Service) shouldDeduplicate(opts CreateOptions) bool {
+	if opts.Alias != nil || opts.ExpiresAt != nil || opts.TTL != nil || opts.Password != nil || opts.MaxClicks != nil || opts.ActivatesAt != nil || opts.ExpiredRedirectURL != nil || opts.RedirectType != nil || opts.Uncacheable != nil || opts.QueryPassthrough != nil || opts.PathPassthrough != nil || opts.TemplateDefaults != nil || opts.CountryURLs != nil || opts.IOSURL != nil || opts.AndroidURL != nil || opts.Variants != nil || opts.StickyVariants != nil || opts.RedirectRules != nil {
+		return false
+	}
+	if opts.Deduplicate != nil {
+		return *opts.Deduplicate
+	}
+	return s.config.DeduplicateURLs
+}
+
+// Returns the expiration time for a new short code. Uses the configured default
+// TTL if neither an absolute expiration nor a TTL was requested. Requested
+// values must lie within the configured minimum and maximum TTL.
+func (s *Service) resolveExpiresAt(now time.Time, expiresAt *time.Time, ttl *time.Duration) (time.Time, error) {
+	if expiresAt != nil && ttl != nil {
+		return time.Time{}, apperrors.ErrInvalidExpiration
+	}
+
+	if expiresAt != nil {
+		if err := ValidateExpiresAt(now, *expiresAt, s.config.ShortCodeTTLMin, s.config.ShortCodeTTLMax); err != nil {
+			return time.Time{}, err
+		}
+		return *expiresAt, nil
+	}
+
+	if ttl != nil {
+		if *ttl <= 0 {
+			return time.Time{}, apperrors.ErrInvalidExpiration
+		}
+		if !validateTTL(*ttl, s.config.ShortCodeTTLMin, s.config.ShortCodeTTLMax) {
+			return time.Time{}, apperrors.ErrExpirationOutOfRange
+		}
+		return now.Add(*ttl), nil
+	}
+
+	return now.Add(s.config.ShortCodeTTL), nil
+}
@@ -2,24 +2,33 @@ package create
 
 import (
 	"context"
+	"net/http"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 	"tiny-bitly/internal/apperrors"
 	"tiny-bitly/internal/config"
 	"tiny-bitly/internal/dao"
 	mock_daotypes "tiny-bitly/internal/dao/generated"
+	"tiny-bitly/internal/middleware"
 	"tiny-bitly/internal/model"
 
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type CreateServiceSuite struct {
 	suite.Suite
-	ctx          context.Context
-	ctrl         *gomock.Controller
-	service      *Service
-	dao          dao.DAO
-	urlRecordDAO *mock_daotypes.MockURLRecordDAO
+	ctx               context.Context
+	ctrl              *gomock.Controller
+	service           *Service
+	dao               dao.DAO
+	urlRecordDAO      *mock_daotypes.MockURLRecordDAO
+	idempotencyKeyDAO *mock_daotypes.MockIdempotencyKeyDAO
+	quotaDAO          *mock_daotypes.MockQuotaDAO
+	domainDAO         *mock_daotypes.MockDomainDAO
 }
 
 func TestCreateServiceSuite(t *testing.T) {
@@ -30,8 +39,14 @@ func (suite *CreateServiceSuite) SetupTest() {
 	suite.ctx = context.Background()
 	suite.ctrl = gomock.NewController(suite.T())
 	suite.urlRecordDAO = mock_daotypes.NewMockURLRecordDAO(suite.ctrl)
+	suite.idempotencyKeyDAO = mock_daotypes.NewMockIdempotencyKeyDAO(suite.ctrl)
+	suite.quotaDAO = mock_daotypes.NewMockQuotaDAO(suite.ctrl)
+	suite.domainDAO = mock_daotypes.NewMockDomainDAO(suite.ctrl)
 	suite.dao = dao.DAO{
-		URLRecordDAO: suite.urlRecordDAO,
+		URLRecordDAO:      suite.urlRecordDAO,
+		IdempotencyKeyDAO: suite.idempotencyKeyDAO,
+		QuotaDAO:          suite.quotaDAO,
+		DomainDAO:         suite.domainDAO,
 	}
 	cfg := config.GetTestConfig(config.Config{})
 	suite.service = NewService(suite.dao, &cfg)
@@ -39,14 +54,14 @@ func (suite *CreateServiceSuite) SetupTest() {
 
 func (suite *CreateServiceSuite) TestErrorInputURLEmpty() {
 	originalURL := ""
-	_, err := suite.service.CreateShortCode(suite.ctx, originalURL, nil)
+	_, err := suite.service.CreateShortCode(suite.ctx, originalURL, CreateOptions{})
 	suite.NotNil(err)
 	suite.ErrorContains(err, "invalid URL")
 }
 
 func (suite *CreateServiceSuite) TestErrorInputURLInvalidChars() {
 	originalURL := "www.`.com"
-	_, err := suite.service.CreateShortCode(suite.ctx, originalURL, nil)
+	_, err := suite.service.CreateShortCode(suite.ctx, originalURL, CreateOptions{})
 	suite.NotNil(err)
 	suite.ErrorContains(err, "invalid URL")
 }
@@ -55,7 +70,7 @@ func (suite *CreateServiceSuite) TestErrorInputURLTooLong() {
 	cfg := config.GetTestConfig(config.Config{MaxURLLength: 2})
 	service := NewService(suite.dao, &cfg)
 	originalURL := "abc"
-	_, err := service.CreateShortCode(suite.ctx, originalURL, nil)
+	_, err := service.CreateShortCode(suite.ctx, originalURL, CreateOptions{})
 	suite.NotNil(err)
 	suite.ErrorIs(err, apperrors.ErrURLLengthExceeded)
 }
@@ -63,7 +78,7 @@ func (suite *CreateServiceSuite) TestErrorInputURLTooLong() {
 func (suite *CreateServiceSuite) TestErrorInputAliasEmpty() {
 	originalURL := "https://www.foo.com"
 	alias := ""
-	_, err := suite.service.CreateShortCode(suite.ctx, originalURL, &alias)
+	_, err := suite.service.CreateShortCode(suite.ctx, originalURL, CreateOptions{Alias: &alias})
 	suite.NotNil(err)
 	suite.ErrorIs(err, apperrors.ErrInvalidAlias)
 }
@@ -71,7 +86,7 @@ func (suite *CreateServiceSuite) TestErrorInputAliasEmpty() {
 func (suite *CreateServiceSuite) TestErrorInputAliasInvalidChars() {
 	originalURL := "https://www.foo.com"
 	alias := "`"
-	_, err := suite.service.CreateShortCode(suite.ctx, originalURL, &alias)
+	_, err := suite.service.CreateShortCode(suite.ctx, originalURL, CreateOptions{Alias: &alias})
 	suite.NotNil(err)
 	suite.ErrorIs(err, apperrors.ErrInvalidAlias)
 }
@@ -82,7 +97,7 @@ func (suite *CreateServiceSuite) TestErrorInputAliasAlreadyUsedForDifferentURL()
 
 	originalURL := "https://www.foo.com"
 	alias := "bar"
-	_, err := suite.service.CreateShortCode(suite.ctx, originalURL, &alias)
+	_, err := suite.service.CreateShortCode(suite.ctx, originalURL, CreateOptions{Alias: &alias})
 	suite.NotNil(err)
 	suite.ErrorIs(err, apperrors.ErrAliasAlreadyInUse)
 }
@@ -98,7 +113,7 @@ func (suite *CreateServiceSuite) TestSuccessConfigAPIHostnameMissing() {
 	suite.MockCreateSuccess().Times(1)
 
 	originalURL := "https://www.foo.com"
-	shortCode, err := service.CreateShortCode(suite.ctx, originalURL, nil)
+	shortCode, err := service.CreateShortCode(suite.ctx, originalURL, CreateOptions{})
 	suite.NoError(err)
 	suite.NotNil(shortCode)
 	suite.NotEmpty(*shortCode)
@@ -109,7 +124,7 @@ func (suite *CreateServiceSuite) TestErrorMaxRetries() {
 	suite.MockCreateFail().AnyTimes()
 
 	originalURL := "https://www.foo.com"
-	_, err := suite.service.CreateShortCode(suite.ctx, originalURL, nil)
+	_, err := suite.service.CreateShortCode(suite.ctx, originalURL, CreateOptions{})
 	suite.NotNil(err)
 	suite.ErrorIs(err, apperrors.ErrMaxRetriesExceeded)
 }
@@ -126,7 +141,7 @@ func (suite *CreateServiceSuite) TestConfigMaxTries() {
 	suite.MockCreateSuccess().Times(0)
 
 	originalURL := "https://www.foo.com"
-	_, err := service.CreateShortCode(suite.ctx, originalURL, nil)
+	_, err := service.CreateShortCode(suite.ctx, originalURL, CreateOptions{})
 
 	suite.Error(err)
 	suite.ErrorIs(err, apperrors.ErrMaxRetriesExceeded)
@@ -140,7 +155,7 @@ func (suite *CreateServiceSuite) TestConfigShortCodeLength() {
 	suite.MockCreateSuccess().Times(1)
 
 	originalURL := "https://www.foo.com"
-	shortCode, err := service.CreateShortCode(suite.ctx, originalURL, nil)
+	shortCode, err := service.CreateShortCode(suite.ctx, originalURL, CreateOptions{})
 	suite.Nil(err)
 	suite.NotNil(shortCode)
 	suite.Len(*shortCode, customShortCodeLength)
@@ -150,7 +165,7 @@ func (suite *CreateServiceSuite) TestSuccess() {
 	suite.MockCreateSuccess().Times(1)
 
 	originalURL := "https://www.foo.com"
-	shortCode, err := suite.service.CreateShortCode(suite.ctx, originalURL, nil)
+	shortCode, err := suite.service.CreateShortCode(suite.ctx, originalURL, CreateOptions{})
 	suite.Nil(err)
 	suite.NotNil(shortCode)
 
@@ -159,6 +174,948 @@ func (suite *CreateServiceSuite) TestSuccess() {
 	suite.NotEmpty(*shortCode)
 }
 
+func (suite *CreateServiceSuite) TestErrorExpiresAtInPast() {
+	originalURL := "https://www.foo.com"
+	expiresAt := time.Now().Add(-time.Hour)
+	_, err := suite.service.CreateShortCode(suite.ctx, originalURL, CreateOptions{ExpiresAt: &expiresAt})
+	suite.ErrorIs(err, apperrors.ErrExpirationInPast)
+}
+
+func (suite *CreateServiceSuite) TestErrorExpiresAtBelowMinimum() {
+	cfg := config.GetTestConfig(config.Config{ShortCodeTTLMin: time.Hour})
+	service := NewService(suite.dao, &cfg)
+
+	originalURL := "https://www.foo.com"
+	expiresAt := time.Now().Add(time.Minute)
+	_, err := service.CreateShortCode(suite.ctx, originalURL, CreateOptions{ExpiresAt: &expiresAt})
+	suite.ErrorIs(err, apperrors.ErrExpirationOutOfRange)
+}
+
+func (suite *CreateServiceSuite) TestErrorTTLAboveMaximum() {
+	cfg := config.GetTestConfig(config.Config{ShortCodeTTLMax: time.Hour})
+	service := NewService(suite.dao, &cfg)
+
+	originalURL := "https://www.foo.com"
+	ttl := 2 * time.Hour
+	_, err := service.CreateShortCode(suite.ctx, originalURL, CreateOptions{TTL: &ttl})
+	suite.ErrorIs(err, apperrors.ErrExpirationOutOfRange)
+}
+
+func (suite *CreateServiceSuite) TestErrorTTLNotPositive() {
+	originalURL := "https://www.foo.com"
+	ttl := time.Duration(0)
+	_, err := suite.service.CreateShortCode(suite.ctx, originalURL, CreateOptions{TTL: &ttl})
+	suite.ErrorIs(err, apperrors.ErrInvalidExpiration)
+}
+
+func (suite *CreateServiceSuite) TestErrorExpiresAtAndTTLBothProvided() {
+	originalURL := "https://www.foo.com"
+	expiresAt := time.Now().Add(time.Hour)
+	ttl := time.Hour
+	_, err := suite.service.CreateShortCode(suite.ctx, originalURL, CreateOptions{ExpiresAt: &expiresAt, TTL: &ttl})
+	suite.ErrorIs(err, apperrors.ErrInvalidExpiration)
+}
+
+func (suite *CreateServiceSuite) TestSuccessExpiresAt() {
+	expiresAt := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	suite.urlRecordDAO.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, urlRecord model.URLRecord) (*model.URLRecordEntity, error) {
+			suite.True(urlRecord.ExpiresAt.Equal(expiresAt))
+			return &model.URLRecordEntity{URLRecord: urlRecord}, nil
+		})
+
+	originalURL := "https://www.foo.com"
+	shortCode, err := suite.service.CreateShortCode(suite.ctx, originalURL, CreateOptions{ExpiresAt: &expiresAt})
+	suite.NoError(err)
+	suite.NotNil(shortCode)
+}
+
+func (suite *CreateServiceSuite) TestSuccessTTL() {
+	ttl := 2 * time.Hour
+	before := time.Now()
+	suite.urlRecordDAO.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, urlRecord model.URLRecord) (*model.URLRecordEntity, error) {
+			suite.WithinRange(urlRecord.ExpiresAt, before.Add(ttl), time.Now().Add(ttl))
+			return &model.URLRecordEntity{URLRecord: urlRecord}, nil
+		})
+
+	originalURL := "https://www.foo.com"
+	shortCode, err := suite.service.CreateShortCode(suite.ctx, originalURL, CreateOptions{TTL: &ttl})
+	suite.NoError(err)
+	suite.NotNil(shortCode)
+}
+
+func (suite *CreateServiceSuite) TestBatchErrorEmpty() {
+	_, err := suite.service.CreateShortCodes(suite.ctx, []BatchItem{})
+	suite.ErrorIs(err, apperrors.ErrInvalidBatchSize)
+}
+
+func (suite *CreateServiceSuite) TestBatchErrorTooLarge() {
+	cfg := config.GetTestConfig(config.Config{MaxBatchSize: 1})
+	service := NewService(suite.dao, &cfg)
+
+	items := []BatchItem{
+		{OriginalURL: "https://www.foo.com"},
+		{OriginalURL: "https://www.bar.com"},
+	}
+	_, err := service.CreateShortCodes(suite.ctx, items)
+	suite.ErrorIs(err, apperrors.ErrInvalidBatchSize)
+}
+
+func (suite *CreateServiceSuite) TestBatchPartialFailure() {
+	alias := "taken"
+	items := []BatchItem{
+		{OriginalURL: "https://www.foo.com"},
+		{OriginalURL: "www.`.com"},
+		{OriginalURL: "https://www.bar.com", Options: CreateOptions{Alias: &alias}},
+	}
+
+	// Mock: the invalid URL is never saved, and the alias is already taken.
+	suite.urlRecordDAO.
+		EXPECT().
+		CreateBatch(gomock.Any(), gomock.Len(2)).
+		DoAndReturn(func(_ context.Context, urlRecords []model.URLRecord) ([]*model.URLRecordEntity, error) {
+			suite.Equal(alias, urlRecords[1].ShortCode)
+			return []*model.URLRecordEntity{{URLRecord: urlRecords[0]}, nil}, nil
+		})
+
+	results, err := suite.service.CreateShortCodes(suite.ctx, items)
+	suite.NoError(err)
+	suite.Len(results, 3)
+
+	suite.NoError(results[0].Err)
+	suite.NotNil(results[0].ShortCode)
+	suite.ErrorIs(results[1].Err, apperrors.ErrInvalidURL)
+	suite.ErrorIs(results[2].Err, apperrors.ErrAliasAlreadyInUse)
+}
+
+func (suite *CreateServiceSuite) TestBatchRetriesCollisions() {
+	items := []BatchItem{
+		{OriginalURL: "https://www.foo.com"},
+		{OriginalURL: "https://www.bar.com"},
+	}
+
+	// Mock: the second item collides on the first attempt, then succeeds.
+	gomock.InOrder(
+		suite.urlRecordDAO.
+			EXPECT().
+			CreateBatch(gomock.Any(), gomock.Len(2)).
+			DoAndReturn(func(_ context.Context, urlRecords []model.URLRecord) ([]*model.URLRecordEntity, error) {
+				return []*model.URLRecordEntity{{URLRecord: urlRecords[0]}, nil}, nil
+			}),
+		suite.urlRecordDAO.
+			EXPECT().
+			CreateBatch(gomock.Any(), gomock.Len(1)).
+			DoAndReturn(func(_ context.Context, urlRecords []model.URLRecord) ([]*model.URLRecordEntity, error) {
+				suite.Equal("https://www.bar.com", urlRecords[0].OriginalURL)
+				return []*model.URLRecordEntity{{URLRecord: urlRecords[0]}}, nil
+			}),
+	)
+
+	results, err := suite.service.CreateShortCodes(suite.ctx, items)
+	suite.NoError(err)
+	for _, result := range results {
+		suite.NoError(result.Err)
+		suite.NotNil(result.ShortCode)
+	}
+}
+
+func (suite *CreateServiceSuite) TestBatchErrorMaxRetries() {
+	cfg := config.GetTestConfig(config.Config{MaxTriesCreateShortCode: 2})
+	service := NewService(suite.dao, &cfg)
+
+	// Mock: every attempt collides.
+	suite.urlRecordDAO.
+		EXPECT().
+		CreateBatch(gomock.Any(), gomock.Any()).
+		Times(2).
+		Return([]*model.URLRecordEntity{nil}, nil)
+
+	results, err := service.CreateShortCodes(suite.ctx, []BatchItem{{OriginalURL: "https://www.foo.com"}})
+	suite.NoError(err)
+	suite.ErrorIs(results[0].Err, apperrors.ErrMaxRetriesExceeded)
+}
+
+func (suite *CreateServiceSuite) TestBatchErrorDataStoreUnavailable() {
+	suite.urlRecordDAO.
+		EXPECT().
+		CreateBatch(gomock.Any(), gomock.Any()).
+		Return(nil, context.DeadlineExceeded)
+
+	results, err := suite.service.CreateShortCodes(suite.ctx, []BatchItem{{OriginalURL: "https://www.foo.com"}})
+	suite.NoError(err)
+	suite.ErrorIs(results[0].Err, apperrors.ErrDataStoreUnavailable)
+}
+
+func (suite *CreateServiceSuite) TestDeduplicateReusesExisting() {
+	cfg := config.GetTestConfig(config.Config{DeduplicateURLs: true})
+	service := NewService(suite.dao, &cfg)
+
+	// Mock: an active record already exists for the normalized URL.
+	suite.urlRecordDAO.
+		EXPECT().
+		GetByOriginalURL(gomock.Any(), "https://www.foo.com/Help", "", gomock.Nil()).
+		Return(&model.URLRecordEntity{URLRecord: model.URLRecord{ShortCode: "abc123"}}, nil)
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	shortCode, err := service.CreateShortCode(suite.ctx, "HTTPS://WWW.FOO.COM/Help", CreateOptions{})
+	suite.NoError(err)
+	suite.Equal("abc123", *shortCode)
+}
+
+func (suite *CreateServiceSuite) TestDeduplicateCreatesIfNoneExists() {
+	deduplicate := true
+	suite.urlRecordDAO.EXPECT().GetByOriginalURL(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+	suite.MockCreateSuccess().Times(1)
+
+	shortCode, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{Deduplicate: &deduplicate})
+	suite.NoError(err)
+	suite.NotNil(shortCode)
+}
+
+func (suite *CreateServiceSuite) TestDeduplicateRequestOverridesConfig() {
+	cfg := config.GetTestConfig(config.Config{DeduplicateURLs: true})
+	service := NewService(suite.dao, &cfg)
+
+	// No lookup happens when the request opts out.
+	deduplicate := false
+	suite.urlRecordDAO.EXPECT().GetByOriginalURL(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	suite.MockCreateSuccess().Times(1)
+
+	_, err := service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{Deduplicate: &deduplicate})
+	suite.NoError(err)
+}
+
+func (suite *CreateServiceSuite) TestDeduplicateSkippedForAlias() {
+	deduplicate := true
+	alias := "myalias"
+	suite.urlRecordDAO.EXPECT().GetByOriginalURL(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	suite.MockCreateSuccess().Times(1)
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{Alias: &alias, Deduplicate: &deduplicate})
+	suite.NoError(err)
+}
+
+func (suite *CreateServiceSuite) TestDeduplicateErrorDataStoreUnavailable() {
+	deduplicate := true
+	suite.urlRecordDAO.EXPECT().GetByOriginalURL(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, context.DeadlineExceeded)
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{Deduplicate: &deduplicate})
+	suite.ErrorIs(err, apperrors.ErrDataStoreUnavailable)
+}
+
+func (suite *CreateServiceSuite) TestDomainSuccess() {
+	domain := "Go.Example.com"
+	suite.domainDAO.
+		EXPECT().
+		GetByHostname(gomock.Any(), "go.example.com").
+		Return(&model.Domain{Hostname: "go.example.com"}, nil)
+	suite.urlRecordDAO.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, urlRecord model.URLRecord) (*model.URLRecordEntity, error) {
+			suite.Equal("go.example.com", urlRecord.Domain)
+			return &model.URLRecordEntity{URLRecord: urlRecord}, nil
+		})
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{Domain: &domain})
+	suite.NoError(err)
+}
+
+func (suite *CreateServiceSuite) TestDomainNotRegistered() {
+	domain := "go.example.com"
+	suite.domainDAO.EXPECT().GetByHostname(gomock.Any(), domain).Return(nil, nil)
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{Domain: &domain})
+	suite.ErrorIs(err, apperrors.ErrInvalidDomain)
+}
+
+func (suite *CreateServiceSuite) TestDomainOwnedByAnotherOwner() {
+	domain := "go.example.com"
+	otherOwnerID := "globex"
+	suite.domainDAO.
+		EXPECT().
+		GetByHostname(gomock.Any(), domain).
+		Return(&model.Domain{Hostname: domain, OwnerID: &otherOwnerID}, nil)
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	ctx := middleware.WithPrincipal(suite.ctx, &middleware.Principal{OwnerID: "acme"})
+	_, err := suite.service.CreateShortCode(ctx, "https://www.foo.com", CreateOptions{Domain: &domain})
+	suite.ErrorIs(err, apperrors.ErrInvalidDomain)
+}
+
+func (suite *CreateServiceSuite) TestDomainEmpty() {
+	domain := " "
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{Domain: &domain})
+	suite.ErrorIs(err, apperrors.ErrInvalidDomain)
+}
+
+func (suite *CreateServiceSuite) TestDomainErrorDataStoreUnavailable() {
+	domain := "go.example.com"
+	suite.domainDAO.EXPECT().GetByHostname(gomock.Any(), domain).Return(nil, context.DeadlineExceeded)
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{Domain: &domain})
+	suite.ErrorIs(err, apperrors.ErrDataStoreUnavailable)
+}
+
+func (suite *CreateServiceSuite) TestPasswordStoredAsHash() {
+	password := "correct horse"
+	suite.urlRecordDAO.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, urlRecord model.URLRecord) (*model.URLRecordEntity, error) {
+			suite.Require().NotNil(urlRecord.PasswordHash)
+			suite.NotEqual(password, *urlRecord.PasswordHash)
+			suite.NoError(bcrypt.CompareHashAndPassword([]byte(*urlRecord.PasswordHash), []byte(password)))
+			return &model.URLRecordEntity{URLRecord: urlRecord}, nil
+		})
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{Password: &password})
+	suite.NoError(err)
+}
+
+func (suite *CreateServiceSuite) TestPasswordSkipsDeduplicate() {
+	deduplicate := true
+	password := "correct horse"
+	suite.urlRecordDAO.EXPECT().GetByOriginalURL(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	suite.MockCreateSuccess().Times(1)
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{Deduplicate: &deduplicate, Password: &password})
+	suite.NoError(err)
+}
+
+func (suite *CreateServiceSuite) TestErrorPasswordEmpty() {
+	password := ""
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{Password: &password})
+	suite.ErrorIs(err, apperrors.ErrInvalidPassword)
+}
+
+func (suite *CreateServiceSuite) TestErrorPasswordTooLong() {
+	password := strings.Repeat("a", maxPasswordLength+1)
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{Password: &password})
+	suite.ErrorIs(err, apperrors.ErrInvalidPassword)
+}
+
+func (suite *CreateServiceSuite) TestMaxClicks() {
+	maxClicks := 3
+	suite.urlRecordDAO.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, urlRecord model.URLRecord) (*model.URLRecordEntity, error) {
+			suite.Require().NotNil(urlRecord.MaxClicks)
+			suite.Require().NotNil(urlRecord.RemainingClicks)
+			suite.Equal(3, *urlRecord.MaxClicks)
+			suite.Equal(3, *urlRecord.RemainingClicks)
+			return &model.URLRecordEntity{URLRecord: urlRecord}, nil
+		})
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{MaxClicks: &maxClicks})
+	suite.NoError(err)
+}
+
+func (suite *CreateServiceSuite) TestMaxClicksSkipsDeduplicate() {
+	deduplicate := true
+	maxClicks := 1
+	suite.urlRecordDAO.EXPECT().GetByOriginalURL(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	suite.MockCreateSuccess().Times(1)
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{Deduplicate: &deduplicate, MaxClicks: &maxClicks})
+	suite.NoError(err)
+}
+
+func (suite *CreateServiceSuite) TestErrorMaxClicksNotPositive() {
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	for _, maxClicks := range []int{0, -1} {
+		_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{MaxClicks: &maxClicks})
+		suite.ErrorIs(err, apperrors.ErrInvalidMaxClicks)
+	}
+}
+
+func (suite *CreateServiceSuite) TestActivatesAt() {
+	activatesAt := time.Now().Add(24 * time.Hour)
+	suite.urlRecordDAO.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, urlRecord model.URLRecord) (*model.URLRecordEntity, error) {
+			suite.Require().NotNil(urlRecord.ActivatesAt)
+			suite.True(urlRecord.ActivatesAt.Equal(activatesAt))
+			return &model.URLRecordEntity{URLRecord: urlRecord}, nil
+		})
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{ActivatesAt: &activatesAt})
+	suite.NoError(err)
+}
+
+func (suite *CreateServiceSuite) TestErrorActivatesAtNotBeforeExpiration() {
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	for _, activatesAt := range []time.Time{expiresAt, expiresAt.Add(time.Hour)} {
+		_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{ExpiresAt: &expiresAt, ActivatesAt: &activatesAt})
+		suite.ErrorIs(err, apperrors.ErrInvalidActivation)
+	}
+}
+
+func (suite *CreateServiceSuite) TestExpiredRedirectURL() {
+	expiredRedirectURL := "HTTPS://WWW.Foo.com/expired"
+	suite.urlRecordDAO.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, urlRecord model.URLRecord) (*model.URLRecordEntity, error) {
+			suite.Require().NotNil(urlRecord.ExpiredRedirectURL)
+			suite.Equal("https://www.foo.com/expired", *urlRecord.ExpiredRedirectURL)
+			return &model.URLRecordEntity{URLRecord: urlRecord}, nil
+		})
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{ExpiredRedirectURL: &expiredRedirectURL})
+	suite.NoError(err)
+}
+
+func (suite *CreateServiceSuite) TestErrorExpiredRedirectURLInvalid() {
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	expiredRedirectURL := "://"
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{ExpiredRedirectURL: &expiredRedirectURL})
+	suite.ErrorIs(err, apperrors.ErrInvalidExpiredRedirectURL)
+}
+
+func (suite *CreateServiceSuite) TestRedirectType() {
+	redirectType := 308
+	suite.urlRecordDAO.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, urlRecord model.URLRecord) (*model.URLRecordEntity, error) {
+			suite.Require().NotNil(urlRecord.RedirectType)
+			suite.Equal(308, *urlRecord.RedirectType)
+			return &model.URLRecordEntity{URLRecord: urlRecord}, nil
+		})
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{RedirectType: &redirectType})
+	suite.NoError(err)
+}
+
+func (suite *CreateServiceSuite) TestErrorRedirectTypeUnsupported() {
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	for _, redirectType := range []int{0, 200, 303, 404} {
+		_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{RedirectType: &redirectType})
+		suite.ErrorIs(err, apperrors.ErrInvalidRedirectType, "redirectType %d", redirectType)
+	}
+}
+
+func (suite *CreateServiceSuite) TestErrorRedirectTypePermanentWithPassword() {
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	redirectType := 301
+	password := "secret"
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{RedirectType: &redirectType, Password: &password})
+	suite.ErrorIs(err, apperrors.ErrInvalidRedirectType)
+}
+
+func (suite *CreateServiceSuite) TestErrorRedirectTypePermanentWithMaxClicks() {
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	redirectType := 308
+	maxClicks := 5
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{RedirectType: &redirectType, MaxClicks: &maxClicks})
+	suite.ErrorIs(err, apperrors.ErrInvalidRedirectType)
+}
+
+func (suite *CreateServiceSuite) TestUncacheable() {
+	uncacheable := true
+	suite.urlRecordDAO.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, urlRecord model.URLRecord) (*model.URLRecordEntity, error) {
+			suite.True(urlRecord.Uncacheable)
+			return &model.URLRecordEntity{URLRecord: urlRecord}, nil
+		})
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{Uncacheable: &uncacheable})
+	suite.NoError(err)
+}
+
+func (suite *CreateServiceSuite) TestErrorRedirectTypePermanentUncacheable() {
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	redirectType := 301
+	uncacheable := true
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{RedirectType: &redirectType, Uncacheable: &uncacheable})
+	suite.ErrorIs(err, apperrors.ErrInvalidRedirectType)
+}
+
+func (suite *CreateServiceSuite) TestTemplateDefaults() {
+	suite.urlRecordDAO.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, urlRecord model.URLRecord) (*model.URLRecordEntity, error) {
+			suite.Equal("https://www.foo.com/?utm_campaign={utm_campaign}&ref={query.ref}", urlRecord.OriginalURL)
+			suite.Equal(model.TemplateValues{"utm_campaign": "spring"}, urlRecord.TemplateDefaults)
+			return &model.URLRecordEntity{URLRecord: urlRecord}, nil
+		})
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com/?utm_campaign={utm_campaign}&ref={query.ref}", CreateOptions{
+		TemplateDefaults: map[string]string{"utm_campaign": "spring"},
+	})
+	suite.NoError(err)
+}
+
+func (suite *CreateServiceSuite) TestErrorTemplateUnknownPlaceholder() {
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com/?id={user_id}", CreateOptions{})
+	suite.ErrorIs(err, apperrors.ErrInvalidTemplate)
+}
+
+func (suite *CreateServiceSuite) TestErrorTemplateUnclosedPlaceholder() {
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com/?c={utm_campaign", CreateOptions{})
+	suite.ErrorIs(err, apperrors.ErrInvalidTemplate)
+}
+
+func (suite *CreateServiceSuite) TestErrorTemplateDefaultForUnknownPlaceholder() {
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com/?c={utm_campaign}", CreateOptions{
+		TemplateDefaults: map[string]string{"campaign": "spring"},
+	})
+	suite.ErrorIs(err, apperrors.ErrInvalidTemplate)
+}
+
+func (suite *CreateServiceSuite) TestCountryURLs() {
+	suite.urlRecordDAO.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, urlRecord model.URLRecord) (*model.URLRecordEntity, error) {
+			suite.Equal(model.CountryURLs{"DE": "https://www.foo.de", "FR": "https://www.foo.fr/shop"}, urlRecord.CountryURLs)
+			return &model.URLRecordEntity{URLRecord: urlRecord}, nil
+		})
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{
+		CountryURLs: map[string]string{"de": "https://WWW.foo.de", "FR": "www.foo.fr/shop"},
+	})
+	suite.NoError(err)
+}
+
+func (suite *CreateServiceSuite) TestErrorCountryURLsInvalidCountryCode() {
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{
+		CountryURLs: map[string]string{"Germany": "https://www.foo.de"},
+	})
+	suite.ErrorIs(err, apperrors.ErrInvalidCountryURLs)
+}
+
+func (suite *CreateServiceSuite) TestErrorCountryURLsDuplicateCountryCode() {
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{
+		CountryURLs: map[string]string{"DE": "https://www.foo.de", "de": "https://www.bar.de"},
+	})
+	suite.ErrorIs(err, apperrors.ErrInvalidCountryURLs)
+}
+
+func (suite *CreateServiceSuite) TestErrorCountryURLsInvalidURL() {
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{
+		CountryURLs: map[string]string{"DE": "https://"},
+	})
+	suite.ErrorIs(err, apperrors.ErrInvalidCountryURLs)
+}
+
+func (suite *CreateServiceSuite) TestPlatformURLs() {
+	suite.urlRecordDAO.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, urlRecord model.URLRecord) (*model.URLRecordEntity, error) {
+			suite.Equal("itms-apps://apps.apple.com/app/id123", *urlRecord.IOSURL)
+			suite.Equal("intent://open/#Intent;scheme=foo;package=com.foo;end", *urlRecord.AndroidURL)
+			return &model.URLRecordEntity{URLRecord: urlRecord}, nil
+		})
+
+	iosURL := "itms-apps://apps.apple.com/app/id123"
+	androidURL := "intent://open/#Intent;scheme=foo;package=com.foo;end"
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{IOSURL: &iosURL, AndroidURL: &androidURL})
+	suite.NoError(err)
+}
+
+func (suite *CreateServiceSuite) TestErrorPlatformURLSchemeNotAllowed() {
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	// Each platform has its own allowed schemes.
+	iosURL := "market://details?id=com.foo"
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{IOSURL: &iosURL})
+	suite.ErrorIs(err, apperrors.ErrInvalidPlatformURL)
+
+	androidURL := "http://www.foo.com/app"
+	_, err = suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{AndroidURL: &androidURL})
+	suite.ErrorIs(err, apperrors.ErrInvalidPlatformURL)
+}
+
+func (suite *CreateServiceSuite) TestVariants() {
+	suite.urlRecordDAO.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, urlRecord model.URLRecord) (*model.URLRecordEntity, error) {
+			suite.Equal([]model.URLVariant{{URL: "https://www.foo.com/a", Weight: 3}, {URL: "https://www.foo.com/b", Weight: 1}}, urlRecord.Variants)
+			suite.True(urlRecord.StickyVariants)
+			return &model.URLRecordEntity{URLRecord: urlRecord}, nil
+		})
+
+	stickyVariants := true
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{
+		Variants:       []model.URLVariant{{URL: "https://WWW.foo.com/a", Weight: 3}, {URL: "www.foo.com/b", Weight: 1}},
+		StickyVariants: &stickyVariants,
+	})
+	suite.NoError(err)
+}
+
+func (suite *CreateServiceSuite) TestErrorVariantsInvalid() {
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	for _, tc := range []struct {
+		name     string
+		variants []model.URLVariant
+	}{
+		{"Single variant", []model.URLVariant{{URL: "https://www.foo.com/a", Weight: 1}}},
+		{"Too many variants", slices.Repeat([]model.URLVariant{{URL: "https://www.foo.com/a", Weight: 1}}, model.MaxURLVariants+1)},
+		{"Zero weight", []model.URLVariant{{URL: "https://www.foo.com/a", Weight: 1}, {URL: "https://www.foo.com/b", Weight: 0}}},
+		{"Weight too large", []model.URLVariant{{URL: "https://www.foo.com/a", Weight: 1}, {URL: "https://www.foo.com/b", Weight: maxVariantWeight + 1}}},
+		{"Invalid URL", []model.URLVariant{{URL: "https://www.foo.com/a", Weight: 1}, {URL: "https://", Weight: 1}}},
+	} {
+		suite.Run(tc.name, func() {
+			_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{Variants: tc.variants})
+			suite.ErrorIs(err, apperrors.ErrInvalidVariants)
+		})
+	}
+}
+
+func (suite *CreateServiceSuite) TestErrorStickyVariantsWithoutVariants() {
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	stickyVariants := true
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{StickyVariants: &stickyVariants})
+	suite.ErrorIs(err, apperrors.ErrInvalidVariants)
+}
+
+func (suite *CreateServiceSuite) TestErrorVariantsWithPermanentRedirectType() {
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	redirectType := 301
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{
+		Variants:     []model.URLVariant{{URL: "https://www.foo.com/a", Weight: 1}, {URL: "https://www.foo.com/b", Weight: 1}},
+		RedirectType: &redirectType,
+	})
+	suite.ErrorIs(err, apperrors.ErrInvalidRedirectType)
+}
+
+func (suite *CreateServiceSuite) TestRedirectRules() {
+	suite.urlRecordDAO.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, urlRecord model.URLRecord) (*model.URLRecordEntity, error) {
+			suite.Require().Len(urlRecord.RedirectRules, 2)
+			suite.Equal("https://www.foo.com/de", urlRecord.RedirectRules[0].URL)
+			suite.Equal("https://www.foo.com/night", urlRecord.RedirectRules[1].URL)
+
+			// The rules are compiled, ready to evaluate.
+			request := model.RedirectRequest{
+				Time:   time.Date(2030, time.January, 1, 23, 0, 0, 0, time.UTC),
+				Header: http.Header{"Accept-Language": {"de-DE,de;q=0.9"}},
+			}
+			suite.Equal(0, urlRecord.RedirectRules.Match(request))
+			return &model.URLRecordEntity{URLRecord: urlRecord}, nil
+		})
+
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{
+		RedirectRules: []model.RedirectRule{
+			{When: model.RedirectCondition{Languages: []string{"de"}}, URL: "https://WWW.foo.com/de"},
+			{When: model.RedirectCondition{TimeOfDay: &model.TimeOfDayWindow{Start: "22:00", End: "06:00"}}, URL: "www.foo.com/night"},
+		},
+	})
+	suite.NoError(err)
+}
+
+func (suite *CreateServiceSuite) TestErrorRedirectRulesInvalid() {
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	german := model.RedirectCondition{Languages: []string{"de"}}
+	for _, tc := range []struct {
+		name  string
+		rules []model.RedirectRule
+	}{
+		{"Too many rules", slices.Repeat([]model.RedirectRule{{When: german, URL: "https://www.foo.com/de"}}, model.MaxRedirectRules+1)},
+		{"Invalid URL", []model.RedirectRule{{When: german, URL: "https://"}}},
+		{"Empty condition", []model.RedirectRule{{URL: "https://www.foo.com/a"}}},
+		{"Time zone only", []model.RedirectRule{{When: model.RedirectCondition{TimeZone: "Europe/Berlin"}, URL: "https://www.foo.com/a"}}},
+		{"Unknown time zone", []model.RedirectRule{{When: model.RedirectCondition{TimeZone: "Mars/Olympus", DaysOfWeek: []string{"mon"}}, URL: "https://www.foo.com/a"}}},
+		{"Local time zone", []model.RedirectRule{{When: model.RedirectCondition{TimeZone: "Local", DaysOfWeek: []string{"mon"}}, URL: "https://www.foo.com/a"}}},
+		{"Malformed time of day", []model.RedirectRule{{When: model.RedirectCondition{TimeOfDay: &model.TimeOfDayWindow{Start: "9am", End: "17:00"}}, URL: "https://www.foo.com/a"}}},
+		{"Empty time of day", []model.RedirectRule{{When: model.RedirectCondition{TimeOfDay: &model.TimeOfDayWindow{Start: "09:00", End: "09:00"}}, URL: "https://www.foo.com/a"}}},
+		{"Unknown day of week", []model.RedirectRule{{When: model.RedirectCondition{DaysOfWeek: []string{"monday"}}, URL: "https://www.foo.com/a"}}},
+		{"Invalid language tag", []model.RedirectRule{{When: model.RedirectCondition{Languages: []string{"en_US"}}, URL: "https://www.foo.com/a"}}},
+		{"Invalid header name", []model.RedirectRule{{When: model.RedirectCondition{Headers: map[string]string{"X Campaign": "spring"}}, URL: "https://www.foo.com/a"}}},
+		{"Invalid header pattern", []model.RedirectRule{{When: model.RedirectCondition{Headers: map[string]string{"X-Campaign": "(spring"}}, URL: "https://www.foo.com/a"}}},
+		{"Header pattern too long", []model.RedirectRule{{When: model.RedirectCondition{Headers: map[string]string{"X-Campaign": strings.Repeat("a", 257)}}, URL: "https://www.foo.com/a"}}},
+		{"Invalid referrer host", []model.RedirectRule{{When: model.RedirectCondition{ReferrerHosts: []string{"https://example.com/"}}, URL: "https://www.foo.com/a"}}},
+	} {
+		suite.Run(tc.name, func() {
+			_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{RedirectRules: tc.rules})
+			suite.ErrorIs(err, apperrors.ErrInvalidRedirectRules)
+		})
+	}
+}
+
+func (suite *CreateServiceSuite) TestErrorRedirectRulesWithPermanentRedirectType() {
+	suite.urlRecordDAO.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	redirectType := 308
+	_, err := suite.service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{
+		RedirectRules: []model.RedirectRule{{When: model.RedirectCondition{Languages: []string{"de"}}, URL: "https://www.foo.com/de"}},
+		RedirectType:  &redirectType,
+	})
+	suite.ErrorIs(err, apperrors.ErrInvalidRedirectType)
+}
+
+func (suite *CreateServiceSuite) TestIdempotencyNewKey() {
+	suite.idempotencyKeyDAO.
+		EXPECT().
+		Reserve(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, idempotencyKey model.IdempotencyKey) (*model.IdempotencyKey, error) {
+			suite.Equal(scopeIdempotencyKey(suite.ctx, "key"), idempotencyKey.Key)
+			suite.Equal("fingerprint", idempotencyKey.Fingerprint)
+			suite.WithinDuration(time.Now().Add(24*time.Hour), idempotencyKey.ExpiresAt, time.Minute)
+			return nil, nil
+		})
+
+	stored, err := suite.service.BeginIdempotentRequest(suite.ctx, "key", "fingerprint")
+	suite.NoError(err)
+	suite.Nil(stored)
+}
+
+func (suite *CreateServiceSuite) TestIdempotencyReplay() {
+	existing := &model.IdempotencyKey{
+		Key:          "key",
+		Fingerprint:  "fingerprint",
+		StatusCode:   201,
+		ResponseBody: []byte(`{"shortUrl":"http://localhost:8080/abc123"}`),
+	}
+	suite.idempotencyKeyDAO.EXPECT().Reserve(gomock.Any(), gomock.Any()).Return(existing, nil)
+
+	stored, err := suite.service.BeginIdempotentRequest(suite.ctx, "key", "fingerprint")
+	suite.NoError(err)
+	suite.Equal(existing, stored)
+}
+
+func (suite *CreateServiceSuite) TestIdempotencyErrorMismatch() {
+	existing := &model.IdempotencyKey{Key: "key", Fingerprint: "other", StatusCode: 201}
+	suite.idempotencyKeyDAO.EXPECT().Reserve(gomock.Any(), gomock.Any()).Return(existing, nil)
+
+	_, err := suite.service.BeginIdempotentRequest(suite.ctx, "key", "fingerprint")
+	suite.ErrorIs(err, apperrors.ErrIdempotencyKeyMismatch)
+}
+
+func (suite *CreateServiceSuite) TestIdempotencyErrorInProgress() {
+	existing := &model.IdempotencyKey{Key: "key", Fingerprint: "fingerprint"}
+	suite.idempotencyKeyDAO.EXPECT().Reserve(gomock.Any(), gomock.Any()).Return(existing, nil)
+
+	_, err := suite.service.BeginIdempotentRequest(suite.ctx, "key", "fingerprint")
+	suite.ErrorIs(err, apperrors.ErrIdempotencyKeyInProgress)
+}
+
+func (suite *CreateServiceSuite) TestIdempotencyErrorKeyTooLong() {
+	key := strings.Repeat("a", maxIdempotencyKeyLength+1)
+	_, err := suite.service.BeginIdempotentRequest(suite.ctx, key, "fingerprint")
+	suite.ErrorIs(err, apperrors.ErrInvalidIdempotencyKey)
+}
+
+func (suite *CreateServiceSuite) TestIdempotencyCompleteAfterCancel() {
+	// The response must be stored even if the request has timed out.
+	ctx, cancel := context.WithCancel(suite.ctx)
+	cancel()
+
+	suite.idempotencyKeyDAO.
+		EXPECT().
+		Complete(gomock.Any(), scopeIdempotencyKey(suite.ctx, "key"), 201, []byte("body")).
+		DoAndReturn(func(ctx context.Context, _ string, _ int, _ []byte) error {
+			suite.NoError(ctx.Err())
+			return nil
+		})
+
+	suite.service.CompleteIdempotentRequest(ctx, "key", 201, []byte("body"))
+}
+
+func (suite *CreateServiceSuite) TestIdempotencyKeyScopedToOwner() {
+	acmeCtx := middleware.WithPrincipal(suite.ctx, &middleware.Principal{OwnerID: "acme"})
+	otherCtx := middleware.WithPrincipal(suite.ctx, &middleware.Principal{OwnerID: "other"})
+
+	suite.NotEqual(scopeIdempotencyKey(acmeCtx, "key"), scopeIdempotencyKey(otherCtx, "key"))
+	suite.NotEqual(scopeIdempotencyKey(acmeCtx, "key"), scopeIdempotencyKey(suite.ctx, "key"))
+	suite.Equal(scopeIdempotencyKey(acmeCtx, "key"), scopeIdempotencyKey(acmeCtx, "key"))
+}
+
+func (suite *CreateServiceSuite) TestOwnerFromPrincipal() {
+	ownerID := "acme"
+	suite.urlRecordDAO.EXPECT().ReserveActiveLinks(gomock.Any(), ownerID, 1, 0).Return(true, nil)
+	suite.urlRecordDAO.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, urlRecord model.URLRecord) (*model.URLRecordEntity, error) {
+			suite.Equal(&ownerID, urlRecord.OwnerID)
+			return &model.URLRecordEntity{URLRecord: urlRecord}, nil
+		})
+
+	ctx := middleware.WithPrincipal(suite.ctx, &middleware.Principal{OwnerID: ownerID})
+	_, err := suite.service.CreateShortCode(ctx, "https://www.foo.com", CreateOptions{})
+	suite.NoError(err)
+}
+
+func (suite *CreateServiceSuite) TestQuotaSkippedForAnonymous() {
+	cfg := config.GetTestConfig(config.Config{QuotaCreatesPerDay: 1, QuotaMaxActiveLinks: 1})
+	service := NewService(suite.dao, &cfg)
+	suite.MockCreateSuccess()
+
+	_, err := service.CreateShortCode(suite.ctx, "https://www.foo.com", CreateOptions{})
+	suite.NoError(err)
+}
+
+func (suite *CreateServiceSuite) TestQuotaSuccess() {
+	cfg := config.GetTestConfig(config.Config{QuotaCreatesPerDay: 10, QuotaMaxActiveLinks: 10})
+	service := NewService(suite.dao, &cfg)
+	ctx := middleware.WithPrincipal(suite.ctx, &middleware.Principal{OwnerID: "acme"})
+
+	suite.urlRecordDAO.EXPECT().ReserveActiveLinks(gomock.Any(), "acme", 1, 10).Return(true, nil)
+	suite.quotaDAO.EXPECT().IncrementDailyCreates(gomock.Any(), "acme", gomock.Any(), 1, 10).Return(true, nil)
+	suite.MockCreateSuccess()
+
+	_, err := service.CreateShortCode(ctx, "https://www.foo.com", CreateOptions{})
+	suite.NoError(err)
+}
+
+func (suite *CreateServiceSuite) TestQuotaErrorActiveLinks() {
+	cfg := config.GetTestConfig(config.Config{QuotaCreatesPerDay: 10, QuotaMaxActiveLinks: 10})
+	service := NewService(suite.dao, &cfg)
+	ctx := middleware.WithPrincipal(suite.ctx, &middleware.Principal{OwnerID: "acme"})
+
+	suite.urlRecordDAO.EXPECT().ReserveActiveLinks(gomock.Any(), "acme", 1, 10).Return(false, nil)
+
+	_, err := service.CreateShortCode(ctx, "https://www.foo.com", CreateOptions{})
+	suite.ErrorIs(err, apperrors.ErrActiveLinkQuotaExceeded)
+}
+
+func (suite *CreateServiceSuite) TestQuotaErrorDailyCreates() {
+	cfg := config.GetTestConfig(config.Config{QuotaCreatesPerDay: 10})
+	service := NewService(suite.dao, &cfg)
+	ctx := middleware.WithPrincipal(suite.ctx, &middleware.Principal{OwnerID: "acme"})
+
+	gomock.InOrder(
+		suite.urlRecordDAO.EXPECT().ReserveActiveLinks(gomock.Any(), "acme", 1, 0).Return(true, nil),
+		suite.quotaDAO.EXPECT().IncrementDailyCreates(gomock.Any(), "acme", gomock.Any(), 1, 10).Return(false, nil),
+		suite.urlRecordDAO.EXPECT().ReleaseActiveLinks(gomock.Any(), "acme", 1).Return(nil),
+	)
+
+	_, err := service.CreateShortCode(ctx, "https://www.foo.com", CreateOptions{})
+	suite.ErrorIs(err, apperrors.ErrDailyCreateQuotaExceeded)
+}
+
+func (suite *CreateServiceSuite) TestQuotaReleasedOnFailure() {
+	cfg := config.GetTestConfig(config.Config{QuotaCreatesPerDay: 10})
+	service := NewService(suite.dao, &cfg)
+	ctx := middleware.WithPrincipal(suite.ctx, &middleware.Principal{OwnerID: "acme"})
+	alias := "taken"
+
+	gomock.InOrder(
+		suite.urlRecordDAO.EXPECT().ReserveActiveLinks(gomock.Any(), "acme", 1, 0).Return(true, nil),
+		suite.quotaDAO.EXPECT().IncrementDailyCreates(gomock.Any(), "acme", gomock.Any(), 1, 10).Return(true, nil),
+		suite.MockCreateFail(),
+		suite.urlRecordDAO.EXPECT().ReleaseActiveLinks(gomock.Any(), "acme", 1).Return(nil),
+		suite.quotaDAO.EXPECT().DecrementDailyCreates(gomock.Any(), "acme", gomock.Any(), 1).Return(nil),
+	)
+
+	_, err := service.CreateShortCode(ctx, "https://www.foo.com", CreateOptions{Alias: &alias})
+	suite.ErrorIs(err, apperrors.ErrAliasAlreadyInUse)
+}
+
+func (suite *CreateServiceSuite) TestBatchQuotaReleasesFailedItems() {
+	cfg := config.GetTestConfig(config.Config{QuotaCreatesPerDay: 10})
+	service := NewService(suite.dao, &cfg)
+	ctx := middleware.WithPrincipal(suite.ctx, &middleware.Principal{OwnerID: "acme"})
+	alias := "taken"
+	items := []BatchItem{
+		{OriginalURL: "https://www.foo.com"},
+		{OriginalURL: "www.`.com"},
+		{OriginalURL: "https://www.bar.com", Options: CreateOptions{Alias: &alias}},
+	}
+
+	// Mock: only the two valid items are counted, and the taken alias is given
+	// back.
+	gomock.InOrder(
+		suite.urlRecordDAO.EXPECT().ReserveActiveLinks(gomock.Any(), "acme", 2, 0).Return(true, nil),
+		suite.quotaDAO.EXPECT().IncrementDailyCreates(gomock.Any(), "acme", gomock.Any(), 2, 10).Return(true, nil),
+		suite.urlRecordDAO.
+			EXPECT().
+			CreateBatch(gomock.Any(), gomock.Len(2)).
+			DoAndReturn(func(_ context.Context, urlRecords []model.URLRecord) ([]*model.URLRecordEntity, error) {
+				return []*model.URLRecordEntity{{URLRecord: urlRecords[0]}, nil}, nil
+			}),
+		suite.urlRecordDAO.EXPECT().ReleaseActiveLinks(gomock.Any(), "acme", 1).Return(nil),
+		suite.quotaDAO.EXPECT().DecrementDailyCreates(gomock.Any(), "acme", gomock.Any(), 1).Return(nil),
+	)
+
+	results, err := service.CreateShortCodes(ctx, items)
+	suite.NoError(err)
+	suite.NotNil(results[0].ShortCode)
+	suite.ErrorIs(results[1].Err, apperrors.ErrInvalidURL)
+	suite.ErrorIs(results[2].Err, apperrors.ErrAliasAlreadyInUse)
+}
+
+func (suite *CreateServiceSuite) TestBatchQuotaError() {
+	cfg := config.GetTestConfig(config.Config{QuotaCreatesPerDay: 1})
+	service := NewService(suite.dao, &cfg)
+	ctx := middleware.WithPrincipal(suite.ctx, &middleware.Principal{OwnerID: "acme"})
+	items := []BatchItem{
+		{OriginalURL: "https://www.foo.com"},
+		{OriginalURL: "https://www.bar.com"},
+	}
+
+	gomock.InOrder(
+		suite.urlRecordDAO.EXPECT().ReserveActiveLinks(gomock.Any(), "acme", 2, 0).Return(true, nil),
+		suite.quotaDAO.EXPECT().IncrementDailyCreates(gomock.Any(), "acme", gomock.Any(), 2, 1).Return(false, nil),
+		suite.urlRecordDAO.EXPECT().ReleaseActiveLinks(gomock.Any(), "acme", 2).Return(nil),
+	)
+
+	results, err := service.CreateShortCodes(ctx, items)
+	suite.NoError(err)
+	for _, result := range results {
+		suite.ErrorIs(result.Err, apperrors.ErrDailyCreateQuotaExceeded)
+	}
+}
+
+func (suite *CreateServiceSuite) TestFingerprintRequest() {
+	alias := "alias"
+	a, err := fingerprintRequest(CreateURLRequest{URL: "https://www.foo.com", Alias: &alias})
+	suite.NoError(err)
+	b, err := fingerprintRequest(CreateURLRequest{URL: "https://www.foo.com", Alias: &alias})
+	suite.NoError(err)
+	c, err := fingerprintRequest(CreateURLRequest{URL: "https://www.foo.com"})
+	suite.NoError(err)
+
+	suite.Equal(a, b)
+	suite.NotEqual(a, c)
+}
+
 func (suite *CreateServiceSuite) MockCreateFail() *gomock.Call {
 	return suite.urlRecordDAO.
 		EXPECT().
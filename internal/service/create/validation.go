@@ -5,9 +5,22 @@ import (
 	"net/url"
 	"slices"
 	"strings"
+	"time"
+	"tiny-bitly/internal/apperrors"
 	"tiny-bitly/internal/constants"
+	"tiny-bitly/internal/model"
 )
 
+// The URL schemes that visitors on each platform may be redirected to: HTTPS,
+// which the platform may open as a universal or app link, and the schemes of
+// its app store and app links.
+var iosURLSchemes = []string{"https", "itms-apps"}
+var androidURLSchemes = []string{"https", "intent", "market"}
+
+// The largest weight that a variant may have. Keeps the total weight of a
+// record's variants from overflowing.
+const maxVariantWeight = 10000
+
 // Returns true if the provided URL alias is a valid base62 string, or false
 // otherwise.
 func validateAlias(alias string, maxLength int) bool {
@@ -38,10 +51,10 @@ func validateAlias(alias string, maxLength int) bool {
 	return true
 }
 
-// Ensures that the provided URL has a valid URL structure per url.Parse. Adds a
-// protocol if one is missing, defaulting to HTTPS. Returns an error if the URL
-// is otherwise invalid.
-func validateURL(rawURL string) (*string, error) {
+// ValidateURL ensures that the provided URL has a valid URL structure per
+// url.Parse. Adds a protocol if one is missing, defaulting to HTTPS. Returns an
+// error if the URL is otherwise invalid.
+func ValidateURL(rawURL string) (*string, error) {
 	rawURLWithProtocol := ensureProtocol(rawURL)
 	parsedURL, err := url.Parse(rawURLWithProtocol)
 	if err != nil {
@@ -53,6 +66,209 @@ func validateURL(rawURL string) (*string, error) {
 	return &rawURLWithProtocol, nil
 }
 
+// NormalizeURL returns the canonical form of a validated URL, so that
+// equivalent URLs are stored identically. Lowercases the scheme and host and
+// drops a default port. Leaves the path, query and fragment untouched, since
+// they may be case-sensitive.
+func NormalizeURL(validatedURL string) string {
+	scheme, rest, ok := strings.Cut(validatedURL, "://")
+	if !ok {
+		return validatedURL
+	}
+	scheme = strings.ToLower(scheme)
+
+	// Split the authority ("[userinfo@]host[:port]") from the rest of the URL.
+	authorityEnd := strings.IndexAny(rest, "/?#")
+	if authorityEnd == -1 {
+		authorityEnd = len(rest)
+	}
+	authority, remainder := rest[:authorityEnd], rest[authorityEnd:]
+	userinfo, host := "", authority
+	if i := strings.LastIndex(authority, "@"); i != -1 {
+		userinfo, host = authority[:i+1], authority[i+1:]
+	}
+
+	host = strings.ToLower(host)
+	if (scheme == "http" && strings.HasSuffix(host, ":80")) || (scheme == "https" && strings.HasSuffix(host, ":443")) {
+		host = host[:strings.LastIndex(host, ":")]
+	}
+
+	return scheme + "://" + userinfo + host + remainder
+}
+
+// ValidateURLLength returns true if the provided URL does not exceed maxLength,
+// or false otherwise.
+func ValidateURLLength(rawURL string, maxLength int) bool {
+	return len(rawURL) <= maxLength
+}
+
+// ValidateTemplate ensures that every placeholder in the provided URL, e.g.
+// {utm_campaign} or {query.ref}, is closed and known, and that every default
+// is for a known placeholder. The URL must remain valid and within maxLength
+// once its placeholders are filled in with their defaults.
+func ValidateTemplate(rawURL string, defaults map[string]string, maxLength int) error {
+	names, ok := model.TemplatePlaceholders(rawURL)
+	if !ok {
+		return apperrors.ErrInvalidTemplate
+	}
+	for _, name := range names {
+		if !model.IsValidTemplatePlaceholder(name) {
+			return apperrors.ErrInvalidTemplate
+		}
+	}
+	for name := range defaults {
+		if !model.IsValidTemplatePlaceholder(name) {
+			return apperrors.ErrInvalidTemplate
+		}
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+	expandedURL := model.ExpandTemplate(rawURL, func(name string) string {
+		return defaults[name]
+	})
+	if _, err := ValidateURL(expandedURL); err != nil {
+		return apperrors.ErrInvalidTemplate
+	}
+	if !ValidateURLLength(expandedURL, maxLength) {
+		return apperrors.ErrURLLengthExceeded
+	}
+	return nil
+}
+
+// Validates the per-country URLs of a new short code like its original URL,
+// including their placeholders, and returns them normalized, with country codes
+// uppercased. Returns nil if there are none.
+func validateCountryURLs(countryURLs map[string]string, templateDefaults map[string]string, maxLength int) (model.CountryURLs, error) {
+	if len(countryURLs) == 0 {
+		return nil, nil
+	}
+
+	normalizedCountryURLs := make(model.CountryURLs, len(countryURLs))
+	for country, countryURL := range countryURLs {
+		country = strings.ToUpper(country)
+		if !model.IsValidCountryCode(country) {
+			return nil, apperrors.ErrInvalidCountryURLs
+		}
+		if _, ok := normalizedCountryURLs[country]; ok {
+			return nil, apperrors.ErrInvalidCountryURLs
+		}
+
+		validatedURL, err := ValidateURL(countryURL)
+		if err != nil || !ValidateURLLength(countryURL, maxLength) {
+			return nil, apperrors.ErrInvalidCountryURLs
+		}
+		if err := ValidateTemplate(countryURL, templateDefaults, maxLength); err != nil {
+			return nil, err
+		}
+		normalizedCountryURLs[country] = NormalizeURL(*validatedURL)
+	}
+	return normalizedCountryURLs, nil
+}
+
+// Validates a per-platform URL of a new short code like its original URL,
+// including its placeholders, and returns it normalized. Its scheme must be one
+// of the allowed schemes, unlike an original URL's. Returns nil if there is
+// none.
+func validatePlatformURL(platformURL *string, allowedSchemes []string, templateDefaults map[string]string, maxLength int) (*string, error) {
+	if platformURL == nil {
+		return nil, nil
+	}
+
+	validatedURL, err := ValidateURL(*platformURL)
+	if err != nil || !ValidateURLLength(*platformURL, maxLength) {
+		return nil, apperrors.ErrInvalidPlatformURL
+	}
+	normalizedURL := NormalizeURL(*validatedURL)
+	scheme, _, _ := strings.Cut(normalizedURL, "://")
+	if !slices.Contains(allowedSchemes, scheme) {
+		return nil, apperrors.ErrInvalidPlatformURL
+	}
+	if err := ValidateTemplate(normalizedURL, templateDefaults, maxLength); err != nil {
+		return nil, err
+	}
+	return &normalizedURL, nil
+}
+
+// Validates the variants of a new short code, including the placeholders of
+// their URLs, and returns them with their URLs normalized. A split test needs
+// at least two variants, each with a URL that is valid like an original URL and
+// a weight between 1 and maxVariantWeight. Returns nil if there are none.
+func validateVariants(variants []model.URLVariant, templateDefaults map[string]string, maxLength int) ([]model.URLVariant, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+	if len(variants) < 2 || len(variants) > model.MaxURLVariants {
+		return nil, apperrors.ErrInvalidVariants
+	}
+
+	normalizedVariants := make([]model.URLVariant, len(variants))
+	for i, variant := range variants {
+		if variant.Weight < 1 || variant.Weight > maxVariantWeight {
+			return nil, apperrors.ErrInvalidVariants
+		}
+		validatedURL, err := ValidateURL(variant.URL)
+		if err != nil || !ValidateURLLength(variant.URL, maxLength) {
+			return nil, apperrors.ErrInvalidVariants
+		}
+		normalizedURL := NormalizeURL(*validatedURL)
+		if err := ValidateTemplate(normalizedURL, templateDefaults, maxLength); err != nil {
+			return nil, err
+		}
+		normalizedVariants[i] = model.URLVariant{URL: normalizedURL, Weight: variant.Weight}
+	}
+	return normalizedVariants, nil
+}
+
+// Validates the redirect rules of a new short code, compiling their conditions,
+// and returns them with their URLs normalized. Each rule's URL must be valid
+// like an original URL, including its placeholders. Returns nil if there are
+// none.
+func validateRedirectRules(rules []model.RedirectRule, templateDefaults map[string]string, maxLength int) (model.RedirectRules, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	if len(rules) > model.MaxRedirectRules {
+		return nil, apperrors.ErrInvalidRedirectRules
+	}
+
+	compiledRules := make(model.RedirectRules, len(rules))
+	for i, rule := range rules {
+		validatedURL, err := ValidateURL(rule.URL)
+		if err != nil || !ValidateURLLength(rule.URL, maxLength) {
+			return nil, apperrors.ErrInvalidRedirectRules
+		}
+		rule.URL = NormalizeURL(*validatedURL)
+		if err := ValidateTemplate(rule.URL, templateDefaults, maxLength); err != nil {
+			return nil, err
+		}
+		if err := rule.Compile(); err != nil {
+			return nil, apperrors.ErrInvalidRedirectRules
+		}
+		compiledRules[i] = rule
+	}
+	return compiledRules, nil
+}
+
+// ValidateExpiresAt ensures that the provided expiration time is in the future
+// and that its distance from now lies within [minTTL, maxTTL].
+func ValidateExpiresAt(now time.Time, expiresAt time.Time, minTTL time.Duration, maxTTL time.Duration) error {
+	if !expiresAt.After(now) {
+		return apperrors.ErrExpirationInPast
+	}
+	if !validateTTL(expiresAt.Sub(now), minTTL, maxTTL) {
+		return apperrors.ErrExpirationOutOfRange
+	}
+	return nil
+}
+
+// Returns true if the provided TTL lies within [minTTL, maxTTL], or false
+// otherwise.
+func validateTTL(ttl time.Duration, minTTL time.Duration, maxTTL time.Duration) bool {
+	return ttl >= minTTL && ttl <= maxTTL
+}
+
 // Returns true if the provided character is a valid base62 character, or false
 // otherwise.
 func validateChar(char byte) bool {
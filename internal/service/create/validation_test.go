@@ -2,6 +2,7 @@ package create
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -41,6 +42,31 @@ func TestValidateAlias(t *testing.T) {
 	}
 }
 
+func TestValidateTTL(t *testing.T) {
+	type testCase struct {
+		description string
+		input       time.Duration
+		expected    bool
+	}
+
+	minTTL := time.Minute
+	maxTTL := time.Hour
+
+	testCases := []testCase{
+		{description: "InvalidBelowMin", input: time.Second, expected: false},
+		{description: "InvalidAboveMax", input: 2 * time.Hour, expected: false},
+		{description: "ValidMin", input: time.Minute, expected: true},
+		{description: "ValidMax", input: time.Hour, expected: true},
+		{description: "ValidBetween", input: 30 * time.Minute, expected: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(tt *testing.T) {
+			require.Equal(tt, validateTTL(testCase.input, minTTL, maxTTL), testCase.expected)
+		})
+	}
+}
+
 type ValidateURLSuite struct {
 	suite.Suite
 }
@@ -50,79 +76,105 @@ func TestValidateURLSuite(t *testing.T) {
 }
 
 func (suite *ValidateURLSuite) TestDetectsValid() {
-	validatedURL, err := validateURL("https://www.example.com")
+	validatedURL, err := ValidateURL("https://www.example.com")
 	suite.Nil(err)
 	suite.Equal(*validatedURL, "https://www.example.com")
 }
 
 func (suite *ValidateURLSuite) TestAddsHTTPSIfMissing() {
-	validatedURL, err := validateURL("www.example.com")
+	validatedURL, err := ValidateURL("www.example.com")
 	suite.Nil(err)
 	suite.Equal(*validatedURL, "https://www.example.com")
 }
 
 func (suite *ValidateURLSuite) TestKeepsSubdomain() {
-	validatedURL, err := validateURL("http://a.b.c.example.com")
+	validatedURL, err := ValidateURL("http://a.b.c.example.com")
 	suite.Nil(err)
 	suite.Equal(*validatedURL, "http://a.b.c.example.com")
 }
 
 func (suite *ValidateURLSuite) TestKeepsHTTP() {
-	validatedURL, err := validateURL("http://www.example.com")
+	validatedURL, err := ValidateURL("http://www.example.com")
 	suite.Nil(err)
 	suite.Equal(*validatedURL, "http://www.example.com")
 }
 
 func (suite *ValidateURLSuite) TestKeepsFTP() {
-	validatedURL, err := validateURL("ftp://www.example.com")
+	validatedURL, err := ValidateURL("ftp://www.example.com")
 	suite.Nil(err)
 	suite.Equal(*validatedURL, "ftp://www.example.com")
 }
 
 func (suite *ValidateURLSuite) TestKeepsTrailingSlash() {
-	validatedURL, err := validateURL("ftp://www.example.com/")
+	validatedURL, err := ValidateURL("ftp://www.example.com/")
 	suite.Nil(err)
 	suite.Equal(*validatedURL, "ftp://www.example.com/")
 }
 
 func (suite *ValidateURLSuite) TestKeepsPath() {
-	validatedURL, err := validateURL("https://www.example.com/a/b/c")
+	validatedURL, err := ValidateURL("https://www.example.com/a/b/c")
 	suite.Nil(err)
 	suite.Equal(*validatedURL, "https://www.example.com/a/b/c")
 }
 
 func (suite *ValidateURLSuite) TestKeepsParams() {
-	validatedURL, err := validateURL("https://www.example.com?a=1&b=2&c=3")
+	validatedURL, err := ValidateURL("https://www.example.com?a=1&b=2&c=3")
 	suite.Nil(err)
 	suite.Equal(*validatedURL, "https://www.example.com?a=1&b=2&c=3")
 }
 
 func (suite *ValidateURLSuite) TestKeepsAnchor() {
-	validatedURL, err := validateURL("https://www.example.com#a")
+	validatedURL, err := ValidateURL("https://www.example.com#a")
 	suite.Nil(err)
 	suite.Equal(*validatedURL, "https://www.example.com#a")
 }
 
 func (suite *ValidateURLSuite) TestKeepsAll() {
-	validatedURL, err := validateURL("https://a.b.c.example.com/a/b/c/?a=1&b=2&c=3#a")
+	validatedURL, err := ValidateURL("https://a.b.c.example.com/a/b/c/?a=1&b=2&c=3#a")
 	suite.Nil(err)
 	suite.Equal(*validatedURL, "https://a.b.c.example.com/a/b/c/?a=1&b=2&c=3#a")
 }
 
 func (suite *ValidateURLSuite) TestLocalhostWithPort() {
-	validatedURL, err := validateURL("http://localhost:3000/")
+	validatedURL, err := ValidateURL("http://localhost:3000/")
 	suite.Nil(err)
 	suite.Equal(*validatedURL, "http://localhost:3000/")
 }
 
 func (suite *ValidateURLSuite) TestInvalidEmpty() {
-	validatedURL, err := validateURL("")
+	validatedURL, err := ValidateURL("")
 	suite.ErrorContains(err, "invalid URL")
 	suite.Nil(validatedURL)
 }
 
 func (suite *ValidateURLSuite) TestInvalidProtocolOnly() {
-	validatedURL, err := validateURL("https://")
+	validatedURL, err := ValidateURL("https://")
 	suite.ErrorContains(err, "invalid URL")
 	suite.Nil(validatedURL)
 }
+
+func TestNormalizeURL(t *testing.T) {
+	type testCase struct {
+		description string
+		input       string
+		expected    string
+	}
+
+	testCases := []testCase{
+		{description: "Unchanged", input: "https://www.example.com/a?b=1#c", expected: "https://www.example.com/a?b=1#c"},
+		{description: "LowercasesScheme", input: "HTTPS://www.example.com", expected: "https://www.example.com"},
+		{description: "LowercasesHost", input: "https://WWW.Example.COM/Path", expected: "https://www.example.com/Path"},
+		{description: "KeepsPathCase", input: "https://www.example.com/A/B?C=D#E", expected: "https://www.example.com/A/B?C=D#E"},
+		{description: "DropsDefaultHTTPPort", input: "http://www.example.com:80/a", expected: "http://www.example.com/a"},
+		{description: "DropsDefaultHTTPSPort", input: "https://www.example.com:443", expected: "https://www.example.com"},
+		{description: "KeepsOtherPort", input: "https://www.example.com:8080/a", expected: "https://www.example.com:8080/a"},
+		{description: "KeepsPortOfOtherScheme", input: "http://www.example.com:443/a", expected: "http://www.example.com:443/a"},
+		{description: "KeepsUserinfo", input: "https://User@Example.com", expected: "https://User@example.com"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(tt *testing.T) {
+			require.Equal(tt, testCase.expected, NormalizeURL(testCase.input))
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"net/http"
+	"net/url"
+	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/model"
+)
+
+// DomainQueryParam is the query parameter that selects the domain of a short
+// code on the /urls/{shortCode} endpoints. Omitting it selects the default
+// domain.
+const DomainQueryParam = "domain"
+
+// ReadRequestDomain returns the normalized domain selected by the request's
+// query string, or "" for the default domain.
+func ReadRequestDomain(r *http.Request) string {
+	return model.NormalizeHostname(r.URL.Query().Get(DomainQueryParam))
+}
+
+// BuildShortURL returns the short URL for a short code on the provided domain.
+// Short codes on the default domain ("") use the public URL of the request,
+// which may differ from the URL to the K8s pod. Short codes on custom domains
+// always use HTTPS.
+func BuildShortURL(r *http.Request, fallbackBaseURL string, domain string, shortCode string) (string, error) {
+	baseURL := "https://" + domain
+	if domain == "" {
+		baseURL = middleware.PublicBaseURL(r, fallbackBaseURL)
+	}
+	return url.JoinPath(baseURL, shortCode)
+}
@@ -0,0 +1,10 @@
+package domain
+
+// The maximum permitted length of a hostname, per RFC 1035.
+const maxHostnameLength = 253
+
+// The maximum permitted length of an owner ID.
+const maxOwnerIDLength = 255
+
+// The maximum permitted length of each dot-separated label of a hostname.
+const maxLabelLength = 63
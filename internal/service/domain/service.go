@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/config"
+	"tiny-bitly/internal/dao"
+	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/model"
+)
+
+// Service handles custom domain management.
+type Service struct {
+	dao    dao.DAO
+	config *config.Config
+}
+
+// NewService creates a new domain service with the provided dependencies.
+func NewService(dao dao.DAO, config *config.Config) *Service {
+	return &Service{
+		dao:    dao,
+		config: config,
+	}
+}
+
+// AddDomain registers a custom domain that short codes can be created on. If
+// ownerID is nil, every caller may use the domain; otherwise only the owner
+// may.
+func (s *Service) AddDomain(ctx context.Context, hostname string, ownerID *string) (*model.Domain, error) {
+	hostname = model.NormalizeHostname(hostname)
+	if !validateHostname(hostname) {
+		return nil, apperrors.ErrInvalidDomain
+	}
+	if ownerID != nil && (*ownerID == "" || len(*ownerID) > maxOwnerIDLength) {
+		return nil, apperrors.ErrInvalidOwnerID
+	}
+
+	domain, err := s.dao.DomainDAO.Create(ctx, model.Domain{
+		Hostname: hostname,
+		OwnerID:  ownerID,
+	})
+	if errors.Is(err, apperrors.ErrDomainAlreadyExists) {
+		return nil, err
+	}
+	if err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to save domain", "hostname", hostname)
+		return nil, apperrors.ErrDataStoreUnavailable
+	}
+
+	middleware.LogWithRequestID(ctx, "Added domain", "hostname", hostname)
+	return domain, nil
+}
+
+// ListDomains returns all custom domains, ordered by hostname.
+func (s *Service) ListDomains(ctx context.Context) ([]model.Domain, error) {
+	domains, err := s.dao.DomainDAO.List(ctx)
+	if err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to list domains")
+		return nil, apperrors.ErrDataStoreUnavailable
+	}
+	return domains, nil
+}
+
+// RemoveDomain unregisters a custom domain. Short codes on the domain are kept,
+// but stop resolving once servers refresh their set of domains.
+func (s *Service) RemoveDomain(ctx context.Context, hostname string) error {
+	hostname = model.NormalizeHostname(hostname)
+	err := s.dao.DomainDAO.Delete(ctx, hostname)
+	if errors.Is(err, apperrors.ErrDomainNotFound) {
+		return err
+	}
+	if err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to remove domain", "hostname", hostname)
+		return apperrors.ErrDataStoreUnavailable
+	}
+
+	middleware.LogWithRequestID(ctx, "Removed domain", "hostname", hostname)
+	return nil
+}
+
+// Returns true if the normalized hostname is a plausible fully qualified
+// domain name: dot-separated labels of letters, digits, and hyphens.
+func validateHostname(hostname string) bool {
+	if hostname == "" || len(hostname) > maxHostnameLength {
+		return false
+	}
+	labels := strings.Split(hostname, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > maxLabelLength || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z') && !(c >= '0' && c <= '9') && c != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
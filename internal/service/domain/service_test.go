@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/config"
+	"tiny-bitly/internal/dao"
+	mock_daotypes "tiny-bitly/internal/dao/generated"
+	"tiny-bitly/internal/model"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type DomainServiceSuite struct {
+	suite.Suite
+	ctrl      *gomock.Controller
+	service   *Service
+	dao       dao.DAO
+	domainDAO *mock_daotypes.MockDomainDAO
+}
+
+func TestDomainServiceSuite(t *testing.T) {
+	suite.Run(t, new(DomainServiceSuite))
+}
+
+func (suite *DomainServiceSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.domainDAO = mock_daotypes.NewMockDomainDAO(suite.ctrl)
+	suite.dao = dao.DAO{
+		DomainDAO: suite.domainDAO,
+	}
+	cfg := config.GetTestConfig(config.Config{})
+	suite.service = NewService(suite.dao, &cfg)
+}
+
+func (suite *DomainServiceSuite) TestAddInvalidHostname() {
+	for _, hostname := range []string{
+		"",
+		"localhost",
+		"go..example.com",
+		"-go.example.com",
+		"go_links.example.com",
+		"https://go.example.com",
+		strings.Repeat("a", maxLabelLength+1) + ".com",
+	} {
+		domain, err := suite.service.AddDomain(context.Background(), hostname, nil)
+		suite.ErrorIs(err, apperrors.ErrInvalidDomain, hostname)
+		suite.Nil(domain)
+	}
+}
+
+func (suite *DomainServiceSuite) TestAddOwnerEmpty() {
+	ownerID := ""
+	domain, err := suite.service.AddDomain(context.Background(), "go.example.com", &ownerID)
+	suite.ErrorIs(err, apperrors.ErrInvalidOwnerID)
+	suite.Nil(domain)
+}
+
+func (suite *DomainServiceSuite) TestAddNormalizesHostname() {
+	ownerID := "acme"
+	suite.domainDAO.
+		EXPECT().
+		Create(gomock.Any(), model.Domain{Hostname: "go.example.com", OwnerID: &ownerID}).
+		DoAndReturn(func(_ context.Context, domain model.Domain) (*model.Domain, error) {
+			return &domain, nil
+		})
+
+	domain, err := suite.service.AddDomain(context.Background(), "Go.Example.com.", &ownerID)
+	suite.NoError(err)
+	suite.Equal("go.example.com", domain.Hostname)
+}
+
+func (suite *DomainServiceSuite) TestAddAlreadyExists() {
+	suite.domainDAO.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Return(nil, apperrors.ErrDomainAlreadyExists)
+
+	_, err := suite.service.AddDomain(context.Background(), "go.example.com", nil)
+	suite.ErrorIs(err, apperrors.ErrDomainAlreadyExists)
+}
+
+func (suite *DomainServiceSuite) TestAddError() {
+	suite.domainDAO.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("database error"))
+
+	_, err := suite.service.AddDomain(context.Background(), "go.example.com", nil)
+	suite.ErrorIs(err, apperrors.ErrDataStoreUnavailable)
+}
+
+func (suite *DomainServiceSuite) TestRemoveNotFound() {
+	suite.domainDAO.
+		EXPECT().
+		Delete(gomock.Any(), "go.example.com").
+		Return(apperrors.ErrDomainNotFound)
+
+	err := suite.service.RemoveDomain(context.Background(), "GO.example.com")
+	suite.ErrorIs(err, apperrors.ErrDomainNotFound)
+}
+
+func (suite *DomainServiceSuite) TestRemoveError() {
+	suite.domainDAO.
+		EXPECT().
+		Delete(gomock.Any(), "go.example.com").
+		Return(errors.New("database error"))
+
+	err := suite.service.RemoveDomain(context.Background(), "go.example.com")
+	suite.ErrorIs(err, apperrors.ErrDataStoreUnavailable)
+}
@@ -27,19 +27,21 @@ func HandleServiceError(ctx context.Context, w http.ResponseWriter, err error, m
 
 	w.Header().Set("Content-Type", "application/json")
 
-	found := false
+	entry := LookupErrorMapping(err, mappings)
+	writeResponse(w, entry.StatusCode, entry.UserMessage, requestID)
+}
+
+// LookupErrorMapping returns the mapping for the provided error, or a generic
+// 500 Internal Server Error mapping if none matches.
+func LookupErrorMapping(err error, mappings map[error]ErrorMapping) ErrorMapping {
 	for entryError, entry := range mappings {
 		if errors.Is(err, entryError) {
-			writeResponse(w, entry.StatusCode, entry.UserMessage, requestID)
-			found = true
+			return entry
 		}
 	}
-	if !found {
-		writeResponse(w,
-			http.StatusInternalServerError,
-			"An unexpected error occurred",
-			requestID,
-		)
+	return ErrorMapping{
+		StatusCode:  http.StatusInternalServerError,
+		UserMessage: "An unexpected error occurred",
 	}
 }
 
@@ -24,7 +24,7 @@ func (s *Service) CheckReady(ctx context.Context) bool {
 	// Using a non-existent short code to avoid side effects.
 	// GetByShortCode returns (nil, nil) for not found, or (entity, nil) for found,
 	// or (nil, error) for actual errors. We just need to verify the DAO responds.
-	_, err := s.dao.URLRecordDAO.GetByShortCode(ctx, "__health_check__")
+	_, err := s.dao.URLRecordDAO.GetByShortCode(ctx, "", "__health_check__")
 
 	// If there's an error, the DAO is not accessible (connection failure, etc.)
 	// If err is nil, the DAO responded successfully (even if record not found)
@@ -37,7 +37,7 @@ func (suite *HealthServiceSuite) TestCheckReadySuccess() {
 	// Mock: DAO responds successfully and record does not exist.
 	suite.urlRecordDAO.
 		EXPECT().
-		GetByShortCode(gomock.Any(), "__health_check__").
+		GetByShortCode(gomock.Any(), "", "__health_check__").
 		Return(nil, nil)
 
 	isHealthy := suite.service.CheckReady(context.Background())
@@ -48,7 +48,7 @@ func (suite *HealthServiceSuite) TestCheckReadyWithRecordFound() {
 	// Mock: DAO responds successfully and record exists.
 	suite.urlRecordDAO.
 		EXPECT().
-		GetByShortCode(gomock.Any(), "__health_check__").
+		GetByShortCode(gomock.Any(), "", "__health_check__").
 		Return(&model.URLRecordEntity{}, nil)
 
 	isHealthy := suite.service.CheckReady(context.Background())
@@ -59,7 +59,7 @@ func (suite *HealthServiceSuite) TestCheckReadyFailure() {
 	// Mock: DAO fails to respond.
 	suite.urlRecordDAO.
 		EXPECT().
-		GetByShortCode(gomock.Any(), "__health_check__").
+		GetByShortCode(gomock.Any(), "", "__health_check__").
 		Return(nil, errors.New("database connection failed"))
 
 	isHealthy := suite.service.CheckReady(context.Background())
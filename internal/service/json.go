@@ -0,0 +1,34 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"tiny-bitly/internal/middleware"
+)
+
+// ReadRequestJson reads a JSON object of type T from the provided HTTP request.
+// Returns an error if decoding fails.
+func ReadRequestJson[T any](r *http.Request) (*T, error) {
+	var request T
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		middleware.LogErrorWithRequestID(r.Context(), err, "Bad request")
+		return nil, err
+	}
+	return &request, nil
+}
+
+// WriteResponseJson writes a JSON object of type T to the provided HTTP response.
+// Returns an error if encoding fails.
+// Note: Content-Type header should be set before calling this function.
+func WriteResponseJson[T any](w http.ResponseWriter, body T) error {
+	// Send the JSON response - or an error.
+	err := json.NewEncoder(w).Encode(body)
+	if err != nil {
+		// Note: We don't have request context here, so use regular log
+		// In practice, this error is rare and would be caught by the handler
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,7 @@
+package list
+
+// The number of records returned per page if the client does not specify one.
+var defaultPageSize = 50
+
+// The maximum number of records a client may request per page.
+var maxPageSize = 100
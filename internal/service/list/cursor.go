@@ -0,0 +1,44 @@
+package list
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"tiny-bitly/internal/model"
+)
+
+// Encodes a cursor as an opaque, URL-safe string.
+func encodeCursor(cursor model.URLRecordCursor) string {
+	raw := strconv.FormatInt(cursor.CreatedAt.UnixNano(), 10) + ":" + strconv.FormatUint(uint64(cursor.ID), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Decodes a cursor produced by encodeCursor. Returns an error if the cursor is
+// malformed.
+func decodeCursor(encoded string) (*model.URLRecordCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAtStr, idStr, found := strings.Cut(string(raw), ":")
+	if !found {
+		return nil, errors.New("invalid cursor")
+	}
+
+	createdAtNanos, err := strconv.ParseInt(createdAtStr, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.ParseUint(idStr, 10, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.URLRecordCursor{
+		CreatedAt: time.Unix(0, createdAtNanos).UTC(),
+		ID:        uint(id),
+	}, nil
+}
@@ -0,0 +1,27 @@
+package list
+
+import (
+	"context"
+	"net/http"
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/service"
+)
+
+// Maps service errors to appropriate HTTP status codes and responses. Logs
+// detailed error information while returning user-friendly messages.
+func handleServiceError(ctx context.Context, w http.ResponseWriter, err error) {
+	service.HandleServiceError(ctx, w, err, map[error]service.ErrorMapping{
+		apperrors.ErrInvalidFilter: {
+			StatusCode:  http.StatusBadRequest,
+			UserMessage: "Invalid filter. Timestamps must be RFC 3339, status must be active or expired, and limit must be between 1 and 100",
+		},
+		apperrors.ErrInvalidCursor: {
+			StatusCode:  http.StatusBadRequest,
+			UserMessage: "Invalid cursor",
+		},
+		apperrors.ErrDataStoreUnavailable: {
+			StatusCode:  http.StatusServiceUnavailable,
+			UserMessage: "Service temporarily unavailable. Please try again later",
+		},
+	})
+}
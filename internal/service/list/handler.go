@@ -0,0 +1,111 @@
+package list
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/model"
+	"tiny-bitly/internal/service"
+	"tiny-bitly/internal/service/read"
+)
+
+type ListURLsResponse struct {
+	URLs       []read.URLMetadataResponse `json:"urls"`
+	NextCursor *string                    `json:"nextCursor"`
+}
+
+// NewGetURLsHandler creates an HTTP handler for GET /urls that uses the provided service.
+// Supports the query parameters createdAfter, createdBefore, expiringBefore
+// (RFC 3339), status (active|expired), host, limit, and cursor.
+// - 200 OK with a ListURLsResponse on success
+// - 400 Bad Request if a query parameter or the cursor is invalid
+// - 500 Internal Server Error for other errors
+// - 503 Service Unavailable if the data store is unavailable
+func NewGetURLsHandler(listService *Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		opts, err := parseListOptions(r.URL.Query())
+		if err != nil {
+			handleServiceError(r.Context(), w, err)
+			return
+		}
+
+		result, err := listService.ListURLRecords(r.Context(), *opts)
+		if err != nil {
+			handleServiceError(r.Context(), w, err)
+			return
+		}
+
+		// Build short URLs on each short code's domain.
+		response := ListURLsResponse{
+			URLs:       make([]read.URLMetadataResponse, 0, len(result.URLRecords)),
+			NextCursor: result.NextCursor,
+		}
+		for _, urlRecord := range result.URLRecords {
+			shortURL, err := service.BuildShortURL(r, listService.config.APIHostname, urlRecord.Domain, urlRecord.ShortCode)
+			if err != nil {
+				middleware.LogErrorWithRequestID(r.Context(), err, "Failed to build short URL")
+				handleServiceError(r.Context(), w, err)
+				return
+			}
+			response.URLs = append(response.URLs, read.NewURLMetadataResponse(&urlRecord, shortURL))
+		}
+
+		// Send the JSON response.
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = service.WriteResponseJson(w, response)
+		if err != nil {
+			handleServiceError(r.Context(), w, err)
+			return
+		}
+	}
+}
+
+// Parses listing options from the request's query parameters. Returns
+// apperrors.ErrInvalidFilter if a parameter is malformed.
+func parseListOptions(query url.Values) (*ListOptions, error) {
+	opts := &ListOptions{
+		Filter: model.URLRecordFilter{
+			Status: model.URLRecordStatus(query.Get("status")),
+			Host:   query.Get("host"),
+		},
+		Cursor: query.Get("cursor"),
+	}
+
+	var err error
+	if opts.Filter.CreatedAfter, err = parseTimeParam(query, "createdAfter"); err != nil {
+		return nil, err
+	}
+	if opts.Filter.CreatedBefore, err = parseTimeParam(query, "createdBefore"); err != nil {
+		return nil, err
+	}
+	if opts.Filter.ExpiringBefore, err = parseTimeParam(query, "expiringBefore"); err != nil {
+		return nil, err
+	}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return nil, apperrors.ErrInvalidFilter
+		}
+		opts.Limit = limit
+	}
+
+	return opts, nil
+}
+
+// Parses an optional RFC 3339 timestamp from the named query parameter.
+func parseTimeParam(query url.Values, name string) (*time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, apperrors.ErrInvalidFilter
+	}
+	return &parsed, nil
+}
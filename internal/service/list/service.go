@@ -0,0 +1,93 @@
+package list
+
+import (
+	"context"
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/config"
+	"tiny-bitly/internal/dao"
+	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/model"
+)
+
+// Service handles URL listing operations.
+type Service struct {
+	dao    dao.DAO
+	config *config.Config
+}
+
+// NewService creates a new list service with the provided dependencies.
+func NewService(dao dao.DAO, config *config.Config) *Service {
+	return &Service{
+		dao:    dao,
+		config: config,
+	}
+}
+
+// ListOptions holds the filters and pagination settings for a listing.
+type ListOptions struct {
+	Filter model.URLRecordFilter
+
+	// An opaque cursor returned by a previous call. If empty, the listing
+	// starts from the most recently created record.
+	Cursor string
+
+	// The maximum number of records to return. If zero, a default is used.
+	Limit int
+}
+
+// ListResult holds one page of URL records.
+type ListResult struct {
+	URLRecords []model.URLRecordEntity
+
+	// An opaque cursor for the next page, or nil if this is the last page.
+	NextCursor *string
+}
+
+// ListURLRecords returns one page of the caller's non-deleted URL records
+// matching the provided filter, most recently created first.
+func (s *Service) ListURLRecords(ctx context.Context, opts ListOptions) (*ListResult, error) {
+	limit := opts.Limit
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+	if limit < 0 || limit > maxPageSize {
+		return nil, apperrors.ErrInvalidFilter
+	}
+
+	switch opts.Filter.Status {
+	case model.URLRecordStatusAny, model.URLRecordStatusActive, model.URLRecordStatusExpired:
+	default:
+		return nil, apperrors.ErrInvalidFilter
+	}
+
+	var after *model.URLRecordCursor
+	if opts.Cursor != "" {
+		cursor, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, apperrors.ErrInvalidCursor
+		}
+		after = cursor
+	}
+
+	// Fetch one extra record to learn whether another page exists.
+	urlRecords, err := s.dao.URLRecordDAO.List(ctx, model.URLRecordListParams{
+		OwnerID: middleware.GetOwnerID(ctx),
+		Filter:  opts.Filter,
+		After:   after,
+		Limit:   limit + 1,
+	})
+	if err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to list URL records")
+		return nil, apperrors.ErrDataStoreUnavailable
+	}
+
+	result := &ListResult{URLRecords: urlRecords}
+	if len(urlRecords) > limit {
+		result.URLRecords = urlRecords[:limit]
+		last := result.URLRecords[limit-1]
+		nextCursor := encodeCursor(model.URLRecordCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		result.NextCursor = &nextCursor
+	}
+
+	return result, nil
+}
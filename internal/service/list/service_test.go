@@ -0,0 +1,142 @@
+package list
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/config"
+	"tiny-bitly/internal/dao"
+	mock_daotypes "tiny-bitly/internal/dao/generated"
+	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/model"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type ListServiceSuite struct {
+	suite.Suite
+	ctx          context.Context
+	ctrl         *gomock.Controller
+	service      *Service
+	dao          dao.DAO
+	urlRecordDAO *mock_daotypes.MockURLRecordDAO
+}
+
+func TestListServiceSuite(t *testing.T) {
+	suite.Run(t, new(ListServiceSuite))
+}
+
+func (suite *ListServiceSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.urlRecordDAO = mock_daotypes.NewMockURLRecordDAO(suite.ctrl)
+	suite.dao = dao.DAO{
+		URLRecordDAO: suite.urlRecordDAO,
+	}
+	cfg := config.GetTestConfig(config.Config{})
+	suite.service = NewService(suite.dao, &cfg)
+}
+
+func (suite *ListServiceSuite) TestErrorLimitTooLarge() {
+	_, err := suite.service.ListURLRecords(suite.ctx, ListOptions{Limit: maxPageSize + 1})
+	suite.ErrorIs(err, apperrors.ErrInvalidFilter)
+}
+
+func (suite *ListServiceSuite) TestErrorInvalidStatus() {
+	_, err := suite.service.ListURLRecords(suite.ctx, ListOptions{
+		Filter: model.URLRecordFilter{Status: "deleted"},
+	})
+	suite.ErrorIs(err, apperrors.ErrInvalidFilter)
+}
+
+func (suite *ListServiceSuite) TestErrorInvalidCursor() {
+	_, err := suite.service.ListURLRecords(suite.ctx, ListOptions{Cursor: "not a cursor"})
+	suite.ErrorIs(err, apperrors.ErrInvalidCursor)
+}
+
+func (suite *ListServiceSuite) TestListError() {
+	suite.urlRecordDAO.
+		EXPECT().
+		List(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("database error"))
+
+	_, err := suite.service.ListURLRecords(suite.ctx, ListOptions{})
+	suite.ErrorIs(err, apperrors.ErrDataStoreUnavailable)
+}
+
+func (suite *ListServiceSuite) TestScopedToOwner() {
+	ownerID := "acme"
+	suite.urlRecordDAO.
+		EXPECT().
+		List(gomock.Any(), model.URLRecordListParams{OwnerID: &ownerID, Limit: defaultPageSize + 1}).
+		Return(nil, nil)
+
+	ctx := middleware.WithPrincipal(suite.ctx, &middleware.Principal{OwnerID: ownerID})
+	_, err := suite.service.ListURLRecords(ctx, ListOptions{})
+	suite.NoError(err)
+}
+
+func (suite *ListServiceSuite) TestLastPageHasNoCursor() {
+	suite.urlRecordDAO.
+		EXPECT().
+		List(gomock.Any(), model.URLRecordListParams{Limit: defaultPageSize + 1}).
+		Return(suite.makeEntities(2), nil)
+
+	result, err := suite.service.ListURLRecords(suite.ctx, ListOptions{})
+	suite.NoError(err)
+	suite.Len(result.URLRecords, 2)
+	suite.Nil(result.NextCursor)
+}
+
+func (suite *ListServiceSuite) TestNextCursorResumesAfterLastRecord() {
+	entities := suite.makeEntities(3)
+	suite.urlRecordDAO.
+		EXPECT().
+		List(gomock.Any(), model.URLRecordListParams{Limit: 3}).
+		Return(entities, nil)
+
+	result, err := suite.service.ListURLRecords(suite.ctx, ListOptions{Limit: 2})
+	suite.NoError(err)
+	suite.Len(result.URLRecords, 2)
+	suite.Require().NotNil(result.NextCursor)
+
+	// The cursor must point at the last record returned.
+	suite.urlRecordDAO.
+		EXPECT().
+		List(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, params model.URLRecordListParams) ([]model.URLRecordEntity, error) {
+			suite.Require().NotNil(params.After)
+			suite.Equal(entities[1].ID, params.After.ID)
+			suite.True(entities[1].CreatedAt.Equal(params.After.CreatedAt))
+			return entities[2:], nil
+		})
+
+	result, err = suite.service.ListURLRecords(suite.ctx, ListOptions{Limit: 2, Cursor: *result.NextCursor})
+	suite.NoError(err)
+	suite.Len(result.URLRecords, 1)
+	suite.Nil(result.NextCursor)
+}
+
+// Returns n entities ordered by creation time descending.
+func (suite *ListServiceSuite) makeEntities(n int) []model.URLRecordEntity {
+	now := time.Now()
+	entities := make([]model.URLRecordEntity, n)
+	for i := range n {
+		entities[i] = model.URLRecordEntity{
+			Entity: model.Entity{ID: uint(n - i), CreatedAt: now.Add(-time.Duration(i) * time.Minute)},
+		}
+	}
+	return entities
+}
+
+func (suite *ListServiceSuite) TestCursorDecodesInUTC() {
+	createdAt := time.Date(2030, time.January, 1, 12, 0, 0, 5, time.FixedZone("UTC+2", 2*60*60))
+	cursor, err := decodeCursor(encodeCursor(model.URLRecordCursor{CreatedAt: createdAt, ID: 42}))
+	suite.Require().NoError(err)
+	suite.Equal(time.UTC, cursor.CreatedAt.Location())
+	suite.True(createdAt.Equal(cursor.CreatedAt))
+	suite.Equal(uint(42), cursor.ID)
+}
@@ -0,0 +1,34 @@
+package purge
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// purgeMetrics holds all Prometheus metrics of the purger.
+type purgeMetrics struct {
+	// RecordsPurged counts the records removed from url_records, by whether
+	// they were archived to url_record_history or deleted outright.
+	RecordsPurged *prometheus.CounterVec
+
+	// RunFailures counts the purge runs that stopped early on an error.
+	RunFailures prometheus.Counter
+}
+
+// metrics is the global instance of purge metrics.
+// Metrics are initialized at package load time using promauto for automatic registration.
+var metrics = &purgeMetrics{
+	RecordsPurged: promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "url_records_purged_total",
+			Help: "Total number of expired or deleted URL records purged, labeled by action (archived or deleted)",
+		},
+		[]string{"action"},
+	),
+	RunFailures: promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "url_record_purge_failures_total",
+			Help: "Total number of purge runs that failed",
+		},
+	),
+}
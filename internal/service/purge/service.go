@@ -0,0 +1,77 @@
+package purge
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"tiny-bitly/internal/config"
+	"tiny-bitly/internal/dao"
+)
+
+// Service removes expired and deleted URL records from the data store, so that
+// the table of URL records does not grow forever.
+type Service struct {
+	dao    dao.DAO
+	config *config.Config
+}
+
+// NewService creates a new purge service with the provided dependencies.
+func NewService(dao dao.DAO, config *config.Config) *Service {
+	return &Service{
+		dao:    dao,
+		config: config,
+	}
+}
+
+// PurgeExpired removes every URL record that expired, was deleted or ran out of
+// clicks more than the configured grace period ago, in batches of the configured size so that no
+// single statement locks many rows. Returns the number of records removed,
+// including those removed before an error.
+func (s *Service) PurgeExpired(ctx context.Context) (int, error) {
+	before := time.Now().Add(-s.config.PurgeGracePeriod)
+	action := "deleted"
+	if s.config.PurgeArchive {
+		action = "archived"
+	}
+
+	total := 0
+	for {
+		purged, err := s.dao.URLRecordDAO.PurgeExpired(ctx, before, s.config.PurgeBatchSize, s.config.PurgeArchive)
+		if err != nil {
+			metrics.RunFailures.Inc()
+			slog.Error("Failed to purge expired URL records", "error", err, "purged", total)
+			return total, err
+		}
+		total += len(purged)
+		metrics.RecordsPurged.WithLabelValues(action).Add(float64(len(purged)))
+
+		// A short batch means there is nothing left to purge.
+		if len(purged) < s.config.PurgeBatchSize {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+
+	slog.Info("Purged expired URL records", "purged", total, "action", action, "before", before)
+	return total, nil
+}
+
+// Run purges expired URL records at the configured interval until the context
+// is done. Errors are logged and retried at the next interval.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Errors are logged by PurgeExpired.
+			_, _ = s.PurgeExpired(ctx)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package purge
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"tiny-bitly/internal/config"
+	"tiny-bitly/internal/dao"
+	mock_daotypes "tiny-bitly/internal/dao/generated"
+	"tiny-bitly/internal/model"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+var batchSizeForTest int = 2
+var gracePeriodForTest time.Duration = 24 * time.Hour
+
+type PurgeServiceSuite struct {
+	suite.Suite
+	ctrl         *gomock.Controller
+	service      *Service
+	dao          dao.DAO
+	urlRecordDAO *mock_daotypes.MockURLRecordDAO
+}
+
+func TestPurgeServiceSuite(t *testing.T) {
+	suite.Run(t, new(PurgeServiceSuite))
+}
+
+func (suite *PurgeServiceSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.urlRecordDAO = mock_daotypes.NewMockURLRecordDAO(suite.ctrl)
+	suite.dao = dao.DAO{
+		URLRecordDAO: suite.urlRecordDAO,
+	}
+	cfg := config.GetTestConfig(config.Config{
+		PurgeBatchSize:   batchSizeForTest,
+		PurgeGracePeriod: gracePeriodForTest,
+	})
+	suite.service = NewService(suite.dao, &cfg)
+}
+
+func (suite *PurgeServiceSuite) TestPurgesInBatchesUntilShortBatch() {
+	archived := testutil.ToFloat64(metrics.RecordsPurged.WithLabelValues("archived"))
+	before := time.Now().Add(-gracePeriodForTest)
+
+	// Two full batches and then a short one.
+	gomock.InOrder(
+		suite.urlRecordDAO.
+			EXPECT().
+			PurgeExpired(gomock.Any(), gomock.Any(), batchSizeForTest, true).
+			DoAndReturn(func(_ context.Context, purgeBefore time.Time, _ int, _ bool) ([]model.URLRecordEntity, error) {
+				// Only records older than the grace period are purged.
+				suite.WithinDuration(before, purgeBefore, time.Second)
+				return make([]model.URLRecordEntity, 2), nil
+			}),
+		suite.urlRecordDAO.
+			EXPECT().
+			PurgeExpired(gomock.Any(), gomock.Any(), batchSizeForTest, true).
+			Return(make([]model.URLRecordEntity, 2), nil),
+		suite.urlRecordDAO.
+			EXPECT().
+			PurgeExpired(gomock.Any(), gomock.Any(), batchSizeForTest, true).
+			Return(make([]model.URLRecordEntity, 1), nil),
+	)
+
+	purged, err := suite.service.PurgeExpired(context.Background())
+	suite.NoError(err)
+	suite.Equal(5, purged)
+	suite.Equal(archived+5, testutil.ToFloat64(metrics.RecordsPurged.WithLabelValues("archived")))
+}
+
+func (suite *PurgeServiceSuite) TestNothingToPurge() {
+	suite.urlRecordDAO.
+		EXPECT().
+		PurgeExpired(gomock.Any(), gomock.Any(), batchSizeForTest, true).
+		Return([]model.URLRecordEntity{}, nil)
+
+	purged, err := suite.service.PurgeExpired(context.Background())
+	suite.NoError(err)
+	suite.Equal(0, purged)
+}
+
+func (suite *PurgeServiceSuite) TestDeletesWithoutArchiving() {
+	suite.service.config.PurgeArchive = false
+	suite.urlRecordDAO.
+		EXPECT().
+		PurgeExpired(gomock.Any(), gomock.Any(), batchSizeForTest, false).
+		Return(make([]model.URLRecordEntity, 1), nil)
+
+	purged, err := suite.service.PurgeExpired(context.Background())
+	suite.NoError(err)
+	suite.Equal(1, purged)
+}
+
+func (suite *PurgeServiceSuite) TestStopsOnError() {
+	failures := testutil.ToFloat64(metrics.RunFailures)
+	gomock.InOrder(
+		suite.urlRecordDAO.
+			EXPECT().
+			PurgeExpired(gomock.Any(), gomock.Any(), batchSizeForTest, true).
+			Return(make([]model.URLRecordEntity, 2), nil),
+		suite.urlRecordDAO.
+			EXPECT().
+			PurgeExpired(gomock.Any(), gomock.Any(), batchSizeForTest, true).
+			Return(nil, errors.New("database error")),
+	)
+
+	purged, err := suite.service.PurgeExpired(context.Background())
+	suite.Error(err)
+	suite.Equal(2, purged)
+	suite.Equal(failures+1, testutil.ToFloat64(metrics.RunFailures))
+}
@@ -0,0 +1,78 @@
+package read
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"tiny-bitly/internal/model"
+)
+
+// permanentRedirectMaxAge is how long browsers may cache a permanent redirect
+// of a short URL that does not expire sooner.
+const permanentRedirectMaxAge = 365 * 24 * time.Hour
+
+// Sets the caching headers of a redirect to the provided record. Cacheable
+// redirects may be cached by browsers and CDNs for up to maxAgeCeiling (or
+// permanentRedirectMaxAge in browsers, for permanent redirects), but never past
+// the record's expiration. Redirects that vary by visitor may only be cached by
+// browsers, and redirects that vary by platform are cached per User-Agent.
+// Returns true if the request's If-None-Match already names the redirect, in
+// which case the caller should respond with 304 Not Modified instead.
+func writeRedirectCacheHeaders(w http.ResponseWriter, r *http.Request, urlRecord *model.URLRecordEntity, redirectStatus int, maxAgeCeiling time.Duration, now time.Time) bool {
+	if !urlRecord.IsCacheable() {
+		w.Header().Set("Cache-Control", "private, no-store")
+		return false
+	}
+
+	untilExpiration := urlRecord.ExpiresAt.Sub(now)
+	sharedMaxAge := max(min(maxAgeCeiling, untilExpiration), 0).Truncate(time.Second)
+	maxAge := sharedMaxAge
+	if model.IsPermanentRedirectType(redirectStatus) {
+		// Browsers follow a cached permanent redirect without asking us
+		// again, so it can never be retargeted anyway. Let them cache it for
+		// long.
+		maxAge = max(min(permanentRedirectMaxAge, untilExpiration), 0).Truncate(time.Second)
+	}
+
+	lastModified := urlRecord.UpdatedAt
+	if lastModified.IsZero() {
+		lastModified = urlRecord.CreatedAt
+	}
+	etag := redirectETag(urlRecord, redirectStatus)
+
+	if urlRecord.VariesByVisitor() {
+		w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds())))
+	} else {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d, s-maxage=%d", int(maxAge.Seconds()), int(sharedMaxAge.Seconds())))
+	}
+	w.Header().Set("Expires", now.Add(maxAge).UTC().Format(http.TimeFormat))
+	w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	w.Header().Set("ETag", etag)
+	if urlRecord.IsPlatformTargeted() {
+		w.Header().Set("Vary", "Accept-Encoding, User-Agent")
+	} else {
+		w.Header().Set("Vary", "Accept-Encoding")
+	}
+
+	return matchesETag(r.Header.Get("If-None-Match"), etag)
+}
+
+// Returns an entity tag that changes whenever a redirect to the record would,
+// i.e. when the record is replaced or updated, or its redirect status changes.
+func redirectETag(urlRecord *model.URLRecordEntity, redirectStatus int) string {
+	return fmt.Sprintf(`"%d-%d-%d"`, urlRecord.ID, urlRecord.UpdatedAt.UnixNano(), redirectStatus)
+}
+
+// Returns true if an If-None-Match header value names the entity tag, using
+// the weak comparison that RFC 9110 prescribes for If-None-Match.
+func matchesETag(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,18 @@
+package read
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchesETag(t *testing.T) {
+	etag := `"1-2-302"`
+
+	assert.True(t, matchesETag(`"1-2-302"`, etag))
+	assert.True(t, matchesETag(`W/"1-2-302"`, etag))
+	assert.True(t, matchesETag(`"other", "1-2-302"`, etag))
+	assert.True(t, matchesETag(`*`, etag))
+	assert.False(t, matchesETag(``, etag))
+	assert.False(t, matchesETag(`"1-2-301"`, etag))
+}
@@ -0,0 +1,82 @@
+package read
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"tiny-bitly/internal/dao"
+	"tiny-bitly/internal/model"
+)
+
+// domainRefreshInterval is how long the set of custom domains is cached before
+// being reloaded from the data store.
+const domainRefreshInterval = 1 * time.Minute
+
+// domainRegistry caches the set of registered custom domains, so that
+// resolving the domain of a redirect does not cost a query per request.
+type domainRegistry struct {
+	dao dao.DomainDAO
+
+	mu          sync.RWMutex
+	hostnames   map[string]struct{}
+	refreshedAt time.Time
+}
+
+func newDomainRegistry(domainDAO dao.DomainDAO) *domainRegistry {
+	return &domainRegistry{dao: domainDAO}
+}
+
+// Resolve returns the domain namespace for a request host: the host itself if
+// it is a registered custom domain, or "" for the default domain.
+func (r *domainRegistry) Resolve(ctx context.Context, host string) (string, error) {
+	hostname := model.NormalizeHostname(host)
+	if hostname == "" {
+		return "", nil
+	}
+
+	hostnames, err := r.load(ctx)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := hostnames[hostname]; ok {
+		return hostname, nil
+	}
+	return "", nil
+}
+
+// Returns the cached set of hostnames, reloading it if it is stale. Keeps
+// serving a stale set if reloading fails, and fails only if the set was never
+// loaded.
+func (r *domainRegistry) load(ctx context.Context) (map[string]struct{}, error) {
+	r.mu.RLock()
+	hostnames, refreshedAt := r.hostnames, r.refreshedAt
+	r.mu.RUnlock()
+	if hostnames != nil && time.Since(refreshedAt) < domainRefreshInterval {
+		return hostnames, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Another request may have refreshed the set while we waited for the lock.
+	if r.hostnames != nil && time.Since(r.refreshedAt) < domainRefreshInterval {
+		return r.hostnames, nil
+	}
+
+	domains, err := r.dao.List(ctx)
+	if err != nil {
+		if r.hostnames != nil {
+			return r.hostnames, nil
+		}
+		return nil, err
+	}
+
+	hostnames = make(map[string]struct{}, len(domains))
+	for _, domain := range domains {
+		hostnames[domain.Hostname] = struct{}{}
+	}
+	r.hostnames = hostnames
+	r.refreshedAt = time.Now()
+	return hostnames, nil
+}
@@ -11,10 +11,34 @@ import (
 // detailed error information while returning user-friendly messages.
 func handleServiceError(ctx context.Context, w http.ResponseWriter, err error) {
 	service.HandleServiceError(ctx, w, err, map[error]service.ErrorMapping{
+		apperrors.ErrInvalidURL: {
+			StatusCode:  http.StatusBadRequest,
+			UserMessage: "Request does not produce a valid destination URL",
+		},
+		apperrors.ErrURLLengthExceeded: {
+			StatusCode:  http.StatusBadRequest,
+			UserMessage: "Request produces a destination URL that exceeds maximum length",
+		},
+		apperrors.ErrTooManyPasswordAttempts: {
+			StatusCode:  http.StatusTooManyRequests,
+			UserMessage: "Too many incorrect password attempts. Please try again later",
+		},
 		apperrors.ErrDataStoreUnavailable: {
 			StatusCode:  http.StatusServiceUnavailable,
 			UserMessage: "Service temporarily unavailable. Please try again later",
 		},
+		apperrors.ErrShortCodeDeleted: {
+			StatusCode:  http.StatusGone,
+			UserMessage: "Short code has been deleted",
+		},
+		apperrors.ErrShortCodeExpired: {
+			StatusCode:  http.StatusGone,
+			UserMessage: "Short code has expired",
+		},
+		apperrors.ErrShortCodeNotYetActive: {
+			StatusCode:  http.StatusNotFound,
+			UserMessage: "Short code is not active yet",
+		},
 		apperrors.ErrShortCodeNotFound: {
 			StatusCode:  http.StatusNotFound,
 			UserMessage: "Short code does not exist",
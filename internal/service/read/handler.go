@@ -1,14 +1,149 @@
 package read
 
 import (
+	"errors"
 	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+	"tiny-bitly/internal/apperrors"
 	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/model"
+	"tiny-bitly/internal/service"
 )
 
-// NewGetURLHandler creates an HTTP handler for GET /{shortCode} that uses the provided service.
-// - 302 Temporary Redirect if an original URL is found
+type URLMetadataResponse struct {
+	OriginalURL string    `json:"originalUrl"`
+	ShortCode   string    `json:"shortCode"`
+	Domain      string    `json:"domain,omitempty"`
+	ShortURL    string    `json:"shortUrl"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+
+	// Whether visitors must enter a password before being redirected.
+	PasswordProtected bool `json:"passwordProtected,omitempty"`
+
+	// The click limit and the number of clicks left, for click-limited short
+	// URLs. The number of clicks left may lag behind briefly.
+	MaxClicks       *int `json:"maxClicks,omitempty"`
+	RemainingClicks *int `json:"remainingClicks,omitempty"`
+
+	// The time before which the short URL does not redirect, for scheduled
+	// short URLs.
+	ActivatesAt *time.Time `json:"activatesAt,omitempty"`
+
+	// The URL that visitors are redirected to once the short URL has expired.
+	ExpiredRedirectURL *string `json:"expiredRedirectUrl,omitempty"`
+
+	// The HTTP status code that the short URL redirects with, if it was chosen
+	// at creation rather than left to the server's default.
+	RedirectType *int `json:"redirectType,omitempty"`
+
+	// Whether browsers and CDNs must not cache redirects of the short URL.
+	Uncacheable bool `json:"uncacheable,omitempty"`
+
+	// How the query parameters of a visit are merged into the original URL,
+	// if they are passed through.
+	QueryPassthrough *string `json:"queryPassthrough,omitempty"`
+
+	// Whether the path after the short code in a visit is appended to the
+	// original URL.
+	PathPassthrough bool `json:"pathPassthrough,omitempty"`
+
+	// The values that placeholders in the original URL default to.
+	TemplateDefaults map[string]string `json:"templateDefaults,omitempty"`
+
+	// The URLs that visitors are redirected to instead of the original URL, by
+	// the country they visit from.
+	CountryURLs map[string]string `json:"countryUrls,omitempty"`
+
+	// The URLs that visitors on iOS and Android devices are redirected to
+	// instead of the original URL.
+	IOSURL     *string `json:"iosUrl,omitempty"`
+	AndroidURL *string `json:"androidUrl,omitempty"`
+
+	// The URLs that visitors are split across instead of the original URL,
+	// with their weights, and whether returning visitors keep their variant.
+	Variants       []model.URLVariant `json:"variants,omitempty"`
+	StickyVariants bool               `json:"stickyVariants,omitempty"`
+
+	// The rules that send visitors whose requests meet their conditions to
+	// other URLs, in the order that they are evaluated.
+	RedirectRules []model.RedirectRule `json:"redirectRules,omitempty"`
+}
+
+// NewURLMetadataResponse returns the details of a URL record, with the short
+// URL built for its short code on its domain.
+func NewURLMetadataResponse(urlRecord *model.URLRecordEntity, shortURL string) URLMetadataResponse {
+	return URLMetadataResponse{
+		OriginalURL:        urlRecord.OriginalURL,
+		ShortCode:          urlRecord.ShortCode,
+		Domain:             urlRecord.Domain,
+		ShortURL:           shortURL,
+		CreatedAt:          urlRecord.CreatedAt,
+		UpdatedAt:          urlRecord.UpdatedAt,
+		ExpiresAt:          urlRecord.ExpiresAt,
+		PasswordProtected:  urlRecord.IsPasswordProtected(),
+		MaxClicks:          urlRecord.MaxClicks,
+		RemainingClicks:    urlRecord.RemainingClicks,
+		ActivatesAt:        urlRecord.ActivatesAt,
+		ExpiredRedirectURL: urlRecord.ExpiredRedirectURL,
+		RedirectType:       urlRecord.RedirectType,
+		Uncacheable:        urlRecord.Uncacheable,
+		QueryPassthrough:   urlRecord.QueryPassthrough,
+		PathPassthrough:    urlRecord.PathPassthrough,
+		TemplateDefaults:   urlRecord.TemplateDefaults,
+		CountryURLs:        urlRecord.CountryURLs,
+		IOSURL:             urlRecord.IOSURL,
+		AndroidURL:         urlRecord.AndroidURL,
+		Variants:           urlRecord.Variants,
+		StickyVariants:     urlRecord.StickyVariants,
+		RedirectRules:      urlRecord.RedirectRules,
+	}
+}
+
+// RedirectRulesDryRunRequest describes a sample request for a short URL to
+// evaluate its redirect rules against.
+type RedirectRulesDryRunRequest struct {
+	// The time at which the sample request is made. If not provided, the
+	// current time.
+	Time *time.Time `json:"time"`
+
+	// The headers of the sample request by name, e.g.
+	// {"Accept-Language": "de-DE,de;q=0.9", "Referer": "https://news.example.com/"}.
+	Headers map[string]string `json:"headers"`
+}
+
+type RedirectRulesDryRunResponse struct {
+	// The index of the rule that the sample request matches first, or nil if
+	// it matches none and would be redirected as usual.
+	MatchedRule *int `json:"matchedRule"`
+
+	// The URL that the matched rule redirects to, before placeholders and
+	// passthrough are applied, or nil if no rule matches.
+	URL *string `json:"url"`
+
+	// Why the sample request matches each rule or not, in order.
+	Rules []RuleExplanation `json:"rules"`
+}
+
+// NewGetURLHandler creates an HTTP handler for GET /{shortCode} and GET /{shortCode}/{path...} that uses
+// the provided service. Resolves the short code on the custom domain that the request was sent to, if any.
+// Passes the request's path suffix and query parameters through to the original URL if the short code does.
+// Redirects visitors per the first of the short code's redirect rules that their request matches, if any.
+// Splits visitors of split-tested short codes across their variants, setting a cookie that keeps returning
+// visitors on their variant if the short code's variants are sticky.
+// - 301, 302, 307 or 308 redirect, per the short code's redirect type, if an original URL is found
+// - 304 Not Modified if the request's If-None-Match names the current redirect
+// - 302 Temporary Redirect to the configured placeholder URL if the short code is not active yet
+// - 302 Temporary Redirect to the short code's fallback URL if it has expired or has no clicks left
 // - 400 Bad Request if the short code is empty
-// - 404 Not Found if an original URL is not found (or if the short URL is expired)
+// - 400 Bad Request if the request turns the original URL into an invalid or overlong destination
+// - 401 Unauthorized with a password form if the short code is password protected and not unlocked
+// - 404 Not Found if an original URL is not found (or if the short URL is not active yet without a placeholder URL,
+// or the request has a path suffix that the short URL does not pass through)
+// - 410 Gone if the short URL has expired or has no clicks left, without a fallback URL
 // - 500 Internal Server Error for other errors
 // - 503 Service Unavailable if the data store is unavailable
 func NewGetURLHandler(service *Service) http.HandlerFunc {
@@ -25,15 +160,23 @@ func NewGetURLHandler(service *Service) http.HandlerFunc {
 		// Log the inbound request.
 		middleware.LogDebugWithRequestID(r.Context(), "Resolving short URL with code", "shortCode", shortCode)
 
-		// Get the original URL for this short code.
-		originalURL, err := service.GetOriginalURL(r.Context(), shortCode)
+		// Get the URL record for this short code.
+		urlRecord, err := service.ResolveShortCode(r.Context(), middleware.PublicHost(r, service.config.TrustedProxies), shortCode)
+		if errors.Is(err, apperrors.ErrShortCodeNotYetActive) {
+			writeNotYetActive(w, r, service.config.ScheduledLinkPlaceholderURL)
+			return
+		}
+		if errors.Is(err, apperrors.ErrShortCodeExpired) {
+			writeExpired(w, r, urlRecord)
+			return
+		}
 		if err != nil {
 			handleServiceError(r.Context(), w, err)
 			return
 		}
 
 		// Return 404 if original URL not found.
-		if originalURL == nil {
+		if urlRecord == nil {
 			middleware.LogDebugWithRequestID(r.Context(), "No URL found for short code", "shortCode", shortCode)
 			// Cache 404s for a short time to reduce load on invalid codes.
 			w.Header().Set("Cache-Control", "public, max-age=60")
@@ -41,12 +184,303 @@ func NewGetURLHandler(service *Service) http.HandlerFunc {
 			return
 		}
 
-		// Set cache headers for CDN caching (302 redirects are cacheable).
-		// Cache for 24 hours - short codes rarely change, and expired codes are filtered by DB query.
-		w.Header().Set("Cache-Control", "public, max-age=86400, s-maxage=86400")
-		w.Header().Set("Vary", "Accept-Encoding")
+		// Only short codes that pass the path through have sub-paths.
+		visit := newVisit(r, service.config.TrustedProxies)
+		if visit.PathSuffix != "" && !urlRecord.PathPassthrough {
+			handleServiceError(r.Context(), w, apperrors.ErrShortCodeNotFound)
+			return
+		}
+
+		// Let browsers and CDNs cache the redirect until the short code
+		// expires, unless it must not be served without reaching us, e.g. the
+		// password form. Cached copies may be revalidated by ETag.
+		redirectStatus := urlRecord.RedirectStatus(service.config.DefaultRedirectType)
+		if writeRedirectCacheHeaders(w, r, urlRecord, redirectStatus, service.config.RedirectCacheMaxAge, time.Now()) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		// Ask for the password unless this visitor has already entered it.
+		if urlRecord.IsPasswordProtected() && !service.isUnlocked(urlRecord, readUnlockCookie(r), time.Now()) {
+			writePasswordForm(r.Context(), w, http.StatusUnauthorized, "")
+			return
+		}
+
+		// Build the destination before counting the click, so that a visit
+		// that cannot be redirected does not use one up.
+		destinationURL, variant, err := service.BuildDestinationURL(r.Context(), urlRecord, visit)
+		if err != nil {
+			handleServiceError(r.Context(), w, err)
+			return
+		}
+
+		// Count the click, if the short code is click-limited.
+		if err := service.ConsumeClick(r.Context(), urlRecord); errors.Is(err, apperrors.ErrShortCodeExpired) {
+			writeExpired(w, r, urlRecord)
+			return
+		} else if err != nil {
+			handleServiceError(r.Context(), w, err)
+			return
+		}
+
+		// Redirect with the short code's redirect type, recording the visitor's
+		// variant, if any.
+		if variant >= 0 {
+			recordVariantRedirect(r.Context(), urlRecord, variant)
+			if urlRecord.StickyVariants {
+				http.SetCookie(w, service.newVariantCookie(r, urlRecord, variant))
+			}
+		}
+		http.Redirect(w, r, destinationURL, redirectStatus)
+	}
+}
+
+// Returns the details of a request for a short code that the URL it redirects
+// to may depend on. Proxy headers name the client only if the request comes
+// from one of the trusted proxies.
+func newVisit(r *http.Request, trustedProxies []netip.Prefix) Visit {
+	// Take the escaped path after the short code, e.g. "extra/path" for
+	// /abc123/extra/path.
+	_, pathSuffix, _ := strings.Cut(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+
+	return Visit{
+		PathSuffix:    pathSuffix,
+		RawQuery:      r.URL.RawQuery,
+		ClientIP:      middleware.ClientIP(r, trustedProxies),
+		UserAgent:     r.UserAgent(),
+		Header:        r.Header,
+		Time:          time.Now(),
+		VariantCookie: readVariantCookie(r),
+	}
+}
+
+// Responds to a request for a short code that is not active yet with a redirect
+// to the provided placeholder URL, or with 404 if it is empty. Never reveals the
+// original URL.
+func writeNotYetActive(w http.ResponseWriter, r *http.Request, placeholderURL string) {
+	// Never let shared caches keep serving the placeholder after activation.
+	w.Header().Set("Cache-Control", "private, no-store")
+
+	if placeholderURL == "" {
+		handleServiceError(r.Context(), w, apperrors.ErrShortCodeNotYetActive)
+		return
+	}
+	http.Redirect(w, r, placeholderURL, http.StatusFound)
+}
+
+// Responds to a request for an expired short code with a redirect to its
+// fallback URL, or with 410 if it has none.
+func writeExpired(w http.ResponseWriter, r *http.Request, urlRecord *model.URLRecordEntity) {
+	// Cache for a short time, like 404s, in case the short code is reclaimed.
+	w.Header().Set("Cache-Control", "public, max-age=60")
+
+	if urlRecord.ExpiredRedirectURL == nil {
+		handleServiceError(r.Context(), w, apperrors.ErrShortCodeExpired)
+		return
+	}
+	http.Redirect(w, r, *urlRecord.ExpiredRedirectURL, http.StatusFound)
+}
+
+// NewPostURLPasswordHandler creates an HTTP handler for POST /{shortCode} and POST /{shortCode}/{path...}
+// that uses the provided service.
+// Accepts the `password` form field for a password-protected short code. On success, sets a signed,
+// short-lived cookie that unlocks the short code and redirects to the original URL.
+// - 303 See Other to the original URL if the password is correct, or if the short code is not password protected
+// - 302 Temporary Redirect to the short code's fallback URL if it has expired or has no clicks left
+// - 400 Bad Request if the request turns the original URL into an invalid or overlong destination
+// - 401 Unauthorized with the password form if the password is incorrect
+// - 404 Not Found if an original URL is not found (or if the short URL is not active yet, or the request has
+// a path suffix that the short URL does not pass through)
+// - 410 Gone if the short URL has expired or has no clicks left, without a fallback URL
+// - 429 Too Many Requests with the password form if there have been too many failed attempts
+// - 500 Internal Server Error for other errors
+// - 503 Service Unavailable if the data store is unavailable
+func NewPostURLPasswordHandler(readService *Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Parse `shortCode` out of the URL.
+		shortCode := r.PathValue("shortCode")
+
+		// Log the inbound request.
+		middleware.LogDebugWithRequestID(r.Context(), "Unlocking short URL with code", "shortCode", shortCode)
+
+		urlRecord, err := readService.ResolveShortCode(r.Context(), middleware.PublicHost(r, readService.config.TrustedProxies), shortCode)
+		if errors.Is(err, apperrors.ErrShortCodeExpired) {
+			writeExpired(w, r, urlRecord)
+			return
+		}
+		if err != nil {
+			handleServiceError(r.Context(), w, err)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "private, no-store")
 
-		// 302 Temporary Redirect to the original URL.
-		http.Redirect(w, r, *originalURL, http.StatusFound)
+		// Only short codes that pass the path through have sub-paths.
+		visit := newVisit(r, readService.config.TrustedProxies)
+		if visit.PathSuffix != "" && !urlRecord.PathPassthrough {
+			handleServiceError(r.Context(), w, apperrors.ErrShortCodeNotFound)
+			return
+		}
+
+		err = readService.UnlockShortCode(r.Context(), urlRecord, r.PostFormValue(passwordFormField), middleware.ClientIP(r, readService.config.TrustedProxies))
+		switch {
+		case errors.Is(err, apperrors.ErrIncorrectPassword):
+			writePasswordForm(r.Context(), w, http.StatusUnauthorized, "Incorrect password. Please try again.")
+			return
+		case errors.Is(err, apperrors.ErrTooManyPasswordAttempts):
+			writePasswordForm(r.Context(), w, http.StatusTooManyRequests, "Too many incorrect attempts. Please try again later.")
+			return
+		case err != nil:
+			handleServiceError(r.Context(), w, err)
+			return
+		}
+
+		// Build the destination before counting the click, so that a visit
+		// that cannot be redirected does not use one up.
+		destinationURL, variant, err := readService.BuildDestinationURL(r.Context(), urlRecord, visit)
+		if err != nil {
+			handleServiceError(r.Context(), w, err)
+			return
+		}
+
+		// Count the click, if the short code is click-limited.
+		if err := readService.ConsumeClick(r.Context(), urlRecord); errors.Is(err, apperrors.ErrShortCodeExpired) {
+			writeExpired(w, r, urlRecord)
+			return
+		} else if err != nil {
+			handleServiceError(r.Context(), w, err)
+			return
+		}
+
+		if variant >= 0 {
+			recordVariantRedirect(r.Context(), urlRecord, variant)
+			if urlRecord.StickyVariants {
+				http.SetCookie(w, readService.newVariantCookie(r, urlRecord, variant))
+			}
+		}
+
+		if urlRecord.IsPasswordProtected() {
+			http.SetCookie(w, &http.Cookie{
+				Name:     unlockCookieName,
+				Value:    readService.newUnlockToken(urlRecord, time.Now()),
+				Path:     "/" + urlRecord.ShortCode,
+				MaxAge:   int(readService.config.LinkPasswordCookieTTL.Seconds()),
+				HttpOnly: true,
+				Secure:   middleware.PublicProto(r) == "https",
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+
+		// 303 See Other so that the browser follows with a GET.
+		http.Redirect(w, r, destinationURL, http.StatusSeeOther)
+	}
+}
+
+// Returns the value of the request's unlock cookie, or "" if it has none.
+func readUnlockCookie(r *http.Request) string {
+	cookie, err := r.Cookie(unlockCookieName)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// NewGetURLMetadataHandler creates an HTTP handler for GET /urls/{shortCode} that uses the provided service.
+// Returns the details of a short URL without redirecting. Selects a short code on a custom domain
+// via the `domain` query parameter.
+// - 200 OK with a URLMetadataResponse if the short code is active
+// - 404 Not Found if the short code never existed
+// - 410 Gone if the short code has expired or been deleted
+// - 500 Internal Server Error for other errors
+// - 503 Service Unavailable if the data store is unavailable
+func NewGetURLMetadataHandler(readService *Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Parse `shortCode` out of the URL.
+		shortCode := r.PathValue("shortCode")
+		domain := service.ReadRequestDomain(r)
+
+		// Log the inbound request.
+		middleware.LogDebugWithRequestID(r.Context(), "Getting metadata for short code", "domain", domain, "shortCode", shortCode)
+
+		urlRecord, err := readService.GetURLMetadata(r.Context(), domain, shortCode)
+		if err != nil {
+			handleServiceError(r.Context(), w, err)
+			return
+		}
+
+		// Build the short URL on the short code's domain.
+		shortURL, err := service.BuildShortURL(r, readService.config.APIHostname, urlRecord.Domain, urlRecord.ShortCode)
+		if err != nil {
+			middleware.LogErrorWithRequestID(r.Context(), err, "Failed to build short URL")
+			handleServiceError(r.Context(), w, err)
+			return
+		}
+
+		// Send the JSON response.
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = service.WriteResponseJson(w, NewURLMetadataResponse(urlRecord, shortURL))
+		if err != nil {
+			handleServiceError(r.Context(), w, err)
+			return
+		}
+	}
+}
+
+// NewPostRedirectRulesDryRunHandler creates an HTTP handler for POST /urls/{shortCode}/rules/dry-run that uses
+// the provided service. Evaluates the short code's redirect rules against the sample request in the
+// RedirectRulesDryRunRequest body, without redirecting, and explains which rule it matches. Selects a short code
+// on a custom domain via the `domain` query parameter.
+// - 200 OK with a RedirectRulesDryRunResponse if the short code is active
+// - 400 Bad Request if the body is malformed
+// - 404 Not Found if the short code never existed
+// - 410 Gone if the short code has expired or been deleted
+// - 500 Internal Server Error for other errors
+// - 503 Service Unavailable if the data store is unavailable
+func NewPostRedirectRulesDryRunHandler(readService *Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Parse `shortCode` out of the URL.
+		shortCode := r.PathValue("shortCode")
+		domain := service.ReadRequestDomain(r)
+
+		// Attempt to read the JSON request body.
+		request, err := service.ReadRequestJson[RedirectRulesDryRunRequest](r)
+		if err != nil {
+			http.Error(w, "Malformatted request JSON", http.StatusBadRequest)
+			return
+		}
+
+		// Log the inbound request.
+		middleware.LogDebugWithRequestID(r.Context(), "Dry-running redirect rules for short code", "domain", domain, "shortCode", shortCode)
+
+		// Build the sample request.
+		sample := model.RedirectRequest{Time: time.Now(), Header: make(http.Header, len(request.Headers))}
+		if request.Time != nil {
+			sample.Time = *request.Time
+		}
+		for name, value := range request.Headers {
+			sample.Header.Set(name, value)
+		}
+
+		explanations, matchedRule, err := readService.ExplainRedirectRules(r.Context(), domain, shortCode, sample)
+		if err != nil {
+			handleServiceError(r.Context(), w, err)
+			return
+		}
+
+		response := RedirectRulesDryRunResponse{Rules: explanations}
+		if matchedRule >= 0 {
+			response.MatchedRule = &matchedRule
+			response.URL = &explanations[matchedRule].URL
+		}
+
+		// Send the JSON response.
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = service.WriteResponseJson(w, response)
+		if err != nil {
+			handleServiceError(r.Context(), w, err)
+			return
+		}
 	}
 }
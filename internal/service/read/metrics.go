@@ -0,0 +1,27 @@
+package read
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// readMetrics holds all Prometheus metrics of redirects.
+type readMetrics struct {
+	// VariantRedirects counts the redirects to variants of split-tested short
+	// codes, by domain, short code and the index of the variant, so that the
+	// variants of each short code can be compared. Only split-tested short
+	// codes are counted, with at most model.MaxURLVariants series each.
+	VariantRedirects *prometheus.CounterVec
+}
+
+// metrics is the global instance of redirect metrics.
+// Metrics are initialized at package load time using promauto for automatic registration.
+var metrics = &readMetrics{
+	VariantRedirects: promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "url_variant_redirects_total",
+			Help: "Total number of redirects to variants of split-tested short URLs, labeled by domain, short code and variant index",
+		},
+		[]string{"domain", "short_code", "variant"},
+	),
+}
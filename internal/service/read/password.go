@@ -0,0 +1,140 @@
+package read
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/model"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The name of the cookie that unlocks a password-protected short code. The
+// cookie's path is the short code, so each short code has its own.
+const unlockCookieName = "tb_unlock"
+
+// The number of random bytes in a generated cookie secret.
+const cookieSecretBytes = 32
+
+// UnlockShortCode checks a password entered for a password-protected short
+// code. Returns apperrors.ErrIncorrectPassword if it is wrong, or
+// apperrors.ErrTooManyPasswordAttempts if the short code or the client has
+// made too many failed attempts recently. Failed attempts are counted per
+// short code and per client IP.
+func (s *Service) UnlockShortCode(ctx context.Context, urlRecord *model.URLRecordEntity, password string, clientIP string) error {
+	if !urlRecord.IsPasswordProtected() {
+		return nil
+	}
+
+	attemptKeys := []passwordAttemptKey{
+		{key: "code:" + urlRecord.Domain + "/" + urlRecord.ShortCode, limit: s.config.LinkPasswordMaxFailuresPerCode},
+		{key: "ip:" + clientIP, limit: s.config.LinkPasswordMaxFailuresPerIP},
+	}
+
+	// Count the attempt as failed before checking the password, so that
+	// concurrent attempts cannot all get past the limits before any of them is
+	// counted. Reject the attempt without checking the password if it exceeds
+	// either limit, so that a correct guess cannot be confirmed.
+	overLimit := false
+	for _, attemptKey := range attemptKeys {
+		attempts, err := s.dao.PasswordAttemptDAO.IncrementFailedAttempts(ctx, attemptKey.key, s.config.LinkPasswordFailureWindow)
+		if err != nil {
+			middleware.LogErrorWithRequestID(ctx, err, "Failed to count password attempt")
+			return apperrors.ErrDataStoreUnavailable
+		}
+		if attempts > attemptKey.limit {
+			middleware.LogWithRequestID(ctx, "Rejected password attempt over limit", "domain", urlRecord.Domain, "shortCode", urlRecord.ShortCode, "key", attemptKey.key)
+			overLimit = true
+		}
+	}
+	if overLimit {
+		return apperrors.ErrTooManyPasswordAttempts
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(*urlRecord.PasswordHash), []byte(password))
+	if err == nil {
+		s.releasePasswordAttempts(ctx, attemptKeys)
+		return nil
+	}
+	if !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to compare password hash", "domain", urlRecord.Domain, "shortCode", urlRecord.ShortCode)
+		s.releasePasswordAttempts(ctx, attemptKeys)
+		return err
+	}
+
+	middleware.LogDebugWithRequestID(ctx, "Incorrect password for short code", "domain", urlRecord.Domain, "shortCode", urlRecord.ShortCode)
+	return apperrors.ErrIncorrectPassword
+}
+
+// A key that failed password attempts are counted against, and the number of
+// failed attempts allowed against it per window.
+type passwordAttemptKey struct {
+	key   string
+	limit int
+}
+
+// Returns an attempt that was counted as failed but did not fail to each of
+// the keys' counts.
+func (s *Service) releasePasswordAttempts(ctx context.Context, attemptKeys []passwordAttemptKey) {
+	// Release even if the request was cancelled, since the attempt was counted.
+	releaseCtx := context.WithoutCancel(ctx)
+	for _, attemptKey := range attemptKeys {
+		if err := s.dao.PasswordAttemptDAO.DecrementFailedAttempts(releaseCtx, attemptKey.key); err != nil {
+			middleware.LogErrorWithRequestID(ctx, err, "Failed to release password attempt", "key", attemptKey.key)
+		}
+	}
+}
+
+// Returns a signed token that unlocks the password-protected URL record until
+// the configured cookie TTL has elapsed.
+func (s *Service) newUnlockToken(urlRecord *model.URLRecordEntity, now time.Time) string {
+	expiresAt := now.Add(s.config.LinkPasswordCookieTTL).Unix()
+	return strconv.FormatInt(expiresAt, 10) + "." + s.signUnlockToken(urlRecord, expiresAt)
+}
+
+// Returns true if the token was issued for the URL record and has not expired.
+// Tokens stop working when the record's password changes.
+func (s *Service) isUnlocked(urlRecord *model.URLRecordEntity, token string, now time.Time) bool {
+	expiresAtStr, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	expiresAt, err := strconv.ParseInt(expiresAtStr, 10, 64)
+	if err != nil || now.Unix() >= expiresAt {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(s.signUnlockToken(urlRecord, expiresAt)))
+}
+
+// Returns the HMAC of an unlock token's claims. Covers the record's ID and
+// password hash, so that a token cannot unlock another record on the same short
+// code, or the same record after its password changes.
+func (s *Service) signUnlockToken(urlRecord *model.URLRecordEntity, expiresAt int64) string {
+	mac := hmac.New(sha256.New, s.cookieSecret)
+	fmt.Fprintf(mac, "%s\n%s\n%d\n%s\n%d", urlRecord.Domain, urlRecord.ShortCode, urlRecord.ID, *urlRecord.PasswordHash, expiresAt)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Returns the configured cookie secret, or a random one if none is configured.
+func loadCookieSecret(secret string) []byte {
+	if secret != "" {
+		return []byte(secret)
+	}
+
+	slog.Warn("No link password cookie secret configured; unlock cookies will only work on this instance until it restarts")
+	generated := make([]byte, cookieSecretBytes)
+	// Never returns an error; crashes the program instead if randomness is
+	// unavailable.
+	rand.Read(generated)
+	return generated
+}
@@ -0,0 +1,53 @@
+package read
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"tiny-bitly/internal/middleware"
+)
+
+// The name of the form field that holds the password entered for a
+// password-protected short code.
+const passwordFormField = "password"
+
+// The page shown instead of redirecting for a password-protected short code.
+// The form posts back to the short URL itself.
+var passwordFormTemplate = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Password required</title>
+</head>
+<body>
+<form method="post">
+<p>This link is password protected.</p>
+{{if .Message}}<p role="alert">{{.Message}}</p>{{end}}
+<label for="password">Password</label>
+<input id="password" name="{{.FieldName}}" type="password" required autofocus autocomplete="current-password">
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+type passwordFormData struct {
+	FieldName string
+	Message   string
+}
+
+// Writes the password form with the provided status code and an optional
+// message, e.g. to explain why the previous attempt failed.
+func writePasswordForm(ctx context.Context, w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(statusCode)
+	err := passwordFormTemplate.Execute(w, passwordFormData{
+		FieldName: passwordFormField,
+		Message:   message,
+	})
+	if err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to write password form")
+	}
+}
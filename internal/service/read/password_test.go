@@ -0,0 +1,147 @@
+package read
+
+import (
+	"context"
+	"errors"
+	"time"
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/model"
+
+	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var maxFailuresPerCodeForTest = 3
+var maxFailuresPerIPForTest = 5
+
+var passwordForTest = "correct horse"
+var clientIPForTest = "203.0.113.7"
+
+func (suite *ReadServiceSuite) TestUnlockNotPasswordProtected() {
+	urlRecord := &model.URLRecordEntity{URLRecord: model.URLRecord{ShortCode: "abc123"}}
+
+	err := suite.service.UnlockShortCode(context.Background(), urlRecord, "", clientIPForTest)
+	suite.NoError(err)
+}
+
+func (suite *ReadServiceSuite) TestUnlockCorrectPassword() {
+	urlRecord := suite.newProtectedURLRecord()
+	suite.MockIncrementFailedAttempts("code:/abc123", 1)
+	suite.MockIncrementFailedAttempts("ip:"+clientIPForTest, 1)
+
+	// The attempt is given back once the password turns out to be correct.
+	suite.passwordAttemptDAO.EXPECT().DecrementFailedAttempts(gomock.Any(), "code:/abc123").Return(nil)
+	suite.passwordAttemptDAO.EXPECT().DecrementFailedAttempts(gomock.Any(), "ip:"+clientIPForTest).Return(nil)
+
+	err := suite.service.UnlockShortCode(context.Background(), urlRecord, passwordForTest, clientIPForTest)
+	suite.NoError(err)
+}
+
+func (suite *ReadServiceSuite) TestUnlockCorrectPasswordAtLimit() {
+	urlRecord := suite.newProtectedURLRecord()
+	suite.MockIncrementFailedAttempts("code:/abc123", maxFailuresPerCodeForTest)
+	suite.MockIncrementFailedAttempts("ip:"+clientIPForTest, maxFailuresPerIPForTest)
+	suite.passwordAttemptDAO.EXPECT().DecrementFailedAttempts(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+
+	err := suite.service.UnlockShortCode(context.Background(), urlRecord, passwordForTest, clientIPForTest)
+	suite.NoError(err)
+}
+
+func (suite *ReadServiceSuite) TestUnlockIncorrectPassword() {
+	urlRecord := suite.newProtectedURLRecord()
+	suite.MockIncrementFailedAttempts("code:/abc123", 1)
+	suite.MockIncrementFailedAttempts("ip:"+clientIPForTest, 1)
+
+	err := suite.service.UnlockShortCode(context.Background(), urlRecord, "wrong", clientIPForTest)
+	suite.ErrorIs(err, apperrors.ErrIncorrectPassword)
+}
+
+func (suite *ReadServiceSuite) TestUnlockTooManyFailuresForShortCode() {
+	urlRecord := suite.newProtectedURLRecord()
+	suite.MockIncrementFailedAttempts("code:/abc123", maxFailuresPerCodeForTest+1)
+	suite.MockIncrementFailedAttempts("ip:"+clientIPForTest, 1)
+
+	// The correct password is rejected too, so that the limit cannot be used
+	// to confirm a guess.
+	err := suite.service.UnlockShortCode(context.Background(), urlRecord, passwordForTest, clientIPForTest)
+	suite.ErrorIs(err, apperrors.ErrTooManyPasswordAttempts)
+}
+
+func (suite *ReadServiceSuite) TestUnlockTooManyFailuresForClientIP() {
+	urlRecord := suite.newProtectedURLRecord()
+	suite.MockIncrementFailedAttempts("code:/abc123", 1)
+	suite.MockIncrementFailedAttempts("ip:"+clientIPForTest, maxFailuresPerIPForTest+1)
+
+	err := suite.service.UnlockShortCode(context.Background(), urlRecord, passwordForTest, clientIPForTest)
+	suite.ErrorIs(err, apperrors.ErrTooManyPasswordAttempts)
+}
+
+func (suite *ReadServiceSuite) TestUnlockFailedAttemptsError() {
+	urlRecord := suite.newProtectedURLRecord()
+	suite.passwordAttemptDAO.EXPECT().IncrementFailedAttempts(gomock.Any(), gomock.Any(), gomock.Any()).Return(0, errors.New("database error"))
+
+	err := suite.service.UnlockShortCode(context.Background(), urlRecord, passwordForTest, clientIPForTest)
+	suite.ErrorIs(err, apperrors.ErrDataStoreUnavailable)
+}
+
+func (suite *ReadServiceSuite) TestUnlockTokenValid() {
+	urlRecord := suite.newProtectedURLRecord()
+	now := time.Now()
+
+	token := suite.service.newUnlockToken(urlRecord, now)
+	suite.True(suite.service.isUnlocked(urlRecord, token, now))
+}
+
+func (suite *ReadServiceSuite) TestUnlockTokenExpired() {
+	urlRecord := suite.newProtectedURLRecord()
+	now := time.Now()
+
+	token := suite.service.newUnlockToken(urlRecord, now)
+	suite.False(suite.service.isUnlocked(urlRecord, token, now.Add(suite.service.config.LinkPasswordCookieTTL)))
+}
+
+func (suite *ReadServiceSuite) TestUnlockTokenTampered() {
+	urlRecord := suite.newProtectedURLRecord()
+	now := time.Now()
+
+	token := suite.service.newUnlockToken(urlRecord, now)
+	suite.False(suite.service.isUnlocked(urlRecord, "9999999999"+token[len("9999999999"):], now))
+	suite.False(suite.service.isUnlocked(urlRecord, "", now))
+	suite.False(suite.service.isUnlocked(urlRecord, "garbage", now))
+}
+
+func (suite *ReadServiceSuite) TestUnlockTokenForOtherRecord() {
+	urlRecord := suite.newProtectedURLRecord()
+	now := time.Now()
+	token := suite.service.newUnlockToken(urlRecord, now)
+
+	otherRecord := suite.newProtectedURLRecord()
+	otherRecord.ShortCode = "xyz789"
+	suite.False(suite.service.isUnlocked(otherRecord, token, now))
+
+	// Changing the password invalidates existing tokens.
+	newHash := "different"
+	urlRecord.PasswordHash = &newHash
+	suite.False(suite.service.isUnlocked(urlRecord, token, now))
+}
+
+func (suite *ReadServiceSuite) newProtectedURLRecord() *model.URLRecordEntity {
+	hash, err := bcrypt.GenerateFromPassword([]byte(passwordForTest), bcrypt.MinCost)
+	suite.Require().NoError(err)
+	passwordHash := string(hash)
+	return &model.URLRecordEntity{
+		Entity: model.Entity{ID: 1},
+		URLRecord: model.URLRecord{
+			OriginalURL:  "https://www.example.com",
+			ShortCode:    "abc123",
+			PasswordHash: &passwordHash,
+		},
+	}
+}
+
+func (suite *ReadServiceSuite) MockIncrementFailedAttempts(key string, attempts int) *gomock.Call {
+	return suite.passwordAttemptDAO.
+		EXPECT().
+		IncrementFailedAttempts(gomock.Any(), key, gomock.Any()).
+		Return(attempts, nil)
+}
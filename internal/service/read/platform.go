@@ -0,0 +1,46 @@
+package read
+
+import (
+	"strings"
+
+	"tiny-bitly/internal/model"
+)
+
+// The kinds of devices that short codes may send visitors to dedicated URLs
+// on.
+type platform int
+
+const (
+	platformOther platform = iota
+	platformIOS
+	platformAndroid
+)
+
+// Returns the platform of the device that sent a request with the provided
+// User-Agent. Only tells iOS and Android apart from everything else. iPads
+// that request desktop sites identify as macOS, so they count as other
+// platforms.
+func detectPlatform(userAgent string) platform {
+	switch {
+	// Windows Phone claims to be both Android and iOS.
+	case strings.Contains(userAgent, "Windows Phone"):
+		return platformOther
+	case strings.Contains(userAgent, "Android"):
+		return platformAndroid
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"), strings.Contains(userAgent, "iPod"):
+		return platformIOS
+	}
+	return platformOther
+}
+
+// Returns the URL of the record for visitors on the platform, or nil if they
+// are redirected like everyone else.
+func platformURL(urlRecord *model.URLRecordEntity, p platform) *string {
+	switch p {
+	case platformIOS:
+		return urlRecord.IOSURL
+	case platformAndroid:
+		return urlRecord.AndroidURL
+	}
+	return nil
+}
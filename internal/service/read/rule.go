@@ -0,0 +1,67 @@
+package read
+
+import (
+	"context"
+
+	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/model"
+)
+
+// RuleExplanation tells whether a request matches one of a URL record's
+// redirect rules, and which parts of the rule's condition it meets.
+type RuleExplanation struct {
+	// The index of the rule among the record's rules, starting at 0.
+	Index int `json:"index"`
+
+	// The URL that the rule redirects to, before placeholders and passthrough
+	// are applied.
+	URL string `json:"url"`
+
+	// Whether the request meets every part of the rule's condition.
+	Matched bool `json:"matched"`
+
+	// Whether the request meets each part of the rule's condition.
+	Conditions []model.RedirectConditionResult `json:"conditions"`
+}
+
+// ExplainRedirectRules evaluates the redirect rules of the URL record for a
+// short code on the provided domain ("" for the default domain) against a
+// sample request, without redirecting. Returns an explanation of each rule, in
+// order, and the index of the first rule that the request matches, which a
+// visitor making it would be redirected by, or -1 if it matches none and the
+// visitor would be redirected as usual. Fails like GetURLMetadata if the short
+// code is not active.
+func (s *Service) ExplainRedirectRules(ctx context.Context, domain string, shortCode string, request model.RedirectRequest) ([]RuleExplanation, int, error) {
+	urlRecord, err := s.GetURLMetadata(ctx, domain, shortCode)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	explanations := make([]RuleExplanation, len(urlRecord.RedirectRules))
+	matchedRule := -1
+	for i, rule := range urlRecord.RedirectRules {
+		conditions := rule.Explain(request)
+		matched := rule.Matches(request)
+		explanations[i] = RuleExplanation{Index: i, URL: rule.URL, Matched: matched, Conditions: conditions}
+		if matched && matchedRule < 0 {
+			matchedRule = i
+		}
+	}
+	return explanations, matchedRule, nil
+}
+
+// Returns the first of the URL record's redirect rules that the visit matches,
+// or nil if it matches none. The rules were compiled when the record was
+// loaded, so evaluating them takes no further lookups.
+func matchRedirectRule(ctx context.Context, urlRecord *model.URLRecordEntity, visit Visit) *model.RedirectRule {
+	if !urlRecord.HasRedirectRules() {
+		return nil
+	}
+
+	index := urlRecord.RedirectRules.Match(model.RedirectRequest{Time: visit.Time, Header: visit.Header})
+	if index < 0 {
+		return nil
+	}
+	middleware.LogDebugWithRequestID(ctx, "Redirect rule matched for short code", "domain", urlRecord.Domain, "shortCode", urlRecord.ShortCode, "rule", index)
+	return &urlRecord.RedirectRules[index]
+}
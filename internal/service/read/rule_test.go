@@ -0,0 +1,86 @@
+package read
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"tiny-bitly/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Returns the redirect rules in the JSON array, compiled as if loaded through
+// the DAO.
+func loadRedirectRules(t *testing.T, rulesJSON string) model.RedirectRules {
+	var rules model.RedirectRules
+	require.NoError(t, json.Unmarshal([]byte(rulesJSON), &rules))
+	return rules
+}
+
+func TestRedirectRuleConditions(t *testing.T) {
+	// 07:30 on a Monday in UTC, which is 08:30 in Berlin and still Sunday in
+	// Los Angeles.
+	monday := time.Date(2029, time.December, 31, 7, 30, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name     string
+		when     string
+		request  model.RedirectRequest
+		expected bool
+	}{
+		{"Time of day", `{"timeZone": "Europe/Berlin", "timeOfDay": {"start": "08:00", "end": "09:00"}}`, model.RedirectRequest{Time: monday}, true},
+		{"Time of day in another time zone", `{"timeOfDay": {"start": "08:00", "end": "09:00"}}`, model.RedirectRequest{Time: monday}, false},
+		{"Time of day spanning midnight", `{"timeOfDay": {"start": "22:00", "end": "08:00"}}`, model.RedirectRequest{Time: monday}, true},
+		{"Time of day ends exclusively", `{"timeOfDay": {"start": "06:00", "end": "07:30"}}`, model.RedirectRequest{Time: monday}, false},
+		{"Day of week", `{"daysOfWeek": ["sat", "MON"]}`, model.RedirectRequest{Time: monday}, true},
+		{"Day of week in another time zone", `{"timeZone": "America/Los_Angeles", "daysOfWeek": ["mon"]}`, model.RedirectRequest{Time: monday}, false},
+		{"Language", `{"languages": ["de"]}`, model.RedirectRequest{Header: http.Header{"Accept-Language": {"de-AT, en;q=0.8"}}}, true},
+		{"Less preferred language", `{"languages": ["en"]}`, model.RedirectRequest{Header: http.Header{"Accept-Language": {"de-AT, en;q=0.8"}}}, false},
+		{"Language by quality", `{"languages": ["en-gb"]}`, model.RedirectRequest{Header: http.Header{"Accept-Language": {"de;q=0.5, en-GB"}}}, true},
+		{"More specific language", `{"languages": ["de-CH"]}`, model.RedirectRequest{Header: http.Header{"Accept-Language": {"de"}}}, false},
+		{"No Accept-Language", `{"languages": ["de"]}`, model.RedirectRequest{Header: http.Header{}}, false},
+		{"Header", `{"headers": {"x-campaign": "^spring"}}`, model.RedirectRequest{Header: http.Header{"X-Campaign": {"winter", "spring-2030"}}}, true},
+		{"Header not matching", `{"headers": {"X-Campaign": "^spring"}}`, model.RedirectRequest{Header: http.Header{"X-Campaign": {"winter"}}}, false},
+		{"Header missing", `{"headers": {"X-Campaign": ""}}`, model.RedirectRequest{Header: http.Header{}}, false},
+		{"Referrer host", `{"referrerHosts": ["example.com"]}`, model.RedirectRequest{Header: http.Header{"Referer": {"https://News.Example.com/item?id=1"}}}, true},
+		{"Referrer host suffix only", `{"referrerHosts": ["example.com"]}`, model.RedirectRequest{Header: http.Header{"Referer": {"https://badexample.com/"}}}, false},
+		{"No Referer", `{"referrerHosts": ["example.com"]}`, model.RedirectRequest{Header: http.Header{}}, false},
+		{"Every part must match", `{"daysOfWeek": ["mon"], "languages": ["fr"]}`, model.RedirectRequest{Time: monday, Header: http.Header{"Accept-Language": {"de"}}}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rules := loadRedirectRules(t, `[{"when": `+tc.when+`, "url": "https://www.foo.com/"}]`)
+			assert.Equal(t, tc.expected, rules[0].Matches(tc.request))
+		})
+	}
+}
+
+func TestLoadRedirectRulesInvalid(t *testing.T) {
+	var rules model.RedirectRules
+	assert.Error(t, json.Unmarshal([]byte(`[{"when": {}, "url": "https://www.foo.com/"}]`), &rules))
+	assert.Error(t, json.Unmarshal([]byte(`[{"when": {"daysOfWeek": ["someday"]}, "url": "https://www.foo.com/"}]`), &rules))
+}
+
+func TestMatchRedirectRule(t *testing.T) {
+	urlRecord := &model.URLRecordEntity{URLRecord: model.URLRecord{
+		RedirectRules: loadRedirectRules(t, `[
+			{"when": {"languages": ["de"]}, "url": "https://www.foo.de/"},
+			{"when": {"referrerHosts": ["example.com"]}, "url": "https://www.foo.com/example"}
+		]`),
+	}}
+
+	// The first matching rule wins.
+	rule := matchRedirectRule(context.Background(), urlRecord, Visit{Header: http.Header{"Accept-Language": {"de"}, "Referer": {"https://example.com/"}}})
+	require.NotNil(t, rule)
+	assert.Equal(t, "https://www.foo.de/", rule.URL)
+
+	rule = matchRedirectRule(context.Background(), urlRecord, Visit{Header: http.Header{"Referer": {"https://example.com/"}}})
+	require.NotNil(t, rule)
+	assert.Equal(t, "https://www.foo.com/example", rule.URL)
+
+	assert.Nil(t, matchRedirectRule(context.Background(), urlRecord, Visit{Header: http.Header{}}))
+	assert.Nil(t, matchRedirectRule(context.Background(), &model.URLRecordEntity{}, Visit{Header: http.Header{}}))
+}
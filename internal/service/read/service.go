@@ -2,28 +2,288 @@ package read
 
 import (
 	"context"
+	"net/http"
+	"net/url"
+	"time"
 	"tiny-bitly/internal/apperrors"
 	"tiny-bitly/internal/config"
 	"tiny-bitly/internal/dao"
+	"tiny-bitly/internal/geoip"
 	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/model"
+	"tiny-bitly/internal/service/create"
 )
 
 // Service handles URL lookup operations.
 type Service struct {
-	dao    dao.DAO
-	config *config.Config
+	dao          dao.DAO
+	config       *config.Config
+	domains      *domainRegistry
+	cookieSecret []byte
+
+	// Looks up the countries of visitors for geo-targeted short codes, or nil
+	// if geo-targeting is disabled.
+	geoIPDatabase *geoip.Database
 }
 
 // NewService creates a new read service with the provided dependencies.
 func NewService(dao dao.DAO, config *config.Config) *Service {
 	return &Service{
-		dao:    dao,
-		config: config,
+		dao:          dao,
+		config:       config,
+		domains:      newDomainRegistry(dao.DomainDAO),
+		cookieSecret: loadCookieSecret(config.LinkPasswordCookieSecret),
+	}
+}
+
+// SetGeoIPDatabase sets the database that the countries of visitors are looked
+// up in, so that visitors of geo-targeted short codes are redirected to the URL
+// for their country. Until it is set, every visitor is redirected to the
+// original URL.
+func (s *Service) SetGeoIPDatabase(geoIPDatabase *geoip.Database) {
+	s.geoIPDatabase = geoIPDatabase
+}
+
+// Visit holds the details of a visitor's request for a short code that the URL
+// they are redirected to may depend on.
+type Visit struct {
+	// The escaped path that followed the short code, e.g. "extra/path" for
+	// /abc123/extra/path, or "" if there was none.
+	PathSuffix string
+
+	// The query string of the request, without the leading "?".
+	RawQuery string
+
+	// The IP address of the visitor.
+	ClientIP string
+
+	// The User-Agent header of the request.
+	UserAgent string
+
+	// The headers of the request and the time that it was made, which the
+	// record's redirect rules may depend on.
+	Header http.Header
+	Time   time.Time
+
+	// The value of the visitor's variant cookie, if any, which sends them back
+	// to the same variant of a record with sticky variants.
+	VariantCookie string
+}
+
+// ResolveShortCode gets the active URL record for a short code, so that the
+// caller can redirect to its original URL. Looks up the short code on the
+// domain that the request was sent to, if it is a registered custom domain, or
+// on the default domain otherwise. Returns apperrors.ErrShortCodeNotYetActive
+// if the record's activation time has not passed yet, and
+// apperrors.ErrShortCodeExpired along with the record if it has expired, so
+// that the caller can send visitors to its fallback URL.
+func (s *Service) ResolveShortCode(ctx context.Context, host string, shortCode string) (*model.URLRecordEntity, error) {
+	// Validate the short code.
+	err := validateShortCode(shortCode, s.config.MaxAliasLength)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve the domain namespace from the request host.
+	domain, err := s.domains.Resolve(ctx, host)
+	if err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to resolve domain for host", "host", host)
+		return nil, apperrors.ErrDataStoreUnavailable
+	}
+
+	// Lookup in the data store.
+	urlRecord, err := s.dao.URLRecordDAO.GetByShortCode(ctx, domain, shortCode)
+	if err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to get URL record for short code", "domain", domain, "shortCode", shortCode)
+		return nil, apperrors.ErrDataStoreUnavailable
+	}
+
+	if urlRecord == nil {
+		middleware.LogDebugWithRequestID(ctx, "URL record is nil for short code", "domain", domain, "shortCode", shortCode)
+		return nil, apperrors.ErrShortCodeNotFound
+	}
+
+	if urlRecord.IsExpired() {
+		middleware.LogDebugWithRequestID(ctx, "URL record has expired for short code", "domain", domain, "shortCode", shortCode)
+		return urlRecord, apperrors.ErrShortCodeExpired
+	}
+
+	// Never reveal the original URL before the activation time.
+	if urlRecord.IsNotYetActive() {
+		middleware.LogDebugWithRequestID(ctx, "URL record is not active yet for short code", "domain", domain, "shortCode", shortCode, "activatesAt", *urlRecord.ActivatesAt)
+		return nil, apperrors.ErrShortCodeNotYetActive
+	}
+
+	return urlRecord, nil
+}
+
+// ConsumeClick consumes one click of a click-limited URL record, just before
+// the caller redirects to its original URL. Returns
+// apperrors.ErrShortCodeExpired if the record has no clicks left, like for an
+// expired record. Does nothing for records that are not click-limited.
+func (s *Service) ConsumeClick(ctx context.Context, urlRecord *model.URLRecordEntity) error {
+	if !urlRecord.IsClickLimited() {
+		return nil
+	}
+
+	consumed, err := s.dao.URLRecordDAO.ConsumeClick(ctx, urlRecord)
+	if err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to consume click for short code", "domain", urlRecord.Domain, "shortCode", urlRecord.ShortCode)
+		return apperrors.ErrDataStoreUnavailable
+	}
+
+	if !consumed {
+		middleware.LogDebugWithRequestID(ctx, "No clicks left for short code", "domain", urlRecord.Domain, "shortCode", urlRecord.ShortCode)
+		return apperrors.ErrShortCodeExpired
 	}
+
+	return nil
+}
+
+// BuildDestinationURL returns the URL to redirect a visitor of the URL record
+// to: the URL of the first of the record's redirect rules that the visit
+// matches, or else the URL for the visitor's device platform or else country if
+// the record has one, or else the visitor's variant if the record is
+// split-tested, or else its original URL, with placeholders such as {utm_campaign} filled in
+// from the request's query parameters or the record's defaults, the path that
+// followed the short code in the request appended and the request's query
+// parameters merged in, if the record passes them through. Also returns the
+// index of the variant that the visitor is sent to, as chosen by
+// ChooseVariant, or -1 if they are not sent to a variant. Returns
+// apperrors.ErrInvalidURL or apperrors.ErrURLLengthExceeded if the request
+// turns the URL into one that could not have been created.
+func (s *Service) BuildDestinationURL(ctx context.Context, urlRecord *model.URLRecordEntity, visit Visit) (string, int, error) {
+	destinationURL, variant := s.selectURL(ctx, urlRecord, visit)
+	destinationURL, err := s.applyVisit(ctx, urlRecord, destinationURL, visit)
+	if err != nil {
+		return "", -1, err
+	}
+	return destinationURL, variant, nil
+}
+
+// Returns the provided URL with the record's placeholders filled in and the
+// visit's path and query string passed through, per BuildDestinationURL.
+func (s *Service) applyVisit(ctx context.Context, urlRecord *model.URLRecordEntity, destinationURL string, visit Visit) (string, error) {
+	appendPath := urlRecord.PathPassthrough && visit.PathSuffix != ""
+	mergeQuery := urlRecord.QueryPassthrough != nil && visit.RawQuery != ""
+	names, _ := model.TemplatePlaceholders(destinationURL)
+	if !appendPath && !mergeQuery && len(names) == 0 {
+		return destinationURL, nil
+	}
+
+	// Fill in the placeholders, preferring the request's values.
+	if len(names) > 0 {
+		query, _ := url.ParseQuery(visit.RawQuery)
+		destinationURL = model.ExpandTemplate(destinationURL, func(name string) string {
+			if values, ok := query[model.TemplateQueryParam(name)]; ok {
+				return values[0]
+			}
+			return urlRecord.TemplateDefaults[name]
+		})
+	}
+
+	destination, err := url.Parse(destinationURL)
+	if err != nil {
+		middleware.LogDebugWithRequestID(ctx, "Failed to parse destination URL for short code", "domain", urlRecord.Domain, "shortCode", urlRecord.ShortCode, "error", err)
+		return "", apperrors.ErrInvalidURL
+	}
+
+	if appendPath {
+		destination = destination.JoinPath(visit.PathSuffix)
+	}
+	if mergeQuery {
+		destination.RawQuery = mergeQueries(destination.RawQuery, visit.RawQuery, *urlRecord.QueryPassthrough)
+	}
+
+	// Hold the destination to the same rules as original URLs.
+	destinationURL = destination.String()
+	if _, err := create.ValidateURL(destinationURL); err != nil {
+		return "", apperrors.ErrInvalidURL
+	}
+	if !create.ValidateURLLength(destinationURL, s.config.MaxURLLength) {
+		return "", apperrors.ErrURLLengthExceeded
+	}
+
+	return destinationURL, nil
 }
 
-// GetOriginalURL gets the original URL from a short code, or returns nil if one does not exist.
-func (s *Service) GetOriginalURL(ctx context.Context, shortCode string) (*string, error) {
+// Returns the URL that the visitor should be sent to before placeholders and
+// passthrough are applied: the URL of the first redirect rule that the visit
+// matches, if any; or else the URL for the visitor's platform, e.g. an app deep
+// link, if the record has one; or else the URL for the visitor's country, if
+// the record is geo-targeted and has one; or else the URL of the visitor's
+// variant, if the record is split-tested; or else the record's original URL.
+// Visitors whose country is unknown are treated as having no country URL.
+// Also returns the index of the visitor's variant, which is only chosen if the
+// URL is the variant's, or -1 otherwise.
+func (s *Service) selectURL(ctx context.Context, urlRecord *model.URLRecordEntity, visit Visit) (string, int) {
+	if rule := matchRedirectRule(ctx, urlRecord, visit); rule != nil {
+		return rule.URL, -1
+	}
+
+	if urlRecord.IsPlatformTargeted() {
+		if deviceURL := platformURL(urlRecord, detectPlatform(visit.UserAgent)); deviceURL != nil {
+			return *deviceURL, -1
+		}
+	}
+
+	if countryURL, ok := s.countryURL(ctx, urlRecord, visit); ok {
+		return countryURL, -1
+	}
+
+	if urlRecord.IsSplitTested() {
+		variant := s.ChooseVariant(urlRecord, visit.VariantCookie)
+		return urlRecord.Variants[variant].URL, variant
+	}
+	return urlRecord.OriginalURL, -1
+}
+
+// Returns the URL of the geo-targeted record for the visitor's country, or
+// false if the record has none or the country is unknown.
+func (s *Service) countryURL(ctx context.Context, urlRecord *model.URLRecordEntity, visit Visit) (string, bool) {
+	if len(urlRecord.CountryURLs) == 0 || s.geoIPDatabase == nil {
+		return "", false
+	}
+
+	country, err := s.geoIPDatabase.Country(visit.ClientIP)
+	if err != nil {
+		middleware.LogDebugWithRequestID(ctx, "Failed to look up country of client IP", "clientIP", visit.ClientIP, "error", err)
+		return "", false
+	}
+	countryURL, ok := urlRecord.CountryURLs[country]
+	return countryURL, ok
+}
+
+// Merges the request's query string into the original URL's query string.
+// Parameters that appear in both are resolved per the provided mode, one of the
+// model.QueryPassthrough constants. Appending keeps both query strings as they
+// are; otherwise the result is re-encoded with its parameters sorted by name.
+func mergeQueries(destinationQuery string, requestQuery string, mode string) string {
+	if mode == model.QueryPassthroughAppend {
+		if destinationQuery == "" {
+			return requestQuery
+		}
+		return destinationQuery + "&" + requestQuery
+	}
+
+	// Malformed pairs are dropped, keeping the rest.
+	destinationValues, _ := url.ParseQuery(destinationQuery)
+	requestValues, _ := url.ParseQuery(requestQuery)
+	for key, values := range requestValues {
+		if _, ok := destinationValues[key]; ok && mode == model.QueryPassthroughDestination {
+			continue
+		}
+		destinationValues[key] = values
+	}
+	return destinationValues.Encode()
+}
+
+// GetURLMetadata gets the URL record for a short code on the provided domain
+// ("" for the default domain) without resolving it. Distinguishes short codes
+// that never existed from ones that have expired or been deleted. Only finds
+// short codes of the request's owner, so that API keys cannot read each
+// other's records.
+func (s *Service) GetURLMetadata(ctx context.Context, domain string, shortCode string) (*model.URLRecordEntity, error) {
 	// Validate the short code.
 	err := validateShortCode(shortCode, s.config.MaxAliasLength)
 	if err != nil {
@@ -31,18 +291,24 @@ func (s *Service) GetOriginalURL(ctx context.Context, shortCode string) (*string
 	}
 
 	// Lookup in the data store.
-	urlRecord, err := s.dao.URLRecordDAO.GetByShortCode(ctx, shortCode)
+	urlRecord, err := s.dao.URLRecordDAO.GetByShortCodeIncludingInactive(ctx, domain, shortCode, middleware.GetOwnerID(ctx))
 	if err != nil {
-		middleware.LogErrorWithRequestID(ctx, err, "Failed to get URL record for short code", "shortCode", shortCode)
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to get URL record for short code", "domain", domain, "shortCode", shortCode)
 		return nil, apperrors.ErrDataStoreUnavailable
 	}
 
 	if urlRecord == nil {
-		middleware.LogDebugWithRequestID(ctx, "URL record is nil for short code", "shortCode", shortCode)
+		middleware.LogDebugWithRequestID(ctx, "URL record is nil for short code", "domain", domain, "shortCode", shortCode)
 		return nil, apperrors.ErrShortCodeNotFound
 	}
+	if urlRecord.IsDeleted() {
+		return nil, apperrors.ErrShortCodeDeleted
+	}
+	if urlRecord.IsExpired() {
+		return nil, apperrors.ErrShortCodeExpired
+	}
 
-	return &urlRecord.OriginalURL, nil
+	return urlRecord, nil
 }
 
 func validateShortCode(shortCode string, maxLength int) error {
@@ -3,11 +3,16 @@ package read
 import (
 	"context"
 	"errors"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 	"tiny-bitly/internal/apperrors"
 	"tiny-bitly/internal/config"
 	"tiny-bitly/internal/dao"
 	mock_daotypes "tiny-bitly/internal/dao/generated"
+	"tiny-bitly/internal/geoip"
+	"tiny-bitly/internal/middleware"
 	"tiny-bitly/internal/model"
 
 	"github.com/stretchr/testify/suite"
@@ -16,12 +21,19 @@ import (
 
 var maxAliasLengthForTest int = 20
 
+// customDomainForTest is a registered custom domain. Other hosts use the
+// default domain.
+var customDomainForTest = "go.example.com"
+
 type ReadServiceSuite struct {
 	suite.Suite
 	ctrl         *gomock.Controller
 	service      *Service
 	dao          dao.DAO
 	urlRecordDAO *mock_daotypes.MockURLRecordDAO
+	domainDAO    *mock_daotypes.MockDomainDAO
+
+	passwordAttemptDAO *mock_daotypes.MockPasswordAttemptDAO
 }
 
 func TestReadServiceSuite(t *testing.T) {
@@ -31,81 +43,579 @@ func TestReadServiceSuite(t *testing.T) {
 func (suite *ReadServiceSuite) SetupTest() {
 	suite.ctrl = gomock.NewController(suite.T())
 	suite.urlRecordDAO = mock_daotypes.NewMockURLRecordDAO(suite.ctrl)
+	suite.domainDAO = mock_daotypes.NewMockDomainDAO(suite.ctrl)
+	suite.passwordAttemptDAO = mock_daotypes.NewMockPasswordAttemptDAO(suite.ctrl)
 	suite.dao = dao.DAO{
-		URLRecordDAO: suite.urlRecordDAO,
+		URLRecordDAO:       suite.urlRecordDAO,
+		DomainDAO:          suite.domainDAO,
+		PasswordAttemptDAO: suite.passwordAttemptDAO,
 	}
-	cfg := config.GetTestConfig(config.Config{MaxAliasLength: maxAliasLengthForTest})
+	cfg := config.GetTestConfig(config.Config{
+		MaxAliasLength:                 maxAliasLengthForTest,
+		LinkPasswordCookieSecret:       "test-secret",
+		LinkPasswordMaxFailuresPerCode: maxFailuresPerCodeForTest,
+		LinkPasswordMaxFailuresPerIP:   maxFailuresPerIPForTest,
+	})
 	suite.service = NewService(suite.dao, &cfg)
 }
 
 func (suite *ReadServiceSuite) TestShortCodeEmpty() {
 	shortCode := ""
-	originalURL, err := suite.service.GetOriginalURL(context.Background(), shortCode)
+	urlRecord, err := suite.service.ResolveShortCode(context.Background(), "localhost:8080", shortCode)
 	suite.ErrorIs(err, apperrors.ErrShortCodeNotFound)
-	suite.Nil(originalURL)
+	suite.Nil(urlRecord)
 }
 
 func (suite *ReadServiceSuite) TestShortCodeTooLong() {
 	shortCode := "0123456789001234567890" // 1 longer than maxAliasLengthForTest
-	originalURL, err := suite.service.GetOriginalURL(context.Background(), shortCode)
+	urlRecord, err := suite.service.ResolveShortCode(context.Background(), "localhost:8080", shortCode)
 	suite.ErrorIs(err, apperrors.ErrShortCodeNotFound)
-	suite.Nil(originalURL)
+	suite.Nil(urlRecord)
 }
 
 func (suite *ReadServiceSuite) TestGetByShortCodeError() {
 	shortCode := "abc123"
-	suite.MockGetError(shortCode, "database error")
+	suite.MockListDomains()
+	suite.MockGetError("", shortCode, "database error")
 
-	originalURL, err := suite.service.GetOriginalURL(context.Background(), shortCode)
+	urlRecord, err := suite.service.ResolveShortCode(context.Background(), "localhost:8080", shortCode)
 	suite.NotNil(err)
-	suite.Nil(originalURL)
+	suite.Nil(urlRecord)
 	suite.ErrorIs(err, apperrors.ErrDataStoreUnavailable)
 }
 
 func (suite *ReadServiceSuite) TestGetByShortCodeNotFound() {
 	shortCode := "nonexistent"
-	suite.MockGetNotFound(shortCode)
+	suite.MockListDomains()
+	suite.MockGetNotFound("", shortCode)
 
-	originalURL, err := suite.service.GetOriginalURL(context.Background(), shortCode)
+	urlRecord, err := suite.service.ResolveShortCode(context.Background(), "localhost:8080", shortCode)
 	suite.NotNil(err)
-	suite.Nil(originalURL)
+	suite.Nil(urlRecord)
 	suite.ErrorIs(err, apperrors.ErrShortCodeNotFound)
 }
 
 func (suite *ReadServiceSuite) TestSuccess() {
 	shortCode := "abc123"
 	expectedOriginalURL := "https://www.example.com"
-	suite.MockGetSuccess(shortCode, expectedOriginalURL)
+	suite.MockListDomains()
+	suite.MockGetSuccess("", shortCode, expectedOriginalURL)
 
-	originalURL, err := suite.service.GetOriginalURL(context.Background(), shortCode)
+	urlRecord, err := suite.service.ResolveShortCode(context.Background(), "localhost:8080", shortCode)
 	suite.Nil(err)
-	suite.NotNil(originalURL)
-	suite.Equal(expectedOriginalURL, *originalURL)
+	suite.NotNil(urlRecord)
+	suite.Equal(expectedOriginalURL, urlRecord.OriginalURL)
+}
+
+func (suite *ReadServiceSuite) TestExpired() {
+	shortCode := "abc123"
+	suite.MockListDomains()
+	suite.urlRecordDAO.
+		EXPECT().
+		GetByShortCode(gomock.Any(), "", shortCode).
+		Return(&model.URLRecordEntity{URLRecord: model.URLRecord{OriginalURL: "https://www.example.com", ExpiresAt: time.Now().Add(-time.Hour)}}, nil)
+
+	urlRecord, err := suite.service.ResolveShortCode(context.Background(), "localhost:8080", shortCode)
+	suite.ErrorIs(err, apperrors.ErrShortCodeExpired)
+	suite.Require().NotNil(urlRecord)
+	suite.Nil(urlRecord.ExpiredRedirectURL)
+}
+
+func (suite *ReadServiceSuite) TestNotYetActive() {
+	shortCode := "abc123"
+	activatesAt := time.Now().Add(time.Hour)
+	suite.MockListDomains()
+	suite.urlRecordDAO.
+		EXPECT().
+		GetByShortCode(gomock.Any(), "", shortCode).
+		Return(&model.URLRecordEntity{URLRecord: model.URLRecord{OriginalURL: "https://www.example.com", ExpiresAt: time.Now().Add(2 * time.Hour), ActivatesAt: &activatesAt}}, nil)
+
+	urlRecord, err := suite.service.ResolveShortCode(context.Background(), "localhost:8080", shortCode)
+	suite.ErrorIs(err, apperrors.ErrShortCodeNotYetActive)
+	suite.Nil(urlRecord)
+}
+
+func (suite *ReadServiceSuite) TestActivated() {
+	shortCode := "abc123"
+	activatesAt := time.Now().Add(-time.Hour)
+	suite.MockListDomains()
+	suite.urlRecordDAO.
+		EXPECT().
+		GetByShortCode(gomock.Any(), "", shortCode).
+		Return(&model.URLRecordEntity{URLRecord: model.URLRecord{OriginalURL: "https://www.example.com", ExpiresAt: time.Now().Add(2 * time.Hour), ActivatesAt: &activatesAt}}, nil)
+
+	urlRecord, err := suite.service.ResolveShortCode(context.Background(), "localhost:8080", shortCode)
+	suite.NoError(err)
+	suite.Equal("https://www.example.com", urlRecord.OriginalURL)
+}
+
+func (suite *ReadServiceSuite) TestCustomDomainSuccess() {
+	shortCode := "abc123"
+	expectedOriginalURL := "https://www.example.com"
+	suite.MockListDomains()
+	suite.MockGetSuccess(customDomainForTest, shortCode, expectedOriginalURL)
+
+	urlRecord, err := suite.service.ResolveShortCode(context.Background(), "GO.example.com:443", shortCode)
+	suite.NoError(err)
+	suite.Equal(expectedOriginalURL, urlRecord.OriginalURL)
+}
+
+func (suite *ReadServiceSuite) TestCustomDomainsAreCached() {
+	shortCode := "abc123"
+	suite.MockListDomains().Times(1)
+	suite.MockGetSuccess(customDomainForTest, shortCode, "https://www.example.com").Times(2)
+
+	for range 2 {
+		_, err := suite.service.ResolveShortCode(context.Background(), customDomainForTest, shortCode)
+		suite.NoError(err)
+	}
+}
+
+func (suite *ReadServiceSuite) TestListDomainsError() {
+	suite.domainDAO.EXPECT().List(gomock.Any()).Return(nil, errors.New("database error"))
+
+	urlRecord, err := suite.service.ResolveShortCode(context.Background(), customDomainForTest, "abc123")
+	suite.ErrorIs(err, apperrors.ErrDataStoreUnavailable)
+	suite.Nil(urlRecord)
+}
+
+func (suite *ReadServiceSuite) TestListDomainsErrorServesStaleDomains() {
+	shortCode := "abc123"
+	suite.MockListDomains()
+	suite.MockGetSuccess(customDomainForTest, shortCode, "https://www.example.com").Times(2)
+	_, err := suite.service.ResolveShortCode(context.Background(), customDomainForTest, shortCode)
+	suite.NoError(err)
+
+	// Force a refresh that fails.
+	suite.service.domains.refreshedAt = time.Time{}
+	suite.domainDAO.EXPECT().List(gomock.Any()).Return(nil, errors.New("database error"))
+
+	_, err = suite.service.ResolveShortCode(context.Background(), customDomainForTest, shortCode)
+	suite.NoError(err)
+}
+
+func (suite *ReadServiceSuite) TestConsumeClickNotClickLimited() {
+	suite.urlRecordDAO.EXPECT().ConsumeClick(gomock.Any(), gomock.Any()).Times(0)
+
+	err := suite.service.ConsumeClick(context.Background(), &model.URLRecordEntity{})
+	suite.NoError(err)
+}
+
+func (suite *ReadServiceSuite) TestConsumeClickSuccess() {
+	urlRecord := newClickLimitedURLRecord(1)
+	suite.urlRecordDAO.EXPECT().ConsumeClick(gomock.Any(), urlRecord).Return(true, nil)
+
+	err := suite.service.ConsumeClick(context.Background(), urlRecord)
+	suite.NoError(err)
+}
+
+func (suite *ReadServiceSuite) TestConsumeClickNoClicksLeft() {
+	urlRecord := newClickLimitedURLRecord(1)
+	suite.urlRecordDAO.EXPECT().ConsumeClick(gomock.Any(), urlRecord).Return(false, nil)
+
+	err := suite.service.ConsumeClick(context.Background(), urlRecord)
+	suite.ErrorIs(err, apperrors.ErrShortCodeExpired)
+}
+
+func (suite *ReadServiceSuite) TestConsumeClickError() {
+	urlRecord := newClickLimitedURLRecord(1)
+	suite.urlRecordDAO.EXPECT().ConsumeClick(gomock.Any(), urlRecord).Return(false, errors.New("database error"))
+
+	err := suite.service.ConsumeClick(context.Background(), urlRecord)
+	suite.ErrorIs(err, apperrors.ErrDataStoreUnavailable)
+}
+
+func (suite *ReadServiceSuite) TestGetURLMetadataNoClicksLeft() {
+	shortCode := "abc123"
+	suite.MockGetIncludingInactive(shortCode, newClickLimitedURLRecord(0))
+
+	urlRecord, err := suite.service.GetURLMetadata(context.Background(), "", shortCode)
+	suite.Nil(urlRecord)
+	suite.ErrorIs(err, apperrors.ErrShortCodeExpired)
+}
+
+func (suite *ReadServiceSuite) TestGetURLMetadataNotFound() {
+	shortCode := "nonexistent"
+	suite.MockGetIncludingInactive(shortCode, nil)
+
+	urlRecord, err := suite.service.GetURLMetadata(context.Background(), "", shortCode)
+	suite.ErrorIs(err, apperrors.ErrShortCodeNotFound)
+	suite.Nil(urlRecord)
+}
+
+func (suite *ReadServiceSuite) TestGetURLMetadataExpired() {
+	shortCode := "abc123"
+	suite.MockGetIncludingInactive(shortCode, &model.URLRecordEntity{
+		URLRecord: model.URLRecord{ShortCode: shortCode, ExpiresAt: time.Now().Add(-time.Hour)},
+	})
+
+	urlRecord, err := suite.service.GetURLMetadata(context.Background(), "", shortCode)
+	suite.ErrorIs(err, apperrors.ErrShortCodeExpired)
+	suite.Nil(urlRecord)
+}
+
+func (suite *ReadServiceSuite) TestGetURLMetadataDeleted() {
+	shortCode := "abc123"
+	entity := &model.URLRecordEntity{
+		URLRecord: model.URLRecord{ShortCode: shortCode, ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	entity.DeletedAt.Time = time.Now()
+	entity.DeletedAt.Valid = true
+	suite.MockGetIncludingInactive(shortCode, entity)
+
+	urlRecord, err := suite.service.GetURLMetadata(context.Background(), "", shortCode)
+	suite.ErrorIs(err, apperrors.ErrShortCodeDeleted)
+	suite.Nil(urlRecord)
+}
+
+func (suite *ReadServiceSuite) TestGetURLMetadataSuccess() {
+	shortCode := "abc123"
+	suite.MockGetIncludingInactive(shortCode, &model.URLRecordEntity{
+		URLRecord: model.URLRecord{
+			OriginalURL: "https://www.example.com",
+			ShortCode:   shortCode,
+			ExpiresAt:   time.Now().Add(time.Hour),
+		},
+	})
+
+	urlRecord, err := suite.service.GetURLMetadata(context.Background(), "", shortCode)
+	suite.NoError(err)
+	suite.Equal("https://www.example.com", urlRecord.OriginalURL)
+}
+
+func (suite *ReadServiceSuite) TestGetURLMetadataOfAnotherOwner() {
+	shortCode := "abc123"
+	ownerID := "acme"
+	suite.urlRecordDAO.
+		EXPECT().
+		GetByShortCodeIncludingInactive(gomock.Any(), "", shortCode, &ownerID).
+		Return(nil, nil)
+
+	ctx := middleware.WithPrincipal(context.Background(), &middleware.Principal{OwnerID: ownerID})
+	urlRecord, err := suite.service.GetURLMetadata(ctx, "", shortCode)
+	suite.ErrorIs(err, apperrors.ErrShortCodeNotFound)
+	suite.Nil(urlRecord)
+}
+
+func (suite *ReadServiceSuite) TestBuildDestinationURL() {
+	for _, tc := range []struct {
+		name             string
+		originalURL      string
+		queryPassthrough string
+		pathPassthrough  bool
+		pathSuffix       string
+		rawQuery         string
+		expectedURL      string
+	}{
+		{"No passthrough", "https://www.foo.com/a?x=1", "", false, "extra", "x=2", "https://www.foo.com/a?x=1"},
+		{"Path appended", "https://www.foo.com/a", "", true, "extra/path", "", "https://www.foo.com/a/extra/path"},
+		{"Path appended to host", "https://www.foo.com", "", true, "extra", "", "https://www.foo.com/extra"},
+		{"Escaped path kept", "https://www.foo.com/a/", "", true, "b%2Fc", "", "https://www.foo.com/a/b%2Fc"},
+		{"Destination wins", "https://www.foo.com/a?x=1", model.QueryPassthroughDestination, false, "", "x=2&y=3", "https://www.foo.com/a?x=1&y=3"},
+		{"Request wins", "https://www.foo.com/a?x=1", model.QueryPassthroughRequest, false, "", "x=2&y=3", "https://www.foo.com/a?x=2&y=3"},
+		{"Append", "https://www.foo.com/a?x=1", model.QueryPassthroughAppend, false, "", "x=2&y=3", "https://www.foo.com/a?x=1&x=2&y=3"},
+		{"Append without destination query", "https://www.foo.com/a", model.QueryPassthroughAppend, false, "", "x=2", "https://www.foo.com/a?x=2"},
+		{"Path and query", "https://www.foo.com/a?x=1", model.QueryPassthroughRequest, true, "b", "y=2", "https://www.foo.com/a/b?x=1&y=2"},
+	} {
+		suite.Run(tc.name, func() {
+			urlRecord := &model.URLRecordEntity{URLRecord: model.URLRecord{
+				OriginalURL:     tc.originalURL,
+				PathPassthrough: tc.pathPassthrough,
+			}}
+			if tc.queryPassthrough != "" {
+				urlRecord.QueryPassthrough = &tc.queryPassthrough
+			}
+			destinationURL, _, err := suite.service.BuildDestinationURL(context.Background(), urlRecord, Visit{PathSuffix: tc.pathSuffix, RawQuery: tc.rawQuery})
+			suite.NoError(err)
+			suite.Equal(tc.expectedURL, destinationURL)
+		})
+	}
+}
+
+func (suite *ReadServiceSuite) TestBuildDestinationURLTemplate() {
+	for _, tc := range []struct {
+		name        string
+		originalURL string
+		defaults    model.TemplateValues
+		rawQuery    string
+		expectedURL string
+	}{
+		{"Default value", "https://www.foo.com/a?utm_campaign={utm_campaign}", model.TemplateValues{"utm_campaign": "spring"}, "", "https://www.foo.com/a?utm_campaign=spring"},
+		{"Request value wins", "https://www.foo.com/a?utm_campaign={utm_campaign}", model.TemplateValues{"utm_campaign": "spring"}, "utm_campaign=email", "https://www.foo.com/a?utm_campaign=email"},
+		{"Missing value is empty", "https://www.foo.com/a?src={utm_source}", nil, "", "https://www.foo.com/a?src="},
+		{"Query placeholder", "https://www.foo.com/a?ref={query.ref}", nil, "ref=x%26y", "https://www.foo.com/a?ref=x%26y"},
+		{"Path placeholder is escaped", "https://www.foo.com/{query.page}", nil, "page=a/b", "https://www.foo.com/a%2Fb"},
+	} {
+		suite.Run(tc.name, func() {
+			urlRecord := &model.URLRecordEntity{URLRecord: model.URLRecord{
+				OriginalURL:      tc.originalURL,
+				TemplateDefaults: tc.defaults,
+			}}
+			destinationURL, _, err := suite.service.BuildDestinationURL(context.Background(), urlRecord, Visit{RawQuery: tc.rawQuery})
+			suite.NoError(err)
+			suite.Equal(tc.expectedURL, destinationURL)
+		})
+	}
+}
+
+func (suite *ReadServiceSuite) TestBuildDestinationURLTemplateTooLong() {
+	urlRecord := &model.URLRecordEntity{URLRecord: model.URLRecord{
+		OriginalURL: "https://www.foo.com/a?ref={query.ref}",
+	}}
+	_, _, err := suite.service.BuildDestinationURL(context.Background(), urlRecord, Visit{RawQuery: "ref=" + strings.Repeat("x", 2000)})
+	suite.ErrorIs(err, apperrors.ErrURLLengthExceeded)
+}
+
+// The fixture database maps 192.0.2.0/24 to DE and 198.51.100.0/24 to FR.
+const geoIPFixturePath = "../../geoip/testdata/country-test.mmdb"
+
+func (suite *ReadServiceSuite) TestBuildDestinationURLCountry() {
+	geoIPDatabase, err := geoip.Open(geoIPFixturePath)
+	suite.Require().NoError(err)
+	defer geoIPDatabase.Close()
+	suite.service.SetGeoIPDatabase(geoIPDatabase)
+
+	urlRecord := &model.URLRecordEntity{URLRecord: model.URLRecord{
+		OriginalURL: "https://www.foo.com/",
+		CountryURLs: model.CountryURLs{"DE": "https://www.foo.de/", "JP": "https://www.foo.jp/?c={utm_campaign}"},
+	}}
+	for _, tc := range []struct {
+		name        string
+		visit       Visit
+		expectedURL string
+	}{
+		{"Country with URL", Visit{ClientIP: "192.0.2.10"}, "https://www.foo.de/"},
+		{"Country without URL", Visit{ClientIP: "198.51.100.1"}, "https://www.foo.com/"},
+		{"Unknown country", Visit{ClientIP: "203.0.113.1"}, "https://www.foo.com/"},
+		{"Invalid IP", Visit{ClientIP: "unknown"}, "https://www.foo.com/"},
+	} {
+		suite.Run(tc.name, func() {
+			destinationURL, _, err := suite.service.BuildDestinationURL(context.Background(), urlRecord, tc.visit)
+			suite.NoError(err)
+			suite.Equal(tc.expectedURL, destinationURL)
+		})
+	}
+}
+
+func (suite *ReadServiceSuite) TestBuildDestinationURLCountryWithoutGeoIPDatabase() {
+	urlRecord := &model.URLRecordEntity{URLRecord: model.URLRecord{
+		OriginalURL: "https://www.foo.com/",
+		CountryURLs: model.CountryURLs{"DE": "https://www.foo.de/"},
+	}}
+	destinationURL, _, err := suite.service.BuildDestinationURL(context.Background(), urlRecord, Visit{ClientIP: "192.0.2.10"})
+	suite.NoError(err)
+	suite.Equal("https://www.foo.com/", destinationURL)
+}
+
+func (suite *ReadServiceSuite) TestBuildDestinationURLPlatform() {
+	geoIPDatabase, err := geoip.Open(geoIPFixturePath)
+	suite.Require().NoError(err)
+	defer geoIPDatabase.Close()
+	suite.service.SetGeoIPDatabase(geoIPDatabase)
+
+	iosURL := "https://apps.apple.com/app/id123"
+	urlRecord := &model.URLRecordEntity{URLRecord: model.URLRecord{
+		OriginalURL: "https://www.foo.com/",
+		CountryURLs: model.CountryURLs{"DE": "https://www.foo.de/"},
+		IOSURL:      &iosURL,
+	}}
+	for _, tc := range []struct {
+		name        string
+		visit       Visit
+		expectedURL string
+	}{
+		{"iOS URL wins over country URL", Visit{ClientIP: "192.0.2.10", UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X)"}, iosURL},
+		{"Platform without URL falls back to country URL", Visit{ClientIP: "192.0.2.10", UserAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8)"}, "https://www.foo.de/"},
+		{"Other platform", Visit{ClientIP: "198.51.100.1", UserAgent: "Mozilla/5.0 (X11; Linux x86_64)"}, "https://www.foo.com/"},
+	} {
+		suite.Run(tc.name, func() {
+			destinationURL, _, err := suite.service.BuildDestinationURL(context.Background(), urlRecord, tc.visit)
+			suite.NoError(err)
+			suite.Equal(tc.expectedURL, destinationURL)
+		})
+	}
+}
+
+func (suite *ReadServiceSuite) TestBuildDestinationURLVariant() {
+	geoIPDatabase, err := geoip.Open(geoIPFixturePath)
+	suite.Require().NoError(err)
+	defer geoIPDatabase.Close()
+	suite.service.SetGeoIPDatabase(geoIPDatabase)
+
+	// The variants are sticky, so that the visitor's cookie chooses one.
+	urlRecord := &model.URLRecordEntity{
+		Entity: model.Entity{ID: 42},
+		URLRecord: model.URLRecord{
+			OriginalURL:    "https://www.foo.com/",
+			CountryURLs:    model.CountryURLs{"DE": "https://www.foo.de/"},
+			Variants:       []model.URLVariant{{URL: "https://www.foo.com/a?src={utm_source}", Weight: 1}, {URL: "https://www.foo.com/b", Weight: 1}},
+			StickyVariants: true,
+		},
+	}
+	for _, tc := range []struct {
+		name            string
+		visit           Visit
+		expectedURL     string
+		expectedVariant int
+	}{
+		{"First variant", Visit{ClientIP: "198.51.100.1", RawQuery: "utm_source=mail", VariantCookie: "42.0"}, "https://www.foo.com/a?src=mail", 0},
+		{"Second variant", Visit{ClientIP: "198.51.100.1", VariantCookie: "42.1"}, "https://www.foo.com/b", 1},
+		{"Country URL wins over variant", Visit{ClientIP: "192.0.2.10", VariantCookie: "42.1"}, "https://www.foo.de/", -1},
+	} {
+		suite.Run(tc.name, func() {
+			destinationURL, variant, err := suite.service.BuildDestinationURL(context.Background(), urlRecord, tc.visit)
+			suite.NoError(err)
+			suite.Equal(tc.expectedURL, destinationURL)
+			suite.Equal(tc.expectedVariant, variant)
+		})
+	}
+}
+
+func (suite *ReadServiceSuite) TestChooseVariant() {
+	// Weight the variants so that a random choice is all but certain to be the
+	// second.
+	urlRecord := &model.URLRecordEntity{
+		Entity: model.Entity{ID: 42},
+		URLRecord: model.URLRecord{
+			OriginalURL: "https://www.foo.com/",
+			Variants:    []model.URLVariant{{URL: "https://www.foo.com/a", Weight: 1}, {URL: "https://www.foo.com/b", Weight: 1_000_000_000}},
+		},
+	}
+
+	// The cookie is ignored unless the variants are sticky.
+	suite.Equal(1, suite.service.ChooseVariant(urlRecord, "42.0"))
+
+	urlRecord.StickyVariants = true
+	suite.Equal(0, suite.service.ChooseVariant(urlRecord, "42.0"))
+	suite.Equal(1, suite.service.ChooseVariant(urlRecord, "7.0"))
+
+	suite.Equal(0, suite.service.ChooseVariant(&model.URLRecordEntity{}, ""))
+}
+
+func (suite *ReadServiceSuite) TestBuildDestinationURLRedirectRules() {
+	iosURL := "https://apps.apple.com/app/id1"
+	urlRecord := &model.URLRecordEntity{
+		Entity: model.Entity{ID: 42},
+		URLRecord: model.URLRecord{
+			OriginalURL:    "https://www.foo.com/",
+			IOSURL:         &iosURL,
+			Variants:       []model.URLVariant{{URL: "https://www.foo.com/a", Weight: 1}, {URL: "https://www.foo.com/b", Weight: 1}},
+			StickyVariants: true,
+			RedirectRules: loadRedirectRules(suite.T(), `[
+				{"when": {"languages": ["de"]}, "url": "https://www.foo.de/?src={utm_source}"}
+			]`),
+		},
+	}
+	iPhoneUserAgent := "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"
+	for _, tc := range []struct {
+		name            string
+		visit           Visit
+		expectedURL     string
+		expectedVariant int
+	}{
+		{"Rule wins over other targeting", Visit{UserAgent: iPhoneUserAgent, RawQuery: "utm_source=mail", Header: http.Header{"Accept-Language": {"de-DE"}}, VariantCookie: "42.1"}, "https://www.foo.de/?src=mail", -1},
+		{"Platform URL without matching rule", Visit{UserAgent: iPhoneUserAgent, Header: http.Header{"Accept-Language": {"en"}}, VariantCookie: "42.1"}, "https://apps.apple.com/app/id1", -1},
+		{"Variant without matching rule", Visit{Header: http.Header{"Accept-Language": {"en"}}, VariantCookie: "42.1"}, "https://www.foo.com/b", 1},
+	} {
+		suite.Run(tc.name, func() {
+			destinationURL, variant, err := suite.service.BuildDestinationURL(context.Background(), urlRecord, tc.visit)
+			suite.NoError(err)
+			suite.Equal(tc.expectedURL, destinationURL)
+			suite.Equal(tc.expectedVariant, variant)
+		})
+	}
+}
+
+func (suite *ReadServiceSuite) TestExplainRedirectRules() {
+	shortCode := "abc123"
+	suite.MockGetIncludingInactive(shortCode, &model.URLRecordEntity{URLRecord: model.URLRecord{
+		OriginalURL: "https://www.foo.com/",
+		ShortCode:   shortCode,
+		ExpiresAt:   time.Now().Add(time.Hour),
+		RedirectRules: loadRedirectRules(suite.T(), `[
+			{"when": {"daysOfWeek": ["sat", "sun"], "languages": ["de"]}, "url": "https://www.foo.de/wochenende"},
+			{"when": {"languages": ["de"]}, "url": "https://www.foo.de/"},
+			{"when": {"headers": {"X-Campaign": "spring"}}, "url": "https://www.foo.com/spring"}
+		]`),
+	}})
+
+	// A Monday, so the weekend rule does not match, though its language does.
+	explanations, matchedRule, err := suite.service.ExplainRedirectRules(context.Background(), "", shortCode, model.RedirectRequest{
+		Time:   time.Date(2029, time.December, 31, 12, 0, 0, 0, time.UTC),
+		Header: http.Header{"Accept-Language": {"de"}, "X-Campaign": {"spring"}},
+	})
+	suite.NoError(err)
+	suite.Equal(1, matchedRule)
+	suite.Equal([]RuleExplanation{
+		{Index: 0, URL: "https://www.foo.de/wochenende", Matched: false, Conditions: []model.RedirectConditionResult{{Condition: "daysOfWeek", Matched: false}, {Condition: "languages", Matched: true}}},
+		{Index: 1, URL: "https://www.foo.de/", Matched: true, Conditions: []model.RedirectConditionResult{{Condition: "languages", Matched: true}}},
+		{Index: 2, URL: "https://www.foo.com/spring", Matched: true, Conditions: []model.RedirectConditionResult{{Condition: "headers", Matched: true}}},
+	}, explanations)
+}
+
+func (suite *ReadServiceSuite) TestExplainRedirectRulesExpired() {
+	shortCode := "abc123"
+	suite.MockGetIncludingInactive(shortCode, &model.URLRecordEntity{URLRecord: model.URLRecord{
+		ShortCode: shortCode,
+		ExpiresAt: time.Now().Add(-time.Hour),
+	}})
+
+	_, matchedRule, err := suite.service.ExplainRedirectRules(context.Background(), "", shortCode, model.RedirectRequest{Time: time.Now()})
+	suite.ErrorIs(err, apperrors.ErrShortCodeExpired)
+	suite.Equal(-1, matchedRule)
+}
+
+func newClickLimitedURLRecord(remainingClicks int) *model.URLRecordEntity {
+	maxClicks := 1
+	return &model.URLRecordEntity{
+		Entity: model.Entity{ID: 1},
+		URLRecord: model.URLRecord{
+			OriginalURL:     "https://www.example.com",
+			ShortCode:       "abc123",
+			ExpiresAt:       time.Now().Add(time.Hour),
+			MaxClicks:       &maxClicks,
+			RemainingClicks: &remainingClicks,
+		},
+	}
+}
+
+func (suite *ReadServiceSuite) MockListDomains() *gomock.Call {
+	return suite.domainDAO.
+		EXPECT().
+		List(gomock.Any()).
+		Return([]model.Domain{{Hostname: customDomainForTest}}, nil)
+}
+
+func (suite *ReadServiceSuite) MockGetIncludingInactive(shortCode string, entity *model.URLRecordEntity) *gomock.Call {
+	return suite.urlRecordDAO.
+		EXPECT().
+		GetByShortCodeIncludingInactive(gomock.Any(), "", shortCode, gomock.Nil()).
+		Return(entity, nil)
 }
 
-func (suite *ReadServiceSuite) MockGetSuccess(shortCode, expectedOriginalURL string) *gomock.Call {
+func (suite *ReadServiceSuite) MockGetSuccess(domain, shortCode, expectedOriginalURL string) *gomock.Call {
 	return suite.urlRecordDAO.
 		EXPECT().
-		GetByShortCode(gomock.Any(), shortCode).
+		GetByShortCode(gomock.Any(), domain, shortCode).
 		Return(
 			&model.URLRecordEntity{
 				Entity:    model.Entity{},
-				URLRecord: model.URLRecord{OriginalURL: expectedOriginalURL},
+				URLRecord: model.URLRecord{OriginalURL: expectedOriginalURL, ExpiresAt: time.Now().Add(time.Hour)},
 			},
 			nil,
 		)
 }
 
-func (suite *ReadServiceSuite) MockGetNotFound(shortCode string) *gomock.Call {
+func (suite *ReadServiceSuite) MockGetNotFound(domain, shortCode string) *gomock.Call {
 	return suite.urlRecordDAO.
 		EXPECT().
-		GetByShortCode(gomock.Any(), shortCode).
+		GetByShortCode(gomock.Any(), domain, shortCode).
 		Return(nil, nil)
 }
 
-func (suite *ReadServiceSuite) MockGetError(shortCode, errorMessage string) *gomock.Call {
+func (suite *ReadServiceSuite) MockGetError(domain, shortCode, errorMessage string) *gomock.Call {
 	return suite.urlRecordDAO.
 		EXPECT().
-		GetByShortCode(gomock.Any(), shortCode).
+		GetByShortCode(gomock.Any(), domain, shortCode).
 		Return(nil, errors.New(errorMessage))
 }
@@ -0,0 +1,99 @@
+package read
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/model"
+)
+
+// The name of the cookie that remembers which variant of a split-tested short
+// code a visitor was sent to. The cookie's path is the short code, so each
+// short code has its own.
+const variantCookieName = "tb_variant"
+
+// ChooseVariant returns the index of the variant of a split-tested URL record
+// that a visitor should be sent to: the variant named by the visitor's variant
+// cookie, if the record's variants are sticky and the cookie was set for this
+// record, or else one chosen at random in proportion to the variants' weights.
+// Returns 0 for records that are not split-tested.
+func (s *Service) ChooseVariant(urlRecord *model.URLRecordEntity, cookie string) int {
+	if !urlRecord.IsSplitTested() {
+		return 0
+	}
+
+	if urlRecord.StickyVariants {
+		if variant, ok := parseVariantCookie(urlRecord, cookie); ok {
+			return variant
+		}
+	}
+
+	totalWeight := 0
+	for _, variant := range urlRecord.Variants {
+		totalWeight += variant.Weight
+	}
+	return pickVariant(urlRecord.Variants, rand.IntN(totalWeight))
+}
+
+// Returns the index of the variant that a roll between 0 and the total weight
+// of the variants lands on, so that each variant is picked in proportion to
+// its weight when the roll is uniformly random.
+func pickVariant(variants []model.URLVariant, roll int) int {
+	for i, variant := range variants {
+		if roll < variant.Weight {
+			return i
+		}
+		roll -= variant.Weight
+	}
+	return len(variants) - 1
+}
+
+// Returns a cookie that sends the visitor back to the provided variant of the
+// URL record on later visits. The cookie names the record's ID, so that it
+// does not carry over to another record that reclaims the short code.
+func (s *Service) newVariantCookie(r *http.Request, urlRecord *model.URLRecordEntity, variant int) *http.Cookie {
+	return &http.Cookie{
+		Name:     variantCookieName,
+		Value:    fmt.Sprintf("%d.%d", urlRecord.ID, variant),
+		Path:     "/" + urlRecord.ShortCode,
+		MaxAge:   int(s.config.VariantCookieTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   middleware.PublicProto(r) == "https",
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// Returns the variant of the URL record that a variant cookie names, or false
+// if the cookie was set for another record or names no variant of this one.
+func parseVariantCookie(urlRecord *model.URLRecordEntity, cookie string) (int, bool) {
+	recordID, variant, ok := strings.Cut(cookie, ".")
+	if !ok || recordID != strconv.FormatUint(uint64(urlRecord.ID), 10) {
+		return 0, false
+	}
+	index, err := strconv.Atoi(variant)
+	if err != nil || index < 0 || index >= len(urlRecord.Variants) {
+		return 0, false
+	}
+	return index, true
+}
+
+// Returns the value of the request's variant cookie, or "" if it has none.
+func readVariantCookie(r *http.Request) string {
+	cookie, err := r.Cookie(variantCookieName)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// Records that a visitor of the URL record was sent to the provided variant,
+// for comparing the variants' performance.
+func recordVariantRedirect(ctx context.Context, urlRecord *model.URLRecordEntity, variant int) {
+	metrics.VariantRedirects.WithLabelValues(urlRecord.Domain, urlRecord.ShortCode, strconv.Itoa(variant)).Inc()
+	middleware.LogWithRequestID(ctx, "Redirecting to variant of short code", "domain", urlRecord.Domain, "shortCode", urlRecord.ShortCode, "variant", variant, "url", urlRecord.Variants[variant].URL)
+}
@@ -0,0 +1,36 @@
+package read
+
+import (
+	"testing"
+
+	"tiny-bitly/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPickVariant(t *testing.T) {
+	variants := []model.URLVariant{{URL: "https://www.foo.com/a", Weight: 3}, {URL: "https://www.foo.com/b", Weight: 1}}
+	assert.Equal(t, 0, pickVariant(variants, 0))
+	assert.Equal(t, 0, pickVariant(variants, 2))
+	assert.Equal(t, 1, pickVariant(variants, 3))
+}
+
+func TestParseVariantCookie(t *testing.T) {
+	urlRecord := &model.URLRecordEntity{
+		Entity: model.Entity{ID: 42},
+		URLRecord: model.URLRecord{
+			Variants: []model.URLVariant{{URL: "https://www.foo.com/a", Weight: 1}, {URL: "https://www.foo.com/b", Weight: 1}},
+		},
+	}
+
+	variant, ok := parseVariantCookie(urlRecord, "42.1")
+	assert.True(t, ok)
+	assert.Equal(t, 1, variant)
+
+	// Cookies for another record, or for a variant that does not exist, are
+	// ignored.
+	for _, cookie := range []string{"", "7.1", "42.2", "42.-1", "42", "42.x"} {
+		_, ok := parseVariantCookie(urlRecord, cookie)
+		assert.False(t, ok, cookie)
+	}
+}
@@ -0,0 +1,31 @@
+package remove
+
+import (
+	"net/http"
+	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/service"
+)
+
+// NewDeleteURLHandler creates an HTTP handler for DELETE /urls/{shortCode} that uses the provided service.
+// Selects a short code on a custom domain via the `domain` query parameter.
+// - 204 No Content if the short code was deleted
+// - 404 Not Found if no active short code exists (or if the short URL is expired)
+// - 500 Internal Server Error for other errors
+// - 503 Service Unavailable if the data store is unavailable
+func NewDeleteURLHandler(removeService *Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Parse `shortCode` out of the URL.
+		shortCode := r.PathValue("shortCode")
+		domain := service.ReadRequestDomain(r)
+
+		// Log the inbound request.
+		middleware.LogWithRequestID(r.Context(), "Request received", "domain", domain, "shortCode", shortCode)
+
+		if err := removeService.DeleteShortCode(r.Context(), domain, shortCode); err != nil {
+			handleServiceError(r.Context(), w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
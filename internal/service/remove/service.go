@@ -0,0 +1,48 @@
+package remove
+
+import (
+	"context"
+	"errors"
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/config"
+	"tiny-bitly/internal/dao"
+	"tiny-bitly/internal/middleware"
+)
+
+// Service handles URL deletion operations.
+type Service struct {
+	dao    dao.DAO
+	config *config.Config
+}
+
+// NewService creates a new remove service with the provided dependencies.
+func NewService(dao dao.DAO, config *config.Config) *Service {
+	return &Service{
+		dao:    dao,
+		config: config,
+	}
+}
+
+// DeleteShortCode soft-deletes the caller's active URL record with the provided
+// short code on the provided domain ("" for the default domain), so that it no
+// longer resolves.
+func (s *Service) DeleteShortCode(ctx context.Context, domain string, shortCode string) error {
+	// Validate the short code.
+	if shortCode == "" || len(shortCode) > s.config.MaxAliasLength {
+		return apperrors.ErrShortCodeNotFound
+	}
+
+	// Only the caller's own records may be deleted.
+	err := s.dao.URLRecordDAO.Delete(ctx, domain, shortCode, middleware.GetOwnerID(ctx))
+	if errors.Is(err, apperrors.ErrShortCodeNotFound) {
+		middleware.LogDebugWithRequestID(ctx, "No active URL record to delete for short code", "domain", domain, "shortCode", shortCode)
+		return err
+	}
+	if err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to delete URL record for short code", "domain", domain, "shortCode", shortCode)
+		return apperrors.ErrDataStoreUnavailable
+	}
+
+	middleware.LogWithRequestID(ctx, "Deleted short code", "domain", domain, "shortCode", shortCode)
+	return nil
+}
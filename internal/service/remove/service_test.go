@@ -0,0 +1,107 @@
+package remove
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/config"
+	"tiny-bitly/internal/dao"
+	mock_daotypes "tiny-bitly/internal/dao/generated"
+	"tiny-bitly/internal/middleware"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+var maxAliasLengthForTest int = 20
+
+type RemoveServiceSuite struct {
+	suite.Suite
+	ctrl         *gomock.Controller
+	service      *Service
+	dao          dao.DAO
+	urlRecordDAO *mock_daotypes.MockURLRecordDAO
+}
+
+func TestRemoveServiceSuite(t *testing.T) {
+	suite.Run(t, new(RemoveServiceSuite))
+}
+
+func (suite *RemoveServiceSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.urlRecordDAO = mock_daotypes.NewMockURLRecordDAO(suite.ctrl)
+	suite.dao = dao.DAO{
+		URLRecordDAO: suite.urlRecordDAO,
+	}
+	cfg := config.GetTestConfig(config.Config{MaxAliasLength: maxAliasLengthForTest})
+	suite.service = NewService(suite.dao, &cfg)
+}
+
+func (suite *RemoveServiceSuite) TestShortCodeEmpty() {
+	err := suite.service.DeleteShortCode(context.Background(), "", "")
+	suite.ErrorIs(err, apperrors.ErrShortCodeNotFound)
+}
+
+func (suite *RemoveServiceSuite) TestShortCodeTooLong() {
+	shortCode := "0123456789001234567890" // 1 longer than maxAliasLengthForTest
+	err := suite.service.DeleteShortCode(context.Background(), "", shortCode)
+	suite.ErrorIs(err, apperrors.ErrShortCodeNotFound)
+}
+
+func (suite *RemoveServiceSuite) TestDeleteNotFound() {
+	shortCode := "nonexistent"
+	suite.urlRecordDAO.
+		EXPECT().
+		Delete(gomock.Any(), "", shortCode, gomock.Nil()).
+		Return(apperrors.ErrShortCodeNotFound)
+
+	err := suite.service.DeleteShortCode(context.Background(), "", shortCode)
+	suite.ErrorIs(err, apperrors.ErrShortCodeNotFound)
+}
+
+func (suite *RemoveServiceSuite) TestDeleteError() {
+	shortCode := "abc123"
+	suite.urlRecordDAO.
+		EXPECT().
+		Delete(gomock.Any(), "", shortCode, gomock.Nil()).
+		Return(errors.New("database error"))
+
+	err := suite.service.DeleteShortCode(context.Background(), "", shortCode)
+	suite.ErrorIs(err, apperrors.ErrDataStoreUnavailable)
+}
+
+func (suite *RemoveServiceSuite) TestSuccess() {
+	shortCode := "abc123"
+	suite.urlRecordDAO.
+		EXPECT().
+		Delete(gomock.Any(), "", shortCode, gomock.Nil()).
+		Return(nil)
+
+	err := suite.service.DeleteShortCode(context.Background(), "", shortCode)
+	suite.NoError(err)
+}
+
+func (suite *RemoveServiceSuite) TestScopedToOwner() {
+	shortCode := "abc123"
+	ownerID := "acme"
+	suite.urlRecordDAO.
+		EXPECT().
+		Delete(gomock.Any(), "", shortCode, &ownerID).
+		Return(nil)
+
+	ctx := middleware.WithPrincipal(context.Background(), &middleware.Principal{OwnerID: ownerID})
+	err := suite.service.DeleteShortCode(ctx, "", shortCode)
+	suite.NoError(err)
+}
+
+func (suite *RemoveServiceSuite) TestCustomDomain() {
+	shortCode := "abc123"
+	suite.urlRecordDAO.
+		EXPECT().
+		Delete(gomock.Any(), "go.example.com", shortCode, gomock.Nil()).
+		Return(nil)
+
+	err := suite.service.DeleteShortCode(context.Background(), "go.example.com", shortCode)
+	suite.NoError(err)
+}
@@ -0,0 +1,51 @@
+package update
+
+import (
+	"context"
+	"net/http"
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/service"
+)
+
+// Maps service errors to appropriate HTTP status codes and responses. Logs
+// detailed error information while returning user-friendly messages.
+func handleServiceError(ctx context.Context, w http.ResponseWriter, err error) {
+	service.HandleServiceError(ctx, w, err, map[error]service.ErrorMapping{
+		apperrors.ErrInvalidURL: {
+			StatusCode:  http.StatusBadRequest,
+			UserMessage: "Invalid URL format",
+		},
+		apperrors.ErrURLLengthExceeded: {
+			StatusCode:  http.StatusBadRequest,
+			UserMessage: "URL exceeds maximum length",
+		},
+		apperrors.ErrInvalidTemplate: {
+			StatusCode:  http.StatusBadRequest,
+			UserMessage: "URL placeholders must be closed and one of {utm_source}, {utm_medium}, {utm_campaign}, {utm_term}, {utm_content} or {query.<name>}",
+		},
+		apperrors.ErrExpirationInPast: {
+			StatusCode:  http.StatusBadRequest,
+			UserMessage: "Expiration must be in the future",
+		},
+		apperrors.ErrExpirationOutOfRange: {
+			StatusCode:  http.StatusBadRequest,
+			UserMessage: "Expiration is outside the allowed range",
+		},
+		apperrors.ErrInvalidActivation: {
+			StatusCode:  http.StatusBadRequest,
+			UserMessage: "expiresAt must be after the activation time",
+		},
+		apperrors.ErrNoFieldsToUpdate: {
+			StatusCode:  http.StatusBadRequest,
+			UserMessage: "Provide at least one of originalUrl or expiresAt",
+		},
+		apperrors.ErrShortCodeNotFound: {
+			StatusCode:  http.StatusNotFound,
+			UserMessage: "Short code does not exist",
+		},
+		apperrors.ErrDataStoreUnavailable: {
+			StatusCode:  http.StatusServiceUnavailable,
+			UserMessage: "Service temporarily unavailable. Please try again later",
+		},
+	})
+}
@@ -0,0 +1,69 @@
+package update
+
+import (
+	"net/http"
+	"time"
+	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/service"
+	"tiny-bitly/internal/service/read"
+)
+
+type UpdateURLRequest struct {
+	// The new destination URL. If not provided, the destination is unchanged.
+	URL *string `json:"originalUrl"`
+
+	// The new RFC 3339 expiration timestamp. If not provided, the expiration is
+	// unchanged.
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// NewPatchURLHandler creates an HTTP handler for PATCH /urls/{shortCode} that uses the provided service.
+// Selects a short code on a custom domain via the `domain` query parameter.
+// - 200 OK with the updated record's URLMetadataResponse on success
+// - 400 Bad Request if the URL is invalid, exceeds length, has an unknown placeholder or one that its template defaults do not fit, expiration is invalid or not after the activation time, or nothing changes
+// - 404 Not Found if no active short code exists (or if the short URL is expired)
+// - 500 Internal Server Error for other errors
+// - 503 Service Unavailable if the data store is unavailable
+func NewPatchURLHandler(updateService *Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Parse `shortCode` out of the URL.
+		shortCode := r.PathValue("shortCode")
+		domain := service.ReadRequestDomain(r)
+
+		// Attempt to read the JSON request body.
+		request, err := service.ReadRequestJson[UpdateURLRequest](r)
+		if err != nil {
+			http.Error(w, "Malformatted request JSON", http.StatusBadRequest)
+			return
+		}
+
+		// Log the inbound request.
+		middleware.LogWithRequestID(r.Context(), "Request received", "domain", domain, "shortCode", shortCode)
+
+		entity, err := updateService.UpdateShortCode(r.Context(), domain, shortCode, UpdateOptions{
+			OriginalURL: request.URL,
+			ExpiresAt:   request.ExpiresAt,
+		})
+		if err != nil {
+			handleServiceError(r.Context(), w, err)
+			return
+		}
+
+		// Build the short URL on the short code's domain.
+		shortURL, err := service.BuildShortURL(r, updateService.config.APIHostname, entity.Domain, entity.ShortCode)
+		if err != nil {
+			middleware.LogErrorWithRequestID(r.Context(), err, "Failed to build short URL")
+			handleServiceError(r.Context(), w, err)
+			return
+		}
+
+		// Send the JSON response.
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = service.WriteResponseJson(w, read.NewURLMetadataResponse(entity, shortURL))
+		if err != nil {
+			handleServiceError(r.Context(), w, err)
+			return
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"time"
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/config"
+	"tiny-bitly/internal/dao"
+	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/model"
+	"tiny-bitly/internal/service/create"
+)
+
+// Service handles URL update operations.
+type Service struct {
+	dao    dao.DAO
+	config *config.Config
+}
+
+// NewService creates a new update service with the provided dependencies.
+func NewService(dao dao.DAO, config *config.Config) *Service {
+	return &Service{
+		dao:    dao,
+		config: config,
+	}
+}
+
+// UpdateOptions holds the fields to change on an existing short code. Nil
+// fields are left unchanged.
+type UpdateOptions struct {
+	OriginalURL *string
+	ExpiresAt   *time.Time
+}
+
+// UpdateShortCode changes the destination and/or expiration of the caller's
+// active URL record with the provided short code on the provided domain (""
+// for the default domain), then returns the updated record.
+func (s *Service) UpdateShortCode(
+	ctx context.Context,
+	domain string,
+	shortCode string,
+	opts UpdateOptions,
+) (*model.URLRecordEntity, error) {
+	// Validate the short code.
+	if shortCode == "" || len(shortCode) > s.config.MaxAliasLength {
+		return nil, apperrors.ErrShortCodeNotFound
+	}
+
+	var update model.URLRecordUpdate
+
+	// Validate the URL using the same rules as for creation.
+	if opts.OriginalURL != nil {
+		validatedURL, err := create.ValidateURL(*opts.OriginalURL)
+		if err != nil {
+			return nil, apperrors.ErrInvalidURL
+		}
+		if !create.ValidateURLLength(*opts.OriginalURL, s.config.MaxURLLength) {
+			return nil, apperrors.ErrURLLengthExceeded
+		}
+		normalizedURL := create.NormalizeURL(*validatedURL)
+		update.OriginalURL = &normalizedURL
+	}
+
+	// Validate the expiration using the same bounds as for creation.
+	if opts.ExpiresAt != nil {
+		err := create.ValidateExpiresAt(time.Now(), *opts.ExpiresAt, s.config.ShortCodeTTLMin, s.config.ShortCodeTTLMax)
+		if err != nil {
+			return nil, err
+		}
+		update.ExpiresAt = opts.ExpiresAt
+	}
+
+	if update.IsEmpty() {
+		return nil, apperrors.ErrNoFieldsToUpdate
+	}
+
+	// Only the caller's own records may be updated.
+	ownerID := middleware.GetOwnerID(ctx)
+	existing, err := s.dao.URLRecordDAO.GetByShortCodeIncludingInactive(ctx, domain, shortCode, ownerID)
+	if err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to get URL record for short code", "domain", domain, "shortCode", shortCode)
+		return nil, apperrors.ErrDataStoreUnavailable
+	}
+	if existing == nil || existing.IsDeleted() || existing.IsExpired() {
+		middleware.LogDebugWithRequestID(ctx, "No active URL record to update for short code", "domain", domain, "shortCode", shortCode)
+		return nil, apperrors.ErrShortCodeNotFound
+	}
+
+	// The updated record must still be valid as a whole. Template defaults and
+	// the activation time cannot be changed here, so the stored ones apply.
+	if opts.OriginalURL != nil {
+		if err := create.ValidateTemplate(*opts.OriginalURL, existing.TemplateDefaults, s.config.MaxURLLength); err != nil {
+			return nil, err
+		}
+	}
+	if update.ExpiresAt != nil && existing.ActivatesAt != nil && !existing.ActivatesAt.Before(*update.ExpiresAt) {
+		return nil, apperrors.ErrInvalidActivation
+	}
+
+	entity, err := s.dao.URLRecordDAO.Update(ctx, domain, shortCode, ownerID, update)
+	if errors.Is(err, apperrors.ErrShortCodeNotFound) {
+		middleware.LogDebugWithRequestID(ctx, "No active URL record to update for short code", "domain", domain, "shortCode", shortCode)
+		return nil, err
+	}
+	if err != nil {
+		middleware.LogErrorWithRequestID(ctx, err, "Failed to update URL record for short code", "domain", domain, "shortCode", shortCode)
+		return nil, apperrors.ErrDataStoreUnavailable
+	}
+
+	middleware.LogWithRequestID(ctx, "Updated short code", "domain", domain, "shortCode", shortCode, "originalURL", entity.OriginalURL, "expiresAt", entity.ExpiresAt)
+	return entity, nil
+}
@@ -0,0 +1,236 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/config"
+	"tiny-bitly/internal/dao"
+	mock_daotypes "tiny-bitly/internal/dao/generated"
+	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/model"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+var maxAliasLengthForTest int = 20
+
+type UpdateServiceSuite struct {
+	suite.Suite
+	ctx          context.Context
+	ctrl         *gomock.Controller
+	service      *Service
+	dao          dao.DAO
+	urlRecordDAO *mock_daotypes.MockURLRecordDAO
+}
+
+func TestUpdateServiceSuite(t *testing.T) {
+	suite.Run(t, new(UpdateServiceSuite))
+}
+
+func (suite *UpdateServiceSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.urlRecordDAO = mock_daotypes.NewMockURLRecordDAO(suite.ctrl)
+	suite.dao = dao.DAO{
+		URLRecordDAO: suite.urlRecordDAO,
+	}
+	cfg := config.GetTestConfig(config.Config{MaxAliasLength: maxAliasLengthForTest})
+	suite.service = NewService(suite.dao, &cfg)
+}
+
+// Returns the provided record as stored, expiring in a day unless it sets an
+// expiration.
+func activeRecord(urlRecord model.URLRecord) *model.URLRecordEntity {
+	if urlRecord.ExpiresAt.IsZero() {
+		urlRecord.ExpiresAt = time.Now().Add(24 * time.Hour)
+	}
+	return &model.URLRecordEntity{URLRecord: urlRecord}
+}
+
+// Expects a lookup of the short code on the provided domain and owner,
+// returning the provided existing record.
+func (suite *UpdateServiceSuite) expectExisting(domain string, ownerID *string, existing *model.URLRecordEntity) {
+	ownerMatcher := gomock.Nil()
+	if ownerID != nil {
+		ownerMatcher = gomock.Eq(ownerID)
+	}
+	suite.urlRecordDAO.
+		EXPECT().
+		GetByShortCodeIncludingInactive(gomock.Any(), domain, "abc123", ownerMatcher).
+		Return(existing, nil)
+}
+
+func (suite *UpdateServiceSuite) TestShortCodeTooLong() {
+	shortCode := "0123456789001234567890" // 1 longer than maxAliasLengthForTest
+	originalURL := "https://www.foo.com"
+	_, err := suite.service.UpdateShortCode(suite.ctx, "", shortCode, UpdateOptions{OriginalURL: &originalURL})
+	suite.ErrorIs(err, apperrors.ErrShortCodeNotFound)
+}
+
+func (suite *UpdateServiceSuite) TestErrorNoFields() {
+	_, err := suite.service.UpdateShortCode(suite.ctx, "", "abc123", UpdateOptions{})
+	suite.ErrorIs(err, apperrors.ErrNoFieldsToUpdate)
+}
+
+func (suite *UpdateServiceSuite) TestErrorInputURLInvalid() {
+	originalURL := "https://"
+	_, err := suite.service.UpdateShortCode(suite.ctx, "", "abc123", UpdateOptions{OriginalURL: &originalURL})
+	suite.ErrorIs(err, apperrors.ErrInvalidURL)
+}
+
+func (suite *UpdateServiceSuite) TestErrorInputURLTooLong() {
+	cfg := config.GetTestConfig(config.Config{MaxURLLength: 10})
+	service := NewService(suite.dao, &cfg)
+	originalURL := "https://www.foo.com"
+	_, err := service.UpdateShortCode(suite.ctx, "", "abc123", UpdateOptions{OriginalURL: &originalURL})
+	suite.ErrorIs(err, apperrors.ErrURLLengthExceeded)
+}
+
+func (suite *UpdateServiceSuite) TestErrorExpiresAtInPast() {
+	expiresAt := time.Now().Add(-time.Hour)
+	_, err := suite.service.UpdateShortCode(suite.ctx, "", "abc123", UpdateOptions{ExpiresAt: &expiresAt})
+	suite.ErrorIs(err, apperrors.ErrExpirationInPast)
+}
+
+func (suite *UpdateServiceSuite) TestUpdateNotFound() {
+	originalURL := "https://www.foo.com"
+	suite.expectExisting("", nil, activeRecord(model.URLRecord{}))
+	suite.urlRecordDAO.
+		EXPECT().
+		Update(gomock.Any(), "", "abc123", gomock.Nil(), gomock.Any()).
+		Return(nil, apperrors.ErrShortCodeNotFound)
+
+	_, err := suite.service.UpdateShortCode(suite.ctx, "", "abc123", UpdateOptions{OriginalURL: &originalURL})
+	suite.ErrorIs(err, apperrors.ErrShortCodeNotFound)
+}
+
+func (suite *UpdateServiceSuite) TestNotFoundBeforeUpdate() {
+	originalURL := "https://www.foo.com"
+	suite.expectExisting("", nil, nil)
+
+	_, err := suite.service.UpdateShortCode(suite.ctx, "", "abc123", UpdateOptions{OriginalURL: &originalURL})
+	suite.ErrorIs(err, apperrors.ErrShortCodeNotFound)
+}
+
+func (suite *UpdateServiceSuite) TestExpiredNotFound() {
+	originalURL := "https://www.foo.com"
+	suite.expectExisting("", nil, &model.URLRecordEntity{
+		URLRecord: model.URLRecord{ExpiresAt: time.Now().Add(-time.Hour)},
+	})
+
+	_, err := suite.service.UpdateShortCode(suite.ctx, "", "abc123", UpdateOptions{OriginalURL: &originalURL})
+	suite.ErrorIs(err, apperrors.ErrShortCodeNotFound)
+}
+
+func (suite *UpdateServiceSuite) TestErrorLookup() {
+	originalURL := "https://www.foo.com"
+	suite.urlRecordDAO.
+		EXPECT().
+		GetByShortCodeIncludingInactive(gomock.Any(), "", "abc123", gomock.Nil()).
+		Return(nil, errors.New("database error"))
+
+	_, err := suite.service.UpdateShortCode(suite.ctx, "", "abc123", UpdateOptions{OriginalURL: &originalURL})
+	suite.ErrorIs(err, apperrors.ErrDataStoreUnavailable)
+}
+
+func (suite *UpdateServiceSuite) TestErrorTemplateDoesNotFitStoredDefaults() {
+	// The new URL fits on its own, but not once filled in with the stored
+	// default.
+	cfg := config.GetTestConfig(config.Config{MaxAliasLength: maxAliasLengthForTest, MaxURLLength: 60})
+	service := NewService(suite.dao, &cfg)
+	originalURL := "https://www.foo.com/?campaign={utm_campaign}"
+	suite.expectExisting("", nil, activeRecord(model.URLRecord{TemplateDefaults: model.TemplateValues{"utm_campaign": strings.Repeat("a", 40)}}))
+
+	_, err := service.UpdateShortCode(suite.ctx, "", "abc123", UpdateOptions{OriginalURL: &originalURL})
+	suite.ErrorIs(err, apperrors.ErrURLLengthExceeded)
+}
+
+func (suite *UpdateServiceSuite) TestTemplateUsesStoredDefaults() {
+	originalURL := "https://www.foo.com/?campaign={utm_campaign}"
+	suite.expectExisting("", nil, activeRecord(model.URLRecord{TemplateDefaults: model.TemplateValues{"utm_campaign": "spring"}}))
+	suite.urlRecordDAO.
+		EXPECT().
+		Update(gomock.Any(), "", "abc123", gomock.Nil(), gomock.Any()).
+		Return(&model.URLRecordEntity{
+			URLRecord: model.URLRecord{ShortCode: "abc123", OriginalURL: originalURL},
+		}, nil)
+
+	_, err := suite.service.UpdateShortCode(suite.ctx, "", "abc123", UpdateOptions{OriginalURL: &originalURL})
+	suite.NoError(err)
+}
+
+func (suite *UpdateServiceSuite) TestErrorExpiresAtNotAfterActivation() {
+	activatesAt := time.Now().Add(48 * time.Hour)
+	expiresAt := time.Now().Add(24 * time.Hour)
+	suite.expectExisting("", nil, &model.URLRecordEntity{
+		URLRecord: model.URLRecord{ActivatesAt: &activatesAt, ExpiresAt: time.Now().Add(72 * time.Hour)},
+	})
+
+	_, err := suite.service.UpdateShortCode(suite.ctx, "", "abc123", UpdateOptions{ExpiresAt: &expiresAt})
+	suite.ErrorIs(err, apperrors.ErrInvalidActivation)
+}
+
+func (suite *UpdateServiceSuite) TestUpdateError() {
+	originalURL := "https://www.foo.com"
+	suite.expectExisting("", nil, activeRecord(model.URLRecord{}))
+	suite.urlRecordDAO.
+		EXPECT().
+		Update(gomock.Any(), "", "abc123", gomock.Nil(), gomock.Any()).
+		Return(nil, errors.New("database error"))
+
+	_, err := suite.service.UpdateShortCode(suite.ctx, "", "abc123", UpdateOptions{OriginalURL: &originalURL})
+	suite.ErrorIs(err, apperrors.ErrDataStoreUnavailable)
+}
+
+func (suite *UpdateServiceSuite) TestSuccessAddsProtocol() {
+	originalURL := "www.foo.com/new"
+	suite.expectExisting("", nil, activeRecord(model.URLRecord{}))
+	suite.urlRecordDAO.
+		EXPECT().
+		Update(gomock.Any(), "", "abc123", gomock.Nil(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, shortCode string, _ *string, update model.URLRecordUpdate) (*model.URLRecordEntity, error) {
+			suite.Equal("https://www.foo.com/new", *update.OriginalURL)
+			suite.Nil(update.ExpiresAt)
+			return &model.URLRecordEntity{
+				URLRecord: model.URLRecord{ShortCode: shortCode, OriginalURL: *update.OriginalURL},
+			}, nil
+		})
+
+	entity, err := suite.service.UpdateShortCode(suite.ctx, "", "abc123", UpdateOptions{OriginalURL: &originalURL})
+	suite.NoError(err)
+	suite.Equal("https://www.foo.com/new", entity.OriginalURL)
+}
+
+func (suite *UpdateServiceSuite) TestScopedToOwner() {
+	originalURL := "https://www.foo.com/new"
+	ownerID := "acme"
+	suite.expectExisting("", &ownerID, activeRecord(model.URLRecord{}))
+	suite.urlRecordDAO.
+		EXPECT().
+		Update(gomock.Any(), "", "abc123", &ownerID, gomock.Any()).
+		Return(nil, apperrors.ErrShortCodeNotFound)
+
+	ctx := middleware.WithPrincipal(suite.ctx, &middleware.Principal{OwnerID: ownerID})
+	_, err := suite.service.UpdateShortCode(ctx, "", "abc123", UpdateOptions{OriginalURL: &originalURL})
+	suite.ErrorIs(err, apperrors.ErrShortCodeNotFound)
+}
+
+func (suite *UpdateServiceSuite) TestCustomDomain() {
+	originalURL := "https://www.foo.com/new"
+	suite.expectExisting("go.example.com", nil, activeRecord(model.URLRecord{}))
+	suite.urlRecordDAO.
+		EXPECT().
+		Update(gomock.Any(), "go.example.com", "abc123", gomock.Nil(), gomock.Any()).
+		Return(&model.URLRecordEntity{
+			URLRecord: model.URLRecord{Domain: "go.example.com", ShortCode: "abc123", OriginalURL: originalURL},
+		}, nil)
+
+	entity, err := suite.service.UpdateShortCode(suite.ctx, "go.example.com", "abc123", UpdateOptions{OriginalURL: &originalURL})
+	suite.NoError(err)
+	suite.Equal("go.example.com", entity.Domain)
+}
@@ -1,26 +1,171 @@
 package test
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
 
 	"tiny-bitly/internal/config"
 	"tiny-bitly/internal/dao"
+	"tiny-bitly/internal/geoip"
 	"tiny-bitly/internal/middleware"
+	"tiny-bitly/internal/model"
+	"tiny-bitly/internal/router"
+	"tiny-bitly/internal/service/apikey"
 	"tiny-bitly/internal/service/create"
+	"tiny-bitly/internal/service/domain"
 	"tiny-bitly/internal/service/health"
+	"tiny-bitly/internal/service/list"
 	"tiny-bitly/internal/service/read"
+	"tiny-bitly/internal/service/remove"
+	"tiny-bitly/internal/service/update"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// testServer is an API server backed by the memory DAO and routed by the same
+// router as cmd/server, so that tests exercise the real routes and API key
+// authentication.
+type testServer struct {
+	*httptest.Server
+
+	config        *config.Config
+	appDAO        *dao.DAO
+	apiKeyService *apikey.Service
+
+	// A client that doesn't follow redirects, so that tests can check them.
+	client *http.Client
+
+	// The API key that newRequest authenticates requests with.
+	apiKey string
+}
+
+// Starts a test server with the provided config, on top of the test defaults.
+// The server is closed when the test finishes.
+func newTestServer(t *testing.T, cfg config.Config) *testServer {
+	t.Helper()
+
+	testConfig := config.GetTestConfig(cfg)
+
+	// Initialize dependencies as cmd/server does.
+	appDAO := dao.NewMemoryDAO()
+	apiKeyService := apikey.NewService(*appDAO, &testConfig)
+	readService := read.NewService(*appDAO, &testConfig)
+	if testConfig.GeoIPDatabasePath != "" {
+		geoIPDatabase, err := geoip.Open(testConfig.GeoIPDatabasePath)
+		require.NoError(t, err)
+		t.Cleanup(func() { geoIPDatabase.Close() })
+		readService.SetGeoIPDatabase(geoIPDatabase)
+	}
+
+	authenticate := func(next http.Handler) http.Handler {
+		return middleware.APIKeyAuthMiddleware(next, apiKeyService.Authenticate, testConfig.RequireAPIKey)
+	}
+	mux := router.New(
+		authenticate,
+		create.NewService(*appDAO, &testConfig),
+		readService,
+		list.NewService(*appDAO, &testConfig),
+		update.NewService(*appDAO, &testConfig),
+		remove.NewService(*appDAO, &testConfig),
+		health.NewService(*appDAO),
+	)
+	server := httptest.NewServer(middleware.RequestIDMiddleware(mux))
+	t.Cleanup(server.Close)
+
+	apiKey, err := apiKeyService.CreateAPIKey(context.Background(), "integration-test", "")
+	require.NoError(t, err)
+
+	return &testServer{
+		Server:        server,
+		config:        &testConfig,
+		appDAO:        appDAO,
+		apiKeyService: apiKeyService,
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		apiKey: apiKey.Key,
+	}
+}
+
+// Returns a request for the path on the server, authenticated with the
+// server's API key. A non-empty body is sent as JSON.
+func (s *testServer) newRequest(t *testing.T, method string, path string, body string) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req
+}
+
+// Sends the request without following redirects. The caller closes the
+// response body.
+func (s *testServer) do(t *testing.T, req *http.Request) *http.Response {
+	t.Helper()
+
+	resp, err := s.client.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// Sends an authenticated GET request for the path. The caller closes the
+// response body.
+func (s *testServer) get(t *testing.T, path string) *http.Response {
+	t.Helper()
+	return s.do(t, s.newRequest(t, http.MethodGet, path, ""))
+}
+
+// Creates a short URL with the server's API key. Returns the response status
+// code and, if it was created, the short URL.
+func (s *testServer) createShortURL(t *testing.T, body map[string]any) (int, string) {
+	t.Helper()
+
+	reqBody, err := json.Marshal(body)
+	require.NoError(t, err)
+	resp := s.do(t, s.newRequest(t, http.MethodPost, "/urls", string(reqBody)))
+	defer resp.Body.Close()
+
+	var createResp create.CreateURLResponse
+	if resp.StatusCode == http.StatusCreated {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&createResp))
+	}
+	return resp.StatusCode, createResp.ShortURL
+}
+
+// Gets the metadata of the short code with the server's API key.
+func (s *testServer) getMetadata(t *testing.T, shortCode string) read.URLMetadataResponse {
+	t.Helper()
+
+	resp := s.get(t, "/urls/"+shortCode)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var metadata read.URLMetadataResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&metadata))
+	return metadata
+}
+
+// Returns the short code at the end of a short URL.
+func shortCodeOf(shortURL string) string {
+	return shortURL[strings.LastIndex(shortURL, "/")+1:]
+}
+
 // TestIntegration_HealthReadyCreateGetExpire tests the full flow:
 // 1. Health and ready checks
 // 2. Create a short URL
@@ -28,135 +173,1196 @@ import (
 // 4. Wait for expiration
 // 5. Verify it's no longer accessible
 func TestIntegration_HealthReadyCreateGetExpire(t *testing.T) {
-	// Use a very short TTL for testing (100ms) to avoid long test times
+	// Use a very short TTL for testing (200ms) to avoid long test times
 	shortTTL := 200 * time.Millisecond
-	testConfig := config.GetTestConfig(config.Config{
-		APIHostname:  "http://localhost:8080",
-		ShortCodeTTL: shortTTL,
-	})
+	server := newTestServer(t, config.Config{ShortCodeTTL: shortTTL})
 
-	// Initialize dependencies.
-	appDAO := dao.NewMemoryDAO()
-	createService := create.NewService(*appDAO, &testConfig)
-	readService := read.NewService(*appDAO, &testConfig)
-	healthService := health.NewService(*appDAO)
-
-	// Build router.
-	mux := http.NewServeMux()
-	mux.HandleFunc("GET /health", health.NewGetHealthHandler(healthService))
-	mux.HandleFunc("GET /ready", health.NewGetReadyHandler(healthService))
-	mux.HandleFunc("POST /urls", create.NewPostURLHandler(createService))
-	mux.HandleFunc("GET /{shortCode}", read.NewGetURLHandler(readService))
-
-	// Apply middleware.
-	handler := middleware.RequestIDMiddleware(mux)
-
-	// Create test server.
-	server := httptest.NewServer(handler)
-	defer server.Close()
-
-	client := &http.Client{
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			// Don't follow redirects automatically, we want to check the status code
-			return http.ErrUseLastResponse
-		},
-		Timeout: 5 * time.Second,
+	// Step 1: Test health and ready endpoints
+	for _, tc := range []struct {
+		path           string
+		expectedStatus string
+	}{
+		{"/health", "healthy"},
+		{"/ready", "ready"},
+	} {
+		t.Run("Check "+tc.path, func(t *testing.T) {
+			resp := server.get(t, tc.path)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+			var statusResp struct {
+				Status string `json:"status"`
+			}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&statusResp))
+			assert.Equal(t, tc.expectedStatus, statusResp.Status)
+		})
 	}
 
-	// Step 1: Test health endpoint
-	t.Run("Health check", func(t *testing.T) {
-		resp, err := client.Get(server.URL + "/health")
+	// Step 2: Create a short URL
+	var shortCode string
+	originalURL := "https://www.example.com/test"
+	t.Run("Create short URL", func(t *testing.T) {
+		status, shortURL := server.createShortURL(t, map[string]any{"url": originalURL})
+		require.Equal(t, http.StatusCreated, status)
+		shortCode = shortCodeOf(shortURL)
+		require.NotEmpty(t, shortCode)
+	})
+
+	// Step 3: Get the short URL (should redirect)
+	t.Run("Get short URL before expiration", func(t *testing.T) {
+		resp := server.get(t, "/"+shortCode)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+		assert.Equal(t, originalURL, resp.Header.Get("Location"))
+	})
+
+	// Step 4: Wait slightly longer than the TTL, then verify the short URL is no
+	// longer accessible
+	t.Run("Get short URL after expiration", func(t *testing.T) {
+		time.Sleep(shortTTL + 50*time.Millisecond)
+
+		resp := server.get(t, "/"+shortCode)
+		defer resp.Body.Close()
+
+		// Should return 410 after expiration, unlike a short code that never existed
+		assert.Equal(t, http.StatusGone, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
 		require.NoError(t, err)
+		assert.Contains(t, string(body), "Short code has expired")
+	})
+}
+
+// TestIntegration_CreateDeleteGet tests that a deleted short URL stops
+// resolving immediately, reports 410 Gone in its metadata, and cannot be
+// deleted twice.
+func TestIntegration_CreateDeleteGet(t *testing.T) {
+	server := newTestServer(t, config.Config{})
+
+	shortCode := "deleteme"
+	status, _ := server.createShortURL(t, map[string]any{"url": "https://www.example.com/delete", "alias": shortCode})
+	require.Equal(t, http.StatusCreated, status)
+
+	t.Run("Get metadata before deletion", func(t *testing.T) {
+		metadata := server.getMetadata(t, shortCode)
+		assert.Equal(t, "https://www.example.com/delete", metadata.OriginalURL)
+		assert.Equal(t, shortCode, metadata.ShortCode)
+		assert.True(t, strings.HasSuffix(metadata.ShortURL, "/"+shortCode))
+	})
+
+	// Each step depends on the ones before it.
+	for _, tc := range []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{"Delete short URL", http.MethodDelete, "/urls/" + shortCode, http.StatusNoContent},
+		{"Get short URL after deletion", http.MethodGet, "/" + shortCode, http.StatusNotFound},
+		{"Get metadata after deletion", http.MethodGet, "/urls/" + shortCode, http.StatusGone},
+		{"Delete short URL again", http.MethodDelete, "/urls/" + shortCode, http.StatusNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := server.do(t, server.newRequest(t, tc.method, tc.path, ""))
+			resp.Body.Close()
+			assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+		})
+	}
+}
+
+// TestIntegration_CreateUpdateGet tests that retargeting a short URL changes
+// where it redirects without changing the short code.
+func TestIntegration_CreateUpdateGet(t *testing.T) {
+	server := newTestServer(t, config.Config{})
+
+	shortCode := "retarget"
+	status, _ := server.createShortURL(t, map[string]any{"url": "https://www.example.com/typo", "alias": shortCode})
+	require.Equal(t, http.StatusCreated, status)
+
+	newURL := "https://www.example.com/fixed"
+	t.Run("Update short URL", func(t *testing.T) {
+		resp := server.do(t, server.newRequest(t, http.MethodPatch, "/urls/"+shortCode, `{"originalUrl": "`+newURL+`"}`))
 		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
 
-		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var updateResp read.URLMetadataResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&updateResp))
+		assert.Equal(t, newURL, updateResp.OriginalURL)
+		assert.Equal(t, shortCode, updateResp.ShortCode)
 
-		var healthResp struct {
-			Status string `json:"status"`
+		// The update responds with the same metadata as GET /urls/{shortCode}.
+		assert.Equal(t, server.getMetadata(t, shortCode), updateResp)
+	})
+
+	t.Run("Get short URL after update", func(t *testing.T) {
+		resp := server.get(t, "/"+shortCode)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+		assert.Equal(t, newURL, resp.Header.Get("Location"))
+	})
+}
+
+// TestIntegration_CreateList tests that listing pages through every created
+// short URL exactly once and applies filters.
+func TestIntegration_CreateList(t *testing.T) {
+	server := newTestServer(t, config.Config{})
+
+	listURLs := func(t *testing.T, query string) list.ListURLsResponse {
+		resp := server.get(t, "/urls?"+query)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var listResp list.ListURLsResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&listResp))
+		return listResp
+	}
+
+	// Create short URLs on two hosts.
+	numURLs := 5
+	for i := range numURLs {
+		host := "a.example.com"
+		if i%2 == 1 {
+			host = "b.example.com"
 		}
-		err = json.NewDecoder(resp.Body).Decode(&healthResp)
-		require.NoError(t, err)
-		assert.Equal(t, "healthy", healthResp.Status)
+		status, _ := server.createShortURL(t, map[string]any{"url": fmt.Sprintf("https://%s/%d", host, i)})
+		require.Equal(t, http.StatusCreated, status)
+	}
+
+	t.Run("List all pages", func(t *testing.T) {
+		seen := map[string]bool{}
+		query := "limit=2"
+		numPages := 0
+		for {
+			listResp := listURLs(t, query)
+			numPages++
+			for _, u := range listResp.URLs {
+				assert.False(t, seen[u.ShortCode], "short code listed twice")
+				seen[u.ShortCode] = true
+			}
+			if listResp.NextCursor == nil {
+				break
+			}
+			query = "limit=2&cursor=" + *listResp.NextCursor
+		}
+		assert.Len(t, seen, numURLs)
+		assert.Equal(t, 3, numPages)
 	})
 
-	// Step 2: Test ready endpoint
-	t.Run("Ready check", func(t *testing.T) {
-		resp, err := client.Get(server.URL + "/ready")
-		require.NoError(t, err)
+	t.Run("List by host", func(t *testing.T) {
+		listResp := listURLs(t, "host=B.example.com")
+		assert.Len(t, listResp.URLs, 2)
+		for _, u := range listResp.URLs {
+			assert.Contains(t, u.OriginalURL, "b.example.com")
+		}
+	})
+
+	t.Run("List expired", func(t *testing.T) {
+		assert.Empty(t, listURLs(t, "status=expired").URLs)
+	})
+
+	t.Run("List with invalid filter", func(t *testing.T) {
+		resp := server.get(t, "/urls?createdAfter=yesterday")
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestIntegration_CreateBatchGet(t *testing.T) {
+	server := newTestServer(t, config.Config{})
+
+	// Take an alias with a single create.
+	status, _ := server.createShortURL(t, map[string]any{"url": "https://www.example.com/taken", "alias": "taken"})
+	require.Equal(t, http.StatusCreated, status)
+
+	// Create a batch with partial failures.
+	var batchResp create.BatchCreateURLResponse
+	t.Run("Create batch", func(t *testing.T) {
+		reqBody := `[
+			{"url": "https://www.example.com/a"},
+			{"url": "not a url"},
+			{"url": "https://www.example.com/b", "alias": "taken"},
+			{"url": "https://www.example.com/c", "alias": "fresh"}
+		]`
+		resp := server.do(t, server.newRequest(t, http.MethodPost, "/urls/batch", reqBody))
 		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
 
-		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&batchResp))
+		require.Len(t, batchResp.Results, 4)
+
+		assert.NotNil(t, batchResp.Results[0].ShortURL)
+		require.NotNil(t, batchResp.Results[1].Error)
+		assert.Equal(t, http.StatusBadRequest, batchResp.Results[1].Error.StatusCode)
+		require.NotNil(t, batchResp.Results[2].Error)
+		assert.Equal(t, http.StatusConflict, batchResp.Results[2].Error.StatusCode)
+		require.NotNil(t, batchResp.Results[3].ShortURL)
+		assert.Equal(t, server.URL+"/fresh", *batchResp.Results[3].ShortURL)
+	})
 
-		var readyResp struct {
-			Status string `json:"status"`
+	t.Run("Get created short URLs", func(t *testing.T) {
+		for i, originalURL := range map[int]string{0: "https://www.example.com/a", 3: "https://www.example.com/c"} {
+			resp := server.get(t, "/"+shortCodeOf(*batchResp.Results[i].ShortURL))
+			resp.Body.Close()
+			assert.Equal(t, http.StatusFound, resp.StatusCode)
+			assert.Equal(t, originalURL, resp.Header.Get("Location"))
 		}
-		err = json.NewDecoder(resp.Body).Decode(&readyResp)
-		require.NoError(t, err)
-		assert.Equal(t, "ready", readyResp.Status)
 	})
 
-	// Step 3: Create a short URL
+	t.Run("Reject empty batch", func(t *testing.T) {
+		resp := server.do(t, server.newRequest(t, http.MethodPost, "/urls/batch", "[]"))
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestIntegration_CreateIdempotent(t *testing.T) {
+	server := newTestServer(t, config.Config{})
+
+	postURL := func(t *testing.T, idempotencyKey string, body string) (int, string) {
+		req := server.newRequest(t, http.MethodPost, "/urls", body)
+		if idempotencyKey != "" {
+			req.Header.Set(create.IdempotencyKeyHeader, idempotencyKey)
+		}
+		resp := server.do(t, req)
+		defer resp.Body.Close()
+
+		var createResp create.CreateURLResponse
+		if resp.StatusCode == http.StatusCreated {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&createResp))
+		}
+		return resp.StatusCode, createResp.ShortURL
+	}
+
+	status, firstShortURL := postURL(t, "retry-me", `{"url": "https://www.example.com/idempotent"}`)
+	require.Equal(t, http.StatusCreated, status)
+	require.NotEmpty(t, firstShortURL)
+
+	// Each step depends on the ones before it.
+	for _, tc := range []struct {
+		name             string
+		idempotencyKey   string
+		body             string
+		expectedStatus   int
+		expectedShortURL bool
+	}{
+		{"Replay with same key and an equivalent body", "retry-me", `{ "url":"https://www.example.com/idempotent" }`, http.StatusCreated, true},
+		{"Reject key reused with different body", "retry-me", `{"url": "https://www.example.com/other"}`, http.StatusUnprocessableEntity, false},
+		{"Create without key", "", `{"url": "https://www.example.com/idempotent"}`, http.StatusCreated, false},
+		{"Fail with key", "fix-me", `{"url": "https://www.example.com/fixed", "ttlSeconds": 0}`, http.StatusBadRequest, false},
+		{"Retry after failure", "fix-me", `{"url": "https://www.example.com/fixed"}`, http.StatusCreated, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			status, shortURL := postURL(t, tc.idempotencyKey, tc.body)
+			require.Equal(t, tc.expectedStatus, status)
+			assert.Equal(t, tc.expectedShortURL, shortURL == firstShortURL)
+		})
+	}
+}
+
+func TestIntegration_CreateDeduplicate(t *testing.T) {
+	server := newTestServer(t, config.Config{DeduplicateURLs: true})
+
+	createShortURL := func(t *testing.T, body map[string]any) string {
+		status, shortURL := server.createShortURL(t, body)
+		require.Equal(t, http.StatusCreated, status)
+		return shortURL
+	}
+
+	var firstShortURL string
+	t.Run("Reuse for equivalent URL", func(t *testing.T) {
+		firstShortURL = createShortURL(t, map[string]any{"url": "https://help.example.com/faq"})
+		assert.Equal(t, firstShortURL, createShortURL(t, map[string]any{"url": "HTTPS://Help.Example.com:443/faq"}))
+	})
+
+	t.Run("Opt out per request", func(t *testing.T) {
+		assert.NotEqual(t, firstShortURL, createShortURL(t, map[string]any{"url": "https://help.example.com/faq", "deduplicate": false}))
+	})
+
+	t.Run("Reindex on update", func(t *testing.T) {
+		shortURL := createShortURL(t, map[string]any{"url": "https://help.example.com/old"})
+		resp := server.do(t, server.newRequest(t, http.MethodPatch, "/urls/"+shortCodeOf(shortURL), `{"originalUrl": "https://help.example.com/new"}`))
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.NotEqual(t, shortURL, createShortURL(t, map[string]any{"url": "https://help.example.com/old"}))
+		assert.Equal(t, shortURL, createShortURL(t, map[string]any{"url": "https://help.example.com/new"}))
+	})
+
+	t.Run("Skip deleted", func(t *testing.T) {
+		shortURL := createShortURL(t, map[string]any{"url": "https://help.example.com/deleted"})
+		resp := server.do(t, server.newRequest(t, http.MethodDelete, "/urls/"+shortCodeOf(shortURL), ""))
+		resp.Body.Close()
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		assert.NotEqual(t, shortURL, createShortURL(t, map[string]any{"url": "https://help.example.com/deleted"}))
+	})
+}
+
+// TestIntegration_APIKeyOwnership tests that API keys are required and that
+// each owner sees and manages only their own short URLs.
+func TestIntegration_APIKeyOwnership(t *testing.T) {
+	server := newTestServer(t, config.Config{})
+
+	ctx := context.Background()
+	aliceKey, err := server.apiKeyService.CreateAPIKey(ctx, "alice", "alice's key")
+	require.NoError(t, err)
+	bobKey, err := server.apiKeyService.CreateAPIKey(ctx, "bob", "bob's key")
+	require.NoError(t, err)
+
+	// Sends a request authenticated with the key, or unauthenticated if it is
+	// empty.
+	do := func(t *testing.T, method string, path string, body string, key string) *http.Response {
+		req := server.newRequest(t, method, path, body)
+		req.Header.Del("Authorization")
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		return server.do(t, req)
+	}
+
+	t.Run("Reject missing or invalid key", func(t *testing.T) {
+		for _, route := range []struct {
+			method string
+			path   string
+		}{
+			{http.MethodPost, "/urls"},
+			{http.MethodPost, "/urls/batch"},
+			{http.MethodGet, "/urls"},
+			{http.MethodGet, "/urls/anything"},
+			{http.MethodPost, "/urls/anything/rules/dry-run"},
+			{http.MethodPatch, "/urls/anything"},
+			{http.MethodDelete, "/urls/anything"},
+		} {
+			resp := do(t, route.method, route.path, `{}`, "")
+			resp.Body.Close()
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, route.method+" "+route.path)
+			assert.NotEmpty(t, resp.Header.Get("WWW-Authenticate"))
+		}
+
+		resp := do(t, http.MethodGet, "/urls", "", "tb_not-a-real-key")
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	// Alice creates a short URL, which redirects without a key.
 	var shortCode string
-	var originalURL = "https://www.example.com/test"
-	t.Run("Create short URL", func(t *testing.T) {
-		createReq := map[string]string{
-			"url": originalURL,
+	t.Run("Create with key", func(t *testing.T) {
+		resp := do(t, http.MethodPost, "/urls", `{"url": "https://www.example.com/alice"}`, aliceKey.Key)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		var createResp create.CreateURLResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&createResp))
+		shortCode = shortCodeOf(createResp.ShortURL)
+
+		redirectResp := do(t, http.MethodGet, "/"+shortCode, "", "")
+		redirectResp.Body.Close()
+		assert.Equal(t, http.StatusFound, redirectResp.StatusCode)
+	})
+
+	t.Run("List is scoped to owner", func(t *testing.T) {
+		countURLs := func(key string) int {
+			resp := do(t, http.MethodGet, "/urls", "", key)
+			defer resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+
+			var listResp list.ListURLsResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&listResp))
+			return len(listResp.URLs)
 		}
-		reqBody, err := json.Marshal(createReq)
-		require.NoError(t, err)
+		assert.Equal(t, 1, countURLs(aliceKey.Key))
+		assert.Equal(t, 0, countURLs(bobKey.Key))
+	})
+
+	t.Run("Metadata is scoped to owner", func(t *testing.T) {
+		resp := do(t, http.MethodGet, "/urls/"+shortCode, "", bobKey.Key)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		resp = do(t, http.MethodPost, "/urls/"+shortCode+"/rules/dry-run", `{}`, bobKey.Key)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		resp = do(t, http.MethodGet, "/urls/"+shortCode, "", aliceKey.Key)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Delete is scoped to owner", func(t *testing.T) {
+		resp := do(t, http.MethodDelete, "/urls/"+shortCode, "", bobKey.Key)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		resp = do(t, http.MethodDelete, "/urls/"+shortCode, "", aliceKey.Key)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	})
+
+	t.Run("Reject revoked key", func(t *testing.T) {
+		require.NoError(t, server.apiKeyService.RevokeAPIKey(ctx, bobKey.APIKey.ID))
+
+		resp := do(t, http.MethodGet, "/urls", "", bobKey.Key)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
+
+// TestIntegration_Quotas tests that an owner's active links and daily creates
+// are limited.
+func TestIntegration_Quotas(t *testing.T) {
+	server := newTestServer(t, config.Config{
+		QuotaCreatesPerDay:  3,
+		QuotaMaxActiveLinks: 2,
+	})
+
+	aliceKey, err := server.apiKeyService.CreateAPIKey(context.Background(), "alice", "")
+	require.NoError(t, err)
+	bobKey, err := server.apiKeyService.CreateAPIKey(context.Background(), "bob", "")
+	require.NoError(t, err)
+
+	do := func(t *testing.T, method string, path string, body string, key string) *http.Response {
+		req := server.newRequest(t, method, path, body)
+		req.Header.Set("Authorization", "Bearer "+key)
+		return server.do(t, req)
+	}
+
+	createShortCode := func(t *testing.T, key string) (int, string) {
+		resp := do(t, http.MethodPost, "/urls", `{"url": "https://www.example.com"}`, key)
+		defer resp.Body.Close()
+
+		var createResp create.CreateURLResponse
+		json.NewDecoder(resp.Body).Decode(&createResp)
+		return resp.StatusCode, shortCodeOf(createResp.ShortURL)
+	}
+
+	// Step 1: Alice reaches her active link quota
+	var shortCode string
+	t.Run("Active link quota", func(t *testing.T) {
+		status, _ := createShortCode(t, aliceKey.Key)
+		require.Equal(t, http.StatusCreated, status)
+		status, shortCode = createShortCode(t, aliceKey.Key)
+		require.Equal(t, http.StatusCreated, status)
 
-		resp, err := client.Post(server.URL+"/urls", "application/json", bytes.NewBuffer(reqBody))
+		status, _ = createShortCode(t, aliceKey.Key)
+		assert.Equal(t, http.StatusForbidden, status)
+	})
+
+	// Step 2: Other owners are unaffected
+	t.Run("Quotas are per owner", func(t *testing.T) {
+		status, _ := createShortCode(t, bobKey.Key)
+		assert.Equal(t, http.StatusCreated, status)
+	})
+
+	// Step 3: Deleting a link frees up active link quota, until the daily quota
+	// runs out
+	t.Run("Daily create quota", func(t *testing.T) {
+		resp := do(t, http.MethodDelete, "/urls/"+shortCode, "", aliceKey.Key)
+		resp.Body.Close()
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		status, shortCode := createShortCode(t, aliceKey.Key)
+		require.Equal(t, http.StatusCreated, status)
+
+		resp = do(t, http.MethodDelete, "/urls/"+shortCode, "", aliceKey.Key)
+		resp.Body.Close()
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		status, _ = createShortCode(t, aliceKey.Key)
+		assert.Equal(t, http.StatusTooManyRequests, status)
+	})
+}
+
+// TestIntegration_CustomDomains tests that the same short code can exist on the
+// default domain and on a custom domain, and that redirects resolve it on the
+// domain they were sent to, ignoring forwarded hosts from untrusted clients.
+func TestIntegration_CustomDomains(t *testing.T) {
+	server := newTestServer(t, config.Config{})
+
+	customDomain := "go.example.com"
+	_, err := domain.NewService(*server.appDAO, server.config).AddDomain(context.Background(), customDomain, nil)
+	require.NoError(t, err)
+
+	resolve := func(t *testing.T, host string, shortCode string) *http.Response {
+		req := server.newRequest(t, http.MethodGet, "/"+shortCode, "")
+		req.Host = host
+		return server.do(t, req)
+	}
+
+	shortCode := "promo"
+	t.Run("Create on both domains", func(t *testing.T) {
+		status, shortURL := server.createShortURL(t, map[string]any{"url": "https://www.example.com/default", "alias": shortCode})
+		require.Equal(t, http.StatusCreated, status)
+		assert.True(t, strings.HasSuffix(shortURL, "/"+shortCode))
+		assert.NotContains(t, shortURL, customDomain)
+
+		status, shortURL = server.createShortURL(t, map[string]any{"url": "https://www.example.com/custom", "alias": shortCode, "domain": "GO.example.com"})
+		require.Equal(t, http.StatusCreated, status)
+		assert.Equal(t, "https://go.example.com/promo", shortURL)
+	})
+
+	t.Run("Resolve by host", func(t *testing.T) {
+		resp := resolve(t, customDomain, shortCode)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+		assert.Equal(t, "https://www.example.com/custom", resp.Header.Get("Location"))
+
+		resp = resolve(t, "localhost:8080", shortCode)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+		assert.Equal(t, "https://www.example.com/default", resp.Header.Get("Location"))
+	})
+
+	t.Run("Forwarded host from an untrusted client is ignored", func(t *testing.T) {
+		req := server.newRequest(t, http.MethodGet, "/"+shortCode, "")
+		req.Host = "localhost:8080"
+		req.Header.Set("X-Forwarded-Host", customDomain)
+		resp := server.do(t, req)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+		assert.Equal(t, "https://www.example.com/default", resp.Header.Get("Location"))
+	})
+
+	t.Run("Get metadata on custom domain", func(t *testing.T) {
+		metadata := server.getMetadata(t, shortCode+"?domain="+customDomain)
+		assert.Equal(t, customDomain, metadata.Domain)
+		assert.Equal(t, "https://go.example.com/promo", metadata.ShortURL)
+	})
+
+	t.Run("Delete on custom domain", func(t *testing.T) {
+		resp := server.do(t, server.newRequest(t, http.MethodDelete, "/urls/"+shortCode+"?domain="+customDomain, ""))
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		resp = resolve(t, customDomain, shortCode)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		resp = resolve(t, "localhost:8080", shortCode)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+	})
+}
+
+// TestIntegration_PasswordProtected tests the password-protected redirect flow:
+// 1. Get it (verify the password form is shown instead of a redirect)
+// 2. Submit an incorrect password (verify the form is shown again)
+// 3. Submit the correct password (verify redirect and unlock cookie)
+// 4. Get it with the unlock cookie (verify redirect)
+// 5. Exhaust the failed attempt limit (verify further attempts are rejected)
+// 6. Make concurrent failed attempts (verify only the limit's worth are checked)
+func TestIntegration_PasswordProtected(t *testing.T) {
+	server := newTestServer(t, config.Config{
+		LinkPasswordCookieSecret:       "test-secret",
+		LinkPasswordMaxFailuresPerCode: 3,
+	})
+
+	originalURL := "https://www.example.com/secret"
+	status, _ := server.createShortURL(t, map[string]any{"url": originalURL, "alias": "secret", "password": "correct horse"})
+	require.Equal(t, http.StatusCreated, status)
+
+	submitPassword := func(t *testing.T, password string) *http.Response {
+		resp, err := server.client.PostForm(server.URL+"/secret", url.Values{"password": {password}})
 		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Get shows password form", func(t *testing.T) {
+		resp := server.get(t, "/secret")
 		defer resp.Body.Close()
 
-		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Location"))
+		assert.Equal(t, "private, no-store", resp.Header.Get("Cache-Control"))
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), `name="password"`)
+		assert.NotContains(t, string(body), originalURL)
+	})
 
-		var createResp struct {
-			ShortURL string `json:"shortUrl"`
+	t.Run("Incorrect password", func(t *testing.T) {
+		resp := submitPassword(t, "wrong")
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Location"))
+		assert.Empty(t, resp.Cookies())
+	})
+
+	var unlockCookie *http.Cookie
+	t.Run("Correct password", func(t *testing.T) {
+		resp := submitPassword(t, "correct horse")
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+		assert.Equal(t, originalURL, resp.Header.Get("Location"))
+		require.Len(t, resp.Cookies(), 1)
+		unlockCookie = resp.Cookies()[0]
+		assert.Equal(t, "/secret", unlockCookie.Path)
+		assert.True(t, unlockCookie.HttpOnly)
+	})
+
+	t.Run("Get with unlock cookie redirects", func(t *testing.T) {
+		req := server.newRequest(t, http.MethodGet, "/secret", "")
+		req.AddCookie(unlockCookie)
+		resp := server.do(t, req)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+		assert.Equal(t, originalURL, resp.Header.Get("Location"))
+		assert.Equal(t, "private, no-store", resp.Header.Get("Cache-Control"))
+	})
+
+	t.Run("Too many failed attempts", func(t *testing.T) {
+		// One failed attempt was already made above.
+		for i := 0; i < 2; i++ {
+			resp := submitPassword(t, "wrong")
+			resp.Body.Close()
+			require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
 		}
-		err = json.NewDecoder(resp.Body).Decode(&createResp)
-		require.NoError(t, err)
-		require.NotEmpty(t, createResp.ShortURL)
-
-		// Extract short code from the short URL
-		// Format: http://localhost:8080/{shortCode}
-		// Find the last '/' and take everything after it
-		slashIndex := strings.LastIndex(createResp.ShortURL, "/")
-		require.Greater(t, slashIndex, -1, "Short URL should contain a slash")
-		shortCode = createResp.ShortURL[slashIndex+1:]
-		require.NotEmpty(t, shortCode)
+
+		// Even the correct password is rejected once the limit is reached.
+		resp := submitPassword(t, "correct horse")
+		resp.Body.Close()
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Empty(t, resp.Cookies())
 	})
 
-	// Step 4: Get the short URL (should redirect)
-	t.Run("Get short URL before expiration", func(t *testing.T) {
-		resp, err := client.Get(server.URL + "/" + shortCode)
+	t.Run("Concurrent failed attempts stay within the limit", func(t *testing.T) {
+		status, _ := server.createShortURL(t, map[string]any{"url": originalURL, "alias": "vault", "password": "correct horse"})
+		require.Equal(t, http.StatusCreated, status)
+
+		attempts := 12
+		statusCodes := make(chan int, attempts)
+		for range attempts {
+			go func() {
+				resp, err := server.client.PostForm(server.URL+"/vault", url.Values{"password": {"wrong"}})
+				if err != nil {
+					statusCodes <- 0
+					return
+				}
+				resp.Body.Close()
+				statusCodes <- resp.StatusCode
+			}()
+		}
+
+		checked := 0
+		for range attempts {
+			switch <-statusCodes {
+			case http.StatusUnauthorized:
+				checked++
+			case http.StatusTooManyRequests:
+			default:
+				t.Error("Unexpected status code")
+			}
+		}
+		assert.Equal(t, 3, checked)
+	})
+}
+
+// TestIntegration_ClickLimited tests that a click-limited short URL:
+// 1. Resolves exactly maxClicks times, even under concurrent clicks
+// 2. Then behaves like an expired short URL
+// 3. Does not use up clicks on visits that cannot be redirected
+func TestIntegration_ClickLimited(t *testing.T) {
+	server := newTestServer(t, config.Config{})
+
+	maxClicks := 5
+	status, _ := server.createShortURL(t, map[string]any{"url": "https://www.example.com/invite", "alias": "invite", "maxClicks": maxClicks})
+	require.Equal(t, http.StatusCreated, status)
+
+	t.Run("Concurrent clicks resolve exactly maxClicks times", func(t *testing.T) {
+		clicks := 4 * maxClicks
+		statusCodes := make(chan int, clicks)
+		for i := 0; i < clicks; i++ {
+			go func() {
+				resp, err := server.client.Get(server.URL + "/invite")
+				if err != nil {
+					statusCodes <- 0
+					return
+				}
+				resp.Body.Close()
+				if resp.StatusCode == http.StatusFound {
+					// Shared caches must not serve further clicks.
+					assert.Equal(t, "private, no-store", resp.Header.Get("Cache-Control"))
+				}
+				statusCodes <- resp.StatusCode
+			}()
+		}
+
+		redirects := 0
+		for i := 0; i < clicks; i++ {
+			switch <-statusCodes {
+			case http.StatusFound:
+				redirects++
+			case http.StatusGone:
+			default:
+				t.Error("Unexpected status code")
+			}
+		}
+		assert.Equal(t, maxClicks, redirects)
+	})
+
+	t.Run("Behaves like an expired short URL", func(t *testing.T) {
+		for _, path := range []string{"/invite", "/urls/invite"} {
+			resp := server.get(t, path)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusGone, resp.StatusCode, path)
+		}
+	})
+
+	t.Run("Visits that cannot be redirected use up no clicks", func(t *testing.T) {
+		status, _ := server.createShortURL(t, map[string]any{"url": "https://www.example.com/invite?ref={query.ref}", "alias": "template", "maxClicks": 1})
+		require.Equal(t, http.StatusCreated, status)
+
+		// The query parameter makes the destination too long.
+		resp := server.get(t, "/template?ref="+strings.Repeat("x", 3000))
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		metadata := server.getMetadata(t, "template")
+		require.NotNil(t, metadata.RemainingClicks)
+		assert.Equal(t, 1, *metadata.RemainingClicks)
+	})
+}
+
+// TestIntegration_Scheduled tests that a short URL with an activation time:
+// 1. Redirects to the placeholder URL, without revealing the original URL, before activation
+// 2. Redirects to the original URL after activation
+func TestIntegration_Scheduled(t *testing.T) {
+	placeholderURL := "https://www.example.com/coming-soon"
+	server := newTestServer(t, config.Config{ScheduledLinkPlaceholderURL: placeholderURL})
+
+	originalURL := "https://www.example.com/launch"
+	activatesAt := time.Now().Add(time.Second)
+	status, _ := server.createShortURL(t, map[string]any{"url": originalURL, "alias": "launch", "activatesAt": activatesAt})
+	require.Equal(t, http.StatusCreated, status)
+
+	t.Run("Redirects to placeholder before activation", func(t *testing.T) {
+		resp := server.get(t, "/launch")
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
 		require.NoError(t, err)
-		defer resp.Body.Close()
 
-		// Should redirect (302)
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+		assert.Equal(t, placeholderURL, resp.Header.Get("Location"))
+		assert.Equal(t, "private, no-store", resp.Header.Get("Cache-Control"))
+		assert.NotContains(t, string(body), originalURL)
+	})
+
+	t.Run("Metadata includes activation time", func(t *testing.T) {
+		metadata := server.getMetadata(t, "launch")
+		require.NotNil(t, metadata.ActivatesAt)
+		assert.True(t, metadata.ActivatesAt.Equal(activatesAt))
+	})
+
+	t.Run("Redirects to original URL after activation", func(t *testing.T) {
+		time.Sleep(time.Until(activatesAt))
+
+		resp := server.get(t, "/launch")
+		resp.Body.Close()
 		assert.Equal(t, http.StatusFound, resp.StatusCode)
 		assert.Equal(t, originalURL, resp.Header.Get("Location"))
 	})
+}
+
+// TestIntegration_ExpiredRedirect tests that an expired short URL:
+// 1. Redirects to its fallback URL if it has one
+// 2. Returns 410 Gone otherwise, unlike a short URL that never existed
+func TestIntegration_ExpiredRedirect(t *testing.T) {
+	server := newTestServer(t, config.Config{})
+
+	// Use one-time short URLs, which expire after their first click.
+	fallbackURL := "https://www.example.com/offer-ended"
+	for alias, expiredRedirectURL := range map[string]*string{"withfallback": &fallbackURL, "withoutfallback": nil} {
+		status, _ := server.createShortURL(t, map[string]any{
+			"url":                "https://www.example.com/offer",
+			"alias":              alias,
+			"maxClicks":          1,
+			"expiredRedirectUrl": expiredRedirectURL,
+		})
+		require.Equal(t, http.StatusCreated, status)
+
+		resp := server.get(t, "/"+alias)
+		resp.Body.Close()
+		require.Equal(t, http.StatusFound, resp.StatusCode)
+	}
+
+	for _, tc := range []struct {
+		name             string
+		path             string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{"Expired short URL redirects to fallback URL", "/withfallback", http.StatusFound, fallbackURL},
+		{"Expired short URL without fallback returns 410", "/withoutfallback", http.StatusGone, ""},
+		{"Short URL that never existed returns 404", "/neverexisted", http.StatusNotFound, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := server.get(t, tc.path)
+			resp.Body.Close()
+			assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+			assert.Equal(t, tc.expectedLocation, resp.Header.Get("Location"))
+		})
+	}
+}
+
+// TestIntegration_Redirects tests where a visit to a short URL with the
+// provided settings redirects, and the headers that tell caches how to store
+// the redirect. Each case creates the short URL "link" on a fresh server.
+func TestIntegration_Redirects(t *testing.T) {
+	// The fixture database maps 192.0.2.0/24 to DE and 198.51.100.0/24 to FR.
+	// Test requests come from the loopback address, which stands in for the
+	// proxy that sets X-Forwarded-For.
+	geoIPConfig := config.Config{GeoIPDatabasePath: "../geoip/testdata/country-test.mmdb"}
+	trustedGeoIPConfig := geoIPConfig
+	trustedGeoIPConfig.TrustedProxies = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+	geoTargeted := map[string]any{
+		"url":         "https://www.example.com/store",
+		"countryUrls": map[string]string{"DE": "https://www.example.de/store"},
+	}
+	platformTargeted := map[string]any{
+		"url":        "https://www.example.com/app",
+		"iosUrl":     "https://apps.apple.com/app/id123",
+		"androidUrl": "market://details?id=com.example",
+	}
+	passthrough := map[string]any{
+		"url":              "https://www.example.com/landing?utm_source=default",
+		"queryPassthrough": "request",
+		"pathPassthrough":  true,
+	}
+	template := map[string]any{
+		"url":              "https://www.example.com/landing?utm_source={utm_source}&utm_campaign={utm_campaign}&ref={query.ref}",
+		"templateDefaults": map[string]string{"utm_source": "direct", "utm_campaign": "spring-sale"},
+	}
+	redirectRules := map[string]any{
+		"url": "https://www.example.com/",
+		"redirectRules": []map[string]any{
+			{"when": map[string]any{"languages": []string{"de"}, "referrerHosts": []string{"news.example.org"}}, "url": "https://www.example.de/news"},
+			{"when": map[string]any{"languages": []string{"de"}}, "url": "https://www.example.de/"},
+		},
+	}
+
+	for _, tc := range []struct {
+		name             string
+		config           config.Config
+		link             map[string]any
+		path             string
+		header           http.Header
+		expectedStatus   int
+		expectedLocation string
+
+		// Response headers that must have exactly these values.
+		expectedHeaders map[string]string
+	}{
+		// Redirect types
+		{"Server default redirect type", config.Config{DefaultRedirectType: http.StatusMovedPermanently}, map[string]any{"url": "https://www.example.com/"}, "/link", nil, http.StatusMovedPermanently, "https://www.example.com/", nil},
+		{"Permanent redirect", config.Config{DefaultRedirectType: http.StatusMovedPermanently}, map[string]any{"url": "https://www.example.com/", "redirectType": 308}, "/link", nil, http.StatusPermanentRedirect, "https://www.example.com/", nil},
+		{"Temporary redirect", config.Config{DefaultRedirectType: http.StatusMovedPermanently}, map[string]any{"url": "https://www.example.com/", "redirectType": 302}, "/link", nil, http.StatusFound, "https://www.example.com/", nil},
+		{"Method-preserving redirect", config.Config{DefaultRedirectType: http.StatusMovedPermanently}, map[string]any{"url": "https://www.example.com/", "redirectType": 307}, "/link", nil, http.StatusTemporaryRedirect, "https://www.example.com/", nil},
+		{"Click-limited short URL never uses a permanent default", config.Config{DefaultRedirectType: http.StatusMovedPermanently}, map[string]any{"url": "https://www.example.com/", "maxClicks": 5}, "/link", nil, http.StatusFound, "https://www.example.com/", nil},
+
+		// Caching
+		{"Redirect is cached for at most the configured maximum age", config.Config{RedirectCacheMaxAge: 2 * time.Hour}, map[string]any{"url": "https://www.example.com/", "ttlSeconds": 86400}, "/link", nil, http.StatusFound, "https://www.example.com/", map[string]string{"Cache-Control": "public, max-age=7200, s-maxage=7200"}},
+		{"Uncacheable redirect is never cached", config.Config{}, map[string]any{"url": "https://www.example.com/", "uncacheable": true}, "/link", nil, http.StatusFound, "https://www.example.com/", map[string]string{"Cache-Control": "private, no-store", "ETag": ""}},
+
+		// Passthrough
+		{"Query is merged", config.Config{}, passthrough, "/link?utm_source=x&utm_medium=email", nil, http.StatusFound, "https://www.example.com/landing?utm_medium=email&utm_source=x", nil},
+		{"Path is appended", config.Config{}, passthrough, "/link/extra/path", nil, http.StatusFound, "https://www.example.com/landing/extra/path?utm_source=default", nil},
+		{"Path and query are passed through", config.Config{}, passthrough, "/link/extra?utm_source=x", nil, http.StatusFound, "https://www.example.com/landing/extra?utm_source=x", nil},
+		{"Query is ignored without passthrough", config.Config{}, map[string]any{"url": "https://www.example.com/plain?utm_source=default"}, "/link?utm_source=x", nil, http.StatusFound, "https://www.example.com/plain?utm_source=default", nil},
+		{"Path without passthrough returns 404", config.Config{}, map[string]any{"url": "https://www.example.com/plain"}, "/link/extra", nil, http.StatusNotFound, "", nil},
 
-	// Step 5: Wait for expiration
-	t.Run("Wait for expiration", func(t *testing.T) {
-		// Wait slightly longer than TTL to ensure expiration
-		waitTime := shortTTL + 50*time.Millisecond
-		time.Sleep(waitTime)
+		// Template parameters
+		{"Defaults fill in placeholders", config.Config{}, template, "/link", nil, http.StatusFound, "https://www.example.com/landing?utm_source=direct&utm_campaign=spring-sale&ref=", nil},
+		{"Request values override defaults", config.Config{}, template, "/link?utm_source=newsletter&ref=abc", nil, http.StatusFound, "https://www.example.com/landing?utm_source=newsletter&utm_campaign=spring-sale&ref=abc", nil},
+		{"Overlong destination returns 400", config.Config{MaxURLLength: 200}, template, "/link?ref=" + strings.Repeat("x", 200), nil, http.StatusBadRequest, "", nil},
+
+		// Geo-targeting. Shared caches must not serve one country's redirect to
+		// another.
+		{"Visitor from a country with a URL", trustedGeoIPConfig, geoTargeted, "/link", http.Header{"X-Forwarded-For": {"192.0.2.10"}}, http.StatusFound, "https://www.example.de/store", map[string]string{"Cache-Control": "private, max-age=86400"}},
+		{"Visitor from another country", trustedGeoIPConfig, geoTargeted, "/link", http.Header{"X-Forwarded-For": {"198.51.100.1"}}, http.StatusFound, "https://www.example.com/store", map[string]string{"Cache-Control": "private, max-age=86400"}},
+		{"Visitor from an unknown country", trustedGeoIPConfig, geoTargeted, "/link", http.Header{"X-Forwarded-For": {"203.0.113.1"}}, http.StatusFound, "https://www.example.com/store", map[string]string{"Cache-Control": "private, max-age=86400"}},
+		{"Visitor naming a country without a trusted proxy", geoIPConfig, geoTargeted, "/link", http.Header{"X-Forwarded-For": {"192.0.2.10"}}, http.StatusFound, "https://www.example.com/store", map[string]string{"Cache-Control": "private, max-age=86400"}},
+
+		// Platform targeting. CDNs must cache each platform's redirect
+		// separately.
+		{"iOS visitor", config.Config{}, platformTargeted, "/link", http.Header{"User-Agent": {"Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148"}}, http.StatusFound, "https://apps.apple.com/app/id123", map[string]string{"Vary": "Accept-Encoding, User-Agent"}},
+		{"Android visitor", config.Config{}, platformTargeted, "/link", http.Header{"User-Agent": {"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Mobile Safari/537.36"}}, http.StatusFound, "market://details?id=com.example", map[string]string{"Vary": "Accept-Encoding, User-Agent"}},
+		{"Desktop visitor", config.Config{}, platformTargeted, "/link", http.Header{"User-Agent": {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36"}}, http.StatusFound, "https://www.example.com/app", map[string]string{"Vary": "Accept-Encoding, User-Agent"}},
+
+		// Redirect rules. Every visit must reach the server to evaluate them.
+		{"First matching rule decides the destination", config.Config{}, redirectRules, "/link", http.Header{"Accept-Language": {"de-DE,de;q=0.9"}, "Referer": {"https://news.example.org/story"}}, http.StatusFound, "https://www.example.de/news", map[string]string{"Cache-Control": "private, no-store"}},
+		{"Later rule matches", config.Config{}, redirectRules, "/link", http.Header{"Accept-Language": {"de-DE,de;q=0.9"}}, http.StatusFound, "https://www.example.de/", map[string]string{"Cache-Control": "private, no-store"}},
+		{"Visitors matching no rule are redirected as usual", config.Config{}, redirectRules, "/link", http.Header{"Accept-Language": {"en-US"}}, http.StatusFound, "https://www.example.com/", nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, tc.config)
+
+			link := map[string]any{"alias": "link"}
+			for key, value := range tc.link {
+				link[key] = value
+			}
+			status, _ := server.createShortURL(t, link)
+			require.Equal(t, http.StatusCreated, status)
+
+			req := server.newRequest(t, http.MethodGet, tc.path, "")
+			for name, values := range tc.header {
+				req.Header[name] = values
+			}
+			resp := server.do(t, req)
+			resp.Body.Close()
+			assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+			assert.Equal(t, tc.expectedLocation, resp.Header.Get("Location"))
+			for name, value := range tc.expectedHeaders {
+				assert.Equal(t, value, resp.Header.Get(name), name)
+			}
+		})
+	}
+}
+
+// TestIntegration_CreateInvalid tests that short URLs with invalid settings
+// are rejected.
+func TestIntegration_CreateInvalid(t *testing.T) {
+	server := newTestServer(t, config.Config{})
+
+	for _, tc := range []struct {
+		name string
+		link map[string]any
+	}{
+		{"Unregistered domain", map[string]any{"domain": "unknown.example.com"}},
+		{"Unsupported redirect type", map[string]any{"redirectType": 303}},
+		{"Permanent redirect type with password", map[string]any{"redirectType": 301, "password": "secret"}},
+		{"Permanent redirect type for uncacheable short URL", map[string]any{"redirectType": 301, "uncacheable": true}},
+		{"Unsupported query passthrough", map[string]any{"queryPassthrough": "merge"}},
+		{"Unknown placeholder", map[string]any{"url": "https://www.example.com/landing?id={user_id}"}},
+		{"Invalid country code", map[string]any{"countryUrls": map[string]string{"XYZ": "https://www.example.de/store"}}},
+		{"Scheme not allowed for platform", map[string]any{"iosUrl": "intent://open/#Intent;end"}},
+		{"Invalid variant weight", map[string]any{"variants": []map[string]any{
+			{"url": "https://www.example.com/a", "weight": 1},
+			{"url": "https://www.example.com/b", "weight": -1},
+		}}},
+		{"Invalid redirect rule", map[string]any{"redirectRules": []map[string]any{
+			{"when": map[string]any{"timeOfDay": map[string]any{"start": "25:00", "end": "06:00"}}, "url": "https://www.example.com/night"},
+		}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			link := map[string]any{"url": "https://www.example.com/"}
+			for key, value := range tc.link {
+				link[key] = value
+			}
+			status, _ := server.createShortURL(t, link)
+			assert.Equal(t, http.StatusBadRequest, status)
+		})
+	}
+}
+
+// TestIntegration_Metadata tests that the metadata of a short URL includes the
+// settings it was created with.
+func TestIntegration_Metadata(t *testing.T) {
+	server := newTestServer(t, config.Config{})
+
+	variants := []model.URLVariant{{URL: "https://www.example.com/a", Weight: 1}, {URL: "https://www.example.com/b", Weight: 1}}
+	for _, link := range []map[string]any{
+		{"url": "https://www.example.com/landing", "alias": "passthrough", "queryPassthrough": "request", "pathPassthrough": true},
+		{"url": "https://www.example.com/", "alias": "variants", "variants": variants, "stickyVariants": true},
+		{"url": "https://www.example.com/", "alias": "rules", "redirectRules": []map[string]any{
+			{"when": map[string]any{"referrerHosts": []string{"news.example.org"}}, "url": "https://www.example.de/news"},
+		}},
+	} {
+		status, _ := server.createShortURL(t, link)
+		require.Equal(t, http.StatusCreated, status)
+	}
+
+	t.Run("Passthrough settings", func(t *testing.T) {
+		metadata := server.getMetadata(t, "passthrough")
+		require.NotNil(t, metadata.QueryPassthrough)
+		assert.Equal(t, "request", *metadata.QueryPassthrough)
+		assert.True(t, metadata.PathPassthrough)
 	})
 
-	// Step 6: Verify the short URL is no longer accessible
-	t.Run("Get short URL after expiration", func(t *testing.T) {
-		resp, err := client.Get(server.URL + "/" + shortCode)
+	t.Run("Variants", func(t *testing.T) {
+		metadata := server.getMetadata(t, "variants")
+		assert.Equal(t, variants, metadata.Variants)
+		assert.True(t, metadata.StickyVariants)
+	})
+
+	t.Run("Redirect rules", func(t *testing.T) {
+		metadata := server.getMetadata(t, "rules")
+		require.Len(t, metadata.RedirectRules, 1)
+		assert.Equal(t, "https://www.example.de/news", metadata.RedirectRules[0].URL)
+		assert.Equal(t, []string{"news.example.org"}, metadata.RedirectRules[0].When.ReferrerHosts)
+	})
+}
+
+// TestIntegration_RedirectCaching tests the caching headers of redirects,
+// which depend on how long the short URL has left.
+func TestIntegration_RedirectCaching(t *testing.T) {
+	server := newTestServer(t, config.Config{RedirectCacheMaxAge: 2 * time.Hour})
+
+	for _, link := range []map[string]any{
+		{"url": "https://www.example.com/long-lived", "alias": "longlived", "ttlSeconds": 86400},
+		{"url": "https://www.example.com/short-lived", "alias": "shortlived", "ttlSeconds": 600},
+		{"url": "https://www.example.com/permanent", "alias": "permanent", "redirectType": 301, "ttlSeconds": 3600},
+	} {
+		status, _ := server.createShortURL(t, link)
+		require.Equal(t, http.StatusCreated, status)
+	}
+
+	t.Run("Redirect expires with the configured maximum age", func(t *testing.T) {
+		resp := server.get(t, "/longlived")
+		resp.Body.Close()
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+
+		expires, err := http.ParseTime(resp.Header.Get("Expires"))
 		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(2*time.Hour), expires, 5*time.Second)
+		assert.NotEmpty(t, resp.Header.Get("Last-Modified"))
+		assert.NotEmpty(t, resp.Header.Get("ETag"))
+	})
+
+	// The age is rounded down, so it may be a second less if the clock ticked
+	// since the short URL was created.
+	for _, tc := range []struct {
+		name   string
+		path   string
+		maxAge int
+	}{
+		{"Redirect is not cached past expiration", "/shortlived", 599},
+		{"Permanent redirect is cached until expiration", "/permanent", 3599},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := server.get(t, tc.path)
+			resp.Body.Close()
+			assert.Contains(t, []string{
+				fmt.Sprintf("public, max-age=%d, s-maxage=%d", tc.maxAge, tc.maxAge),
+				fmt.Sprintf("public, max-age=%d, s-maxage=%d", tc.maxAge-1, tc.maxAge-1),
+			}, resp.Header.Get("Cache-Control"))
+		})
+	}
+
+	t.Run("Revalidation with a matching ETag returns 304", func(t *testing.T) {
+		resp := server.get(t, "/longlived")
+		resp.Body.Close()
+		etag := resp.Header.Get("ETag")
+
+		req := server.newRequest(t, http.MethodGet, "/longlived", "")
+		req.Header.Set("If-None-Match", etag)
+		resp = server.do(t, req)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+		req.Header.Set("If-None-Match", `"stale"`)
+		resp = server.do(t, req)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+	})
+}
+
+func TestIntegration_SplitTesting(t *testing.T) {
+	server := newTestServer(t, config.Config{})
+
+	variants := []map[string]any{
+		{"url": "https://www.example.com/a", "weight": 1},
+		{"url": "https://www.example.com/b", "weight": 1},
+	}
+	for _, link := range []map[string]any{
+		{"url": "https://www.example.com/", "alias": "split", "variants": variants},
+		{"url": "https://www.example.com/", "alias": "sticky", "variants": variants, "stickyVariants": true},
+		{"url": "https://www.example.com/", "alias": "stickyapp", "variants": variants, "stickyVariants": true, "iosUrl": "https://apps.apple.com/app/id1"},
+	} {
+		status, _ := server.createShortURL(t, link)
+		require.Equal(t, http.StatusCreated, status)
+	}
+
+	t.Run("Visits are split across variants", func(t *testing.T) {
+		locations := map[string]bool{}
+		for range 50 {
+			resp := server.get(t, "/split")
+			resp.Body.Close()
+			require.Equal(t, http.StatusFound, resp.StatusCode)
+			locations[resp.Header.Get("Location")] = true
+
+			// Every click must reach the server to be assigned a variant.
+			assert.Equal(t, "private, no-store", resp.Header.Get("Cache-Control"))
+			assert.Empty(t, resp.Cookies())
+		}
+		assert.Equal(t, map[string]bool{"https://www.example.com/a": true, "https://www.example.com/b": true}, locations)
+	})
+
+	t.Run("Returning visitors keep their sticky variant", func(t *testing.T) {
+		resp := server.get(t, "/sticky")
+		resp.Body.Close()
+		require.Equal(t, http.StatusFound, resp.StatusCode)
+		location := resp.Header.Get("Location")
+		cookies := resp.Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, "/sticky", cookies[0].Path)
+
+		for range 20 {
+			req := server.newRequest(t, http.MethodGet, "/sticky", "")
+			req.AddCookie(cookies[0])
+			resp := server.do(t, req)
+			resp.Body.Close()
+			assert.Equal(t, location, resp.Header.Get("Location"))
+		}
+	})
+
+	t.Run("Visitors sent elsewhere are not assigned a variant", func(t *testing.T) {
+		req := server.newRequest(t, http.MethodGet, "/stickyapp", "")
+		req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)")
+		resp := server.do(t, req)
+		resp.Body.Close()
+		require.Equal(t, http.StatusFound, resp.StatusCode)
+		assert.Equal(t, "https://apps.apple.com/app/id1", resp.Header.Get("Location"))
+		assert.Empty(t, resp.Cookies())
+	})
+}
+
+func TestIntegration_RedirectRulesDryRun(t *testing.T) {
+	server := newTestServer(t, config.Config{})
+
+	status, _ := server.createShortURL(t, map[string]any{
+		"url":   "https://www.example.com/",
+		"alias": "rules",
+		"redirectRules": []map[string]any{
+			{"when": map[string]any{"languages": []string{"de"}, "referrerHosts": []string{"news.example.org"}}, "url": "https://www.example.de/news"},
+			{"when": map[string]any{"languages": []string{"de"}}, "url": "https://www.example.de/"},
+		},
+	})
+	require.Equal(t, http.StatusCreated, status)
+
+	dryRun := func(t *testing.T, shortCode string, body string) (int, read.RedirectRulesDryRunResponse) {
+		resp := server.do(t, server.newRequest(t, http.MethodPost, "/urls/"+shortCode+"/rules/dry-run", body))
 		defer resp.Body.Close()
 
-		// Should return 404 after expiration
-		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		var dryRunResp read.RedirectRulesDryRunResponse
+		if resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&dryRunResp))
+		}
+		return resp.StatusCode, dryRunResp
+	}
 
-		// Verify error message
-		body, err := io.ReadAll(resp.Body)
-		require.NoError(t, err)
-		assert.Contains(t, string(body), "Short code does not exist")
+	t.Run("Dry run explains which rule matches", func(t *testing.T) {
+		status, resp := dryRun(t, "rules", `{"time": "2030-01-01T12:00:00Z", "headers": {"accept-language": "de", "Referer": "https://example.com/"}}`)
+		require.Equal(t, http.StatusOK, status)
+		require.NotNil(t, resp.MatchedRule)
+		assert.Equal(t, 1, *resp.MatchedRule)
+		require.NotNil(t, resp.URL)
+		assert.Equal(t, "https://www.example.de/", *resp.URL)
+		require.Len(t, resp.Rules, 2)
+		assert.False(t, resp.Rules[0].Matched)
+		assert.Equal(t, []model.RedirectConditionResult{{Condition: "languages", Matched: true}, {Condition: "referrerHosts", Matched: false}}, resp.Rules[0].Conditions)
+	})
+
+	t.Run("Dry run without a matching rule", func(t *testing.T) {
+		status, resp := dryRun(t, "rules", `{}`)
+		require.Equal(t, http.StatusOK, status)
+		assert.Nil(t, resp.MatchedRule)
+		assert.Nil(t, resp.URL)
+		assert.Len(t, resp.Rules, 2)
+	})
+
+	t.Run("Dry run for an unknown short code returns 404", func(t *testing.T) {
+		status, _ := dryRun(t, "missing", `{}`)
+		assert.Equal(t, http.StatusNotFound, status)
 	})
 }
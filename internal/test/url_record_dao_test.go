@@ -0,0 +1,264 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"tiny-bitly/internal/apperrors"
+	"tiny-bitly/internal/config"
+	"tiny-bitly/internal/dao"
+	"tiny-bitly/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Verifies that every URLRecordDAO implementation reclaims short codes and
+// purges expired records the same way. The database implementation only runs when POSTGRES_DB points at a
+// migrated database.
+func TestURLRecordDAO_ExpiredRecords(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		testExpiredRecords(t, dao.NewMemoryDAO().URLRecordDAO)
+	})
+
+	t.Run("Database", func(t *testing.T) {
+		cfg, err := config.LoadConfig()
+		if err != nil || cfg.PostgresDB == "" {
+			t.Skip("POSTGRES_DB is not set")
+		}
+		appDAO, err := dao.NewDatabaseDAO(cfg.PostgresPort, cfg.PostgresDB, cfg.PostgresUser, cfg.PostgresPassword)
+		require.NoError(t, err)
+		testExpiredRecords(t, appDAO.URLRecordDAO)
+	})
+}
+
+func testExpiredRecords(t *testing.T, urlRecordDAO dao.URLRecordDAO) {
+	ctx := context.Background()
+
+	// Short codes must not collide with earlier runs against the same database.
+	runID := time.Now().UnixNano()
+	newRecord := func(name string, expiresAt time.Time) model.URLRecord {
+		return model.URLRecord{
+			OriginalURL: "https://example.com/" + name,
+			ShortCode:   fmt.Sprintf("reclaim-%s-%d", name, runID),
+			ExpiresAt:   expiresAt,
+		}
+	}
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	t.Run("ActiveShortCodeIsNotReclaimed", func(t *testing.T) {
+		urlRecord := newRecord("active", future)
+		original, err := urlRecordDAO.Create(ctx, urlRecord)
+		require.NoError(t, err)
+
+		_, err = urlRecordDAO.Create(ctx, urlRecord)
+		assert.ErrorIs(t, err, apperrors.ErrShortCodeAlreadyInUse)
+
+		current, err := urlRecordDAO.GetByShortCode(ctx, "", urlRecord.ShortCode)
+		require.NoError(t, err)
+		require.NotNil(t, current)
+		assert.Equal(t, original.ID, current.ID)
+	})
+
+	t.Run("ExpiredShortCodeIsReclaimed", func(t *testing.T) {
+		urlRecord := newRecord("expired", past)
+		original, err := urlRecordDAO.Create(ctx, urlRecord)
+		require.NoError(t, err)
+
+		urlRecord.ExpiresAt = future
+		urlRecord.OriginalURL = "https://example.com/expired-reclaimed"
+		reclaimed, err := urlRecordDAO.Create(ctx, urlRecord)
+		require.NoError(t, err)
+		assert.NotEqual(t, original.ID, reclaimed.ID)
+
+		current, err := urlRecordDAO.GetByShortCode(ctx, "", urlRecord.ShortCode)
+		require.NoError(t, err)
+		require.NotNil(t, current)
+		assert.Equal(t, reclaimed.ID, current.ID)
+		assert.Equal(t, "https://example.com/expired-reclaimed", current.OriginalURL)
+	})
+
+	t.Run("DeletedShortCodeIsReclaimed", func(t *testing.T) {
+		urlRecord := newRecord("deleted", future)
+		original, err := urlRecordDAO.Create(ctx, urlRecord)
+		require.NoError(t, err)
+		require.NoError(t, urlRecordDAO.Delete(ctx, "", urlRecord.ShortCode, nil))
+
+		reclaimed, err := urlRecordDAO.Create(ctx, urlRecord)
+		require.NoError(t, err)
+		assert.NotEqual(t, original.ID, reclaimed.ID)
+	})
+
+	t.Run("ExhaustedShortCodeIsReclaimed", func(t *testing.T) {
+		maxClicks := 1
+		urlRecord := newRecord("exhausted", future)
+		urlRecord.MaxClicks = &maxClicks
+		urlRecord.RemainingClicks = &maxClicks
+		original, err := urlRecordDAO.Create(ctx, urlRecord)
+		require.NoError(t, err)
+
+		consumed, err := urlRecordDAO.ConsumeClick(ctx, original)
+		require.NoError(t, err)
+		require.True(t, consumed)
+
+		reclaimed, err := urlRecordDAO.Create(ctx, newRecord("exhausted", future))
+		require.NoError(t, err)
+		assert.NotEqual(t, original.ID, reclaimed.ID)
+	})
+
+	t.Run("ExpiredDeletedAndExhaustedRecordsArePurged", func(t *testing.T) {
+		maxClicks := 1
+		expiredRecord := newRecord("purge-expired", past)
+		deletedRecord := newRecord("purge-deleted", future)
+		exhaustedRecord := newRecord("purge-exhausted", future)
+		exhaustedRecord.MaxClicks = &maxClicks
+		exhaustedRecord.RemainingClicks = &maxClicks
+		activeRecord := newRecord("purge-active", future)
+		activeRecord.MaxClicks = &maxClicks
+		activeRecord.RemainingClicks = &maxClicks
+		for _, urlRecord := range []model.URLRecord{expiredRecord, deletedRecord, exhaustedRecord, activeRecord} {
+			_, err := urlRecordDAO.Create(ctx, urlRecord)
+			require.NoError(t, err)
+		}
+		require.NoError(t, urlRecordDAO.Delete(ctx, "", deletedRecord.ShortCode, nil))
+		exhaustedEntity, err := urlRecordDAO.GetByShortCode(ctx, "", exhaustedRecord.ShortCode)
+		require.NoError(t, err)
+		consumed, err := urlRecordDAO.ConsumeClick(ctx, exhaustedEntity)
+		require.NoError(t, err)
+		require.True(t, consumed)
+
+		// Purge in small batches until nothing is left, since the database may
+		// hold expired records from elsewhere.
+		purgedShortCodes := make(map[string]bool)
+		for {
+			purged, err := urlRecordDAO.PurgeExpired(ctx, time.Now(), 10, true)
+			require.NoError(t, err)
+			for _, entity := range purged {
+				purgedShortCodes[entity.ShortCode] = true
+			}
+			if len(purged) < 10 {
+				break
+			}
+		}
+		assert.True(t, purgedShortCodes[expiredRecord.ShortCode])
+		assert.True(t, purgedShortCodes[deletedRecord.ShortCode])
+		assert.True(t, purgedShortCodes[exhaustedRecord.ShortCode])
+		assert.False(t, purgedShortCodes[activeRecord.ShortCode])
+
+		// Purged records are gone, not merely expired.
+		entity, err := urlRecordDAO.GetByShortCodeIncludingInactive(ctx, "", expiredRecord.ShortCode, nil)
+		require.NoError(t, err)
+		assert.Nil(t, entity)
+		entity, err = urlRecordDAO.GetByShortCode(ctx, "", activeRecord.ShortCode)
+		require.NoError(t, err)
+		assert.NotNil(t, entity)
+	})
+
+	t.Run("BatchReclaimsOnlyInactiveShortCodes", func(t *testing.T) {
+		expiredRecord := newRecord("batch-expired", past)
+		activeRecord := newRecord("batch-active", future)
+		_, err := urlRecordDAO.Create(ctx, expiredRecord)
+		require.NoError(t, err)
+		_, err = urlRecordDAO.Create(ctx, activeRecord)
+		require.NoError(t, err)
+
+		expiredRecord.ExpiresAt = future
+		entities, err := urlRecordDAO.CreateBatch(ctx, []model.URLRecord{expiredRecord, activeRecord})
+		require.NoError(t, err)
+		require.Len(t, entities, 2)
+		require.NotNil(t, entities[0])
+		assert.Equal(t, expiredRecord.ShortCode, entities[0].ShortCode)
+		assert.Nil(t, entities[1])
+	})
+}
+
+// Verifies that every URLRecordDAO implementation counts the active records of
+// an owner the same way. The database implementation only runs when
+// POSTGRES_DB points at a migrated database.
+func TestURLRecordDAO_ActiveLinks(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		testActiveLinks(t, dao.NewMemoryDAO().URLRecordDAO)
+	})
+
+	t.Run("Database", func(t *testing.T) {
+		cfg, err := config.LoadConfig()
+		if err != nil || cfg.PostgresDB == "" {
+			t.Skip("POSTGRES_DB is not set")
+		}
+		appDAO, err := dao.NewDatabaseDAO(cfg.PostgresPort, cfg.PostgresDB, cfg.PostgresUser, cfg.PostgresPassword)
+		require.NoError(t, err)
+		testActiveLinks(t, appDAO.URLRecordDAO)
+	})
+}
+
+func testActiveLinks(t *testing.T, urlRecordDAO dao.URLRecordDAO) {
+	ctx := context.Background()
+
+	// Owners and short codes must not collide with earlier runs against the
+	// same database.
+	runID := time.Now().UnixNano()
+	newRecord := func(name string, ownerID *string, expiresAt time.Time) model.URLRecord {
+		return model.URLRecord{
+			OriginalURL: "https://example.com/" + name,
+			ShortCode:   fmt.Sprintf("active-%s-%d", name, runID),
+			ExpiresAt:   expiresAt,
+			OwnerID:     ownerID,
+		}
+	}
+	reserve := func(ownerID string, limit int) bool {
+		reserved, err := urlRecordDAO.ReserveActiveLinks(ctx, ownerID, 1, limit)
+		require.NoError(t, err)
+		return reserved
+	}
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	t.Run("InactiveRecordsAreUncounted", func(t *testing.T) {
+		ownerID := fmt.Sprintf("owner-%d", runID)
+
+		require.True(t, reserve(ownerID, 2))
+		activeRecord := newRecord("active", &ownerID, future)
+		_, err := urlRecordDAO.Create(ctx, activeRecord)
+		require.NoError(t, err)
+		require.True(t, reserve(ownerID, 2))
+		_, err = urlRecordDAO.Create(ctx, newRecord("expired", &ownerID, past))
+		require.NoError(t, err)
+
+		// The expired record stops being counted once the owner reaches the
+		// limit.
+		require.True(t, reserve(ownerID, 2))
+		_, err = urlRecordDAO.Create(ctx, newRecord("other", &ownerID, future))
+		require.NoError(t, err)
+		assert.False(t, reserve(ownerID, 2))
+
+		// Deleting a record frees its place once.
+		require.NoError(t, urlRecordDAO.Delete(ctx, "", activeRecord.ShortCode, &ownerID))
+		for {
+			purged, err := urlRecordDAO.PurgeExpired(ctx, time.Now(), 10, true)
+			require.NoError(t, err)
+			if len(purged) < 10 {
+				break
+			}
+		}
+		assert.True(t, reserve(ownerID, 2))
+		assert.False(t, reserve(ownerID, 2))
+
+		require.NoError(t, urlRecordDAO.ReleaseActiveLinks(ctx, ownerID, 1))
+		assert.True(t, reserve(ownerID, 2))
+	})
+
+	t.Run("ReclaimedRecordIsUncounted", func(t *testing.T) {
+		ownerID := fmt.Sprintf("reclaimed-owner-%d", runID)
+
+		require.True(t, reserve(ownerID, 1))
+		_, err := urlRecordDAO.Create(ctx, newRecord("reclaimed", &ownerID, past))
+		require.NoError(t, err)
+
+		_, err = urlRecordDAO.Create(ctx, newRecord("reclaimed", nil, future))
+		require.NoError(t, err)
+		assert.True(t, reserve(ownerID, 1))
+	})
+}